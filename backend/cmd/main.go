@@ -24,6 +24,7 @@ import (
 	billingsvc "github.com/viktorHadz/goInvoice26/internal/service/billing"
 	"github.com/viktorHadz/goInvoice26/internal/service/logo"
 	"github.com/viktorHadz/goInvoice26/internal/service/productimport"
+	"github.com/viktorHadz/goInvoice26/internal/service/recurring"
 	"github.com/viktorHadz/goInvoice26/internal/service/storage"
 	"github.com/viktorHadz/goInvoice26/internal/service/workspace"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/accessTx"
@@ -128,6 +129,9 @@ func main() {
 		PromoRedemptionRetentionDays: cfg.PromoRedemptionRetentionDays,
 	})
 
+	// Recurring invoice schedules
+	go recurring.NewWorker(dbConn, recurring.DefaultInterval).Start(ctx)
+
 	logger.Info("init",
 		"env", cfg.Env,
 		"db", cfg.DBPath,
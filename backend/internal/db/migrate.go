@@ -28,6 +28,9 @@ func Migrate(ctx context.Context, db *sql.DB) error {
 		return fmt.Errorf("enable foreign_keys in tx: %w", err)
 	}
 
+	if err := dropStaleViews(ctx, tx); err != nil {
+		return err
+	}
 	if err := ensureBaseSchema(ctx, tx); err != nil {
 		return err
 	}
@@ -46,12 +49,57 @@ func Migrate(ctx context.Context, db *sql.DB) error {
 	if err := ensureProductsAccountIDColumn(ctx, tx); err != nil {
 		return err
 	}
+	if err := ensureProductsUnitColumn(ctx, tx); err != nil {
+		return err
+	}
 	if err := ensureInvoiceSupplyDateColumn(ctx, tx); err != nil {
 		return err
 	}
+	if err := ensureLineItemColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureCreditNoteItemColumns(ctx, tx); err != nil {
+		return err
+	}
 	if err := ensurePaymentReceiptNumberColumn(ctx, tx); err != nil {
 		return err
 	}
+	if err := ensurePaymentMethodColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensurePaymentClientPaymentColumn(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensurePaymentFromClientCreditColumn(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureInvoiceVoidColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureInvoiceCurrencyColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureClientPaymentCurrencyColumn(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureQuoteCurrencyColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureInvoiceNumberLabelColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureInvoiceTombstoneNumberColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureVATTreatmentColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureInvoiceDeductionColumns(ctx, tx); err != nil {
+		return err
+	}
+	if err := ensureTaxRateColumns(ctx, tx); err != nil {
+		return err
+	}
 	if err := authTx.EnsureUsersGoogleSubColumn(ctx, tx); err != nil {
 		return err
 	}
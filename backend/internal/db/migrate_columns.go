@@ -101,6 +101,87 @@ func ensureInvoiceSupplyDateColumn(ctx context.Context, tx *sql.Tx) error {
 	return nil
 }
 
+// ensureLineItemColumns adds per-line VAT, discount, fractional quantity and
+// unit columns to invoice and quote items on databases created before those features.
+func ensureLineItemColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{name: "vat_rate", def: "INTEGER CHECK (vat_rate IS NULL OR vat_rate BETWEEN 0 AND 10000)"},
+		{name: "discount_type", def: "TEXT NOT NULL DEFAULT 'none' CHECK (discount_type IN ('none','percent','fixed'))"},
+		{name: "discount_rate", def: "INTEGER NOT NULL DEFAULT 0 CHECK (discount_rate BETWEEN 0 AND 10000)"},
+		{name: "discount_minor", def: "INTEGER NOT NULL DEFAULT 0 CHECK (discount_minor >= 0)"},
+		{name: "quantity_milli", def: "INTEGER CHECK (quantity_milli IS NULL OR quantity_milli > 0)"},
+		{name: "unit", def: "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, table := range []string{"invoice_items", "quote_items"} {
+		for _, col := range columns {
+			hasColumn, err := tableHasColumn(ctx, tx, table, col.name)
+			if err != nil {
+				return err
+			}
+			if hasColumn {
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, `ALTER TABLE `+table+` ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+				return fmt.Errorf("add %s.%s: %w", table, col.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureCreditNoteItemColumns adds fractional quantities and per-line VAT
+// rates to credit note lines. Older lines keep a whole quantity and the
+// credit note's VAT rate.
+func ensureCreditNoteItemColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{name: "quantity_milli", def: "INTEGER CHECK (quantity_milli IS NULL OR quantity_milli > 0)"},
+		{name: "vat_rate", def: "INTEGER CHECK (vat_rate IS NULL OR vat_rate BETWEEN 0 AND 10000)"},
+	}
+
+	for _, col := range columns {
+		hasColumn, err := tableHasColumn(ctx, tx, "credit_note_items", col.name)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE credit_note_items ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+			return fmt.Errorf("add credit_note_items.%s: %w", col.name, err)
+		}
+	}
+
+	return nil
+}
+
+func ensureProductsUnitColumn(ctx context.Context, tx *sql.Tx) error {
+	hasColumn, err := tableHasColumn(ctx, tx, "products", "unit")
+	if err != nil {
+		return err
+	}
+
+	if !hasColumn {
+		if _, err := tx.ExecContext(ctx, `
+			ALTER TABLE products
+			ADD COLUMN unit TEXT NOT NULL DEFAULT '';
+		`); err != nil {
+			return fmt.Errorf("add products.unit: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func ensurePaymentReceiptNumberColumn(ctx context.Context, tx *sql.Tx) error {
 	hasColumn, err := tableHasColumn(ctx, tx, "payments", "receipt_no")
 	if err != nil {
@@ -138,9 +219,16 @@ func ensurePaymentReceiptNumberColumn(ctx context.Context, tx *sql.Tx) error {
 		return fmt.Errorf("drop legacy payments receipt number index: %w", err)
 	}
 
+	// Deposit receipts are numbered separately from payment receipts.
+	if _, err := tx.ExecContext(ctx, `
+		DROP INDEX IF EXISTS idx_payments_revision_receipt_no;
+	`); err != nil {
+		return fmt.Errorf("drop legacy payments revision receipt number index: %w", err)
+	}
+
 	if _, err := tx.ExecContext(ctx, `
-		CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_revision_receipt_no
-		ON payments(applied_in_revision_id, receipt_no);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_revision_type_receipt_no
+		ON payments(applied_in_revision_id, payment_type, receipt_no);
 	`); err != nil {
 		return fmt.Errorf("ensure payments receipt number index: %w", err)
 	}
@@ -148,24 +236,394 @@ func ensurePaymentReceiptNumberColumn(ctx context.Context, tx *sql.Tx) error {
 	return nil
 }
 
+// ensurePaymentMethodColumns adds the payment method and bank reference to
+// receipts saved before they were recorded.
+func ensurePaymentMethodColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{name: "payment_method", def: "TEXT CHECK (payment_method IS NULL OR payment_method IN ('bank_transfer','card','cash','cheque','direct_debit','other'))"},
+		{name: "reference", def: "TEXT"},
+	}
+
+	for _, col := range columns {
+		hasColumn, err := tableHasColumn(ctx, tx, "payments", col.name)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE payments ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+			return fmt.Errorf("add payments.%s: %w", col.name, err)
+		}
+	}
+
+	return nil
+}
+
+func ensurePaymentClientPaymentColumn(ctx context.Context, tx *sql.Tx) error {
+	hasColumn, err := tableHasColumn(ctx, tx, "payments", "client_payment_id")
+	if err != nil {
+		return err
+	}
+	if hasColumn {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE payments
+		ADD COLUMN client_payment_id INTEGER REFERENCES client_payments(id) ON DELETE SET NULL;
+	`); err != nil {
+		return fmt.Errorf("add payments.client_payment_id: %w", err)
+	}
+
+	return nil
+}
+
+func ensurePaymentFromClientCreditColumn(ctx context.Context, tx *sql.Tx) error {
+	hasColumn, err := tableHasColumn(ctx, tx, "payments", "from_client_credit")
+	if err != nil {
+		return err
+	}
+	if hasColumn {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE payments
+		ADD COLUMN from_client_credit INTEGER NOT NULL DEFAULT 0 CHECK (from_client_credit IN (0,1));
+	`); err != nil {
+		return fmt.Errorf("add payments.from_client_credit: %w", err)
+	}
+
+	return nil
+}
+
+// ensureInvoiceVoidColumns adds who voided an invoice, when and why to
+// invoices saved before voids were recorded.
+func ensureInvoiceVoidColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{name: "void_reason", def: "TEXT"},
+		{name: "voided_at", def: "TEXT"},
+		{name: "voided_by_user_id", def: "INTEGER"},
+		{name: "voided_by_email", def: "TEXT"},
+	}
+
+	for _, col := range columns {
+		hasColumn, err := tableHasColumn(ctx, tx, "invoices", col.name)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE invoices ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+			return fmt.Errorf("add invoices.%s: %w", col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureInvoiceNumberLabelColumns adds the template number columns. Invoices
+// created before number templates keep a NULL label.
+func ensureInvoiceNumberLabelColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{name: "number_label", def: "TEXT"},
+		{name: "number_period", def: "TEXT"},
+		{name: "number_seq", def: "INTEGER"},
+	}
+
+	for _, col := range columns {
+		hasColumn, err := tableHasColumn(ctx, tx, "invoices", col.name)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE invoices ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+			return fmt.Errorf("add invoices.%s: %w", col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureInvoiceTombstoneNumberColumns adds the template sequence of deleted
+// invoices, so the gap report can place them in their period.
+func ensureInvoiceTombstoneNumberColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{name: "number_period", def: "TEXT"},
+		{name: "number_seq", def: "INTEGER"},
+	}
+
+	for _, col := range columns {
+		hasColumn, err := tableHasColumn(ctx, tx, "invoice_tombstones", col.name)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE invoice_tombstones ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+			return fmt.Errorf("add invoice_tombstones.%s: %w", col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureVATTreatmentColumns adds the client VAT number and the VAT treatment
+// of revisions and quotes. Earlier ones all charged VAT normally.
+func ensureVATTreatmentColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		table string
+		name  string
+		def   string
+	}{
+		{table: "clients", name: "vat_number", def: "TEXT"},
+		{table: "invoice_revisions", name: "client_vat_number", def: "TEXT NOT NULL DEFAULT ''"},
+		{table: "invoice_revisions", name: "vat_treatment", def: "TEXT NOT NULL DEFAULT 'standard' CHECK (vat_treatment IN ('standard','reverse_charge','exempt','outside_scope'))"},
+		{table: "quotes", name: "client_vat_number", def: "TEXT NOT NULL DEFAULT ''"},
+		{table: "quotes", name: "vat_treatment", def: "TEXT NOT NULL DEFAULT 'standard' CHECK (vat_treatment IN ('standard','reverse_charge','exempt','outside_scope'))"},
+	}
+
+	for _, col := range columns {
+		hasColumn, err := tableHasColumn(ctx, tx, col.table, col.name)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE `+col.table+` ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+			return fmt.Errorf("add %s.%s: %w", col.table, col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureInvoiceDeductionColumns adds the CIS / withholding deduction to
+// invoice revisions and quotes. Earlier ones had none.
+func ensureInvoiceDeductionColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		name string
+		def  string
+	}{
+		{name: "deduction_rate", def: "INTEGER NOT NULL DEFAULT 0 CHECK (deduction_rate BETWEEN 0 AND 10000)"},
+		{name: "deduction_line_types", def: "TEXT NOT NULL DEFAULT ''"},
+		{name: "deduction_minor", def: "INTEGER NOT NULL DEFAULT 0 CHECK (deduction_minor >= 0)"},
+	}
+
+	for _, table := range []string{"invoice_revisions", "quotes"} {
+		for _, col := range columns {
+			hasColumn, err := tableHasColumn(ctx, tx, table, col.name)
+			if err != nil {
+				return err
+			}
+			if hasColumn {
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, `ALTER TABLE `+table+` ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+				return fmt.Errorf("add %s.%s: %w", table, col.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ensureTaxRateColumns links products and invoice revisions to the workspace
+// tax rate registry. Rows saved before then keep their raw VAT rate.
+func ensureTaxRateColumns(ctx context.Context, tx *sql.Tx) error {
+	columns := []struct {
+		table string
+		name  string
+		def   string
+	}{
+		{table: "products", name: "tax_rate_id", def: "INTEGER REFERENCES tax_rates(id) ON DELETE SET NULL"},
+		{table: "invoice_revisions", name: "tax_rate_id", def: "INTEGER REFERENCES tax_rates(id) ON DELETE SET NULL"},
+		{table: "invoice_revisions", name: "tax_rate_name", def: "TEXT NOT NULL DEFAULT ''"},
+	}
+
+	for _, col := range columns {
+		hasColumn, err := tableHasColumn(ctx, tx, col.table, col.name)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `ALTER TABLE `+col.table+` ADD COLUMN `+col.name+` `+col.def+`;`); err != nil {
+			return fmt.Errorf("add %s.%s: %w", col.table, col.name, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureInvoiceCurrencyColumns adds the per-revision currency and exchange
+// rate. Revisions saved before then were issued in the workspace currency, so
+// they take it at a rate of 1.
+func ensureInvoiceCurrencyColumns(ctx context.Context, tx *sql.Tx) error {
+	hasRate, err := tableHasColumn(ctx, tx, "invoice_revisions", "exchange_rate_micro")
+	if err != nil {
+		return err
+	}
+	if !hasRate {
+		if _, err := tx.ExecContext(ctx, `
+			ALTER TABLE invoice_revisions
+			ADD COLUMN exchange_rate_micro INTEGER NOT NULL DEFAULT 1000000 CHECK (exchange_rate_micro > 0);
+		`); err != nil {
+			return fmt.Errorf("add invoice_revisions.exchange_rate_micro: %w", err)
+		}
+	}
+
+	hasCurrency, err := tableHasColumn(ctx, tx, "invoice_revisions", "currency")
+	if err != nil {
+		return err
+	}
+	if hasCurrency {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE invoice_revisions
+		ADD COLUMN currency TEXT NOT NULL DEFAULT 'GBP';
+	`); err != nil {
+		return fmt.Errorf("add invoice_revisions.currency: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoice_revisions
+		SET currency = COALESCE((
+			SELECT s.currency
+			FROM invoices i
+			JOIN account_settings s
+				ON s.account_id = i.account_id
+			WHERE i.id = invoice_revisions.invoice_id
+		), 'GBP');
+	`); err != nil {
+		return fmt.Errorf("backfill invoice_revisions.currency: %w", err)
+	}
+
+	return nil
+}
+
+// ensureQuoteCurrencyColumns adds the quote currency and exchange rate.
+// Quotes saved before then were priced in the workspace currency.
+func ensureQuoteCurrencyColumns(ctx context.Context, tx *sql.Tx) error {
+	hasRate, err := tableHasColumn(ctx, tx, "quotes", "exchange_rate_micro")
+	if err != nil {
+		return err
+	}
+	if !hasRate {
+		if _, err := tx.ExecContext(ctx, `
+			ALTER TABLE quotes
+			ADD COLUMN exchange_rate_micro INTEGER NOT NULL DEFAULT 1000000 CHECK (exchange_rate_micro > 0);
+		`); err != nil {
+			return fmt.Errorf("add quotes.exchange_rate_micro: %w", err)
+		}
+	}
+
+	hasCurrency, err := tableHasColumn(ctx, tx, "quotes", "currency")
+	if err != nil {
+		return err
+	}
+	if hasCurrency {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE quotes
+		ADD COLUMN currency TEXT NOT NULL DEFAULT 'GBP';
+	`); err != nil {
+		return fmt.Errorf("add quotes.currency: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quotes
+		SET currency = COALESCE((
+			SELECT s.currency
+			FROM account_settings s
+			WHERE s.account_id = quotes.account_id
+		), 'GBP');
+	`); err != nil {
+		return fmt.Errorf("backfill quotes.currency: %w", err)
+	}
+
+	return nil
+}
+
+// ensureClientPaymentCurrencyColumn adds client_payments.currency. Payments
+// saved before it existed were taken in the workspace currency.
+func ensureClientPaymentCurrencyColumn(ctx context.Context, tx *sql.Tx) error {
+	hasCurrency, err := tableHasColumn(ctx, tx, "client_payments", "currency")
+	if err != nil {
+		return err
+	}
+	if hasCurrency {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE client_payments
+		ADD COLUMN currency TEXT NOT NULL DEFAULT 'GBP';
+	`); err != nil {
+		return fmt.Errorf("add client_payments.currency: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE client_payments
+		SET currency = COALESCE((
+			SELECT s.currency
+			FROM account_settings s
+			WHERE s.account_id = client_payments.account_id
+		), 'GBP');
+	`); err != nil {
+		return fmt.Errorf("backfill client_payments.currency: %w", err)
+	}
+
+	return nil
+}
+
 func reconcileInvoiceStatusesToSavedPayments(ctx context.Context, tx *sql.Tx) error {
 	if _, err := tx.ExecContext(ctx, `
-		WITH payment_totals AS (
+		WITH net_payment_totals AS (
 			SELECT
 				invoice_id,
-				COALESCE(SUM(amount_minor), 0) AS paid_minor
-			FROM payments
-			WHERE payment_type = 'payment'
+				SUM(paid_minor) AS paid_minor
+			FROM invoice_revision_paid
 			GROUP BY invoice_id
 		)
 		UPDATE invoices
 		SET status = CASE
-			WHEN status = 'paid' AND COALESCE((SELECT paid_minor FROM payment_totals pt WHERE pt.invoice_id = invoices.id), 0) < COALESCE((
+			WHEN status = 'paid' AND COALESCE((SELECT paid_minor FROM net_payment_totals pt WHERE pt.invoice_id = invoices.id), 0) < COALESCE((
 				SELECT total_minor
 				FROM invoice_revisions r
 				WHERE r.id = invoices.current_revision_id
 			), 0) THEN 'issued'
-			WHEN status = 'issued' AND COALESCE((SELECT paid_minor FROM payment_totals pt WHERE pt.invoice_id = invoices.id), 0) >= COALESCE((
+			WHEN status = 'issued' AND COALESCE((SELECT paid_minor FROM net_payment_totals pt WHERE pt.invoice_id = invoices.id), 0) >= COALESCE((
 				SELECT total_minor
 				FROM invoice_revisions r
 				WHERE r.id = invoices.current_revision_id
@@ -14,9 +14,11 @@ func ensurePostRebuildIndexes(ctx context.Context, db *sql.DB) error {
 		`CREATE INDEX IF NOT EXISTS idx_invoices_client_id ON invoices(client_id);`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_id_account_client ON invoices(id, account_id, client_id);`,
 		`CREATE INDEX IF NOT EXISTS idx_invoices_client_base ON invoices(account_id, client_id, base_number DESC);`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_account_number_label ON invoices(account_id, number_label) WHERE number_label IS NOT NULL;`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_revisions_id_invoice ON invoice_revisions(id, invoice_id);`,
 		`DROP INDEX IF EXISTS idx_payments_invoice_receipt_no;`,
-		`CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_revision_receipt_no ON payments(applied_in_revision_id, receipt_no);`,
+		`DROP INDEX IF EXISTS idx_payments_revision_receipt_no;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_payments_revision_type_receipt_no ON payments(applied_in_revision_id, payment_type, receipt_no);`,
 		`CREATE INDEX IF NOT EXISTS idx_products_account_client ON products(account_id, client_id);`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_products_account_client_id ON products(account_id, client_id, id);`,
 	}
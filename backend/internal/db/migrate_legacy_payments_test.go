@@ -22,6 +22,11 @@ const currentPaymentsTableSQL = `CREATE TABLE IF NOT EXISTS payments (
     ON DELETE SET NULL
     DEFERRABLE INITIALLY DEFERRED,
   label TEXT,
+  payment_method TEXT
+    CHECK (payment_method IS NULL OR payment_method IN ('bank_transfer','card','cash','cheque','direct_debit','other')),
+  reference TEXT,
+  client_payment_id INTEGER REFERENCES client_payments(id) ON DELETE SET NULL,
+  from_client_credit INTEGER NOT NULL DEFAULT 0 CHECK (from_client_credit IN (0,1)),
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
   FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE
 );`
@@ -82,6 +87,15 @@ func TestMigrateAddsReceiptNumbersToLegacyPaymentsTable(t *testing.T) {
 	if !hasColumn {
 		t.Fatal("expected payments.receipt_no to be added during migration")
 	}
+	for _, column := range []string{"payment_method", "reference", "client_payment_id", "from_client_credit"} {
+		hasColumn, err := dbTableHasColumn(ctx, conn, "payments", column)
+		if err != nil {
+			t.Fatalf("inspect payments columns: %v", err)
+		}
+		if !hasColumn {
+			t.Fatalf("expected payments.%s to be added during migration", column)
+		}
+	}
 
 	rows, err := conn.QueryContext(ctx, `
 		SELECT receipt_no
@@ -112,8 +126,11 @@ func TestMigrateAddsReceiptNumbersToLegacyPaymentsTable(t *testing.T) {
 		t.Fatalf("receipt numbers = %v, want [1 2]", receiptNos)
 	}
 
-	if !hasIndex(t, conn, "idx_payments_revision_receipt_no") {
-		t.Fatal("expected idx_payments_revision_receipt_no to exist after migration")
+	if !hasIndex(t, conn, "idx_payments_revision_type_receipt_no") {
+		t.Fatal("expected idx_payments_revision_type_receipt_no to exist after migration")
+	}
+	if hasIndex(t, conn, "idx_payments_revision_receipt_no") {
+		t.Fatal("expected idx_payments_revision_receipt_no to be dropped after migration")
 	}
 }
 
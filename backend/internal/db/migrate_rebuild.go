@@ -55,6 +55,7 @@ func ensureStrictProductsTable(ctx context.Context, db *sql.DB) error {
 				hourly_rate_minor INTEGER CHECK (hourly_rate_minor IS NULL OR hourly_rate_minor >= 0),
 				default_minutes_worked INTEGER
 					CHECK (default_minutes_worked IS NULL OR default_minutes_worked >= 0),
+				unit TEXT NOT NULL DEFAULT '',
 				client_id INTEGER NOT NULL,
 				created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
 				updated_at TEXT,
@@ -85,6 +86,7 @@ func ensureStrictProductsTable(ctx context.Context, db *sql.DB) error {
 				flat_price_minor,
 				hourly_rate_minor,
 				default_minutes_worked,
+				unit,
 				client_id,
 				created_at,
 				updated_at
@@ -98,6 +100,7 @@ func ensureStrictProductsTable(ctx context.Context, db *sql.DB) error {
 				flat_price_minor,
 				hourly_rate_minor,
 				default_minutes_worked,
+				unit,
 				client_id,
 				created_at,
 				updated_at
@@ -291,6 +294,13 @@ func migrateInvoicesToAccounts(ctx context.Context, db *sql.DB) error {
 				base_number INTEGER NOT NULL CHECK (base_number > 0),
 				status TEXT NOT NULL DEFAULT 'draft'
 					CHECK (status IN ('draft','issued','paid','void')),
+				void_reason TEXT,
+				voided_at TEXT,
+				voided_by_user_id INTEGER,
+				voided_by_email TEXT,
+				number_label TEXT,
+				number_period TEXT,
+				number_seq INTEGER,
 				created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
 				FOREIGN KEY (account_id, client_id) REFERENCES clients(account_id, id) ON DELETE RESTRICT,
 				UNIQUE (account_id, base_number),
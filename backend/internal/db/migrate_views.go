@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// schemaViews lists every view defined in schema.sql, dependents first. Views
+// hold no data and are created with IF NOT EXISTS, so they are dropped on each
+// migrate and the base schema recreates them from the current definitions.
+var schemaViews = []string{
+	"client_credit_balances",
+	"invoice_book_rows",
+	"invoice_revision_paid",
+	"invoice_current_items",
+	"invoice_revision_items",
+}
+
+func dropStaleViews(ctx context.Context, tx *sql.Tx) error {
+	for _, viewName := range schemaViews {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DROP VIEW IF EXISTS %s;`, viewName)); err != nil {
+			return fmt.Errorf("drop view %s: %w", viewName, err)
+		}
+	}
+	return nil
+}
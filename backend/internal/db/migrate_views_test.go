@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateRecreatesStaleViews(t *testing.T) {
+	ctx := context.Background()
+
+	conn, err := OpenDB(filepath.Join(t.TempDir(), "stale-views.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer conn.Close()
+
+	if err := Migrate(ctx, conn); err != nil {
+		t.Fatalf("first migrate: %v", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, `
+		DROP VIEW invoice_revision_paid;
+		CREATE VIEW invoice_revision_paid AS
+		SELECT r.id AS revision_id, r.invoice_id, 0 AS paid_minor
+		FROM invoice_revisions r;
+	`); err != nil {
+		t.Fatalf("replace view with stale definition: %v", err)
+	}
+
+	if err := Migrate(ctx, conn); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+
+	for viewName, want := range map[string]string{
+		"invoice_revision_paid": "deposit_paid_minor",
+		"invoice_current_items": "quantity_milli",
+	} {
+		var sqlText string
+		if err := conn.QueryRowContext(ctx, `
+			SELECT sql
+			FROM sqlite_master
+			WHERE type = 'view'
+			  AND name = ?;
+		`, viewName).Scan(&sqlText); err != nil {
+			t.Fatalf("load %s definition: %v", viewName, err)
+		}
+		if !strings.Contains(sqlText, want) {
+			t.Fatalf("%s definition = %q, want it to contain %q", viewName, sqlText, want)
+		}
+	}
+}
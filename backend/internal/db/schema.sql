@@ -102,8 +102,17 @@ CREATE TABLE IF NOT EXISTS account_settings (
   phone TEXT NOT NULL DEFAULT '',
   company_address TEXT NOT NULL DEFAULT '',
   invoice_prefix TEXT NOT NULL DEFAULT 'INV-',
+  quote_prefix TEXT NOT NULL DEFAULT 'QUO-',
+  invoice_number_template TEXT NOT NULL DEFAULT '',
   currency TEXT NOT NULL DEFAULT 'GBP',
   date_format TEXT NOT NULL DEFAULT 'dd/mm/yyyy',
+  timezone TEXT NOT NULL DEFAULT 'UTC',
+  late_interest_rate_bps INTEGER NOT NULL DEFAULT 800
+    CHECK (late_interest_rate_bps BETWEEN 0 AND 10000),
+  late_base_rate_bps INTEGER NOT NULL DEFAULT 0
+    CHECK (late_base_rate_bps BETWEEN 0 AND 10000),
+  late_compensation_tiers TEXT NOT NULL
+    DEFAULT '[{"fromMinor":0,"amountMinor":4000},{"fromMinor":100000,"amountMinor":7000},{"fromMinor":1000000,"amountMinor":10000}]',
   payment_terms TEXT NOT NULL DEFAULT 'Please make payment within 14 days.',
   payment_details TEXT NOT NULL DEFAULT '',
   notes_footer TEXT NOT NULL DEFAULT '',
@@ -120,11 +129,29 @@ CREATE TABLE IF NOT EXISTS clients (
   company_name TEXT,
   address TEXT,
   email TEXT,
+  vat_number TEXT,
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
   updated_at TEXT,
   UNIQUE (account_id, id)
 );
 
+CREATE TABLE IF NOT EXISTS tax_rates (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL DEFAULT 1 REFERENCES accounts(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  rate_bps INTEGER NOT NULL CHECK (rate_bps BETWEEN 0 AND 10000),
+  effective_from TEXT,
+  effective_to TEXT,
+  is_default INTEGER NOT NULL DEFAULT 0 CHECK (is_default IN (0, 1)),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  updated_at TEXT,
+  UNIQUE (account_id, name),
+  CHECK (effective_from IS NULL OR effective_to IS NULL OR effective_to >= effective_from)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tax_rates_account_default
+  ON tax_rates(account_id) WHERE is_default = 1;
+
 CREATE TABLE IF NOT EXISTS products (
   id INTEGER PRIMARY KEY,
   account_id INTEGER NOT NULL DEFAULT 1 REFERENCES accounts(id) ON DELETE CASCADE,
@@ -134,6 +161,8 @@ CREATE TABLE IF NOT EXISTS products (
   flat_price_minor INTEGER CHECK (flat_price_minor IS NULL OR flat_price_minor >= 0),
   hourly_rate_minor INTEGER CHECK (hourly_rate_minor IS NULL OR hourly_rate_minor >= 0),
   default_minutes_worked INTEGER CHECK (default_minutes_worked IS NULL OR default_minutes_worked >= 0),
+  unit TEXT NOT NULL DEFAULT '',
+  tax_rate_id INTEGER REFERENCES tax_rates(id) ON DELETE SET NULL,
   client_id INTEGER NOT NULL,
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
   updated_at TEXT,
@@ -161,6 +190,15 @@ CREATE TABLE IF NOT EXISTS invoices (
   base_number INTEGER NOT NULL CHECK (base_number > 0),
   status TEXT NOT NULL DEFAULT 'draft'
     CHECK (status IN ('draft','issued','paid','void')),
+  void_reason TEXT,
+  voided_at TEXT,
+  voided_by_user_id INTEGER,
+  voided_by_email TEXT,
+  -- number_label is the number rendered from the account's number template
+  -- when the invoice was created; NULL keeps the prefix and base number.
+  number_label TEXT,
+  number_period TEXT,
+  number_seq INTEGER,
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
   FOREIGN KEY (account_id, client_id) REFERENCES clients(account_id, id) ON DELETE RESTRICT,
   UNIQUE (account_id, base_number),
@@ -174,6 +212,15 @@ CREATE TABLE IF NOT EXISTS invoice_number_seq (
 
 INSERT OR IGNORE INTO invoice_number_seq (account_id, next_base_number) VALUES (1, 1);
 
+-- invoice_number_periods holds the template sequence of each period, e.g.
+-- '2026' for a template that restarts yearly or '' for one that never does.
+CREATE TABLE IF NOT EXISTS invoice_number_periods (
+  account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  period TEXT NOT NULL,
+  next_seq INTEGER NOT NULL DEFAULT 1 CHECK (next_seq > 0),
+  PRIMARY KEY (account_id, period)
+);
+
 CREATE TABLE IF NOT EXISTS invoice_revisions (
   id INTEGER PRIMARY KEY,
   invoice_id INTEGER NOT NULL,
@@ -186,8 +233,17 @@ CREATE TABLE IF NOT EXISTS invoice_revisions (
   client_company_name TEXT NOT NULL DEFAULT '',
   client_address TEXT NOT NULL DEFAULT '',
   client_email TEXT NOT NULL DEFAULT '',
+  client_vat_number TEXT NOT NULL DEFAULT '',
   note TEXT,
+  currency TEXT NOT NULL DEFAULT 'GBP',
+  exchange_rate_micro INTEGER NOT NULL DEFAULT 1000000 CHECK (exchange_rate_micro > 0),
+  vat_treatment TEXT NOT NULL DEFAULT 'standard'
+    CHECK (vat_treatment IN ('standard','reverse_charge','exempt','outside_scope')),
   vat_rate INTEGER NOT NULL DEFAULT 2000 CHECK (vat_rate BETWEEN 0 AND 10000),
+  -- Registry rate the VAT rate came from; the name is a snapshot so renaming
+  -- or deleting the rate leaves saved revisions alone.
+  tax_rate_id INTEGER REFERENCES tax_rates(id) ON DELETE SET NULL,
+  tax_rate_name TEXT NOT NULL DEFAULT '',
   discount_type TEXT NOT NULL DEFAULT 'none'
     CHECK (discount_type IN ('none','percent','fixed')),
   discount_rate INTEGER NOT NULL DEFAULT 0 CHECK (discount_rate BETWEEN 0 AND 10000),
@@ -196,6 +252,11 @@ CREATE TABLE IF NOT EXISTS invoice_revisions (
     CHECK (deposit_type IN ('none','percent','fixed')),
   deposit_rate INTEGER NOT NULL DEFAULT 0 CHECK (deposit_rate BETWEEN 0 AND 10000),
   deposit_minor INTEGER NOT NULL DEFAULT 0 CHECK (deposit_minor >= 0),
+  -- Withheld by the client (CIS, withholding tax); comma-separated line types,
+  -- empty for every line.
+  deduction_rate INTEGER NOT NULL DEFAULT 0 CHECK (deduction_rate BETWEEN 0 AND 10000),
+  deduction_line_types TEXT NOT NULL DEFAULT '',
+  deduction_minor INTEGER NOT NULL DEFAULT 0 CHECK (deduction_minor >= 0),
   subtotal_minor INTEGER NOT NULL CHECK (subtotal_minor >= 0),
   vat_amount_minor INTEGER NOT NULL CHECK (vat_amount_minor >= 0),
   total_minor INTEGER NOT NULL CHECK (total_minor >= 0),
@@ -225,10 +286,17 @@ CREATE TABLE IF NOT EXISTS invoice_items (
   pricing_mode TEXT NOT NULL DEFAULT 'flat'
     CHECK (pricing_mode IN ('flat','hourly')),
   quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+  quantity_milli INTEGER CHECK (quantity_milli IS NULL OR quantity_milli > 0),
+  unit TEXT NOT NULL DEFAULT '',
   unit_price_minor INTEGER NOT NULL CHECK (unit_price_minor >= 0),
   line_total_minor INTEGER NOT NULL DEFAULT 0 CHECK (line_total_minor >= 0),
   minutes_worked INTEGER CHECK (minutes_worked IS NULL OR minutes_worked >= 0),
   sort_order INTEGER NOT NULL DEFAULT 1 CHECK (sort_order >= 1),
+  vat_rate INTEGER CHECK (vat_rate IS NULL OR vat_rate BETWEEN 0 AND 10000),
+  discount_type TEXT NOT NULL DEFAULT 'none'
+    CHECK (discount_type IN ('none','percent','fixed')),
+  discount_rate INTEGER NOT NULL DEFAULT 0 CHECK (discount_rate BETWEEN 0 AND 10000),
+  discount_minor INTEGER NOT NULL DEFAULT 0 CHECK (discount_minor >= 0),
   FOREIGN KEY (invoice_revision_id) REFERENCES invoice_revisions(id) ON DELETE CASCADE,
   FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL,
   UNIQUE (invoice_revision_id, sort_order),
@@ -238,6 +306,30 @@ CREATE TABLE IF NOT EXISTS invoice_items (
   )
 );
 
+CREATE TABLE IF NOT EXISTS invoice_vat_breakdown (
+  invoice_revision_id INTEGER NOT NULL,
+  vat_rate INTEGER NOT NULL CHECK (vat_rate BETWEEN 0 AND 10000),
+  net_minor INTEGER NOT NULL CHECK (net_minor >= 0),
+  vat_minor INTEGER NOT NULL CHECK (vat_minor >= 0),
+  PRIMARY KEY (invoice_revision_id, vat_rate),
+  FOREIGN KEY (invoice_revision_id) REFERENCES invoice_revisions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS client_payments (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  client_id INTEGER NOT NULL,
+  amount_minor INTEGER NOT NULL CHECK (amount_minor > 0),
+  payment_date TEXT NOT NULL,
+  label TEXT,
+  payment_method TEXT
+    CHECK (payment_method IS NULL OR payment_method IN ('bank_transfer','card','cash','cheque','direct_debit','other')),
+  reference TEXT,
+  currency TEXT NOT NULL DEFAULT 'GBP',
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  FOREIGN KEY (account_id, client_id) REFERENCES clients(account_id, id) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS payments (
   id INTEGER PRIMARY KEY,
   invoice_id INTEGER NOT NULL,
@@ -251,10 +343,315 @@ CREATE TABLE IF NOT EXISTS payments (
     ON DELETE SET NULL
     DEFERRABLE INITIALLY DEFERRED,
   label TEXT,
+  payment_method TEXT
+    CHECK (payment_method IS NULL OR payment_method IN ('bank_transfer','card','cash','cheque','direct_debit','other')),
+  reference TEXT,
+  client_payment_id INTEGER REFERENCES client_payments(id) ON DELETE SET NULL,
+  from_client_credit INTEGER NOT NULL DEFAULT 0 CHECK (from_client_credit IN (0,1)),
   created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
   FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS payment_refunds (
+  id INTEGER PRIMARY KEY,
+  invoice_id INTEGER NOT NULL,
+  payment_id INTEGER NOT NULL,
+  applied_in_revision_id INTEGER NOT NULL,
+  refund_no INTEGER NOT NULL CHECK (refund_no >= 1),
+  amount_minor INTEGER NOT NULL CHECK (amount_minor > 0),
+  refund_date TEXT NOT NULL,
+  reason TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+  FOREIGN KEY (payment_id) REFERENCES payments(id) ON DELETE CASCADE,
+  FOREIGN KEY (applied_in_revision_id, invoice_id) REFERENCES invoice_revisions(id, invoice_id) ON DELETE CASCADE,
+  UNIQUE (applied_in_revision_id, refund_no)
+);
+
+CREATE TABLE IF NOT EXISTS credit_notes (
+  id INTEGER PRIMARY KEY,
+  invoice_id INTEGER NOT NULL,
+  invoice_revision_id INTEGER NOT NULL,
+  credit_note_no INTEGER NOT NULL CHECK (credit_note_no >= 1),
+  issue_date TEXT NOT NULL,
+  reason TEXT,
+  vat_rate INTEGER NOT NULL DEFAULT 0 CHECK (vat_rate BETWEEN 0 AND 10000),
+  subtotal_minor INTEGER NOT NULL CHECK (subtotal_minor >= 0),
+  vat_amount_minor INTEGER NOT NULL CHECK (vat_amount_minor >= 0),
+  total_minor INTEGER NOT NULL CHECK (total_minor > 0),
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+  FOREIGN KEY (invoice_revision_id, invoice_id) REFERENCES invoice_revisions(id, invoice_id) ON DELETE CASCADE,
+  UNIQUE (invoice_id, credit_note_no)
+);
+
+CREATE TABLE IF NOT EXISTS credit_note_items (
+  id INTEGER PRIMARY KEY,
+  credit_note_id INTEGER NOT NULL,
+  name TEXT NOT NULL,
+  line_type TEXT NOT NULL DEFAULT 'custom'
+    CHECK (line_type IN ('style','sample','custom')),
+  quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+  quantity_milli INTEGER CHECK (quantity_milli IS NULL OR quantity_milli > 0),
+  unit_price_minor INTEGER NOT NULL CHECK (unit_price_minor >= 0),
+  line_total_minor INTEGER NOT NULL DEFAULT 0 CHECK (line_total_minor >= 0),
+  sort_order INTEGER NOT NULL DEFAULT 1 CHECK (sort_order >= 1),
+  vat_rate INTEGER CHECK (vat_rate IS NULL OR vat_rate BETWEEN 0 AND 10000),
+  FOREIGN KEY (credit_note_id) REFERENCES credit_notes(id) ON DELETE CASCADE,
+  UNIQUE (credit_note_id, sort_order)
+);
+
+CREATE TABLE IF NOT EXISTS quote_number_seq (
+  account_id INTEGER PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+  next_quote_number INTEGER NOT NULL CHECK (next_quote_number > 0)
+);
+
+CREATE TABLE IF NOT EXISTS quotes (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL DEFAULT 1 REFERENCES accounts(id) ON DELETE CASCADE,
+  client_id INTEGER NOT NULL,
+  quote_number INTEGER NOT NULL CHECK (quote_number > 0),
+  status TEXT NOT NULL DEFAULT 'draft'
+    CHECK (status IN ('draft','sent','accepted','declined')),
+  issue_date TEXT NOT NULL,
+  valid_until TEXT,
+  client_name TEXT NOT NULL,
+  client_company_name TEXT NOT NULL DEFAULT '',
+  client_address TEXT NOT NULL DEFAULT '',
+  client_email TEXT NOT NULL DEFAULT '',
+  client_vat_number TEXT NOT NULL DEFAULT '',
+  note TEXT,
+  currency TEXT NOT NULL DEFAULT 'GBP',
+  exchange_rate_micro INTEGER NOT NULL DEFAULT 1000000 CHECK (exchange_rate_micro > 0),
+  vat_treatment TEXT NOT NULL DEFAULT 'standard'
+    CHECK (vat_treatment IN ('standard','reverse_charge','exempt','outside_scope')),
+  vat_rate INTEGER NOT NULL DEFAULT 2000 CHECK (vat_rate BETWEEN 0 AND 10000),
+  discount_type TEXT NOT NULL DEFAULT 'none'
+    CHECK (discount_type IN ('none','percent','fixed')),
+  discount_rate INTEGER NOT NULL DEFAULT 0 CHECK (discount_rate BETWEEN 0 AND 10000),
+  discount_minor INTEGER NOT NULL DEFAULT 0 CHECK (discount_minor >= 0),
+  deposit_type TEXT NOT NULL DEFAULT 'none'
+    CHECK (deposit_type IN ('none','percent','fixed')),
+  deposit_rate INTEGER NOT NULL DEFAULT 0 CHECK (deposit_rate BETWEEN 0 AND 10000),
+  deposit_minor INTEGER NOT NULL DEFAULT 0 CHECK (deposit_minor >= 0),
+  deduction_rate INTEGER NOT NULL DEFAULT 0 CHECK (deduction_rate BETWEEN 0 AND 10000),
+  deduction_line_types TEXT NOT NULL DEFAULT '',
+  deduction_minor INTEGER NOT NULL DEFAULT 0 CHECK (deduction_minor >= 0),
+  subtotal_minor INTEGER NOT NULL CHECK (subtotal_minor >= 0),
+  vat_amount_minor INTEGER NOT NULL CHECK (vat_amount_minor >= 0),
+  total_minor INTEGER NOT NULL CHECK (total_minor >= 0),
+  converted_invoice_id INTEGER REFERENCES invoices(id) ON DELETE SET NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  updated_at TEXT,
+  FOREIGN KEY (account_id, client_id) REFERENCES clients(account_id, id) ON DELETE CASCADE,
+  UNIQUE (account_id, quote_number),
+  CHECK (
+    (discount_type = 'none' AND discount_rate = 0 AND discount_minor = 0) OR
+    (discount_type = 'percent' AND discount_rate BETWEEN 0 AND 10000) OR
+    (discount_type = 'fixed' AND discount_rate = 0)
+  ),
+  CHECK (
+    (deposit_type = 'none' AND deposit_rate = 0 AND deposit_minor = 0) OR
+    (deposit_type = 'percent' AND deposit_rate BETWEEN 0 AND 10000) OR
+    (deposit_type = 'fixed' AND deposit_rate = 0)
+  )
+);
+
+CREATE TABLE IF NOT EXISTS quote_items (
+  id INTEGER PRIMARY KEY,
+  quote_id INTEGER NOT NULL,
+  product_id INTEGER,
+  name TEXT NOT NULL,
+  line_type TEXT NOT NULL DEFAULT 'custom'
+    CHECK (line_type IN ('style','sample','custom')),
+  pricing_mode TEXT NOT NULL DEFAULT 'flat'
+    CHECK (pricing_mode IN ('flat','hourly')),
+  quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0),
+  quantity_milli INTEGER CHECK (quantity_milli IS NULL OR quantity_milli > 0),
+  unit TEXT NOT NULL DEFAULT '',
+  unit_price_minor INTEGER NOT NULL CHECK (unit_price_minor >= 0),
+  line_total_minor INTEGER NOT NULL DEFAULT 0 CHECK (line_total_minor >= 0),
+  minutes_worked INTEGER CHECK (minutes_worked IS NULL OR minutes_worked >= 0),
+  sort_order INTEGER NOT NULL DEFAULT 1 CHECK (sort_order >= 1),
+  vat_rate INTEGER CHECK (vat_rate IS NULL OR vat_rate BETWEEN 0 AND 10000),
+  discount_type TEXT NOT NULL DEFAULT 'none'
+    CHECK (discount_type IN ('none','percent','fixed')),
+  discount_rate INTEGER NOT NULL DEFAULT 0 CHECK (discount_rate BETWEEN 0 AND 10000),
+  discount_minor INTEGER NOT NULL DEFAULT 0 CHECK (discount_minor >= 0),
+  FOREIGN KEY (quote_id) REFERENCES quotes(id) ON DELETE CASCADE,
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE SET NULL,
+  UNIQUE (quote_id, sort_order),
+  CHECK (
+    (pricing_mode = 'flat' AND minutes_worked IS NULL) OR
+    (pricing_mode = 'hourly' AND minutes_worked IS NOT NULL)
+  )
+);
+
+CREATE TABLE IF NOT EXISTS quote_vat_breakdown (
+  quote_id INTEGER NOT NULL,
+  vat_rate INTEGER NOT NULL CHECK (vat_rate BETWEEN 0 AND 10000),
+  net_minor INTEGER NOT NULL CHECK (net_minor >= 0),
+  vat_minor INTEGER NOT NULL CHECK (vat_minor >= 0),
+  PRIMARY KEY (quote_id, vat_rate),
+  FOREIGN KEY (quote_id) REFERENCES quotes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recurring_schedules (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL DEFAULT 1 REFERENCES accounts(id) ON DELETE CASCADE,
+  client_id INTEGER NOT NULL,
+  source_invoice_id INTEGER NOT NULL,
+  source_revision_id INTEGER NOT NULL,
+  cadence TEXT NOT NULL CHECK (cadence IN ('weekly','monthly','yearly')),
+  day_of_month INTEGER CHECK (day_of_month IS NULL OR day_of_month BETWEEN 1 AND 31),
+  next_run_date TEXT NOT NULL,
+  auto_issue INTEGER NOT NULL DEFAULT 0 CHECK (auto_issue IN (0, 1)),
+  active INTEGER NOT NULL DEFAULT 1 CHECK (active IN (0, 1)),
+  last_run_at TEXT,
+  last_invoice_id INTEGER REFERENCES invoices(id) ON DELETE SET NULL,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  updated_at TEXT,
+  FOREIGN KEY (account_id, client_id) REFERENCES clients(account_id, id) ON DELETE CASCADE,
+  FOREIGN KEY (source_revision_id, source_invoice_id) REFERENCES invoice_revisions(id, invoice_id) ON DELETE CASCADE,
+  CHECK (
+    (cadence = 'weekly' AND day_of_month IS NULL) OR
+    (cadence IN ('monthly','yearly') AND day_of_month IS NOT NULL)
+  )
+);
+
+CREATE TABLE IF NOT EXISTS invoice_late_charges (
+  id INTEGER PRIMARY KEY,
+  invoice_id INTEGER NOT NULL,
+  mode TEXT NOT NULL CHECK (mode IN ('revision','invoice')),
+  accrued_from TEXT NOT NULL,
+  charged_until TEXT NOT NULL,
+  annual_rate_bps INTEGER NOT NULL CHECK (annual_rate_bps BETWEEN 0 AND 20000),
+  interest_minor INTEGER NOT NULL CHECK (interest_minor >= 0),
+  compensation_minor INTEGER NOT NULL CHECK (compensation_minor >= 0),
+  revision_id INTEGER REFERENCES invoice_revisions(id) ON DELETE CASCADE,
+  charge_invoice_id INTEGER REFERENCES invoices(id) ON DELETE CASCADE,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  FOREIGN KEY (invoice_id) REFERENCES invoices(id) ON DELETE CASCADE,
+  UNIQUE (invoice_id, charged_until),
+  CHECK (
+    (mode = 'revision' AND revision_id IS NOT NULL AND charge_invoice_id IS NULL) OR
+    (mode = 'invoice' AND charge_invoice_id IS NOT NULL AND revision_id IS NULL)
+  )
+);
+
+-- Append-only record of invoice and payment changes. invoice_id carries no
+-- foreign key so entries outlive the invoice; client_id and base_number keep
+-- them readable after it is gone.
+CREATE TABLE IF NOT EXISTS audit_log (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  user_id INTEGER,
+  actor_email TEXT,
+  invoice_id INTEGER NOT NULL,
+  client_id INTEGER NOT NULL,
+  base_number INTEGER NOT NULL,
+  action TEXT NOT NULL
+    CHECK (action IN (
+      'invoice_created','draft_updated','revision_created','status_changed',
+      'receipt_created','receipt_updated','receipt_deleted',
+      'deposit_created','deposit_deleted','refund_created',
+      'invoice_deleted','credit_note_created'
+    )),
+  entity_id INTEGER,
+  before_hash TEXT,
+  after_hash TEXT,
+  request_id TEXT,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_account_invoice ON audit_log(account_id, client_id, base_number, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_account_user ON audit_log(account_id, user_id, id);
+
+CREATE TRIGGER IF NOT EXISTS trg_audit_log_no_update
+BEFORE UPDATE ON audit_log
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+-- Entries only go when their account is deleted.
+CREATE TRIGGER IF NOT EXISTS trg_audit_log_no_delete
+BEFORE DELETE ON audit_log
+FOR EACH ROW
+WHEN EXISTS (SELECT 1 FROM accounts WHERE id = OLD.account_id)
+BEGIN
+  SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+-- One row per deleted invoice so the invoice number gap report can explain
+-- each missing number. Like audit_log, rows outlive the invoice and are
+-- append-only.
+CREATE TABLE IF NOT EXISTS invoice_tombstones (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  invoice_id INTEGER NOT NULL,
+  client_id INTEGER NOT NULL,
+  base_number INTEGER NOT NULL,
+  number_label TEXT,
+  number_period TEXT,
+  number_seq INTEGER,
+  status TEXT NOT NULL,
+  issue_date TEXT,
+  total_minor INTEGER,
+  deleted_by_user_id INTEGER,
+  deleted_by_email TEXT,
+  deleted_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_tombstones_account_base ON invoice_tombstones(account_id, base_number, id);
+
+CREATE TRIGGER IF NOT EXISTS trg_invoice_tombstones_no_update
+BEFORE UPDATE ON invoice_tombstones
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'invoice tombstones are append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_invoice_tombstones_no_delete
+BEFORE DELETE ON invoice_tombstones
+FOR EACH ROW
+WHEN EXISTS (SELECT 1 FROM accounts WHERE id = OLD.account_id)
+BEGIN
+  SELECT RAISE(ABORT, 'invoice tombstones are append-only');
+END;
+
+-- Every manual change of the starting invoice number, so numbers skipped by
+-- it show up as explained in the gap report.
+CREATE TABLE IF NOT EXISTS invoice_number_start_changes (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  from_number INTEGER NOT NULL,
+  to_number INTEGER NOT NULL,
+  changed_by_user_id INTEGER,
+  changed_by_email TEXT,
+  changed_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_number_start_changes_account ON invoice_number_start_changes(account_id, id);
+
+-- Responses to POST requests sent with an Idempotency-Key, kept for 24 hours
+-- so a retried request replays the first response instead of running again.
+-- response_status stays NULL while the first request is still running.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+  id INTEGER PRIMARY KEY,
+  account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+  idempotency_key TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  response_status INTEGER,
+  response_headers TEXT,
+  response_body BLOB,
+  created_at TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+  expires_at TEXT NOT NULL,
+  UNIQUE (account_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
 CREATE TRIGGER IF NOT EXISTS trg_accounts_id_immutable
 BEFORE UPDATE OF id ON accounts
 FOR EACH ROW
@@ -454,6 +851,72 @@ BEGIN
   SELECT RAISE(ABORT, 'payment applied revision must belong to same invoice');
 END;
 
+CREATE TRIGGER IF NOT EXISTS trg_quote_number_seq_scope_immutable
+BEFORE UPDATE OF account_id ON quote_number_seq
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'quote sequence ownership is immutable');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_quotes_scope_immutable
+BEFORE UPDATE OF id, account_id, client_id ON quotes
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'quote ownership is immutable');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_quote_items_product_scope_insert
+BEFORE INSERT ON quote_items
+FOR EACH ROW
+WHEN NEW.product_id IS NOT NULL
+  AND NOT EXISTS (
+    SELECT 1
+    FROM quotes q
+    JOIN products p
+      ON p.id = NEW.product_id
+    WHERE q.id = NEW.quote_id
+      AND p.account_id = q.account_id
+      AND p.client_id = q.client_id
+  )
+BEGIN
+  SELECT RAISE(ABORT, 'quote item product must belong to same account and client');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_recurring_schedules_scope_immutable
+BEFORE UPDATE OF id, account_id, client_id ON recurring_schedules
+FOR EACH ROW
+BEGIN
+  SELECT RAISE(ABORT, 'recurring schedule ownership is immutable');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_recurring_schedules_source_scope_insert
+BEFORE INSERT ON recurring_schedules
+FOR EACH ROW
+WHEN NOT EXISTS (
+    SELECT 1
+    FROM invoices i
+    WHERE i.id = NEW.source_invoice_id
+      AND i.account_id = NEW.account_id
+      AND i.client_id = NEW.client_id
+  )
+BEGIN
+  SELECT RAISE(ABORT, 'recurring schedule source invoice must belong to same account and client');
+END;
+
+CREATE TRIGGER IF NOT EXISTS trg_recurring_schedules_source_scope_update
+BEFORE UPDATE OF source_invoice_id, source_revision_id ON recurring_schedules
+FOR EACH ROW
+WHEN NOT EXISTS (
+    SELECT 1
+    FROM invoices i
+    WHERE i.id = NEW.source_invoice_id
+      AND i.account_id = NEW.account_id
+      AND i.client_id = NEW.client_id
+  )
+BEGIN
+  SELECT RAISE(ABORT, 'recurring schedule source invoice must belong to same account and client');
+END;
+
 CREATE INDEX IF NOT EXISTS idx_invoices_current_revision_id ON invoices(current_revision_id);
 CREATE INDEX IF NOT EXISTS idx_promo_code_redemption_claims_retention_until ON promo_code_redemption_claims(retention_until);
 
@@ -468,7 +931,7 @@ SELECT
   it.name,
   it.line_type,
   it.pricing_mode,
-  it.quantity,
+  COALESCE(it.quantity_milli, it.quantity * 1000) AS quantity_milli,
   it.unit_price_minor,
   it.minutes_worked,
   it.line_total_minor
@@ -479,6 +942,31 @@ JOIN invoice_items it
   ON it.invoice_revision_id = r.id
 ORDER BY i.id, it.sort_order;
 
+-- Money received against each revision snapshot, net of refunds. Received
+-- deposits count toward paid like any other payment; this is the single
+-- definition every paid total and balance due is derived from.
+CREATE VIEW IF NOT EXISTS invoice_revision_paid AS
+SELECT
+  r.id AS revision_id,
+  r.invoice_id,
+  COALESCE((
+    SELECT SUM(p.amount_minor)
+    FROM payments p
+    WHERE p.applied_in_revision_id = r.id
+      AND p.payment_type IN ('payment','deposit')
+  ), 0) - COALESCE((
+    SELECT SUM(rf.amount_minor)
+    FROM payment_refunds rf
+    WHERE rf.applied_in_revision_id = r.id
+  ), 0) AS paid_minor,
+  COALESCE((
+    SELECT SUM(p.amount_minor)
+    FROM payments p
+    WHERE p.applied_in_revision_id = r.id
+      AND p.payment_type = 'deposit'
+  ), 0) AS deposit_paid_minor
+FROM invoice_revisions r;
+
 CREATE VIEW IF NOT EXISTS invoice_book_rows AS
 SELECT
   i.id,
@@ -489,10 +977,73 @@ SELECT
   r.revision_no,
   r.issue_date,
   r.due_by_date,
-  r.updated_at
+  r.updated_at,
+  r.currency,
+  r.total_minor,
+  r.deduction_minor,
+  rp.paid_minor,
+  rp.deposit_paid_minor,
+  COALESCE((
+    SELECT SUM(cn.total_minor)
+    FROM credit_notes cn
+    WHERE cn.invoice_id = i.id
+  ), 0) AS credited_minor,
+  MAX(
+    r.total_minor
+      - r.deduction_minor
+      - rp.paid_minor
+      - COALESCE((
+        SELECT SUM(cn.total_minor)
+        FROM credit_notes cn
+        WHERE cn.invoice_id = i.id
+      ), 0),
+    0
+  ) AS balance_due_minor
 FROM invoices i
 JOIN invoice_revisions r
-  ON r.id = i.current_revision_id;
+  ON r.id = i.current_revision_id
+JOIN invoice_revision_paid rp
+  ON rp.revision_id = r.id;
+
+-- Money a client has on account, per currency: what non-void invoices
+-- received beyond the amount payable (overpayments and credit notes), less
+-- credit already applied as payments. Credit only offsets invoices in the
+-- currency it was received in.
+CREATE VIEW IF NOT EXISTS client_credit_balances AS
+SELECT
+  client_id,
+  account_id,
+  currency,
+  SUM(credit_in_minor) AS credit_in_minor,
+  SUM(credit_applied_minor) AS credit_applied_minor,
+  SUM(credit_in_minor) - SUM(credit_applied_minor) AS balance_minor
+FROM (
+  SELECT
+    b.client_id,
+    i.account_id,
+    b.currency,
+    MAX(b.paid_minor + b.credited_minor - (b.total_minor - b.deduction_minor), 0) AS credit_in_minor,
+    0 AS credit_applied_minor
+  FROM invoice_book_rows b
+  JOIN invoices i
+    ON i.id = b.id
+  WHERE b.status <> 'void'
+  UNION ALL
+  SELECT
+    i.client_id,
+    i.account_id,
+    r.currency,
+    0 AS credit_in_minor,
+    p.amount_minor AS credit_applied_minor
+  FROM payments p
+  JOIN invoices i
+    ON i.current_revision_id = p.applied_in_revision_id
+  JOIN invoice_revisions r
+    ON r.id = p.applied_in_revision_id
+  WHERE p.payment_type = 'payment'
+    AND p.from_client_credit = 1
+)
+GROUP BY client_id, account_id, currency;
 
 CREATE VIEW IF NOT EXISTS invoice_revision_items AS
 SELECT
@@ -505,7 +1056,7 @@ SELECT
   it.name,
   it.line_type,
   it.pricing_mode,
-  it.quantity,
+  COALESCE(it.quantity_milli, it.quantity * 1000) AS quantity_milli,
   it.unit_price_minor,
   it.minutes_worked,
   it.line_total_minor
@@ -528,6 +1079,16 @@ CREATE INDEX IF NOT EXISTS idx_products_account_client ON products(account_id, c
 CREATE UNIQUE INDEX IF NOT EXISTS idx_products_account_client_id ON products(account_id, client_id, id);
 CREATE INDEX IF NOT EXISTS idx_payments_invoice_id ON payments(invoice_id);
 CREATE INDEX IF NOT EXISTS idx_payments_invoice_revision ON payments(invoice_id, applied_in_revision_id);
+CREATE INDEX IF NOT EXISTS idx_client_payments_account_client ON client_payments(account_id, client_id);
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_payment_id ON payment_refunds(payment_id);
+CREATE INDEX IF NOT EXISTS idx_payment_refunds_invoice_id ON payment_refunds(invoice_id);
+CREATE INDEX IF NOT EXISTS idx_credit_notes_invoice_id ON credit_notes(invoice_id);
+CREATE INDEX IF NOT EXISTS idx_credit_note_items_credit_note_id ON credit_note_items(credit_note_id);
+CREATE INDEX IF NOT EXISTS idx_quotes_account_client ON quotes(account_id, client_id);
+CREATE INDEX IF NOT EXISTS idx_quote_items_quote_id ON quote_items(quote_id);
+CREATE INDEX IF NOT EXISTS idx_recurring_schedules_due ON recurring_schedules(active, next_run_date);
+CREATE INDEX IF NOT EXISTS idx_recurring_schedules_client ON recurring_schedules(account_id, client_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_late_charges_invoice_id ON invoice_late_charges(invoice_id);
 -- Keep indexes for newly introduced columns in targeted migrations so legacy DBs can
 -- add the column before bootstrap tries to reference it.
 CREATE INDEX IF NOT EXISTS idx_stored_files_account_id ON stored_files(account_id);
@@ -0,0 +1,14 @@
+// Package dberr classifies errors returned by the SQLite driver so the
+// transaction packages can map them to their own sentinel errors.
+package dberr
+
+import "strings"
+
+// IsUniqueViolation returns true if the error is a SQLite unique constraint violation.
+func IsUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE") || strings.Contains(msg, "unique")
+}
@@ -23,6 +23,10 @@ func ValidateCreate(client models.CreateClient) (models.CreateClient, []res.Fiel
 
 	client.Email, errs = email(client.Email, "email", 50, errs)
 
+	client.VATNumber, errs = text(client.VATNumber, validate.TextRules{
+		Field: "vatNumber", Max: 30, SingleLine: true, Trim: true,
+	}, errs)
+
 	return client, errs
 }
 
@@ -43,12 +47,16 @@ func ValidateUpdate(client models.UpdateClient) (models.UpdateClient, []res.Fiel
 
 	client.Email, errs = emailPtr(client.Email, "email", 50, errs)
 
+	client.VATNumber, errs = textPtr(client.VATNumber, validate.TextRules{
+		Field: "vatNumber", Max: 30, SingleLine: true, Trim: true,
+	}, errs)
+
 	// Reject empties:
 	// check if name is not nill pointer first (crashes program) then check if its empty
 	if client.Name != nil && *client.Name == "" {
 		errs = append(errs, res.Required("name"))
 	}
-	if client.Name == nil && client.CompanyName == nil && client.Address == nil && client.Email == nil {
+	if client.Name == nil && client.CompanyName == nil && client.Address == nil && client.Email == nil && client.VATNumber == nil {
 		errs = append(errs, res.Invalid("request", "no fields to update"))
 	}
 
@@ -1,8 +1,10 @@
 package editor
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 
@@ -21,9 +23,18 @@ func nullStringPtr(v sql.NullString) *string {
 	return &s
 }
 
+func nullInt64Ptr(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	n := v.Int64
+	return &n
+}
+
 func toEditorTotals(in invoiceTx.InvoiceOverviewTotals) models.InvoiceEditorTotals {
 	return models.InvoiceEditorTotals{
 		BaseNumber:        in.BaseNumber,
+		NumberLabel:       in.NumberLabel.String,
 		RevisionNo:        in.RevisionNo,
 		IssueDate:         in.IssueDate,
 		SupplyDate:        nullStringPtr(in.SupplyDate),
@@ -32,8 +43,12 @@ func toEditorTotals(in invoiceTx.InvoiceOverviewTotals) models.InvoiceEditorTota
 		ClientCompanyName: in.ClientCompanyName,
 		ClientAddress:     in.ClientAddress,
 		ClientEmail:       in.ClientEmail,
+		ClientVATNumber:   in.ClientVATNumber,
 		Note:              nullStringPtr(in.Note),
+		Currency:          in.Currency,
+		ExchangeRateMicro: in.ExchangeRateMicro,
 
+		VATTreatment:  in.VATTreatment,
 		VATRate:       in.VATRate,
 		VATAmountMin:  in.VATAmountMin,
 		DiscountType:  in.DiscountType,
@@ -45,6 +60,16 @@ func toEditorTotals(in invoiceTx.InvoiceOverviewTotals) models.InvoiceEditorTota
 		SubtotalMinor: in.SubtotalMinor,
 		TotalMinor:    in.TotalMinor,
 		PaidMinor:     in.PaidMinor,
+		CreditedMinor: in.CreditedMinor,
+
+		VATBreakdown: in.VATBreakdown,
+
+		DeductionRate:      in.DeductionRate,
+		DeductionLineTypes: in.DeductionLineTypes,
+		DeductionMinor:     in.DeductionMinor,
+
+		TaxRateID:   nullInt64Ptr(in.TaxRateID),
+		TaxRateName: in.TaxRateName,
 	}
 }
 
@@ -58,9 +83,14 @@ func toEditorLines(in []invoiceTx.ItemLine) []models.InvoiceEditorLine {
 			Name:          line.Name,
 			LineType:      line.LineType,
 			Quantity:      line.Quantity,
+			Unit:          line.Unit,
 			UnitPriceMin:  line.UnitPriceMin,
 			LineTotalMin:  line.LineTotalMin,
 			SortOrder:     line.SortOrder,
+			VATRate:       line.VATRate,
+			DiscountType:  line.DiscountType,
+			DiscountRate:  line.DiscountRate,
+			DiscountMinor: line.DiscountMinor,
 		})
 	}
 	return out
@@ -70,16 +100,57 @@ func toEditorReceipts(in []invoiceTx.ReceiptRow) []models.InvoiceEditorReceipt {
 	out := make([]models.InvoiceEditorReceipt, 0, len(in))
 	for _, p := range in {
 		out = append(out, models.InvoiceEditorReceipt{
-			ID:          p.ID,
-			ReceiptNo:   p.ReceiptNo,
-			AmountMinor: p.AmountMinor,
-			PaymentDate: p.PaymentDate,
-			Label:       nullStringPtr(p.Label),
+			ID:            p.ID,
+			ReceiptNo:     p.ReceiptNo,
+			AmountMinor:   p.AmountMinor,
+			PaymentDate:   p.PaymentDate,
+			Label:         nullStringPtr(p.Label),
+			PaymentMethod: nullStringPtr(p.PaymentMethod),
+			Reference:     nullStringPtr(p.Reference),
+			RefundedMinor: p.RefundedMinor,
 		})
 	}
 	return out
 }
 
+// LoadInvoiceEditor loads one invoice revision in the editor shape, tagged
+// with the invoice's current ETag. It returns sql.ErrNoRows when the revision
+// does not exist.
+func LoadInvoiceEditor(ctx context.Context, db *sql.DB, clientID, baseNo, revNo int64) (*models.InvoiceEditorResponse, error) {
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, db, clientID, baseNo, revNo)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, sql.ErrNoRows
+		}
+		return nil, fmt.Errorf("load invoice summary: %w", err)
+	}
+
+	lines, err := invoiceTx.QueryInvoiceLines(ctx, db, clientID, baseNo, revNo)
+	if err != nil {
+		return nil, fmt.Errorf("load invoice lines: %w", err)
+	}
+
+	receipts, err := invoiceTx.QueryInvoiceReceiptsForRevision(ctx, db, clientID, baseNo, revNo)
+	if err != nil {
+		return nil, fmt.Errorf("load invoice receipts: %w", err)
+	}
+
+	etag, err := invoiceTx.QueryInvoiceETag(ctx, db, clientID, baseNo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.InvoiceEditorResponse{
+		ETag:     etag,
+		Status:   summary.Status,
+		Totals:   toEditorTotals(*summary),
+		Lines:    toEditorLines(lines),
+		Receipts: toEditorReceipts(receipts),
+	}, nil
+}
+
+// GetInvoice returns one invoice revision for the editor. The ETag header
+// carries the invoice's current version for If-Match on later edits.
 func GetInvoice(a *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		clientID, ok := params.ValidateParam(w, r, "clientID")
@@ -95,14 +166,14 @@ func GetInvoice(a *app.App) http.HandlerFunc {
 			return
 		}
 
-		summary, err := invoiceTx.QueryInvoiceSummary(r.Context(), a.DB, clientID, baseNo, revNo)
+		out, err := LoadInvoiceEditor(r.Context(), a.DB, clientID, baseNo, revNo)
 		if err != nil {
 			if errors.Is(err, sql.ErrNoRows) {
 				res.NotFound(w, "Invoice revision not found")
 				return
 			}
 			slog.ErrorContext(
-				r.Context(), "DB_ERROR - error while getting invoice summary",
+				r.Context(), "DB_ERROR - error while getting invoice",
 				"err", err,
 				"clientID", clientID,
 				"baseNumber", baseNo,
@@ -112,39 +183,7 @@ func GetInvoice(a *app.App) http.HandlerFunc {
 			return
 		}
 
-		lines, err := invoiceTx.QueryInvoiceLines(r.Context(), a.DB, clientID, baseNo, revNo)
-		if err != nil {
-			slog.ErrorContext(
-				r.Context(), "DB_ERROR - error while getting invoice lines",
-				"err", err,
-				"clientID", clientID,
-				"baseNumber", baseNo,
-				"revisionNumber", revNo,
-			)
-			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
-			return
-		}
-
-		receipts, err := invoiceTx.QueryInvoiceReceiptsForRevision(r.Context(), a.DB, clientID, baseNo, revNo)
-		if err != nil {
-			slog.ErrorContext(
-				r.Context(), "DB_ERROR - error while getting invoice receipts",
-				"err", err,
-				"clientID", clientID,
-				"baseNumber", baseNo,
-				"revisionNumber", revNo,
-			)
-			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
-			return
-		}
-
-		out := models.InvoiceEditorResponse{
-			Status:   summary.Status,
-			Totals:   toEditorTotals(*summary),
-			Lines:    toEditorLines(lines),
-			Receipts: toEditorReceipts(receipts),
-		}
-
+		w.Header().Set("ETag", out.ETag)
 		res.JSON(w, http.StatusOK, out)
 	}
 }
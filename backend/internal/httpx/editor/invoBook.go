@@ -5,11 +5,14 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/editorTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
 func optionalPositiveInt64(raw string) (int64, bool, error) {
@@ -65,10 +68,35 @@ func HandleINVBookData(a *app.App) http.HandlerFunc {
 			offset = v
 		}
 
+		overdue := false
+		if raw := r.URL.Query().Get("overdue"); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				res.Error(w, http.StatusBadRequest, "BAD_QUERY", "Invalid overdue")
+				return
+			}
+			overdue = v
+		}
+
+		accountID, err := accountscope.Require(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "invoice book missing account scope", "err", err)
+			res.Error(w, http.StatusInternalServerError, "INTERNAL", "Failed to load invoices")
+			return
+		}
+		today, err := settingsTx.Today(r.Context(), a.DB, accountID, time.Now())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "DB_ERROR - error while resolving workspace date", "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
 		filters := editorTx.InvoiceBookPageFilters{
 			SortBy:        r.URL.Query().Get("sortBy"),
 			SortDirection: r.URL.Query().Get("sortDirection"),
 			PaymentState:  r.URL.Query().Get("paymentState"),
+			Overdue:       overdue,
+			Today:         today,
 		}
 
 		IBData, err := editorTx.QueryInvoiceBookPage(
@@ -89,6 +117,7 @@ func HandleINVBookData(a *app.App) http.HandlerFunc {
 				"sortBy", filters.SortBy,
 				"sortDirection", filters.SortDirection,
 				"paymentState", filters.PaymentState,
+				"overdue", filters.Overdue,
 			)
 			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
 			return
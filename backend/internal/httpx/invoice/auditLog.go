@@ -0,0 +1,79 @@
+package invoice
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+const maxAuditLogLimit = 500
+
+// ListAuditLog returns the account's invoice and payment audit entries,
+// newest first. ?clientId= and ?baseNumber= narrow it to one client or
+// invoice, ?userId= to one actor, and ?beforeId= pages back.
+func ListAuditLog(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var filters invoiceTx.AuditLogFilters
+
+		for _, q := range []struct {
+			name string
+			dst  *int64
+		}{
+			{name: "clientId", dst: &filters.ClientID},
+			{name: "baseNumber", dst: &filters.BaseNumber},
+			{name: "userId", dst: &filters.UserID},
+			{name: "beforeId", dst: &filters.BeforeID},
+		} {
+			raw := r.URL.Query().Get(q.name)
+			if raw == "" {
+				continue
+			}
+			v, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || v < 1 {
+				res.Error(w, http.StatusBadRequest, "BAD_QUERY", "Invalid "+q.name)
+				return
+			}
+			*q.dst = v
+		}
+
+		if raw := r.URL.Query().Get("limit"); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 1 || v > maxAuditLogLimit {
+				res.Error(w, http.StatusBadRequest, "BAD_QUERY", "Invalid limit")
+				return
+			}
+			filters.Limit = v
+		}
+
+		rows, err := invoiceTx.QueryAuditLog(r.Context(), a.DB, filters)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "query audit log failed", "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		out := make([]models.AuditLogEntryOut, 0, len(rows))
+		for _, row := range rows {
+			out = append(out, models.AuditLogEntryOut{
+				ID:         row.ID,
+				UserID:     nullInt64Out(row.UserID),
+				ActorEmail: nullStringOut(row.ActorEmail),
+				ClientID:   row.ClientID,
+				BaseNumber: row.BaseNumber,
+				Action:     row.Action,
+				EntityID:   nullInt64Out(row.EntityID),
+				BeforeHash: nullStringOut(row.BeforeHash),
+				AfterHash:  nullStringOut(row.AfterHash),
+				RequestID:  nullStringOut(row.RequestID),
+				CreatedAt:  row.CreatedAt,
+			})
+		}
+
+		res.JSON(w, http.StatusOK, out)
+	}
+}
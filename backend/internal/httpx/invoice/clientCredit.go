@@ -0,0 +1,123 @@
+package invoice
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+// GetClientCredit returns the client's credit balances, one per currency, as a
+// statement of what put money on account and where it was applied.
+func GetClientCredit(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+
+		statement, err := invoiceTx.QueryClientCredit(r.Context(), a.DB, clientID)
+		if err != nil {
+			if errors.Is(err, invoiceTx.ErrInvoiceNotFound) {
+				res.NotFound(w, "Client not found")
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"load client credit failed",
+				"client_id", clientID,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		balances := make([]models.ClientCreditBalanceOut, 0, len(statement.Balances))
+		for _, balance := range statement.Balances {
+			balances = append(balances, models.ClientCreditBalanceOut{
+				Currency:     balance.Currency,
+				BalanceMinor: balance.BalanceMinor,
+			})
+		}
+
+		entries := make([]models.ClientCreditEntryOut, 0, len(statement.Entries))
+		for _, entry := range statement.Entries {
+			entries = append(entries, models.ClientCreditEntryOut{
+				Kind:        entry.Kind,
+				BaseNumber:  entry.BaseNumber,
+				RevisionNo:  entry.RevisionNo,
+				ReceiptNo:   nullInt64Out(entry.ReceiptNo),
+				EntryDate:   entry.EntryDate,
+				Currency:    entry.Currency,
+				AmountMinor: entry.AmountMinor,
+			})
+		}
+
+		res.JSON(w, http.StatusOK, models.ClientCreditOut{
+			ClientID: statement.ClientID,
+			Balances: balances,
+			Entries:  entries,
+		})
+	}
+}
+
+func ApplyClientCredit(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+
+		var dto models.ClientCreditApplyIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateClientCreditApply(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		invoiceID, receiptID, receiptNo, err := invoiceTx.ApplyClientCredit(r.Context(), a, clientID, &valid)
+		if err != nil {
+			switch {
+			case errors.Is(err, invoiceTx.ErrInvoiceNotFound):
+				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Client or invoice not found")
+				return
+			case errors.Is(err, invoiceTx.ErrInsufficientClientCredit):
+				res.Validation(w, res.Invalid("amountMinor", "cannot exceed the client's credit balance"))
+				return
+			case errors.Is(err, invoiceTx.ErrCreditExceedsBalanceDue):
+				res.Validation(w, res.Invalid("amountMinor", "cannot exceed the invoice balance due"))
+				return
+			case errors.Is(err, invoiceTx.ErrInvoicePaidForReceipt):
+				res.Error(w, http.StatusConflict, "REVISION_PAID", "This revision is already fully paid")
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceVoidForReceipt):
+				res.Error(w, http.StatusConflict, "INVOICE_VOID", "Invoice is void; payment receipts are not allowed")
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"apply client credit failed",
+				"client_id", clientID,
+				"base_number", valid.BaseNumber,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, map[string]any{
+			"invoiceId": invoiceID,
+			"receiptId": receiptID,
+			"receiptNo": receiptNo,
+		})
+	}
+}
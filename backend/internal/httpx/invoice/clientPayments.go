@@ -0,0 +1,204 @@
+package invoice
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func CreateClientPayment(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+
+		var dto models.ClientPaymentCreateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateClientPaymentCreate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		clientPaymentID, _, err := invoiceTx.CreateClientPayment(r.Context(), a, clientID, &valid)
+		if err != nil {
+			if writeClientPaymentAllocationError(w, err) {
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"create client payment failed",
+				"client_id", clientID,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		writeClientPayment(w, r, a, clientID, clientPaymentID, http.StatusCreated)
+	}
+}
+
+func AllocateClientPayment(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		clientPaymentID, ok := params.ValidateParam(w, r, "clientPaymentID")
+		if !ok {
+			return
+		}
+
+		var dto models.ClientPaymentAllocateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateClientPaymentAllocate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		if _, err := invoiceTx.AllocateClientPayment(r.Context(), a, clientID, clientPaymentID, &valid); err != nil {
+			if writeClientPaymentAllocationError(w, err) {
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"allocate client payment failed",
+				"client_id", clientID,
+				"client_payment_id", clientPaymentID,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		writeClientPayment(w, r, a, clientID, clientPaymentID, http.StatusOK)
+	}
+}
+
+func ListClientPayments(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+
+		payments, err := invoiceTx.ListClientPayments(r.Context(), a.DB, clientID)
+		if err != nil {
+			slog.ErrorContext(r.Context(),
+				"list client payments failed",
+				"client_id", clientID,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		out := make([]models.ClientPaymentOut, 0, len(payments))
+		for _, payment := range payments {
+			out = append(out, clientPaymentOut(payment))
+		}
+
+		res.JSON(w, http.StatusOK, out)
+	}
+}
+
+// GetClientPayment returns one client payment, including the amount still
+// waiting to be allocated to invoices.
+func GetClientPayment(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		clientPaymentID, ok := params.ValidateParam(w, r, "clientPaymentID")
+		if !ok {
+			return
+		}
+
+		writeClientPayment(w, r, a, clientID, clientPaymentID, http.StatusOK)
+	}
+}
+
+func writeClientPayment(w http.ResponseWriter, r *http.Request, a *app.App, clientID, clientPaymentID int64, status int) {
+	payment, err := invoiceTx.QueryClientPayment(r.Context(), a.DB, clientID, clientPaymentID)
+	if err != nil {
+		if errors.Is(err, invoiceTx.ErrClientPaymentNotFound) {
+			res.NotFound(w, "Client payment not found")
+			return
+		}
+
+		slog.ErrorContext(r.Context(),
+			"load client payment failed",
+			"client_id", clientID,
+			"client_payment_id", clientPaymentID,
+			"err", err,
+		)
+		res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return
+	}
+
+	res.JSON(w, status, clientPaymentOut(*payment))
+}
+
+// writeClientPaymentAllocationError maps allocation failures to responses and
+// reports whether it wrote one.
+func writeClientPaymentAllocationError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, invoiceTx.ErrClientPaymentNotFound):
+		res.NotFound(w, "Client payment not found")
+	case errors.Is(err, invoiceTx.ErrInvoiceNotFound):
+		res.Error(w, http.StatusNotFound, "NOT_FOUND", "Client or invoice not found")
+	case errors.Is(err, invoiceTx.ErrAllocationExceedsPayment):
+		res.Validation(w, res.Invalid("allocations", "cannot exceed the unallocated amount"))
+	case errors.Is(err, invoiceTx.ErrAllocationCurrencyMismatch):
+		res.Validation(w, res.Invalid("allocations", "must be to invoices in the payment currency"))
+	case errors.Is(err, invoiceTx.ErrInvoicePaidForReceipt):
+		res.Error(w, http.StatusConflict, "REVISION_PAID", "An allocated invoice is already fully paid")
+	case errors.Is(err, invoiceTx.ErrInvoiceVoidForReceipt):
+		res.Error(w, http.StatusConflict, "INVOICE_VOID", "An allocated invoice is void; payment receipts are not allowed")
+	default:
+		return false
+	}
+	return true
+}
+
+func clientPaymentOut(payment invoiceTx.ClientPaymentRow) models.ClientPaymentOut {
+	allocations := make([]models.ClientPaymentAllocationOut, 0, len(payment.Allocations))
+	for _, alloc := range payment.Allocations {
+		allocations = append(allocations, models.ClientPaymentAllocationOut{
+			BaseNumber:  alloc.BaseNumber,
+			RevisionNo:  alloc.RevisionNo,
+			ReceiptNo:   alloc.ReceiptNo,
+			AmountMinor: alloc.AmountMinor,
+		})
+	}
+
+	return models.ClientPaymentOut{
+		ID:               payment.ID,
+		ClientID:         payment.ClientID,
+		AmountMinor:      payment.AmountMinor,
+		PaymentDate:      payment.PaymentDate,
+		Label:            nullStringOut(payment.Label),
+		PaymentMethod:    nullStringOut(payment.PaymentMethod),
+		Reference:        nullStringOut(payment.Reference),
+		Currency:         payment.Currency,
+		AllocatedMinor:   payment.AllocatedMinor,
+		UnallocatedMinor: payment.AmountMinor - payment.AllocatedMinor,
+		Allocations:      allocations,
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/models"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/clientsTx"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
 func CreateInvoice(a *app.App) http.HandlerFunc {
@@ -82,6 +83,18 @@ func CreateInvoice(a *app.App) http.HandlerFunc {
 
 		invID, revID, err := invoiceTx.Create(r.Context(), a, &canonical)
 		if err != nil {
+			if errors.Is(err, invoiceTx.ErrExchangeRateRequired) {
+				res.Validation(w, res.Invalid("exchangeRateMicro", "is required when the invoice currency differs from the workspace currency"))
+				return
+			}
+			if errors.Is(err, invoiceTx.ErrClientVATNumberRequired) {
+				res.Validation(w, res.Invalid("clientVatNumber", "is required for a reverse charge invoice"))
+				return
+			}
+			if fe, ok := taxRateFieldError(err); ok {
+				res.Validation(w, fe)
+				return
+			}
 			if strings.Contains(err.Error(), "already exists") {
 				res.Validation(w, res.Invalid("baseNumber", "invoice number already in use. Refresh page and try again."))
 				return
@@ -164,8 +177,17 @@ func CreateRevision(a *app.App) http.HandlerFunc {
 			return
 		}
 
-		invoiceID, revisionID, revisionNo, err := invoiceTx.CreateRevision(r.Context(), a, &canonical)
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		invoiceID, revisionID, revisionNo, err := invoiceTx.CreateRevision(ctx, a, &canonical)
 		if err != nil {
+			if errors.Is(err, invoiceTx.ErrETagMismatch) {
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
+			}
 			if errors.Is(err, invoiceTx.ErrInvoiceNotFound) {
 				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
 				return
@@ -190,6 +212,18 @@ func CreateRevision(a *app.App) http.HandlerFunc {
 				res.Validation(w, res.Invalid("totals.paidMinor", "saved payments changed; refresh invoice before saving"))
 				return
 			}
+			if errors.Is(err, invoiceTx.ErrExchangeRateRequired) {
+				res.Validation(w, res.Invalid("exchangeRateMicro", "is required when the invoice currency differs from the workspace currency"))
+				return
+			}
+			if errors.Is(err, invoiceTx.ErrClientVATNumberRequired) {
+				res.Validation(w, res.Invalid("clientVatNumber", "is required for a reverse charge invoice"))
+				return
+			}
+			if fe, ok := taxRateFieldError(err); ok {
+				res.Validation(w, fe)
+				return
+			}
 
 			slog.ErrorContext(r.Context(),
 				"create invoice revision failed",
@@ -202,6 +236,7 @@ func CreateRevision(a *app.App) http.HandlerFunc {
 			return
 		}
 
+		setInvoiceETag(w, r, a, clientID, baseNumber)
 		res.JSON(w, http.StatusCreated, map[string]any{
 			"invoiceId":  invoiceID,
 			"revisionId": revisionID,
@@ -210,6 +245,20 @@ func CreateRevision(a *app.App) http.HandlerFunc {
 	}
 }
 
+// taxRateFieldError maps a rejected registry tax rate reference to the field
+// the user has to fix.
+func taxRateFieldError(err error) (res.FieldError, bool) {
+	switch {
+	case errors.Is(err, settingsTx.ErrTaxRateNotFound):
+		return res.Invalid("totals.taxRateId", "tax rate not found"), true
+	case errors.Is(err, invoiceTx.ErrTaxRateNotEffective):
+		return res.Invalid("totals.taxRateId", "is not in effect on the issue date"), true
+	case errors.Is(err, invoiceTx.ErrTaxRateMismatch):
+		return res.Invalid("totals.vatRate", "must match the selected tax rate"), true
+	}
+	return res.FieldError{}, false
+}
+
 // verifyTotalsMatch returns field errors if server-recalculated totals differ from submitted totals.
 func verifyTotalsMatch(submitted, recalc models.TotalsCreateIn) []res.FieldError {
 	var errs []res.FieldError
@@ -225,5 +274,8 @@ func verifyTotalsMatch(submitted, recalc models.TotalsCreateIn) []res.FieldError
 	if submitted.VatAmountMinor != recalc.VatAmountMinor {
 		errs = append(errs, res.Invalid("totals.vatMinor", "does not match server calculation"))
 	}
+	if submitted.DeductionMinor != recalc.DeductionMinor {
+		errs = append(errs, res.Invalid("totals.deductionMinor", "does not match server calculation"))
+	}
 	return errs
 }
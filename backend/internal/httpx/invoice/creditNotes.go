@@ -0,0 +1,258 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/docx"
+	"github.com/viktorHadz/goInvoice26/internal/service/pdf"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func CreateCreditNote(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		var dto models.CreditNoteCreateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateCreditNoteCreate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		invoiceID, creditNoteID, creditNoteNo, totalMinor, err := invoiceTx.CreateCreditNote(r.Context(), a, clientID, baseNumber, &valid)
+		if err != nil {
+			switch {
+			case errors.Is(err, invoiceTx.ErrInvoiceNotFound):
+				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
+				return
+			case errors.Is(err, invoiceTx.ErrSourceRevisionInvalid):
+				res.Validation(w, res.Invalid("revisionNo", "revision not found for this invoice"))
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceDraftForCreditNote):
+				res.Error(w, http.StatusConflict, "INVOICE_DRAFT", "Issue the invoice before raising a credit note")
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceVoidForCreditNote):
+				res.Error(w, http.StatusConflict, "INVOICE_VOID", "Invoice is void; credit notes are not allowed")
+				return
+			case errors.Is(err, invoiceTx.ErrCreditNoteExceedsInvoice):
+				res.Validation(w, res.Invalid("lines", "total credit cannot exceed the invoice total"))
+				return
+			case errors.Is(err, invoiceTx.ErrCreditNoteTotalInvalid):
+				res.Validation(w, res.Invalid("lines", "credit note total must be greater than 0"))
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"create credit note failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, map[string]any{
+			"invoiceId":    invoiceID,
+			"creditNoteId": creditNoteID,
+			"creditNoteNo": creditNoteNo,
+			"totalMinor":   totalMinor,
+		})
+	}
+}
+
+func ListCreditNotes(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		notes, err := invoiceTx.ListCreditNotes(r.Context(), a.DB, clientID, baseNumber)
+		if err != nil {
+			slog.ErrorContext(r.Context(),
+				"list credit notes failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, toCreditNotesOut(notes))
+	}
+}
+
+func GenerateCreditNotePDFHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		creditNoteNo, ok := params.ValidateParam(w, r, "creditNoteNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildCreditNoteFromDB(r.Context(), a.DB, clientID, baseNumber, creditNoteNo)
+		if err != nil {
+			handleCreditNoteDocumentBuildError(w, r, clientID, baseNumber, creditNoteNo, "pdf", err)
+			return
+		}
+
+		fileBytes, err := pdf.RenderPDF(r.Context(), &pdf.MarotoRenderer{}, doc)
+		if err != nil {
+			handleCreditNoteDocumentRenderError(w, r, clientID, baseNumber, creditNoteNo, "PDF", err)
+			return
+		}
+
+		writeGeneratedDocument(w, "application/pdf", buildCreditNoteFilename(baseNumber, creditNoteNo, "pdf"), fileBytes)
+	}
+}
+
+func GenerateCreditNoteDOCXHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		creditNoteNo, ok := params.ValidateParam(w, r, "creditNoteNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildCreditNoteFromDB(r.Context(), a.DB, clientID, baseNumber, creditNoteNo)
+		if err != nil {
+			handleCreditNoteDocumentBuildError(w, r, clientID, baseNumber, creditNoteNo, "docx", err)
+			return
+		}
+
+		fileBytes, err := docx.RenderDOCX(doc)
+		if err != nil {
+			handleCreditNoteDocumentRenderError(w, r, clientID, baseNumber, creditNoteNo, "DOCX", err)
+			return
+		}
+
+		writeGeneratedDocument(w, docxContentType, buildCreditNoteFilename(baseNumber, creditNoteNo, "docx"), fileBytes)
+	}
+}
+
+func handleCreditNoteDocumentBuildError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	baseNumber int64,
+	creditNoteNo int64,
+	format string,
+	err error,
+) {
+	if errors.Is(err, invoiceTx.ErrCreditNoteNotFound) {
+		res.Error(w, http.StatusNotFound, "CREDIT_NOTE_NOT_FOUND", "Credit note not found")
+		return
+	}
+
+	slog.ErrorContext(r.Context(),
+		"build credit note download data failed",
+		"format", format,
+		"client_id", clientID,
+		"base_number", baseNumber,
+		"credit_note_no", creditNoteNo,
+		"err", err,
+	)
+	res.Error(w, http.StatusInternalServerError, "INTERNAL", "Internal server error")
+}
+
+func handleCreditNoteDocumentRenderError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	baseNumber int64,
+	creditNoteNo int64,
+	formatUpper string,
+	err error,
+) {
+	slog.ErrorContext(r.Context(),
+		"generate credit note file failed",
+		"format", formatUpper,
+		"client_id", clientID,
+		"base_number", baseNumber,
+		"credit_note_no", creditNoteNo,
+		"err", err,
+	)
+	res.Error(
+		w,
+		http.StatusInternalServerError,
+		formatUpper+"_GENERATION_FAILED",
+		fmt.Sprintf("Failed to generate %s", formatUpper),
+	)
+}
+
+func toCreditNotesOut(in []invoiceTx.CreditNoteRow) []models.CreditNoteOut {
+	out := make([]models.CreditNoteOut, 0, len(in))
+	for _, note := range in {
+		var reason *string
+		if note.Reason.Valid {
+			value := note.Reason.String
+			reason = &value
+		}
+
+		lines := make([]models.CreditNoteLineIn, 0, len(note.Lines))
+		for _, ln := range note.Lines {
+			lines = append(lines, models.CreditNoteLineIn{
+				Name:           ln.Name,
+				LineType:       ln.LineType,
+				Quantity:       ln.Quantity,
+				UnitPriceMinor: ln.UnitPriceMinor,
+				LineTotalMinor: ln.LineTotalMinor,
+				SortOrder:      ln.SortOrder,
+				VATRate:        ln.VATRate,
+			})
+		}
+
+		out = append(out, models.CreditNoteOut{
+			ID:             note.ID,
+			CreditNoteNo:   note.CreditNoteNo,
+			RevisionNo:     note.RevisionNo,
+			IssueDate:      note.IssueDate,
+			Reason:         reason,
+			VATRate:        note.VATRate,
+			SubtotalMinor:  note.SubtotalMinor,
+			VatAmountMinor: note.VatAmountMinor,
+			TotalMinor:     note.TotalMinor,
+			Lines:          lines,
+			VATBreakdown:   note.VATBreakdown,
+		})
+	}
+	return out
+}
@@ -31,6 +31,9 @@ func DeleteInvoice(a *app.App) http.HandlerFunc {
 			case errors.Is(err, invoiceTx.ErrInvoiceDeleteVoid):
 				res.Error(w, http.StatusConflict, "INVOICE_VOID", "Void invoices are final records and cannot be deleted")
 				return
+			case errors.Is(err, invoiceTx.ErrInvoiceDeleteCredited):
+				res.Error(w, http.StatusConflict, "INVOICE_CREDITED", "Invoice has credit notes or refunds; void it instead of deleting it")
+				return
 			}
 
 			slog.ErrorContext(r.Context(),
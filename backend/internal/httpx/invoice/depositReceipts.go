@@ -0,0 +1,288 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/docx"
+	"github.com/viktorHadz/goInvoice26/internal/service/pdf"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func CreateDepositReceipt(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		var dto models.PaymentReceiptCreateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidatePaymentReceiptCreate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		invoiceID, depositID, depositNo, err := invoiceTx.CreateDepositReceipt(ctx, a, clientID, baseNumber, &valid)
+		if err != nil {
+			switch {
+			case errors.Is(err, invoiceTx.ErrETagMismatch):
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceNotFound):
+				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceVoidForReceipt):
+				res.Error(w, http.StatusConflict, "INVOICE_VOID", "Invoice is void; deposit receipts are not allowed")
+				return
+			case errors.Is(err, invoiceTx.ErrNoDepositRequested):
+				res.Error(w, http.StatusConflict, "NO_DEPOSIT_REQUESTED", "Invoice does not request a deposit")
+				return
+			case errors.Is(err, invoiceTx.ErrDepositExceedsRequested):
+				res.Validation(w, res.Invalid("amountMinor", "received deposits cannot exceed the requested deposit"))
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"create deposit receipt failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		setInvoiceETag(w, r, a, clientID, baseNumber)
+		res.JSON(w, http.StatusCreated, map[string]any{
+			"invoiceId": invoiceID,
+			"depositId": depositID,
+			"depositNo": depositNo,
+		})
+	}
+}
+
+func DeleteDepositReceipt(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		depositNo, ok := params.ValidateParam(w, r, "depositNo")
+		if !ok {
+			return
+		}
+
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		depositID, err := invoiceTx.DeleteDepositReceipt(ctx, a, clientID, baseNumber, depositNo)
+		if err != nil {
+			switch {
+			case errors.Is(err, invoiceTx.ErrETagMismatch):
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceNotFound), errors.Is(err, invoiceTx.ErrDepositReceiptNotFound):
+				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Deposit receipt not found")
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceVoidForReceipt):
+				res.Error(w, http.StatusConflict, "INVOICE_VOID", "Invoice is void; deposit receipts are not editable")
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"delete deposit receipt failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"deposit_no", depositNo,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		setInvoiceETag(w, r, a, clientID, baseNumber)
+		res.JSON(w, http.StatusOK, map[string]any{
+			"depositId": depositID,
+			"depositNo": depositNo,
+		})
+	}
+}
+
+func ListDepositReceipts(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		deposits, err := invoiceTx.ListDepositReceipts(r.Context(), a.DB, clientID, baseNumber)
+		if err != nil {
+			slog.ErrorContext(r.Context(),
+				"list deposit receipts failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		out := make([]models.DepositReceiptOut, 0, len(deposits))
+		for _, deposit := range deposits {
+			out = append(out, models.DepositReceiptOut{
+				ID:            deposit.ID,
+				DepositNo:     deposit.ReceiptNo,
+				RevisionNo:    deposit.AppliedRevisionNo,
+				PaymentDate:   deposit.PaymentDate,
+				AmountMinor:   deposit.AmountMinor,
+				Label:         nullStringOut(deposit.Label),
+				PaymentMethod: nullStringOut(deposit.PaymentMethod),
+				Reference:     nullStringOut(deposit.Reference),
+			})
+		}
+
+		res.JSON(w, http.StatusOK, out)
+	}
+}
+
+func GenerateDepositReceiptPDFHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		depositNo, ok := params.ValidateParam(w, r, "depositNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildDepositReceiptFromDB(r.Context(), a.DB, clientID, baseNumber, depositNo)
+		if err != nil {
+			handleDepositReceiptDocumentBuildError(w, r, clientID, baseNumber, depositNo, "pdf", err)
+			return
+		}
+
+		fileBytes, err := pdf.RenderPDF(r.Context(), &pdf.MarotoRenderer{}, doc)
+		if err != nil {
+			handleDepositReceiptDocumentRenderError(w, r, clientID, baseNumber, depositNo, "PDF", err)
+			return
+		}
+
+		writeGeneratedDocument(w, "application/pdf", buildDepositReceiptFilename(baseNumber, depositNo, "pdf"), fileBytes)
+	}
+}
+
+func GenerateDepositReceiptDOCXHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		depositNo, ok := params.ValidateParam(w, r, "depositNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildDepositReceiptFromDB(r.Context(), a.DB, clientID, baseNumber, depositNo)
+		if err != nil {
+			handleDepositReceiptDocumentBuildError(w, r, clientID, baseNumber, depositNo, "docx", err)
+			return
+		}
+
+		fileBytes, err := docx.RenderDOCX(doc)
+		if err != nil {
+			handleDepositReceiptDocumentRenderError(w, r, clientID, baseNumber, depositNo, "DOCX", err)
+			return
+		}
+
+		writeGeneratedDocument(w, docxContentType, buildDepositReceiptFilename(baseNumber, depositNo, "docx"), fileBytes)
+	}
+}
+
+func handleDepositReceiptDocumentBuildError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	baseNumber int64,
+	depositNo int64,
+	format string,
+	err error,
+) {
+	if errors.Is(err, invoiceTx.ErrDepositReceiptNotFound) {
+		res.Error(w, http.StatusNotFound, "DEPOSIT_RECEIPT_NOT_FOUND", "Deposit receipt not found")
+		return
+	}
+
+	slog.ErrorContext(r.Context(),
+		"build deposit receipt download data failed",
+		"format", format,
+		"client_id", clientID,
+		"base_number", baseNumber,
+		"deposit_no", depositNo,
+		"err", err,
+	)
+	res.Error(w, http.StatusInternalServerError, "INTERNAL", "Internal server error")
+}
+
+func handleDepositReceiptDocumentRenderError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	baseNumber int64,
+	depositNo int64,
+	formatUpper string,
+	err error,
+) {
+	slog.ErrorContext(r.Context(),
+		"generate deposit receipt file failed",
+		"format", formatUpper,
+		"client_id", clientID,
+		"base_number", baseNumber,
+		"deposit_no", depositNo,
+		"err", err,
+	)
+	res.Error(
+		w,
+		http.StatusInternalServerError,
+		formatUpper+"_GENERATION_FAILED",
+		fmt.Sprintf("Failed to generate %s", formatUpper),
+	)
+}
@@ -0,0 +1,238 @@
+package invoice
+
+import (
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/clientsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+)
+
+// DuplicateInvoice copies an invoice revision into a new draft under the next
+// base number, optionally for another client. Receipts are never copied.
+func DuplicateInvoice(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		var dto models.InvoiceDuplicateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateInvoiceDuplicate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		targetClientID := clientID
+		if valid.ClientID != nil {
+			targetClientID = *valid.ClientID
+		}
+		target, err := clientsTx.GetByID(r.Context(), a, targetClientID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				res.NotFound(w, "client not found")
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "load target client failed before invoice duplicate", "client_id", targetClientID, "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		var revisionNo int64
+		if valid.RevisionNo != nil {
+			revisionNo = *valid.RevisionNo
+		} else {
+			revisionNo, err = invoiceTx.QueryCurrentRevisionNo(r.Context(), a.DB, clientID, baseNumber)
+			if err != nil {
+				handleDuplicateLoadError(w, r, clientID, baseNumber, err)
+				return
+			}
+		}
+
+		summary, err := invoiceTx.QueryInvoiceSummary(r.Context(), a.DB, clientID, baseNumber, revisionNo)
+		if err != nil {
+			handleDuplicateLoadError(w, r, clientID, baseNumber, err)
+			return
+		}
+		lines, err := invoiceTx.QueryInvoiceLines(r.Context(), a.DB, clientID, baseNumber, revisionNo)
+		if err != nil {
+			handleDuplicateLoadError(w, r, clientID, baseNumber, err)
+			return
+		}
+
+		issueDate := ""
+		if valid.IssueDate != nil {
+			issueDate = *valid.IssueDate
+		} else {
+			accountID, err := accountscope.Require(r.Context())
+			if err != nil {
+				slog.ErrorContext(r.Context(), "duplicate invoice missing account scope", "err", err)
+				res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+				return
+			}
+			issueDate, err = settingsTx.Today(r.Context(), a.DB, accountID, time.Now())
+			if err != nil {
+				slog.ErrorContext(r.Context(), "duplicate invoice resolve date failed", "err", err)
+				res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+				return
+			}
+		}
+		dueByDate := valid.DueByDate
+		if dueByDate == nil {
+			dueByDate = shiftDueDate(summary.IssueDate, summary.DueByDate, issueDate)
+		} else if *dueByDate < issueDate {
+			res.Validation(w, res.Invalid("dueByDate", "must be on or after issueDate"))
+			return
+		}
+
+		inv := models.FEInvoiceIn{
+			Overview: models.InvoiceCreateIn{
+				ClientID:          target.ID,
+				IssueDate:         issueDate,
+				DueByDate:         dueByDate,
+				ClientName:        target.Name,
+				ClientCompanyName: target.CompanyName,
+				ClientAddress:     target.Address,
+				ClientEmail:       target.Email,
+				Currency:          summary.Currency,
+				ExchangeRateMicro: summary.ExchangeRateMicro,
+				VATTreatment:      summary.VATTreatment,
+				ClientVATNumber:   target.VATNumber,
+			},
+			Totals: totalsFromSummary(summary),
+		}
+		if summary.Note.Valid {
+			inv.Overview.Note = &summary.Note.String
+		}
+		if inv.Overview.ClientVATNumber == "" && target.ID == clientID {
+			inv.Overview.ClientVATNumber = summary.ClientVATNumber
+		}
+		inv.Totals.PaidMinor = 0
+
+		for _, it := range lines {
+			ln := lineFromItem(it)
+			// Products are scoped to a client; links would fail the item scope trigger.
+			if target.ID != clientID {
+				ln.ProductID = nil
+			}
+			inv.Lines = append(inv.Lines, ln)
+		}
+
+		canonical := RecalcInvoice(inv)
+		invoiceID, revisionID, newBaseNumber, err := invoiceTx.CreateNext(r.Context(), a, &canonical)
+		if err != nil {
+			if errors.Is(err, invoiceTx.ErrClientVATNumberRequired) {
+				res.Validation(w, res.Invalid("clientId", "the target client needs a VAT number for a reverse charge invoice"))
+				return
+			}
+			slog.ErrorContext(r.Context(),
+				"duplicate invoice failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"target_client_id", target.ID,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, map[string]any{
+			"invoiceId":  invoiceID,
+			"revisionId": revisionID,
+			"clientId":   target.ID,
+			"baseNumber": newBaseNumber,
+		})
+	}
+}
+
+func handleDuplicateLoadError(w http.ResponseWriter, r *http.Request, clientID, baseNumber int64, err error) {
+	if errors.Is(err, invoiceTx.ErrInvoiceNotFound) || errors.Is(err, sql.ErrNoRows) {
+		res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice revision not found")
+		return
+	}
+
+	slog.ErrorContext(r.Context(),
+		"load invoice for duplicate failed",
+		"client_id", clientID,
+		"base_number", baseNumber,
+		"err", err,
+	)
+	res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+}
+
+// shiftDueDate keeps the source issue-to-due gap for a copy issued on issueDate.
+func shiftDueDate(sourceIssue string, sourceDue sql.NullString, issueDate string) *string {
+	if !sourceDue.Valid {
+		return nil
+	}
+	issueAt, issueErr := time.Parse("2006-01-02", sourceIssue)
+	dueAt, dueErr := time.Parse("2006-01-02", sourceDue.String)
+	newIssueAt, newErr := time.Parse("2006-01-02", issueDate)
+	if issueErr != nil || dueErr != nil || newErr != nil {
+		return nil
+	}
+	due := newIssueAt.Add(dueAt.Sub(issueAt)).Format("2006-01-02")
+	return &due
+}
+
+// totalsFromSummary copies the pricing inputs of a saved revision; derived
+// totals are left for RecalcInvoice. The registry tax rate link is not copied:
+// the rate may have changed or lapsed since, and the copy keeps the VAT rate.
+func totalsFromSummary(summary *invoiceTx.InvoiceOverviewTotals) models.TotalsCreateIn {
+	return models.TotalsCreateIn{
+		VATRate:       summary.VATRate,
+		DepositType:   summary.DepositType,
+		DepositRate:   summary.DepositRate,
+		DepositMinor:  summary.DepositMinor,
+		DiscountType:  summary.DiscountType,
+		DiscountRate:  summary.DiscountRate,
+		DiscountMinor: summary.DiscountMinor,
+		PaidMinor:     summary.PaidMinor,
+
+		DeductionRate:      summary.DeductionRate,
+		DeductionLineTypes: summary.DeductionLineTypes,
+	}
+}
+
+// lineFromItem maps a saved line back into the create payload shape.
+func lineFromItem(it invoiceTx.ItemLine) models.LineCreateIn {
+	pricingMode := "flat"
+	if it.PricingMode != nil {
+		pricingMode = *it.PricingMode
+	}
+	return models.LineCreateIn{
+		ProductID:      it.ProductID,
+		Name:           it.Name,
+		LineType:       it.LineType,
+		PricingMode:    pricingMode,
+		Quantity:       it.Quantity,
+		Unit:           it.Unit,
+		MinutesWorked:  it.MinutesWorked,
+		UnitPriceMinor: it.UnitPriceMin,
+		LineTotalMinor: it.LineTotalMin,
+		SortOrder:      it.SortOrder,
+		VATRate:        it.VATRate,
+		DiscountType:   it.DiscountType,
+		DiscountRate:   it.DiscountRate,
+		DiscountMinor:  it.DiscountMinor,
+	}
+}
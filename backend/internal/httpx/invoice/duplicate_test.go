@@ -0,0 +1,31 @@
+package invoice
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestShiftDueDate_KeepsSourceGap(t *testing.T) {
+	tests := []struct {
+		name      string
+		sourceDue sql.NullString
+		issueDate string
+		want      string
+	}{
+		{name: "fourteen days", sourceDue: sql.NullString{String: "2026-03-15", Valid: true}, issueDate: "2026-04-30", want: "2026-05-14"},
+		{name: "same day", sourceDue: sql.NullString{String: "2026-03-01", Valid: true}, issueDate: "2026-04-02", want: "2026-04-02"},
+		{name: "no due date", issueDate: "2026-04-02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := shiftDueDate("2026-03-01", tt.sourceDue, tt.issueDate)
+			switch {
+			case tt.want == "" && got != nil:
+				t.Fatalf("shiftDueDate() = %q, want nil", *got)
+			case tt.want != "" && (got == nil || *got != tt.want):
+				t.Fatalf("shiftDueDate() = %v, want %q", got, tt.want)
+			}
+		})
+	}
+}
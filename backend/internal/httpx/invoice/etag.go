@@ -0,0 +1,79 @@
+package invoice
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/editor"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+type invoiceChangedResponse struct {
+	Error   res.APIError                  `json:"error"`
+	ETag    string                        `json:"etag,omitempty"`
+	Current *models.InvoiceEditorResponse `json:"current,omitempty"`
+}
+
+// requireIfMatch returns the request context carrying the If-Match header.
+// Edits to an existing invoice or its receipts must send the ETag they were
+// loaded with, so a missing header writes 428 and returns false.
+func requireIfMatch(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		res.Error(w, http.StatusPreconditionRequired, "PRECONDITION_REQUIRED", "If-Match header is required")
+		return nil, false
+	}
+	return invoiceTx.WithIfMatch(r.Context(), ifMatch), true
+}
+
+// writeInvoiceChanged writes 412 with the invoice's current revision, so the
+// editor can show what changed without another round trip.
+func writeInvoiceChanged(w http.ResponseWriter, r *http.Request, a *app.App, clientID, baseNumber int64) {
+	out := invoiceChangedResponse{
+		Error: res.APIError{
+			Code:    "PRECONDITION_FAILED",
+			Message: "Invoice was changed by someone else; reload it before saving",
+		},
+	}
+
+	revisionNo, err := invoiceTx.QueryCurrentRevisionNo(r.Context(), a.DB, clientID, baseNumber)
+	if err == nil {
+		out.Current, err = editor.LoadInvoiceEditor(r.Context(), a.DB, clientID, baseNumber, revisionNo)
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(),
+			"load current invoice for precondition failure",
+			"client_id", clientID,
+			"base_number", baseNumber,
+			"err", err,
+		)
+	}
+	if out.Current != nil {
+		out.ETag = out.Current.ETag
+		w.Header().Set("ETag", out.ETag)
+	}
+
+	res.JSON(w, http.StatusPreconditionFailed, out)
+}
+
+// setInvoiceETag sets the ETag header to the invoice's version after a
+// successful edit. The edit has already committed, so a lookup failure is
+// only logged.
+func setInvoiceETag(w http.ResponseWriter, r *http.Request, a *app.App, clientID, baseNumber int64) {
+	etag, err := invoiceTx.QueryInvoiceETag(r.Context(), a.DB, clientID, baseNumber)
+	if err != nil {
+		slog.WarnContext(r.Context(),
+			"load invoice etag after edit",
+			"client_id", clientID,
+			"base_number", baseNumber,
+			"err", err,
+		)
+		return
+	}
+	w.Header().Set("ETag", etag)
+}
@@ -0,0 +1,33 @@
+package invoice
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDeletePaymentReceipt_RequiresIfMatch(t *testing.T) {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("clientID", "1")
+	rctx.URLParams.Add("baseNumber", "7")
+	rctx.URLParams.Add("revisionNo", "1")
+	rctx.URLParams.Add("receiptNo", "1")
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/clients/1/invoice/7/revisions/1/receipts/1", nil)
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+
+	// The header check runs before any database access, so no app is needed.
+	DeletePaymentReceipt(nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusPreconditionRequired)
+	}
+	if !strings.Contains(rec.Body.String(), "PRECONDITION_REQUIRED") {
+		t.Fatalf("body = %q, want PRECONDITION_REQUIRED", rec.Body.String())
+	}
+}
@@ -152,3 +152,64 @@ func buildPaymentReceiptFilename(baseNumber int64, revisionNo int64, receiptNo i
 
 	return fmt.Sprintf("Invoice-%d.%d-PR-%d.%s", baseNumber, revisionNo, receiptNo, ext)
 }
+
+func buildCreditNoteFilename(baseNumber int64, creditNoteNo int64, ext string) string {
+	ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+	if ext == "" {
+		ext = "bin"
+	}
+
+	if baseNumber < 1 {
+		return "Credit-Note." + ext
+	}
+	if creditNoteNo < 1 {
+		return fmt.Sprintf("Invoice-%d-CN.%s", baseNumber, ext)
+	}
+
+	return fmt.Sprintf("Invoice-%d-CN-%d.%s", baseNumber, creditNoteNo, ext)
+}
+
+func buildDepositReceiptFilename(baseNumber int64, depositNo int64, ext string) string {
+	ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+	if ext == "" {
+		ext = "bin"
+	}
+
+	if baseNumber < 1 {
+		return "Deposit-Receipt." + ext
+	}
+	if depositNo < 1 {
+		return fmt.Sprintf("Invoice-%d-DR.%s", baseNumber, ext)
+	}
+
+	return fmt.Sprintf("Invoice-%d-DR-%d.%s", baseNumber, depositNo, ext)
+}
+
+func buildRefundFilename(baseNumber int64, refundNo int64, ext string) string {
+	ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+	if ext == "" {
+		ext = "bin"
+	}
+
+	if baseNumber < 1 {
+		return "Refund." + ext
+	}
+	if refundNo < 1 {
+		return fmt.Sprintf("Invoice-%d-RF.%s", baseNumber, ext)
+	}
+
+	return fmt.Sprintf("Invoice-%d-RF-%d.%s", baseNumber, refundNo, ext)
+}
+
+func buildQuoteFilename(quoteNumber int64, ext string) string {
+	ext = strings.TrimPrefix(strings.TrimSpace(ext), ".")
+	if ext == "" {
+		ext = "bin"
+	}
+
+	if quoteNumber < 1 {
+		return "Quote." + ext
+	}
+
+	return fmt.Sprintf("Quote-%d.%s", quoteNumber, ext)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
 	"github.com/viktorHadz/goInvoice26/internal/models"
 	"github.com/viktorHadz/goInvoice26/internal/service/pdf"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
@@ -90,7 +91,12 @@ func QuickPDFHandler(a *app.App) http.HandlerFunc {
 				return models.InvoicePDFData{}, fmt.Errorf("get settings: %w", err)
 			}
 
-			return pdf.BuildQuickInvoice(canonical, settings, revisionNo), nil
+			numberLabel, err := invoiceTx.QueryNumberLabel(r.Context(), a.DB, clientID, baseNumber, &canonical.Overview)
+			if err != nil {
+				return models.InvoicePDFData{}, err
+			}
+
+			return pdf.BuildQuickInvoice(canonical, settings, revisionNo, numberLabel), nil
 		}
 
 		handleInvoiceFileGeneration(
@@ -0,0 +1,101 @@
+package invoice
+
+import (
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+// GetInvoiceHistory lists revisions, payment receipts, refunds and any void for
+// one invoice, oldest first; a void carries its reason as the label.
+// ?paymentMethod= keeps only receipts paid that way and ?diffs=true adds what
+// changed to each revision after the first.
+func GetInvoiceHistory(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		var filters invoiceTx.InvoiceHistoryFilters
+		if raw := r.URL.Query().Get("paymentMethod"); raw != "" {
+			method, errs := validateOptionalPaymentMethod(&raw, "paymentMethod")
+			if len(errs) > 0 {
+				res.Validation(w, errs...)
+				return
+			}
+			if method != nil {
+				filters.PaymentMethod = *method
+			}
+		}
+		if raw := r.URL.Query().Get("diffs"); raw != "" {
+			v, err := strconv.ParseBool(raw)
+			if err != nil {
+				res.Error(w, http.StatusBadRequest, "BAD_QUERY", "Invalid diffs")
+				return
+			}
+			filters.IncludeRevisionDiffs = v
+		}
+
+		rows, err := invoiceTx.QueryInvoiceHistory(r.Context(), a.DB, clientID, baseNumber, filters)
+		if err != nil {
+			slog.ErrorContext(r.Context(),
+				"query invoice history failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		out := make([]models.InvoiceHistoryEntryOut, 0, len(rows))
+		for _, row := range rows {
+			out = append(out, models.InvoiceHistoryEntryOut{
+				Type:          row.Type,
+				CreatedAt:     row.CreatedAt,
+				RevisionNo:    nullInt64Out(row.RevisionNo),
+				ReceiptNo:     nullInt64Out(row.ReceiptNo),
+				IssueDate:     nullStringOut(row.IssueDate),
+				DueByDate:     nullStringOut(row.DueByDate),
+				PaymentDate:   nullStringOut(row.PaymentDate),
+				AmountMinor:   nullInt64Out(row.AmountMinor),
+				Label:         nullStringOut(row.Label),
+				PaymentMethod: nullStringOut(row.PaymentMethod),
+				Reference:     nullStringOut(row.Reference),
+				RefundNo:      nullInt64Out(row.RefundNo),
+				VoidedBy:      nullStringOut(row.VoidedBy),
+				Diff:          revisionDiffOut(row.Diff),
+			})
+		}
+
+		res.JSON(w, http.StatusOK, out)
+	}
+}
+
+func nullStringOut(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := v.String
+	return &s
+}
+
+func nullInt64Out(v sql.NullInt64) *int64 {
+	if !v.Valid {
+		return nil
+	}
+	n := v.Int64
+	return &n
+}
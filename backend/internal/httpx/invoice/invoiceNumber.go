@@ -1,12 +1,18 @@
 package invoice
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"time"
 
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
 func GetNextInvoiceNumber(a *app.App) http.HandlerFunc {
@@ -24,3 +30,53 @@ func GetNextInvoiceNumber(a *app.App) http.HandlerFunc {
 		res.JSON(w, http.StatusOK, maxNum)
 	}
 }
+
+// PreviewNextInvoiceNumber returns the base number and template number a new
+// invoice for the client would get, issued on ?issueDate or today. Nothing is
+// allocated.
+func PreviewNextInvoiceNumber(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+
+		issueDate := r.URL.Query().Get("issueDate")
+		if issueDate != "" {
+			var errs []res.FieldError
+			if issueDate, errs = validateISODateRequired("issueDate", issueDate); len(errs) > 0 {
+				res.Validation(w, errs...)
+				return
+			}
+		} else {
+			accountID, err := accountscope.Require(r.Context())
+			if err != nil {
+				slog.ErrorContext(r.Context(), "preview invoice number missing account scope", "err", err)
+				res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+				return
+			}
+			issueDate, err = settingsTx.Today(r.Context(), a.DB, accountID, time.Now())
+			if err != nil {
+				slog.ErrorContext(r.Context(), "preview invoice number resolve date failed", "err", err)
+				res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+				return
+			}
+		}
+
+		next, err := invoiceTx.PreviewNextInvoiceNumber(r.Context(), a, clientID, issueDate)
+		if err != nil {
+			if errors.Is(err, invoiceTx.ErrInvoiceNotFound) {
+				res.NotFound(w, "Client not found")
+				return
+			}
+			slog.ErrorContext(r.Context(), "preview invoice number failed", "err", err, "client_id", clientID)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, models.NextInvoiceNumberOut{
+			BaseNumber:  next.BaseNumber,
+			NumberLabel: next.NumberLabel,
+		})
+	}
+}
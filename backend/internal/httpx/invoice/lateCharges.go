@@ -0,0 +1,363 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
+	"github.com/viktorHadz/goInvoice26/internal/service/latepayment"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+)
+
+// lateChargeQuote is a computed charge plus the state and settings it came from.
+type lateChargeQuote struct {
+	out      models.LateChargeOut
+	state    *invoiceTx.LateChargeState
+	settings models.Settings
+}
+
+// PreviewLateCharge works out the late payment interest and compensation an
+// overdue invoice has accrued as of today, without raising anything.
+func PreviewLateCharge(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		quote, ok := quoteLateCharge(w, r, a, clientID, baseNumber)
+		if !ok {
+			return
+		}
+
+		res.JSON(w, http.StatusOK, quote.out)
+	}
+}
+
+// CreateLateCharge raises the accrued charge either as a new revision of the
+// overdue invoice or as a separate draft charge invoice.
+func CreateLateCharge(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		var dto models.LateChargeIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+		mode := strings.TrimSpace(strings.ToLower(dto.Mode))
+		switch mode {
+		case "":
+			res.Validation(w, res.Required("mode"))
+			return
+		case invoiceTx.LateChargeModeRevision, invoiceTx.LateChargeModeInvoice:
+		default:
+			res.Validation(w, res.Invalid("mode", "must be one of: revision, invoice"))
+			return
+		}
+
+		quote, ok := quoteLateCharge(w, r, a, clientID, baseNumber)
+		if !ok {
+			return
+		}
+		if quote.out.TotalMinor <= 0 {
+			res.Error(w, http.StatusConflict, "NO_LATE_CHARGE", "Nothing has accrued since the last late payment charge")
+			return
+		}
+
+		charge := invoiceTx.LateCharge{
+			Mode:              mode,
+			AccruedFrom:       quote.out.AccruedFrom,
+			ChargedUntil:      quote.out.AsOf,
+			AnnualRateBps:     quote.out.AnnualRateBps,
+			InterestMinor:     quote.out.InterestMinor,
+			CompensationMinor: quote.out.CompensationMinor,
+		}
+		lines := lateChargeLines(quote.out)
+
+		out := quote.out
+		out.Mode = mode
+
+		var err error
+		switch mode {
+		case invoiceTx.LateChargeModeRevision:
+			summary, lerr := invoiceTx.QueryInvoiceSummary(r.Context(), a.DB, clientID, baseNumber, quote.state.RevisionNo)
+			if lerr != nil {
+				err = lerr
+				break
+			}
+			if summary.DiscountType == "percent" || summary.DepositType == "percent" {
+				res.Error(w, http.StatusConflict, "LATE_CHARGE_NEEDS_INVOICE", "Invoice has a percentage discount or deposit; raise the charge as a separate invoice")
+				return
+			}
+			existing, lerr := invoiceTx.QueryInvoiceLines(r.Context(), a.DB, clientID, baseNumber, quote.state.RevisionNo)
+			if lerr != nil {
+				err = lerr
+				break
+			}
+
+			canonical := RecalcInvoice(lateChargeRevision(clientID, summary, existing, lines))
+			var revisionNo int64
+			_, _, revisionNo, err = invoiceTx.CreateLateChargeRevision(r.Context(), a, &canonical, charge)
+			out.RevisionNo = &revisionNo
+
+		case invoiceTx.LateChargeModeInvoice:
+			summary, lerr := invoiceTx.QueryInvoiceSummary(r.Context(), a.DB, clientID, baseNumber, quote.state.RevisionNo)
+			if lerr != nil {
+				err = lerr
+				break
+			}
+			source := invoiceformat.Numbering{
+				Prefix:     quote.settings.InvoicePrefix,
+				BaseNumber: baseNumber,
+				Label:      summary.NumberLabel.String,
+			}.Invoice(1)
+
+			canonical := RecalcInvoice(lateChargeInvoice(clientID, summary, source, quote.out.AsOf, lines))
+			var chargeBase int64
+			_, chargeBase, err = invoiceTx.CreateLateChargeInvoice(r.Context(), a, baseNumber, &canonical, charge)
+			out.ChargeInvoiceBaseNumber = &chargeBase
+		}
+		if err != nil {
+			switch {
+			case errors.Is(err, invoiceTx.ErrInvoiceNotFound):
+				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceDraftForRevision),
+				errors.Is(err, invoiceTx.ErrInvoiceVoidForRevision),
+				errors.Is(err, invoiceTx.ErrInvoicePaidForRevision):
+				res.Error(w, http.StatusConflict, "INVOICE_NOT_OVERDUE", "Invoice is no longer overdue")
+				return
+			case errors.Is(err, invoiceTx.ErrPaymentStateMismatch), errors.Is(err, invoiceTx.ErrLateChargeExists):
+				res.Error(w, http.StatusConflict, "LATE_CHARGE_CONFLICT", "Invoice changed while charging; refresh and try again")
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"create late charge failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"mode", mode,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, out)
+	}
+}
+
+// quoteLateCharge loads the invoice and workspace settings and runs the
+// calculator. It writes the error response itself and returns false on failure.
+func quoteLateCharge(w http.ResponseWriter, r *http.Request, a *app.App, clientID, baseNumber int64) (lateChargeQuote, bool) {
+	accountID, err := accountscope.Require(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "late charge missing account scope", "err", err)
+		res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return lateChargeQuote{}, false
+	}
+
+	state, err := invoiceTx.QueryLateChargeState(r.Context(), a.DB, clientID, baseNumber)
+	if err != nil {
+		if errors.Is(err, invoiceTx.ErrInvoiceNotFound) {
+			res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
+			return lateChargeQuote{}, false
+		}
+		slog.ErrorContext(r.Context(), "load late charge state failed", "client_id", clientID, "base_number", baseNumber, "err", err)
+		res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return lateChargeQuote{}, false
+	}
+
+	settings, err := settingsTx.Get(r.Context(), a.DB, accountID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "load settings for late charge failed", "err", err)
+		res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return lateChargeQuote{}, false
+	}
+	asOf := time.Now().In(settingsTx.LoadTimezone(settings.Timezone)).Format("2006-01-02")
+
+	if state.Status != "issued" || !state.DueByDate.Valid || state.DueByDate.String >= asOf {
+		res.Error(w, http.StatusConflict, "INVOICE_NOT_OVERDUE", "Only issued invoices past their due date accrue late payment charges")
+		return lateChargeQuote{}, false
+	}
+
+	reductions := make([]latepayment.Reduction, 0, len(state.Reductions))
+	for _, red := range state.Reductions {
+		reductions = append(reductions, latepayment.Reduction{Date: red.Date, AmountMinor: red.AmountMinor})
+	}
+
+	rate := settings.LateBaseRate + settings.LateInterestRate
+	result, err := latepayment.AccruedInterest(latepayment.Input{
+		PrincipalMinor: state.TotalMinor - state.ChargedMinor,
+		DueDate:        state.DueByDate.String,
+		AccrueFrom:     state.LastChargedUntil,
+		AsOf:           asOf,
+		AnnualRateBps:  rate,
+		Reductions:     reductions,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "late charge calculation failed", "client_id", clientID, "base_number", baseNumber, "err", err)
+		res.Error(w, http.StatusInternalServerError, "INTERNAL", "Failed to calculate late payment charges")
+		return lateChargeQuote{}, false
+	}
+	if result.OutstandingMinor <= 0 {
+		res.Error(w, http.StatusConflict, "INVOICE_NOT_OVERDUE", "Invoice has no outstanding balance")
+		return lateChargeQuote{}, false
+	}
+
+	var compensation int64
+	if !state.CompensationCharged {
+		compensation = latepayment.Compensation(settings.LateCompensationTiers, result.DebtAtDueMinor)
+	}
+
+	return lateChargeQuote{
+		out: models.LateChargeOut{
+			AsOf:              asOf,
+			DueByDate:         state.DueByDate.String,
+			AccruedFrom:       result.AccruedFrom,
+			DaysCharged:       result.Days,
+			AnnualRateBps:     rate,
+			DebtAtDueMinor:    result.DebtAtDueMinor,
+			OutstandingMinor:  result.OutstandingMinor,
+			InterestMinor:     result.InterestMinor,
+			CompensationMinor: compensation,
+			TotalMinor:        result.InterestMinor + compensation,
+		},
+		state:    state,
+		settings: settings,
+	}, true
+}
+
+// lateChargeLines returns the interest and compensation as zero-VAT custom lines.
+// Sort orders are left for the caller.
+func lateChargeLines(out models.LateChargeOut) []models.LineCreateIn {
+	var zero int64
+	var lines []models.LineCreateIn
+	if out.InterestMinor > 0 {
+		lines = append(lines, models.LineCreateIn{
+			Name: fmt.Sprintf("Late payment interest at %s a year, %d days from %s to %s",
+				invoiceformat.FormatRateBps(out.AnnualRateBps), out.DaysCharged, out.AccruedFrom, out.AsOf),
+			LineType:       "custom",
+			PricingMode:    "flat",
+			Quantity:       models.WholeQuantity(1),
+			UnitPriceMinor: out.InterestMinor,
+			VATRate:        &zero,
+		})
+	}
+	if out.CompensationMinor > 0 {
+		lines = append(lines, models.LineCreateIn{
+			Name:           "Late payment compensation",
+			LineType:       "custom",
+			PricingMode:    "flat",
+			Quantity:       models.WholeQuantity(1),
+			UnitPriceMinor: out.CompensationMinor,
+			VATRate:        &zero,
+		})
+	}
+	return lines
+}
+
+// lateChargeRevision copies the current revision and appends the charge lines.
+// Existing receipts carry over, so PaidMinor must match the source revision.
+func lateChargeRevision(
+	clientID int64,
+	summary *invoiceTx.InvoiceOverviewTotals,
+	existing []invoiceTx.ItemLine,
+	charges []models.LineCreateIn,
+) models.FEInvoiceIn {
+	sourceRevisionNo := summary.RevisionNo
+	inv := models.FEInvoiceIn{
+		Overview: lateChargeOverview(clientID, summary),
+		Totals:   totalsFromSummary(summary),
+	}
+	inv.Overview.BaseNumber = summary.BaseNumber
+	inv.Overview.SourceRevisionNo = &sourceRevisionNo
+	inv.Overview.IssueDate = summary.IssueDate
+	if summary.SupplyDate.Valid {
+		inv.Overview.SupplyDate = &summary.SupplyDate.String
+	}
+	if summary.DueByDate.Valid {
+		inv.Overview.DueByDate = &summary.DueByDate.String
+	}
+	if summary.Note.Valid {
+		inv.Overview.Note = &summary.Note.String
+	}
+
+	var sortOrder int64
+	for _, it := range existing {
+		inv.Lines = append(inv.Lines, lineFromItem(it))
+		sortOrder = max(sortOrder, it.SortOrder)
+	}
+	for _, ln := range charges {
+		sortOrder++
+		ln.SortOrder = sortOrder
+		inv.Lines = append(inv.Lines, ln)
+	}
+
+	return inv
+}
+
+// lateChargeInvoice builds a standalone charge invoice issued and due on asOf.
+func lateChargeInvoice(
+	clientID int64,
+	summary *invoiceTx.InvoiceOverviewTotals,
+	sourceLabel string,
+	asOf string,
+	charges []models.LineCreateIn,
+) models.FEInvoiceIn {
+	note := fmt.Sprintf("Late payment charges on invoice %s", sourceLabel)
+	due := asOf
+	inv := models.FEInvoiceIn{
+		Overview: lateChargeOverview(clientID, summary),
+		Totals: models.TotalsCreateIn{
+			VATRate:      0,
+			DepositType:  "none",
+			DiscountType: "none",
+		},
+	}
+	inv.Overview.IssueDate = asOf
+	inv.Overview.DueByDate = &due
+	inv.Overview.Note = &note
+
+	for i, ln := range charges {
+		ln.SortOrder = int64(i + 1)
+		inv.Lines = append(inv.Lines, ln)
+	}
+
+	return inv
+}
+
+func lateChargeOverview(clientID int64, summary *invoiceTx.InvoiceOverviewTotals) models.InvoiceCreateIn {
+	return models.InvoiceCreateIn{
+		ClientID:          clientID,
+		ClientName:        summary.ClientName,
+		ClientCompanyName: summary.ClientCompanyName,
+		ClientAddress:     summary.ClientAddress,
+		ClientEmail:       summary.ClientEmail,
+		Currency:          summary.Currency,
+		ExchangeRateMicro: summary.ExchangeRateMicro,
+		VATTreatment:      summary.VATTreatment,
+		ClientVATNumber:   summary.ClientVATNumber,
+	}
+}
@@ -38,9 +38,17 @@ func CreatePaymentReceipt(a *app.App) http.HandlerFunc {
 			return
 		}
 
-		invoiceID, receiptID, receiptNo, err := invoiceTx.CreatePaymentReceipt(r.Context(), a, clientID, baseNumber, revisionNo, &valid)
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		invoiceID, receiptID, receiptNo, err := invoiceTx.CreatePaymentReceipt(ctx, a, clientID, baseNumber, revisionNo, &valid)
 		if err != nil {
 			switch {
+			case errors.Is(err, invoiceTx.ErrETagMismatch):
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
 			case errors.Is(err, invoiceTx.ErrInvoiceNotFound):
 				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice revision not found")
 				return
@@ -63,6 +71,7 @@ func CreatePaymentReceipt(a *app.App) http.HandlerFunc {
 			return
 		}
 
+		setInvoiceETag(w, r, a, clientID, baseNumber)
 		res.JSON(w, http.StatusCreated, map[string]any{
 			"invoiceId": invoiceID,
 			"receiptId": receiptID,
@@ -101,9 +110,17 @@ func UpdatePaymentReceipt(a *app.App) http.HandlerFunc {
 			return
 		}
 
-		receiptID, err := invoiceTx.UpdatePaymentReceiptMetadata(r.Context(), a, clientID, baseNumber, revisionNo, receiptNo, &valid)
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		receiptID, err := invoiceTx.UpdatePaymentReceiptMetadata(ctx, a, clientID, baseNumber, revisionNo, receiptNo, &valid)
 		if err != nil {
 			switch {
+			case errors.Is(err, invoiceTx.ErrETagMismatch):
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
 			case errors.Is(err, invoiceTx.ErrInvoiceNotFound), errors.Is(err, invoiceTx.ErrPaymentReceiptNotFound):
 				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Payment receipt not found")
 				return
@@ -124,6 +141,7 @@ func UpdatePaymentReceipt(a *app.App) http.HandlerFunc {
 			return
 		}
 
+		setInvoiceETag(w, r, a, clientID, baseNumber)
 		res.JSON(w, http.StatusOK, map[string]any{
 			"receiptId": receiptID,
 			"receiptNo": receiptNo,
@@ -150,15 +168,26 @@ func DeletePaymentReceipt(a *app.App) http.HandlerFunc {
 			return
 		}
 
-		receiptID, err := invoiceTx.DeletePaymentReceipt(r.Context(), a, clientID, baseNumber, revisionNo, receiptNo)
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		receiptID, err := invoiceTx.DeletePaymentReceipt(ctx, a, clientID, baseNumber, revisionNo, receiptNo)
 		if err != nil {
 			switch {
+			case errors.Is(err, invoiceTx.ErrETagMismatch):
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
 			case errors.Is(err, invoiceTx.ErrInvoiceNotFound), errors.Is(err, invoiceTx.ErrPaymentReceiptNotFound):
 				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Payment receipt not found")
 				return
 			case errors.Is(err, invoiceTx.ErrInvoiceVoidForReceipt):
 				res.Error(w, http.StatusConflict, "INVOICE_VOID", "Invoice is void; payment receipts are not editable")
 				return
+			case errors.Is(err, invoiceTx.ErrPaymentReceiptRefunded):
+				res.Error(w, http.StatusConflict, "RECEIPT_REFUNDED", "Payment receipt has refunds; it can no longer be deleted")
+				return
 			}
 
 			slog.ErrorContext(r.Context(),
@@ -173,6 +202,7 @@ func DeletePaymentReceipt(a *app.App) http.HandlerFunc {
 			return
 		}
 
+		setInvoiceETag(w, r, a, clientID, baseNumber)
 		res.JSON(w, http.StatusOK, map[string]any{
 			"receiptId": receiptID,
 			"receiptNo": receiptNo,
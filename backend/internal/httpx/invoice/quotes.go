@@ -0,0 +1,437 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/docx"
+	"github.com/viktorHadz/goInvoice26/internal/service/pdf"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/clientsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/quoteTx"
+)
+
+func GetNextQuoteNumber(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Return suggested next number (no allocation); number is "used" only on successful create.
+		next, err := quoteTx.GetSuggestedNextQuoteNumber(r.Context(), a)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "get next quote number failed", "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+		res.JSON(w, http.StatusOK, next)
+	}
+}
+
+func ListQuotes(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+
+		quotes, err := quoteTx.List(r.Context(), a.DB, clientID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "list quotes failed", "client_id", clientID, "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, quotes)
+	}
+}
+
+func GetQuote(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		quoteNo, ok := params.ValidateParam(w, r, "quoteNo")
+		if !ok {
+			return
+		}
+
+		quote, err := quoteTx.Get(r.Context(), a.DB, clientID, quoteNo)
+		if err != nil {
+			handleQuoteError(w, r, clientID, quoteNo, "get", err)
+			return
+		}
+
+		res.JSON(w, http.StatusOK, quote)
+	}
+}
+
+func CreateQuote(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		canonical, ok := decodeQuote(w, r, a)
+		if !ok {
+			return
+		}
+
+		quoteID, err := quoteTx.Create(r.Context(), a, &canonical)
+		if err != nil {
+			handleQuoteError(w, r, canonical.Overview.ClientID, canonical.Overview.QuoteNumber, "create", err)
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, map[string]any{
+			"quoteId":     quoteID,
+			"quoteNumber": canonical.Overview.QuoteNumber,
+		})
+	}
+}
+
+func UpdateQuote(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		canonical, ok := decodeQuote(w, r, a)
+		if !ok {
+			return
+		}
+
+		if err := quoteTx.Update(r.Context(), a, &canonical); err != nil {
+			handleQuoteError(w, r, canonical.Overview.ClientID, canonical.Overview.QuoteNumber, "update", err)
+			return
+		}
+
+		res.NoContent(w)
+	}
+}
+
+func DeleteQuote(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		quoteNo, ok := params.ValidateParam(w, r, "quoteNo")
+		if !ok {
+			return
+		}
+
+		if err := quoteTx.Delete(r.Context(), a, clientID, quoteNo); err != nil {
+			handleQuoteError(w, r, clientID, quoteNo, "delete", err)
+			return
+		}
+
+		res.NoContent(w)
+	}
+}
+
+// PatchQuoteStatus moves a quote between draft, sent, accepted and declined.
+func PatchQuoteStatus(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		quoteNo, ok := params.ValidateParam(w, r, "quoteNo")
+		if !ok {
+			return
+		}
+
+		var body models.QuoteStatusIn
+		if ok := res.DecodeJSON(w, r, &body); !ok {
+			return
+		}
+
+		next := strings.TrimSpace(strings.ToLower(body.Status))
+		switch next {
+		case "":
+			res.Validation(w, res.Required("status"))
+			return
+		case "draft", "sent", "accepted", "declined":
+		default:
+			res.Validation(w, res.Invalid("status", "must be one of: draft, sent, accepted, declined"))
+			return
+		}
+
+		if err := quoteTx.UpdateStatus(r.Context(), a, clientID, quoteNo, next); err != nil {
+			handleQuoteError(w, r, clientID, quoteNo, "patch status", err)
+			return
+		}
+
+		res.JSON(w, http.StatusOK, map[string]any{"status": next})
+	}
+}
+
+// ConvertQuote copies an accepted (or still open) quote into a new draft invoice.
+func ConvertQuote(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		quoteNo, ok := params.ValidateParam(w, r, "quoteNo")
+		if !ok {
+			return
+		}
+
+		var dto models.QuoteConvertIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateQuoteConvert(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		invoiceID, baseNumber, err := quoteTx.ConvertToInvoice(r.Context(), a, clientID, quoteNo, valid)
+		if err != nil {
+			handleQuoteError(w, r, clientID, quoteNo, "convert", err)
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, map[string]any{
+			"invoiceId":  invoiceID,
+			"baseNumber": baseNumber,
+		})
+	}
+}
+
+func GenerateQuotePDFHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		quoteNo, ok := params.ValidateParam(w, r, "quoteNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildQuoteFromDB(r.Context(), a.DB, clientID, quoteNo)
+		if err != nil {
+			handleQuoteError(w, r, clientID, quoteNo, "build pdf", err)
+			return
+		}
+
+		fileBytes, err := pdf.RenderPDF(r.Context(), &pdf.MarotoRenderer{}, doc)
+		if err != nil {
+			handleQuoteDocumentRenderError(w, r, clientID, quoteNo, "PDF", err)
+			return
+		}
+
+		writeGeneratedDocument(w, "application/pdf", buildQuoteFilename(quoteNo, "pdf"), fileBytes)
+	}
+}
+
+func GenerateQuoteDOCXHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		quoteNo, ok := params.ValidateParam(w, r, "quoteNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildQuoteFromDB(r.Context(), a.DB, clientID, quoteNo)
+		if err != nil {
+			handleQuoteError(w, r, clientID, quoteNo, "build docx", err)
+			return
+		}
+
+		fileBytes, err := docx.RenderDOCX(doc)
+		if err != nil {
+			handleQuoteDocumentRenderError(w, r, clientID, quoteNo, "DOCX", err)
+			return
+		}
+
+		writeGeneratedDocument(w, docxContentType, buildQuoteFilename(quoteNo, "docx"), fileBytes)
+	}
+}
+
+// decodeQuote runs a quote body through the invoice validation and recalc
+// pipeline, so quote totals follow exactly the same rules as invoice totals.
+func decodeQuote(w http.ResponseWriter, r *http.Request, a *app.App) (models.QuoteIn, bool) {
+	clientID, ok := params.ValidateParam(w, r, "clientID")
+	if !ok {
+		return models.QuoteIn{}, false
+	}
+	quoteNo, ok := params.ValidateParam(w, r, "quoteNo")
+	if !ok {
+		return models.QuoteIn{}, false
+	}
+
+	if err := clientsTx.VerifyClientID(r.Context(), a, clientID); err != nil {
+		if errors.Is(err, clientsTx.ErrClientNotFound) {
+			res.NotFound(w, "client not found")
+			return models.QuoteIn{}, false
+		}
+
+		slog.ErrorContext(r.Context(), "verify client failed before quote save", "client_id", clientID, "err", err)
+		res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return models.QuoteIn{}, false
+	}
+
+	var dto models.QuoteIn
+	if ok := res.DecodeJSON(w, r, &dto); !ok {
+		return models.QuoteIn{}, false
+	}
+
+	var routeErrs []res.FieldError
+	if dto.Overview.ClientID != clientID {
+		routeErrs = append(routeErrs, res.Invalid("clientId", "does not match route parameter"))
+	}
+	if dto.Overview.QuoteNumber != quoteNo {
+		routeErrs = append(routeErrs, res.Invalid("quoteNumber", "does not match route parameter"))
+	}
+	if dto.Totals.PaidMinor != 0 {
+		routeErrs = append(routeErrs, res.Invalid("totals.paidMinor", "must be 0 for quotes"))
+	}
+	if len(routeErrs) > 0 {
+		res.Validation(w, routeErrs...)
+		return models.QuoteIn{}, false
+	}
+
+	asInvoice := quoteAsInvoice(dto)
+	validInvoice, errs := ValidateInvoiceCreate(asInvoice)
+	if len(errs) > 0 {
+		res.Validation(w, renameQuoteFields(errs)...)
+		return models.QuoteIn{}, false
+	}
+
+	canonical := RecalcInvoice(validInvoice)
+	if errs := verifyTotalsMatch(validInvoice.Totals, canonical.Totals); len(errs) > 0 {
+		res.Validation(w, errs...)
+		return models.QuoteIn{}, false
+	}
+
+	return quoteFromInvoice(canonical), true
+}
+
+func quoteAsInvoice(q models.QuoteIn) models.FEInvoiceIn {
+	return models.FEInvoiceIn{
+		Overview: models.InvoiceCreateIn{
+			ClientID:          q.Overview.ClientID,
+			BaseNumber:        q.Overview.QuoteNumber,
+			IssueDate:         q.Overview.IssueDate,
+			DueByDate:         q.Overview.ValidUntil,
+			ClientName:        q.Overview.ClientName,
+			ClientCompanyName: q.Overview.ClientCompanyName,
+			ClientAddress:     q.Overview.ClientAddress,
+			ClientEmail:       q.Overview.ClientEmail,
+			Note:              q.Overview.Note,
+			Currency:          q.Overview.Currency,
+			ExchangeRateMicro: q.Overview.ExchangeRateMicro,
+			VATTreatment:      q.Overview.VATTreatment,
+			ClientVATNumber:   q.Overview.ClientVATNumber,
+		},
+		Lines:  q.Lines,
+		Totals: q.Totals,
+	}
+}
+
+func quoteFromInvoice(inv models.FEInvoiceIn) models.QuoteIn {
+	return models.QuoteIn{
+		Overview: models.QuoteOverviewIn{
+			ClientID:          inv.Overview.ClientID,
+			QuoteNumber:       inv.Overview.BaseNumber,
+			IssueDate:         inv.Overview.IssueDate,
+			ValidUntil:        inv.Overview.DueByDate,
+			ClientName:        inv.Overview.ClientName,
+			ClientCompanyName: inv.Overview.ClientCompanyName,
+			ClientAddress:     inv.Overview.ClientAddress,
+			ClientEmail:       inv.Overview.ClientEmail,
+			Note:              inv.Overview.Note,
+			Currency:          inv.Overview.Currency,
+			ExchangeRateMicro: inv.Overview.ExchangeRateMicro,
+			VATTreatment:      inv.Overview.VATTreatment,
+			ClientVATNumber:   inv.Overview.ClientVATNumber,
+		},
+		Lines:  inv.Lines,
+		Totals: inv.Totals,
+	}
+}
+
+// renameQuoteFields maps invoice field names back to the quote payload.
+func renameQuoteFields(errs []res.FieldError) []res.FieldError {
+	for i := range errs {
+		switch errs[i].Field {
+		case "baseNumber":
+			errs[i].Field = "quoteNumber"
+		case "dueByDate":
+			errs[i].Field = "validUntil"
+		}
+	}
+	return errs
+}
+
+func handleQuoteError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	quoteNo int64,
+	action string,
+	err error,
+) {
+	switch {
+	case errors.Is(err, quoteTx.ErrQuoteNotFound):
+		res.Error(w, http.StatusNotFound, "QUOTE_NOT_FOUND", "Quote not found")
+		return
+	case errors.Is(err, quoteTx.ErrQuoteNumberTaken):
+		res.Validation(w, res.Invalid("quoteNumber", "quote number already in use. Refresh page and try again."))
+		return
+	case errors.Is(err, quoteTx.ErrQuoteLocked):
+		res.Error(w, http.StatusConflict, "QUOTE_LOCKED", "Accepted, declined or converted quotes cannot be changed")
+		return
+	case errors.Is(err, quoteTx.ErrQuoteStatusTransition):
+		res.Validation(w, res.Invalid("status", "transition not allowed from current status"))
+		return
+	case errors.Is(err, quoteTx.ErrQuoteAlreadyConverted):
+		res.Error(w, http.StatusConflict, "QUOTE_CONVERTED", "Quote has already been converted to an invoice")
+		return
+	case errors.Is(err, quoteTx.ErrQuoteDeclinedForConvert):
+		res.Error(w, http.StatusConflict, "QUOTE_DECLINED", "Declined quotes cannot be converted")
+		return
+	case errors.Is(err, invoiceTx.ErrExchangeRateRequired):
+		res.Validation(w, res.Invalid("exchangeRateMicro", "is required when the quote currency differs from the workspace currency"))
+		return
+	case errors.Is(err, invoiceTx.ErrClientVATNumberRequired):
+		res.Validation(w, res.Invalid("clientVatNumber", "is required for a reverse charge quote"))
+		return
+	}
+
+	slog.ErrorContext(r.Context(),
+		action+" quote failed",
+		"client_id", clientID,
+		"quote_no", quoteNo,
+		"err", err,
+	)
+	res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+}
+
+func handleQuoteDocumentRenderError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	quoteNo int64,
+	formatUpper string,
+	err error,
+) {
+	slog.ErrorContext(r.Context(),
+		"generate quote file failed",
+		"format", formatUpper,
+		"client_id", clientID,
+		"quote_no", quoteNo,
+		"err", err,
+	)
+	res.Error(
+		w,
+		http.StatusInternalServerError,
+		formatUpper+"_GENERATION_FAILED",
+		fmt.Sprintf("Failed to generate %s", formatUpper),
+	)
+}
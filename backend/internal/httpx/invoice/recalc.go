@@ -2,8 +2,11 @@ package invoice
 
 import (
 	"math"
+	"slices"
+	"sort"
 
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 )
 
 func RecalcInvoice(inv models.FEInvoiceIn) models.FEInvoiceIn {
@@ -11,26 +14,36 @@ func RecalcInvoice(inv models.FEInvoiceIn) models.FEInvoiceIn {
 
 	var subtotal int64
 	for i := range out.Lines {
-		ln := out.Lines[i]
-
-		var lt int64
-		if ln.PricingMode == "hourly" && ln.MinutesWorked != nil {
-			lt = int64(math.Round(
-				(float64(ln.Quantity) * float64(ln.UnitPriceMinor) * float64(*ln.MinutesWorked)) / 60.0,
-			))
-		} else {
-			lt = ln.Quantity * ln.UnitPriceMinor
+		ln := &out.Lines[i]
+
+		gross := lineGrossMinor(*ln)
+
+		switch ln.DiscountType {
+		case "percent":
+			ln.DiscountRate = clamp(ln.DiscountRate, 0, 10000)
+		case "fixed":
+			ln.DiscountRate = 0
+		default:
+			ln.DiscountType = "none"
+			ln.DiscountRate = 0
 		}
+		ln.DiscountMinor = lineDiscountMinor(ln.DiscountType, ln.DiscountRate, ln.DiscountMinor, gross)
 
-		if lt < 0 {
-			lt = 0
-		}
-
-		out.Lines[i].LineTotalMinor = lt
+		lt := max(gross-ln.DiscountMinor, 0)
+		ln.LineTotalMinor = lt
 		subtotal += lt
 	}
 
 	vatBps := clamp(out.Totals.VATRate, 0, 10000)
+	// Reverse charge, exempt and out-of-scope supplies carry no VAT, whatever
+	// rates the lines were entered with.
+	if !invoiceformat.ChargesVAT(invoiceformat.NormalizeVATTreatment(out.Overview.VATTreatment)) {
+		vatBps = 0
+		out.Totals.TaxRateID = nil
+		for i := range out.Lines {
+			out.Lines[i].VATRate = nil
+		}
+	}
 
 	discountRate := clamp(out.Totals.DiscountRate, 0, 10000)
 	depositRate := clamp(out.Totals.DepositRate, 0, 10000)
@@ -54,7 +67,11 @@ func RecalcInvoice(inv models.FEInvoiceIn) models.FEInvoiceIn {
 	discountMinor = clamp(discountMinor, 0, subtotal)
 
 	subAfterDisc := max(subtotal-discountMinor, 0)
-	vatMinor := max(int64(math.Round(float64(subAfterDisc*vatBps)/10000.0)), 0)
+	breakdown := vatBreakdown(out.Lines, vatBps, subtotal, discountMinor)
+	var vatMinor int64
+	for _, band := range breakdown {
+		vatMinor += band.VatMinor
+	}
 	totalMinor := max(subAfterDisc+vatMinor, 0)
 
 	var depositMinor int64
@@ -74,7 +91,19 @@ func RecalcInvoice(inv models.FEInvoiceIn) models.FEInvoiceIn {
 	}
 	depositMinor = clamp(depositMinor, 0, totalMinor)
 
-	balanceDue := max(totalMinor-paidMinor, 0)
+	// The deduction is worked out on the net amounts once VAT is known. The
+	// client withholds it, so it lowers what they pay but not the total.
+	deductionRate := clamp(out.Totals.DeductionRate, 0, 10000)
+	deductionLineTypes := out.Totals.DeductionLineTypes
+	if deductionRate == 0 {
+		deductionLineTypes = nil
+	}
+	deductionMinor := deductionAmount(out.Lines, deductionLineTypes, deductionRate, subtotal, discountMinor)
+	payableMinor := max(totalMinor-deductionMinor, 0)
+
+	// paidMinor already includes received deposits (see the invoice_revision_paid
+	// view); a requested deposit does not reduce the balance until it is received.
+	balanceDue := max(payableMinor-paidMinor, 0)
 
 	// !CRITICAL: This logic must stay identical to the frontend invoice recalculation.
 	// Any change here MUST be mirrored in the frontend to avoid total drift.
@@ -86,6 +115,7 @@ func RecalcInvoice(inv models.FEInvoiceIn) models.FEInvoiceIn {
 	out.Totals.BalanceDue = balanceDue
 
 	out.Totals.VATRate = vatBps
+	out.Totals.VATBreakdown = breakdown
 	out.Totals.PaidMinor = paidMinor
 
 	out.Totals.DiscountRate = discountRate
@@ -94,9 +124,118 @@ func RecalcInvoice(inv models.FEInvoiceIn) models.FEInvoiceIn {
 	out.Totals.DepositRate = depositRate
 	out.Totals.DepositMinor = depositMinor
 
+	out.Totals.DeductionRate = deductionRate
+	out.Totals.DeductionLineTypes = deductionLineTypes
+	out.Totals.DeductionMinor = deductionMinor
+	out.Totals.PayableMinor = payableMinor
+
 	return out
 }
 
+// deductionAmount applies rateBps to the net of the lines whose type is in
+// lineTypes (every line when it is empty). Those lines carry their share of
+// the invoice discount, in proportion to their value, like the VAT bands.
+func deductionAmount(lines []models.LineCreateIn, lineTypes []string, rateBps, subtotal, discountMinor int64) int64 {
+	if rateBps == 0 {
+		return 0
+	}
+
+	var base int64
+	for _, ln := range lines {
+		if len(lineTypes) == 0 || slices.Contains(lineTypes, ln.LineType) {
+			base += ln.LineTotalMinor
+		}
+	}
+	if discountMinor > 0 && subtotal > 0 {
+		base -= int64(math.Round(float64(discountMinor) * float64(base) / float64(subtotal)))
+	}
+
+	return max(int64(math.Round(float64(base*rateBps)/10000.0)), 0)
+}
+
+// lineGrossMinor is the line amount before any line discount. Quantity is in
+// milli-units, so fractional quantities round to the nearest minor unit.
+func lineGrossMinor(ln models.LineCreateIn) int64 {
+	var gross int64
+	if ln.PricingMode == "hourly" && ln.MinutesWorked != nil {
+		gross = int64(math.Round(
+			(float64(ln.Quantity) * float64(ln.UnitPriceMinor) * float64(*ln.MinutesWorked)) / (60.0 * models.QuantityScale),
+		))
+	} else {
+		gross = ln.Quantity.Amount(ln.UnitPriceMinor)
+	}
+	return max(gross, 0)
+}
+
+// lineDiscountMinor resolves a line discount against the line's gross amount.
+// Percent discounts are rounded; fixed discounts are capped at the gross amount.
+func lineDiscountMinor(discountType string, rate, fixedMinor, gross int64) int64 {
+	switch discountType {
+	case "percent":
+		return clamp(int64(math.Round(float64(gross*rate)/10000.0)), 0, gross)
+	case "fixed":
+		return clamp(fixedMinor, 0, gross)
+	default:
+		return 0
+	}
+}
+
+// vatBreakdown groups line totals by VAT rate (lines without their own rate
+// use defaultBps), spreads the invoice discount across the bands in
+// proportion to their net value and rounds VAT once per band.
+//
+// The largest band absorbs the discount rounding remainder so the band nets
+// always add up to subtotal - discount. Bands are ordered by rate, highest first.
+func vatBreakdown(lines []models.LineCreateIn, defaultBps, subtotal, discountMinor int64) []models.VATBand {
+	netByRate := make(map[int64]int64)
+	for _, ln := range lines {
+		rate := defaultBps
+		if ln.VATRate != nil {
+			rate = clamp(*ln.VATRate, 0, 10000)
+		}
+		netByRate[rate] += ln.LineTotalMinor
+	}
+	if len(netByRate) == 0 {
+		netByRate[defaultBps] = 0
+	}
+
+	bands := make([]models.VATBand, 0, len(netByRate))
+	for rate, net := range netByRate {
+		bands = append(bands, models.VATBand{VATRate: rate, NetMinor: net})
+	}
+	sort.Slice(bands, func(i, j int) bool { return bands[i].VATRate > bands[j].VATRate })
+
+	largest := 0
+	for i := range bands {
+		if bands[i].NetMinor > bands[largest].NetMinor {
+			largest = i
+		}
+	}
+
+	if discountMinor > 0 && subtotal > 0 {
+		var allocated int64
+		shares := make([]int64, len(bands))
+		for i := range bands {
+			if i == largest {
+				continue
+			}
+			shares[i] = int64(math.Round(float64(discountMinor) * float64(bands[i].NetMinor) / float64(subtotal)))
+			allocated += shares[i]
+		}
+		shares[largest] = discountMinor - allocated
+
+		for i := range bands {
+			bands[i].NetMinor = max(bands[i].NetMinor-shares[i], 0)
+		}
+	}
+
+	for i := range bands {
+		bands[i].VatMinor = max(int64(math.Round(float64(bands[i].NetMinor*bands[i].VATRate)/10000.0)), 0)
+	}
+
+	return bands
+}
+
 func clamp(v, minV, maxV int64) int64 {
 	if v < minV {
 		return minV
@@ -0,0 +1,196 @@
+package invoice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+func TestRecalcInvoice_SplitsVATByLineRate(t *testing.T) {
+	zeroRated := int64(0)
+	inv := models.FEInvoiceIn{
+		Lines: []models.LineCreateIn{
+			{Name: "Standard", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 10000, SortOrder: 1},
+			{Name: "Zero rated", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 5000, SortOrder: 2, VATRate: &zeroRated},
+		},
+		Totals: models.TotalsCreateIn{
+			VATRate:      2000,
+			DiscountType: "percent",
+			DiscountRate: 1000,
+			DepositType:  "none",
+		},
+	}
+
+	got := RecalcInvoice(inv).Totals
+
+	wantBands := []models.VATBand{
+		{VATRate: 2000, NetMinor: 9000, VatMinor: 1800},
+		{VATRate: 0, NetMinor: 4500, VatMinor: 0},
+	}
+	if !reflect.DeepEqual(got.VATBreakdown, wantBands) {
+		t.Fatalf("VATBreakdown = %+v, want %+v", got.VATBreakdown, wantBands)
+	}
+	if got.DiscountMinor != 1500 || got.VatAmountMinor != 1800 || got.TotalMinor != 15300 {
+		t.Fatalf("totals = (discount %d, vat %d, total %d), want (1500, 1800, 15300)",
+			got.DiscountMinor, got.VatAmountMinor, got.TotalMinor)
+	}
+}
+
+func TestRecalcInvoice_ReverseChargeForcesZeroVAT(t *testing.T) {
+	reduced := int64(500)
+	taxRateID := int64(3)
+	inv := models.FEInvoiceIn{
+		Overview: models.InvoiceCreateIn{VATTreatment: "reverse_charge"},
+		Lines: []models.LineCreateIn{
+			{Name: "Standard", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 10000, SortOrder: 1},
+			{Name: "Reduced", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 5000, SortOrder: 2, VATRate: &reduced},
+		},
+		Totals: models.TotalsCreateIn{
+			VATRate:      2000,
+			DiscountType: "none",
+			DepositType:  "none",
+			TaxRateID:    &taxRateID,
+		},
+	}
+
+	got := RecalcInvoice(inv)
+
+	wantBands := []models.VATBand{{VATRate: 0, NetMinor: 15000, VatMinor: 0}}
+	if !reflect.DeepEqual(got.Totals.VATBreakdown, wantBands) {
+		t.Fatalf("VATBreakdown = %+v, want %+v", got.Totals.VATBreakdown, wantBands)
+	}
+	if got.Totals.VATRate != 0 || got.Totals.VatAmountMinor != 0 || got.Totals.TotalMinor != 15000 {
+		t.Fatalf("totals = (rate %d, vat %d, total %d), want (0, 0, 15000)",
+			got.Totals.VATRate, got.Totals.VatAmountMinor, got.Totals.TotalMinor)
+	}
+	if got.Lines[1].VATRate != nil {
+		t.Fatalf("expected line VAT rate override to be cleared, got %d", *got.Lines[1].VATRate)
+	}
+	if got.Totals.TaxRateID != nil {
+		t.Fatalf("expected tax rate reference to be cleared, got %d", *got.Totals.TaxRateID)
+	}
+}
+
+func TestRecalcInvoice_DeductionReducesPayableNotTotal(t *testing.T) {
+	inv := models.FEInvoiceIn{
+		Lines: []models.LineCreateIn{
+			{Name: "Labour", LineType: "style", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 10000, SortOrder: 1},
+			{Name: "Materials", LineType: "custom", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 10000, SortOrder: 2},
+		},
+		Totals: models.TotalsCreateIn{
+			VATRate:            2000,
+			DiscountType:       "fixed",
+			DiscountMinor:      2000,
+			DepositType:        "none",
+			PaidMinor:          1000,
+			DeductionRate:      2000,
+			DeductionLineTypes: []string{"style"},
+		},
+	}
+
+	got := RecalcInvoice(inv)
+
+	// Labour carries half the discount: (10000 - 1000) * 20% = 1800.
+	if got.Totals.TotalMinor != 21600 || got.Totals.DeductionMinor != 1800 {
+		t.Fatalf("totals = (total %d, deduction %d), want (21600, 1800)",
+			got.Totals.TotalMinor, got.Totals.DeductionMinor)
+	}
+	if got.Totals.PayableMinor != 19800 || got.Totals.BalanceDue != 18800 {
+		t.Fatalf("payable = %d, balance = %d, want 19800, 18800",
+			got.Totals.PayableMinor, got.Totals.BalanceDue)
+	}
+
+	got.Totals.DeductionRate = 0
+	got = RecalcInvoice(got)
+	if got.Totals.DeductionMinor != 0 || got.Totals.DeductionLineTypes != nil || got.Totals.PayableMinor != got.Totals.TotalMinor {
+		t.Fatalf("zero rate deduction = %d over %v payable %d, want none",
+			got.Totals.DeductionMinor, got.Totals.DeductionLineTypes, got.Totals.PayableMinor)
+	}
+}
+
+func TestRecalcInvoice_SingleRateMatchesInvoiceLevelVAT(t *testing.T) {
+	inv := models.FEInvoiceIn{
+		Lines: []models.LineCreateIn{
+			{Name: "A", PricingMode: "flat", Quantity: models.WholeQuantity(3), UnitPriceMinor: 333, SortOrder: 1},
+			{Name: "B", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 1001, SortOrder: 2},
+		},
+		Totals: models.TotalsCreateIn{
+			VATRate:       1750,
+			DiscountType:  "fixed",
+			DiscountMinor: 7,
+			DepositType:   "none",
+		},
+	}
+
+	got := RecalcInvoice(inv).Totals
+
+	// (999 + 1001 - 7) * 17.5% = 348.775
+	if got.VatAmountMinor != 349 {
+		t.Fatalf("VatAmountMinor = %d, want 349", got.VatAmountMinor)
+	}
+	if len(got.VATBreakdown) != 1 || got.VATBreakdown[0].NetMinor != 1993 {
+		t.Fatalf("VATBreakdown = %+v, want one band with net 1993", got.VATBreakdown)
+	}
+}
+
+func TestRecalcInvoice_AppliesLineDiscountBeforeInvoiceDiscount(t *testing.T) {
+	inv := models.FEInvoiceIn{
+		Lines: []models.LineCreateIn{
+			{Name: "Style", PricingMode: "flat", Quantity: models.WholeQuantity(2), UnitPriceMinor: 5000, SortOrder: 1, DiscountType: "percent", DiscountRate: 1500},
+			{Name: "Sample", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 3000, SortOrder: 2, DiscountType: "fixed", DiscountMinor: 5000},
+			{Name: "Plain", PricingMode: "flat", Quantity: models.WholeQuantity(1), UnitPriceMinor: 1000, SortOrder: 3},
+		},
+		Totals: models.TotalsCreateIn{
+			VATRate:       2000,
+			DiscountType:  "fixed",
+			DiscountMinor: 500,
+			DepositType:   "none",
+		},
+	}
+
+	got := RecalcInvoice(inv)
+
+	wantLines := []struct {
+		discount, total int64
+		discountType    string
+	}{
+		{discount: 1500, total: 8500, discountType: "percent"},
+		{discount: 3000, total: 0, discountType: "fixed"},
+		{discount: 0, total: 1000, discountType: "none"},
+	}
+	for i, want := range wantLines {
+		ln := got.Lines[i]
+		if ln.DiscountMinor != want.discount || ln.LineTotalMinor != want.total || ln.DiscountType != want.discountType {
+			t.Fatalf("line %d = (%s, discount %d, total %d), want (%s, %d, %d)",
+				i, ln.DiscountType, ln.DiscountMinor, ln.LineTotalMinor, want.discountType, want.discount, want.total)
+		}
+	}
+
+	// subtotal 9500, invoice discount 500, VAT 20% of 9000
+	if got.Totals.SubtotalMinor != 9500 || got.Totals.VatAmountMinor != 1800 || got.Totals.TotalMinor != 10800 {
+		t.Fatalf("totals = (subtotal %d, vat %d, total %d), want (9500, 1800, 10800)",
+			got.Totals.SubtotalMinor, got.Totals.VatAmountMinor, got.Totals.TotalMinor)
+	}
+}
+
+func TestRecalcInvoice_FractionalQuantities(t *testing.T) {
+	minutes := int64(90)
+	inv := models.FEInvoiceIn{
+		Lines: []models.LineCreateIn{
+			{Name: "Fabric", PricingMode: "flat", Quantity: 2500, Unit: "m", UnitPriceMinor: 1299, SortOrder: 1},
+			{Name: "Consulting", PricingMode: "hourly", Quantity: 1500, Unit: "day", UnitPriceMinor: 6000, MinutesWorked: &minutes, SortOrder: 2},
+		},
+		Totals: models.TotalsCreateIn{VATRate: 0, DiscountType: "none", DepositType: "none"},
+	}
+
+	got := RecalcInvoice(inv)
+
+	// 2.5 * 1299 = 3247.5 -> 3248; 1.5 * 6000 * 90 / 60 = 13500
+	if got.Lines[0].LineTotalMinor != 3248 || got.Lines[1].LineTotalMinor != 13500 {
+		t.Fatalf("line totals = %d/%d, want 3248/13500", got.Lines[0].LineTotalMinor, got.Lines[1].LineTotalMinor)
+	}
+	if got.Totals.SubtotalMinor != 16748 {
+		t.Fatalf("subtotal = %d, want 16748", got.Totals.SubtotalMinor)
+	}
+}
@@ -0,0 +1,244 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/docx"
+	"github.com/viktorHadz/goInvoice26/internal/service/pdf"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func CreateRefund(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		revisionNo, ok := params.ValidateParam(w, r, "revisionNo")
+		if !ok {
+			return
+		}
+		receiptNo, ok := params.ValidateParam(w, r, "receiptNo")
+		if !ok {
+			return
+		}
+
+		var dto models.RefundCreateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateRefundCreate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		invoiceID, refundID, refundNo, err := invoiceTx.CreateRefund(ctx, a, clientID, baseNumber, revisionNo, receiptNo, &valid)
+		if err != nil {
+			switch {
+			case errors.Is(err, invoiceTx.ErrETagMismatch):
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceNotFound), errors.Is(err, invoiceTx.ErrPaymentReceiptNotFound):
+				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Payment receipt not found")
+				return
+			case errors.Is(err, invoiceTx.ErrInvoiceVoidForReceipt):
+				res.Error(w, http.StatusConflict, "INVOICE_VOID", "Invoice is void; refunds are not allowed")
+				return
+			case errors.Is(err, invoiceTx.ErrRefundExceedsReceipt):
+				res.Validation(w, res.Invalid("amountMinor", "total refunds cannot exceed the payment receipt amount"))
+				return
+			case errors.Is(err, invoiceTx.ErrRefundBeforePayment):
+				res.Validation(w, res.Invalid("refundDate", "must be on or after the payment date"))
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"create refund failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"revision_no", revisionNo,
+				"receipt_no", receiptNo,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		setInvoiceETag(w, r, a, clientID, baseNumber)
+		res.JSON(w, http.StatusCreated, map[string]any{
+			"invoiceId": invoiceID,
+			"refundId":  refundID,
+			"refundNo":  refundNo,
+		})
+	}
+}
+
+func ListRefunds(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+
+		refunds, err := invoiceTx.ListRefunds(r.Context(), a.DB, clientID, baseNumber)
+		if err != nil {
+			slog.ErrorContext(r.Context(),
+				"list refunds failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		out := make([]models.RefundOut, 0, len(refunds))
+		for _, refund := range refunds {
+			out = append(out, models.RefundOut{
+				ID:          refund.ID,
+				RefundNo:    refund.RefundNo,
+				RevisionNo:  refund.AppliedRevisionNo,
+				ReceiptNo:   refund.ReceiptNo,
+				RefundDate:  refund.RefundDate,
+				AmountMinor: refund.AmountMinor,
+				Reason:      nullStringOut(refund.Reason),
+			})
+		}
+
+		res.JSON(w, http.StatusOK, out)
+	}
+}
+
+func GenerateRefundPDFHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		refundNo, ok := params.ValidateParam(w, r, "refundNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildRefundFromDB(r.Context(), a.DB, clientID, baseNumber, refundNo)
+		if err != nil {
+			handleRefundDocumentBuildError(w, r, clientID, baseNumber, refundNo, "pdf", err)
+			return
+		}
+
+		fileBytes, err := pdf.RenderPDF(r.Context(), &pdf.MarotoRenderer{}, doc)
+		if err != nil {
+			handleRefundDocumentRenderError(w, r, clientID, baseNumber, refundNo, "PDF", err)
+			return
+		}
+
+		writeGeneratedDocument(w, "application/pdf", buildRefundFilename(baseNumber, refundNo, "pdf"), fileBytes)
+	}
+}
+
+func GenerateRefundDOCXHandler(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		refundNo, ok := params.ValidateParam(w, r, "refundNo")
+		if !ok {
+			return
+		}
+
+		doc, err := pdf.BuildRefundFromDB(r.Context(), a.DB, clientID, baseNumber, refundNo)
+		if err != nil {
+			handleRefundDocumentBuildError(w, r, clientID, baseNumber, refundNo, "docx", err)
+			return
+		}
+
+		fileBytes, err := docx.RenderDOCX(doc)
+		if err != nil {
+			handleRefundDocumentRenderError(w, r, clientID, baseNumber, refundNo, "DOCX", err)
+			return
+		}
+
+		writeGeneratedDocument(w, docxContentType, buildRefundFilename(baseNumber, refundNo, "docx"), fileBytes)
+	}
+}
+
+func handleRefundDocumentBuildError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	baseNumber int64,
+	refundNo int64,
+	format string,
+	err error,
+) {
+	if errors.Is(err, invoiceTx.ErrRefundNotFound) {
+		res.Error(w, http.StatusNotFound, "REFUND_NOT_FOUND", "Refund not found")
+		return
+	}
+
+	slog.ErrorContext(r.Context(),
+		"build refund download data failed",
+		"format", format,
+		"client_id", clientID,
+		"base_number", baseNumber,
+		"refund_no", refundNo,
+		"err", err,
+	)
+	res.Error(w, http.StatusInternalServerError, "INTERNAL", "Internal server error")
+}
+
+func handleRefundDocumentRenderError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	baseNumber int64,
+	refundNo int64,
+	formatUpper string,
+	err error,
+) {
+	slog.ErrorContext(r.Context(),
+		"generate refund file failed",
+		"format", formatUpper,
+		"client_id", clientID,
+		"base_number", baseNumber,
+		"refund_no", refundNo,
+		"err", err,
+	)
+	res.Error(
+		w,
+		http.StatusInternalServerError,
+		formatUpper+"_GENERATION_FAILED",
+		fmt.Sprintf("Failed to generate %s", formatUpper),
+	)
+}
@@ -0,0 +1,95 @@
+package invoice
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+// GetInvoiceRevisionDiff compares two revisions of one invoice. Either order
+// works; the diff always reads from the first revision to the second.
+func GetInvoiceRevisionDiff(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		baseNumber, ok := params.ValidateParam(w, r, "baseNumber")
+		if !ok {
+			return
+		}
+		fromRevisionNo, ok := params.ValidateParam(w, r, "revisionNo")
+		if !ok {
+			return
+		}
+		toRevisionNo, ok := params.ValidateParam(w, r, "toRevisionNo")
+		if !ok {
+			return
+		}
+
+		diff, err := invoiceTx.QueryRevisionDiff(r.Context(), a.DB, clientID, baseNumber, fromRevisionNo, toRevisionNo)
+		if err != nil {
+			if errors.Is(err, invoiceTx.ErrRevisionNotFound) {
+				res.NotFound(w, "Invoice revision not found")
+				return
+			}
+
+			slog.ErrorContext(r.Context(),
+				"query invoice revision diff failed",
+				"client_id", clientID,
+				"base_number", baseNumber,
+				"from_revision_no", fromRevisionNo,
+				"to_revision_no", toRevisionNo,
+				"err", err,
+			)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, revisionDiffOut(diff))
+	}
+}
+
+func revisionDiffOut(diff *invoiceTx.RevisionDiff) *models.InvoiceRevisionDiffOut {
+	if diff == nil {
+		return nil
+	}
+
+	lines := make([]models.RevisionLineChangeOut, 0, len(diff.Lines))
+	for _, line := range diff.Lines {
+		lines = append(lines, models.RevisionLineChangeOut{
+			Change:        line.Change,
+			ProductID:     line.ProductID,
+			Name:          line.Name,
+			FromSortOrder: line.FromSortOrder,
+			ToSortOrder:   line.ToSortOrder,
+			Fields:        revisionFieldChangesOut(line.Fields),
+		})
+	}
+
+	return &models.InvoiceRevisionDiffOut{
+		FromRevisionNo: diff.FromRevisionNo,
+		ToRevisionNo:   diff.ToRevisionNo,
+		Fields:         revisionFieldChangesOut(diff.Fields),
+		Lines:          lines,
+	}
+}
+
+func revisionFieldChangesOut(changes []invoiceTx.RevisionFieldChange) []models.RevisionFieldChangeOut {
+	out := make([]models.RevisionFieldChangeOut, 0, len(changes))
+	for _, change := range changes {
+		out = append(out, models.RevisionFieldChangeOut{
+			Section: change.Section,
+			Field:   change.Field,
+			From:    change.From,
+			To:      change.To,
+		})
+	}
+	return out
+}
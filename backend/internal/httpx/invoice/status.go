@@ -11,10 +11,14 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/app"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/validate"
 )
 
 type invoiceStatusBody struct {
 	Status string `json:"status"`
+	// Reason is required when voiding and ignored otherwise.
+	Reason string `json:"reason,omitempty"`
 }
 
 type statusTransitionRules struct {
@@ -23,6 +27,7 @@ type statusTransitionRules struct {
 }
 
 // PatchInvoiceStatus updates invoices.status with allowed transitions only.
+// Voiding needs a reason, which is kept with who voided the invoice and when.
 func PatchInvoiceStatus(a *app.App) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		clientID, ok := params.ValidateParam(w, r, "clientID")
@@ -45,6 +50,23 @@ func PatchInvoiceStatus(a *app.App) http.HandlerFunc {
 			return
 		}
 
+		var voidReason string
+		if next == "void" {
+			reason, errs := validate.Text(body.Reason, validate.TextRules{
+				Field:      "reason",
+				Required:   true,
+				Min:        1,
+				Max:        1000,
+				SingleLine: true,
+				Trim:       true,
+			})
+			if len(errs) > 0 {
+				res.Validation(w, errs...)
+				return
+			}
+			voidReason = reason
+		}
+
 		accountID, err := accountscope.Require(r.Context())
 		if err != nil {
 			slog.ErrorContext(r.Context(), "patch invoice status missing account scope", "err", err)
@@ -56,28 +78,33 @@ func PatchInvoiceStatus(a *app.App) http.HandlerFunc {
 			current       string
 			rules         statusTransitionRules
 			revisionCount int64
-			totalMinor    int64
+			payableMinor  int64
 			paidMinor     int64
+			creditedMinor int64
 		)
 		err = a.DB.QueryRowContext(r.Context(), `
 			SELECT
 				i.status,
 				COUNT(DISTINCT rev.id) AS revision_count,
-				cur.total_minor,
+				cur.total_minor - cur.deduction_minor AS payable_minor,
+				COALESCE((
+					SELECT rp.paid_minor
+					FROM invoice_revision_paid rp
+					WHERE rp.revision_id = i.current_revision_id
+				), 0) AS paid_minor,
 				COALESCE((
-					SELECT SUM(p.amount_minor)
-					FROM payments p
-					WHERE p.applied_in_revision_id = i.current_revision_id
-					  AND p.payment_type = 'payment'
-				), 0) AS paid_minor
+					SELECT SUM(cn.total_minor)
+					FROM credit_notes cn
+					WHERE cn.invoice_id = i.id
+				), 0) AS credited_minor
 			FROM invoices i
 			JOIN invoice_revisions cur
 				ON cur.id = i.current_revision_id
 			LEFT JOIN invoice_revisions rev
 				ON rev.invoice_id = i.id
 			WHERE i.account_id = ? AND i.client_id = ? AND i.base_number = ?
-			GROUP BY i.id, i.status, cur.total_minor
-		`, accountID, clientID, baseNumber).Scan(&current, &revisionCount, &totalMinor, &paidMinor)
+			GROUP BY i.id, i.status, cur.total_minor, cur.deduction_minor
+		`, accountID, clientID, baseNumber).Scan(&current, &revisionCount, &payableMinor, &paidMinor, &creditedMinor)
 		if errors.Is(err, sql.ErrNoRows) {
 			res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
 			return
@@ -89,37 +116,47 @@ func PatchInvoiceStatus(a *app.App) http.HandlerFunc {
 		}
 
 		current = strings.TrimSpace(strings.ToLower(current))
-		rules = statusTransitionRules{
-			CanReturnIssuedToDraft: revisionCount <= 1 && paidMinor == 0,
-			CanReopenPaidToIssued:  paidMinor != expectedPaidMinor(totalMinor),
-		}
+		rules = invoiceStatusRules(revisionCount, payableMinor, paidMinor, creditedMinor)
 		if !allowedStatusTransition(current, next, rules) {
 			res.Validation(w, res.Invalid("status", invalidStatusTransitionMessage(current, next, rules)))
 			return
 		}
 
-		resExec, err := a.DB.ExecContext(r.Context(), `
-			UPDATE invoices
-			SET status = ?
-			WHERE account_id = ? AND client_id = ? AND base_number = ?
-		`, next, accountID, clientID, baseNumber)
+		if next == "void" {
+			err = invoiceTx.VoidInvoice(r.Context(), a, clientID, baseNumber, voidReason)
+		} else {
+			err = invoiceTx.UpdateInvoiceStatus(r.Context(), a, clientID, baseNumber, next)
+		}
 		if err != nil {
+			if errors.Is(err, invoiceTx.ErrInvoiceNotFound) {
+				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
+				return
+			}
+			if errors.Is(err, invoiceTx.ErrTaxRateMismatch) || errors.Is(err, invoiceTx.ErrTaxRateNotEffective) {
+				res.Error(w, http.StatusConflict, "TAX_RATE_CHANGED", "The invoice tax rate changed since the draft was saved; update the draft before issuing")
+				return
+			}
 			slog.ErrorContext(r.Context(), "patch invoice status update failed", "err", err)
 			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
 			return
 		}
-		n, _ := resExec.RowsAffected()
-		if n == 0 {
-			res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
-			return
-		}
 
 		res.JSON(w, http.StatusOK, map[string]any{"status": next})
 	}
 }
 
-func expectedPaidMinor(totalMinor int64) int64 {
-	expected := totalMinor
+// invoiceStatusRules settles an invoice by payments and credit notes together,
+// as the status sync does. Credit notes point at the revision they credit, so
+// an invoice with any cannot return to draft.
+func invoiceStatusRules(revisionCount, payableMinor, paidMinor, creditedMinor int64) statusTransitionRules {
+	return statusTransitionRules{
+		CanReturnIssuedToDraft: revisionCount <= 1 && paidMinor == 0 && creditedMinor == 0,
+		CanReopenPaidToIssued:  paidMinor+creditedMinor != expectedPaidMinor(payableMinor),
+	}
+}
+
+func expectedPaidMinor(payableMinor int64) int64 {
+	expected := payableMinor
 	if expected < 0 {
 		return 0
 	}
@@ -131,9 +168,9 @@ func invalidStatusTransitionMessage(from, to string, rules statusTransitionRules
 	case from == to:
 		return "status is already set to " + to
 	case from == "issued" && to == "draft" && !rules.CanReturnIssuedToDraft:
-		return "issued invoices with saved revisions or payment receipts cannot return to draft"
+		return "issued invoices with saved revisions, payment receipts or credit notes cannot return to draft"
 	case from == "paid" && to == "issued" && !rules.CanReopenPaidToIssued:
-		return "fully paid invoices cannot return to issued"
+		return "fully paid or credited invoices cannot return to issued"
 	default:
 		return "transition not allowed from current status"
 	}
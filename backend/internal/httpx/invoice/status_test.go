@@ -30,3 +30,28 @@ func TestAllowedStatusTransition(t *testing.T) {
 		}
 	}
 }
+
+func TestInvoiceStatusRules_CountsCreditNotes(t *testing.T) {
+	tests := []struct {
+		name          string
+		revisionCount int64
+		paidMinor     int64
+		creditedMinor int64
+		want          statusTransitionRules
+	}{
+		{name: "unsettled", revisionCount: 1, want: statusTransitionRules{CanReturnIssuedToDraft: true, CanReopenPaidToIssued: true}},
+		{name: "paid in full", revisionCount: 1, paidMinor: 1000, want: statusTransitionRules{}},
+		{name: "credited in full", revisionCount: 1, creditedMinor: 1000, want: statusTransitionRules{}},
+		{name: "paid and credited in full", revisionCount: 1, paidMinor: 600, creditedMinor: 400, want: statusTransitionRules{}},
+		{name: "partly credited", revisionCount: 1, creditedMinor: 400, want: statusTransitionRules{CanReopenPaidToIssued: true}},
+		{name: "revised", revisionCount: 2, want: statusTransitionRules{CanReopenPaidToIssued: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := invoiceStatusRules(tt.revisionCount, 1000, tt.paidMinor, tt.creditedMinor); got != tt.want {
+				t.Fatalf("invoiceStatusRules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
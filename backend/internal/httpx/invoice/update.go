@@ -57,9 +57,17 @@ func UpdateInvoice(a *app.App) http.HandlerFunc {
 			return
 		}
 
-		invoiceID, revisionID, err := invoiceTx.UpdateDraft(r.Context(), a, &canonical)
+		ctx, ok := requireIfMatch(w, r)
+		if !ok {
+			return
+		}
+
+		invoiceID, revisionID, err := invoiceTx.UpdateDraft(ctx, a, &canonical)
 		if err != nil {
 			switch {
+			case errors.Is(err, invoiceTx.ErrETagMismatch):
+				writeInvoiceChanged(w, r, a, clientID, baseNumber)
+				return
 			case errors.Is(err, invoiceTx.ErrInvoiceNotFound):
 				res.Error(w, http.StatusNotFound, "NOT_FOUND", "Invoice not found")
 				return
@@ -78,6 +86,16 @@ func UpdateInvoice(a *app.App) http.HandlerFunc {
 			case errors.Is(err, invoiceTx.ErrPaymentStateMismatch):
 				res.Validation(w, res.Invalid("totals.paidMinor", "saved payments changed; refresh invoice before saving"))
 				return
+			case errors.Is(err, invoiceTx.ErrExchangeRateRequired):
+				res.Validation(w, res.Invalid("exchangeRateMicro", "is required when the invoice currency differs from the workspace currency"))
+				return
+			case errors.Is(err, invoiceTx.ErrClientVATNumberRequired):
+				res.Validation(w, res.Invalid("clientVatNumber", "is required for a reverse charge invoice"))
+				return
+			}
+			if fe, ok := taxRateFieldError(err); ok {
+				res.Validation(w, fe)
+				return
 			}
 
 			slog.ErrorContext(r.Context(),
@@ -90,6 +108,7 @@ func UpdateInvoice(a *app.App) http.HandlerFunc {
 			return
 		}
 
+		setInvoiceETag(w, r, a, clientID, baseNumber)
 		res.JSON(w, http.StatusOK, map[string]any{
 			"invoiceId":  invoiceID,
 			"revisionId": revisionID,
@@ -3,12 +3,13 @@ package invoice
 import (
 	"fmt"
 	"log/slog"
-	"math"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 	"github.com/viktorHadz/goInvoice26/internal/validate"
 )
 
@@ -142,6 +143,39 @@ func validateOverview(o models.InvoiceCreateIn) (models.InvoiceCreateIn, []res.F
 		out.Note = &note
 	}
 
+	// currency
+	if currency := strings.ToUpper(strings.TrimSpace(o.Currency)); currency != "" {
+		if !invoiceformat.SupportedCurrency(currency) {
+			errs = append(errs, res.Invalid("currency", "is not a supported currency"))
+		} else {
+			out.Currency = currency
+		}
+	}
+	if o.ExchangeRateMicro < 0 {
+		errs = append(errs, res.Invalid("exchangeRateMicro", "must be greater than 0"))
+	} else {
+		out.ExchangeRateMicro = o.ExchangeRateMicro
+	}
+
+	// VAT treatment
+	vatTreatment := invoiceformat.NormalizeVATTreatment(o.VATTreatment)
+	if !invoiceformat.ValidVATTreatment(vatTreatment) {
+		errs = append(errs, res.Invalid("vatTreatment", "must be one of standard, reverse_charge, exempt, outside_scope"))
+	} else {
+		out.VATTreatment = vatTreatment
+	}
+
+	clientVATNumber, textErrs := validate.Text(o.ClientVATNumber, validate.TextRules{
+		Field:      "clientVatNumber",
+		Required:   false,
+		Min:        0,
+		Max:        30,
+		SingleLine: true,
+		Trim:       true,
+	})
+	errs = append(errs, textErrs...)
+	out.ClientVATNumber = clientVATNumber
+
 	return out, errs
 }
 
@@ -198,13 +232,20 @@ func validateLines(lines []models.LineCreateIn) ([]models.LineCreateIn, []res.Fi
 			errs = append(errs, res.Invalid(prefix("pricingMode"), "must be one of: flat, hourly"))
 		}
 
-		// quantity
-		if ln.Quantity < 1 {
+		// quantity - milli-units, so 0.5 is allowed
+		if ln.Quantity <= 0 {
 			errs = append(errs, res.Invalid(prefix("quantity"), "must be greater than 0"))
 		} else {
 			clean.Quantity = ln.Quantity
 		}
 
+		// unit - optional label such as pcs, m, day, kg
+		unit, unitErrs := validate.Text(ln.Unit, validate.TextRules{
+			Field: prefix("unit"), Max: 20, SingleLine: true, Trim: true,
+		})
+		errs = append(errs, unitErrs...)
+		clean.Unit = unit
+
 		// minutesWorked
 		if ln.MinutesWorked != nil {
 			if *ln.MinutesWorked < 0 {
@@ -229,6 +270,16 @@ func validateLines(lines []models.LineCreateIn) ([]models.LineCreateIn, []res.Fi
 			clean.SortOrder = ln.SortOrder
 		}
 
+		// vatRate - optional per-line override in basis points
+		if ln.VATRate != nil {
+			if *ln.VATRate < 0 || *ln.VATRate > 10000 {
+				errs = append(errs, res.Invalid(prefix("vatRate"), "must be between 0 and 10000"))
+			} else {
+				rate := *ln.VATRate
+				clean.VATRate = &rate
+			}
+		}
+
 		// cross-field rules
 		switch pricingMode {
 		case "hourly":
@@ -242,22 +293,57 @@ func validateLines(lines []models.LineCreateIn) ([]models.LineCreateIn, []res.Fi
 			}
 		}
 
-		var expectedLineTotal int64
-		if pricingMode == "hourly" && ln.MinutesWorked != nil {
-			// Match frontend: round(qty * unit * minutes / 60)
-			expectedLineTotal = int64(math.Round(
-				(float64(ln.Quantity) * float64(ln.UnitPriceMinor) * float64(*ln.MinutesWorked)) / 60.0,
-			))
-		} else {
-			expectedLineTotal = ln.Quantity * ln.UnitPriceMinor
+		// Match frontend: round(qty * unit * minutes / 60) for hourly lines
+		gross := lineGrossMinor(models.LineCreateIn{
+			PricingMode:    pricingMode,
+			Quantity:       ln.Quantity,
+			UnitPriceMinor: ln.UnitPriceMinor,
+			MinutesWorked:  ln.MinutesWorked,
+		})
+
+		// line discount
+		discountType := strings.TrimSpace(ln.DiscountType)
+		if discountType == "" {
+			discountType = "none"
+		}
+		switch discountType {
+		case "none":
+			if ln.DiscountRate != 0 {
+				errs = append(errs, res.Invalid(prefix("discountRate"), "must be 0 unless discountType is percent"))
+			}
+			if ln.DiscountMinor != 0 {
+				errs = append(errs, res.Invalid(prefix("discountMinor"), "must be 0 when discountType is none"))
+			}
+		case "percent":
+			if ln.DiscountRate < 0 || ln.DiscountRate > 10000 {
+				errs = append(errs, res.Invalid(prefix("discountRate"), "must be between 0 and 10000"))
+			}
+		case "fixed":
+			if ln.DiscountRate != 0 {
+				errs = append(errs, res.Invalid(prefix("discountRate"), "must be 0 unless discountType is percent"))
+			}
+			if ln.DiscountMinor < 0 {
+				errs = append(errs, res.Invalid(prefix("discountMinor"), "must be 0 or greater"))
+			} else if ln.DiscountMinor > gross {
+				errs = append(errs, res.Invalid(prefix("discountMinor"), "cannot exceed the line amount"))
+			}
+		default:
+			errs = append(errs, res.Invalid(prefix("discountType"), "must be one of: none, percent, fixed"))
 		}
+		clean.DiscountType = discountType
+		clean.DiscountRate = ln.DiscountRate
+		clean.DiscountMinor = lineDiscountMinor(discountType, ln.DiscountRate, ln.DiscountMinor, gross)
+
+		expectedLineTotal := gross - clean.DiscountMinor
 		if ln.LineTotalMinor < 0 {
 			errs = append(errs, res.Invalid(prefix("lineTotalMinor"), "must be 0 or greater"))
 		} else if ln.LineTotalMinor != expectedLineTotal {
-			if pricingMode == "hourly" {
+			if clean.DiscountMinor > 0 {
+				errs = append(errs, res.Invalid(prefix("lineTotalMinor"), "does not match the line amount less its discount"))
+			} else if pricingMode == "hourly" {
 				errs = append(errs, res.Invalid(prefix("lineTotalMinor"), "does not match rounded(quantity * unitPriceMinor * minutesWorked / 60)"))
 			} else {
-				errs = append(errs, res.Invalid(prefix("lineTotalMinor"), "does not match quantity * unitPriceMinor"))
+				errs = append(errs, res.Invalid(prefix("lineTotalMinor"), "does not match rounded(quantity * unitPriceMinor)"))
 			}
 		} else {
 			clean.LineTotalMinor = ln.LineTotalMinor
@@ -279,6 +365,15 @@ func validateTotals(t models.TotalsCreateIn) (models.TotalsCreateIn, []res.Field
 	} else {
 		out.VATRate = t.VATRate
 	}
+	// Registry tax rate - existence and rate are checked when the revision is saved
+	if t.TaxRateID != nil {
+		if *t.TaxRateID < 1 {
+			errs = append(errs, res.Invalid("totals.taxRateId", "must be greater than 0"))
+		} else {
+			taxRateID := *t.TaxRateID
+			out.TaxRateID = &taxRateID
+		}
+	}
 
 	if t.VatAmountMinor < 0 {
 		errs = append(errs, res.Invalid("totals.vatMinor", "must be 0 or greater"))
@@ -360,12 +455,37 @@ func validateTotals(t models.TotalsCreateIn) (models.TotalsCreateIn, []res.Field
 		out.BalanceDue = t.BalanceDue
 	}
 
+	// deduction
+	if t.DeductionRate < 0 || t.DeductionRate > 10000 {
+		errs = append(errs, res.Invalid("totals.deductionRate", "must be between 0 and 10000"))
+	} else {
+		out.DeductionRate = t.DeductionRate
+	}
+	for i, lineType := range t.DeductionLineTypes {
+		lineType = strings.TrimSpace(lineType)
+		switch lineType {
+		case "custom", "style", "sample":
+			if !slices.Contains(out.DeductionLineTypes, lineType) {
+				out.DeductionLineTypes = append(out.DeductionLineTypes, lineType)
+			}
+		default:
+			errs = append(errs, res.Invalid(fmt.Sprintf("totals.deductionLineTypes[%d]", i), "must be one of: custom, style, sample"))
+		}
+	}
+	slices.Sort(out.DeductionLineTypes)
+	if t.DeductionMinor < 0 {
+		errs = append(errs, res.Invalid("totals.deductionMinor", "must be 0 or greater"))
+	} else {
+		out.DeductionMinor = t.DeductionMinor
+	}
+
 	return out, errs
 }
 
-// ValidatePaidVsDepositTotal ensures paidMinor does not exceed totalMinor (post-recalc canonical totals).
+// ValidatePaidVsDepositTotal ensures paidMinor does not exceed the amount
+// payable, the total less any deduction (post-recalc canonical totals).
 func ValidatePaidVsDepositTotal(t models.TotalsCreateIn) []res.FieldError {
-	maxPaid := max(t.TotalMinor, 0)
+	maxPaid := max(t.TotalMinor-t.DeductionMinor, 0)
 	if t.PaidMinor > maxPaid {
 		return []res.FieldError{res.Invalid("totals.paidMinor", "cannot exceed invoice total")}
 	}
@@ -390,6 +510,14 @@ func ValidatePaymentReceiptCreate(in models.PaymentReceiptCreateIn) (models.Paym
 	errs = append(errs, labelErrs...)
 	out.Label = label
 
+	method, methodErrs := validateOptionalPaymentMethod(in.PaymentMethod, "paymentMethod")
+	errs = append(errs, methodErrs...)
+	out.PaymentMethod = method
+
+	reference, referenceErrs := validateOptionalPaymentReceiptLabel(in.Reference, "reference")
+	errs = append(errs, referenceErrs...)
+	out.Reference = reference
+
 	return out, errs
 }
 
@@ -405,6 +533,319 @@ func ValidatePaymentReceiptUpdate(in models.PaymentReceiptUpdateIn) (models.Paym
 	errs = append(errs, labelErrs...)
 	out.Label = label
 
+	method, methodErrs := validateOptionalPaymentMethod(in.PaymentMethod, "paymentMethod")
+	errs = append(errs, methodErrs...)
+	out.PaymentMethod = method
+
+	reference, referenceErrs := validateOptionalPaymentReceiptLabel(in.Reference, "reference")
+	errs = append(errs, referenceErrs...)
+	out.Reference = reference
+
+	return out, errs
+}
+
+func ValidateRefundCreate(in models.RefundCreateIn) (models.RefundCreateIn, []res.FieldError) {
+	var out models.RefundCreateIn
+	var errs []res.FieldError
+
+	if in.AmountMinor <= 0 {
+		errs = append(errs, res.Invalid("amountMinor", "must be greater than 0"))
+	} else {
+		out.AmountMinor = in.AmountMinor
+	}
+
+	refundDate, dateErrs := validateISODateRequired("refundDate", in.RefundDate)
+	errs = append(errs, dateErrs...)
+	out.RefundDate = refundDate
+
+	if in.Reason != nil {
+		reason, textErrs := validate.Text(*in.Reason, validate.TextRules{
+			Field:      "reason",
+			Required:   false,
+			Min:        0,
+			Max:        1000,
+			SingleLine: true,
+			Trim:       true,
+		})
+		errs = append(errs, textErrs...)
+		out.Reason = &reason
+	}
+
+	return out, errs
+}
+
+func ValidateClientPaymentCreate(in models.ClientPaymentCreateIn) (models.ClientPaymentCreateIn, []res.FieldError) {
+	var out models.ClientPaymentCreateIn
+	var errs []res.FieldError
+
+	if in.AmountMinor <= 0 {
+		errs = append(errs, res.Invalid("amountMinor", "must be greater than 0"))
+	} else {
+		out.AmountMinor = in.AmountMinor
+	}
+
+	paymentDate, dateErrs := validateISODateRequired("paymentDate", in.PaymentDate)
+	errs = append(errs, dateErrs...)
+	out.PaymentDate = paymentDate
+
+	label, labelErrs := validateOptionalPaymentReceiptLabel(in.Label, "label")
+	errs = append(errs, labelErrs...)
+	out.Label = label
+
+	method, methodErrs := validateOptionalPaymentMethod(in.PaymentMethod, "paymentMethod")
+	errs = append(errs, methodErrs...)
+	out.PaymentMethod = method
+
+	reference, referenceErrs := validateOptionalPaymentReceiptLabel(in.Reference, "reference")
+	errs = append(errs, referenceErrs...)
+	out.Reference = reference
+
+	if currency := strings.ToUpper(strings.TrimSpace(in.Currency)); currency != "" {
+		if !invoiceformat.SupportedCurrency(currency) {
+			errs = append(errs, res.Invalid("currency", "is not a supported currency"))
+		} else {
+			out.Currency = currency
+		}
+	}
+
+	allocations, allocationErrs := validateClientPaymentAllocations(in.AutoAllocate, in.Allocations)
+	errs = append(errs, allocationErrs...)
+	out.AutoAllocate = in.AutoAllocate
+	out.Allocations = allocations
+
+	if out.AmountMinor > 0 {
+		var allocated int64
+		for _, alloc := range allocations {
+			allocated += alloc.AmountMinor
+		}
+		if allocated > out.AmountMinor {
+			errs = append(errs, res.Invalid("allocations", "cannot exceed amountMinor"))
+		}
+	}
+
+	return out, errs
+}
+
+func ValidateClientPaymentAllocate(in models.ClientPaymentAllocateIn) (models.ClientPaymentAllocateIn, []res.FieldError) {
+	allocations, errs := validateClientPaymentAllocations(in.AutoAllocate, in.Allocations)
+	if !in.AutoAllocate && len(in.Allocations) == 0 {
+		errs = append(errs, res.Required("allocations"))
+	}
+
+	return models.ClientPaymentAllocateIn{
+		AutoAllocate: in.AutoAllocate,
+		Allocations:  allocations,
+	}, errs
+}
+
+// validateClientPaymentAllocations checks manual allocations. Auto allocation
+// picks invoices itself, so it cannot be combined with a manual list.
+func validateClientPaymentAllocations(autoAllocate bool, in []models.ClientPaymentAllocationIn) ([]models.ClientPaymentAllocationIn, []res.FieldError) {
+	var errs []res.FieldError
+
+	if autoAllocate {
+		if len(in) > 0 {
+			errs = append(errs, res.Invalid("allocations", "must be empty when autoAllocate is true"))
+		}
+		return nil, errs
+	}
+
+	out := make([]models.ClientPaymentAllocationIn, 0, len(in))
+	seen := make(map[int64]bool, len(in))
+	for i, alloc := range in {
+		prefix := func(field string) string { return fmt.Sprintf("allocations[%d].%s", i, field) }
+
+		if alloc.BaseNumber < 1 {
+			errs = append(errs, res.Invalid(prefix("baseNumber"), "must be greater than 0"))
+		} else if seen[alloc.BaseNumber] {
+			errs = append(errs, res.Invalid(prefix("baseNumber"), "must not repeat an invoice"))
+		}
+		seen[alloc.BaseNumber] = true
+
+		if alloc.AmountMinor <= 0 {
+			errs = append(errs, res.Invalid(prefix("amountMinor"), "must be greater than 0"))
+		}
+
+		out = append(out, alloc)
+	}
+
+	return out, errs
+}
+
+func ValidateClientCreditApply(in models.ClientCreditApplyIn) (models.ClientCreditApplyIn, []res.FieldError) {
+	var out models.ClientCreditApplyIn
+	var errs []res.FieldError
+
+	if in.BaseNumber < 1 {
+		errs = append(errs, res.Invalid("baseNumber", "must be greater than 0"))
+	} else {
+		out.BaseNumber = in.BaseNumber
+	}
+
+	if in.AmountMinor <= 0 {
+		errs = append(errs, res.Invalid("amountMinor", "must be greater than 0"))
+	} else {
+		out.AmountMinor = in.AmountMinor
+	}
+
+	paymentDate, dateErrs := validateISODateRequired("paymentDate", in.PaymentDate)
+	errs = append(errs, dateErrs...)
+	out.PaymentDate = paymentDate
+
+	label, labelErrs := validateOptionalPaymentReceiptLabel(in.Label, "label")
+	errs = append(errs, labelErrs...)
+	out.Label = label
+
+	return out, errs
+}
+
+func ValidateCreditNoteCreate(in models.CreditNoteCreateIn) (models.CreditNoteCreateIn, []res.FieldError) {
+	var out models.CreditNoteCreateIn
+	var errs []res.FieldError
+
+	if in.RevisionNo != nil {
+		if *in.RevisionNo < 1 {
+			errs = append(errs, res.Invalid("revisionNo", "must be greater than 0"))
+		} else {
+			revisionNo := *in.RevisionNo
+			out.RevisionNo = &revisionNo
+		}
+	}
+
+	issueDate, dateErrs := validateISODateRequired("issueDate", in.IssueDate)
+	errs = append(errs, dateErrs...)
+	out.IssueDate = issueDate
+
+	if in.Reason != nil {
+		reason, textErrs := validate.Text(*in.Reason, validate.TextRules{
+			Field:      "reason",
+			Required:   false,
+			Min:        0,
+			Max:        1000,
+			SingleLine: true,
+			Trim:       true,
+		})
+		errs = append(errs, textErrs...)
+		out.Reason = &reason
+	}
+
+	if in.VATRate != nil {
+		if *in.VATRate < 0 || *in.VATRate > 10000 {
+			errs = append(errs, res.Invalid("vatRate", "must be between 0 and 10000"))
+		} else {
+			vatRate := *in.VATRate
+			out.VATRate = &vatRate
+		}
+	}
+
+	if len(in.Lines) == 0 {
+		errs = append(errs, res.Invalid("lines", "must contain at least one item"))
+	}
+
+	for i, ln := range in.Lines {
+		var clean models.CreditNoteLineIn
+		prefix := func(field string) string { return fmt.Sprintf("lines[%d].%s", i, field) }
+
+		name, textErrs := validate.Text(ln.Name, validate.TextRules{
+			Field:      prefix("name"),
+			Required:   true,
+			Min:        1,
+			Max:        200,
+			SingleLine: true,
+			Trim:       true,
+		})
+		errs = append(errs, textErrs...)
+		clean.Name = name
+
+		lineType := strings.TrimSpace(ln.LineType)
+		switch lineType {
+		case "custom", "style", "sample":
+			clean.LineType = lineType
+		default:
+			errs = append(errs, res.Invalid(prefix("lineType"), "must be one of: custom, style, sample"))
+		}
+
+		if ln.Quantity <= 0 {
+			errs = append(errs, res.Invalid(prefix("quantity"), "must be greater than 0"))
+		} else {
+			clean.Quantity = ln.Quantity
+		}
+
+		if ln.UnitPriceMinor < 0 {
+			errs = append(errs, res.Invalid(prefix("unitPriceMinor"), "must be 0 or greater"))
+		} else {
+			clean.UnitPriceMinor = ln.UnitPriceMinor
+		}
+
+		// vatRate - optional per-line override in basis points
+		if ln.VATRate != nil {
+			if *ln.VATRate < 0 || *ln.VATRate > 10000 {
+				errs = append(errs, res.Invalid(prefix("vatRate"), "must be between 0 and 10000"))
+			} else {
+				rate := *ln.VATRate
+				clean.VATRate = &rate
+			}
+		}
+
+		if ln.SortOrder < 1 {
+			errs = append(errs, res.Invalid(prefix("sortOrder"), "must be 1 or greater"))
+		} else {
+			clean.SortOrder = ln.SortOrder
+		}
+
+		if ln.LineTotalMinor != ln.Quantity.Amount(ln.UnitPriceMinor) {
+			errs = append(errs, res.Invalid(prefix("lineTotalMinor"), "does not match quantity * unitPriceMinor"))
+		} else {
+			clean.LineTotalMinor = ln.LineTotalMinor
+		}
+
+		out.Lines = append(out.Lines, clean)
+	}
+
+	return out, errs
+}
+
+func ValidateQuoteConvert(in models.QuoteConvertIn) (models.QuoteConvertIn, []res.FieldError) {
+	var out models.QuoteConvertIn
+	var errs []res.FieldError
+
+	issueDate, dateErrs := validateISODateRequired("issueDate", in.IssueDate)
+	errs = append(errs, dateErrs...)
+	out.IssueDate = issueDate
+
+	dueByDate, dateErrs := validateISODateOptional("dueByDate", in.DueByDate)
+	errs = append(errs, dateErrs...)
+	out.DueByDate = dueByDate
+
+	return out, errs
+}
+
+func ValidateInvoiceDuplicate(in models.InvoiceDuplicateIn) (models.InvoiceDuplicateIn, []res.FieldError) {
+	var out models.InvoiceDuplicateIn
+	var errs []res.FieldError
+
+	if in.RevisionNo != nil && *in.RevisionNo < 1 {
+		errs = append(errs, res.Invalid("revisionNo", "must be at least 1"))
+	}
+	out.RevisionNo = in.RevisionNo
+
+	if in.ClientID != nil && *in.ClientID < 1 {
+		errs = append(errs, res.Invalid("clientId", "must be a valid client id"))
+	}
+	out.ClientID = in.ClientID
+
+	issueDate, dateErrs := validateISODateOptional("issueDate", in.IssueDate)
+	errs = append(errs, dateErrs...)
+	out.IssueDate = issueDate
+
+	dueByDate, dateErrs := validateISODateOptional("dueByDate", in.DueByDate)
+	errs = append(errs, dateErrs...)
+	out.DueByDate = dueByDate
+
+	if out.IssueDate != nil && out.DueByDate != nil && *out.DueByDate < *out.IssueDate {
+		errs = append(errs, res.Invalid("dueByDate", "must be on or after issueDate"))
+	}
+
 	return out, errs
 }
 
@@ -428,6 +869,23 @@ func validateOptionalPaymentReceiptLabel(value *string, field string) (*string,
 	return &label, nil
 }
 
+// validateOptionalPaymentMethod normalizes a receipt payment method; blank means not recorded.
+func validateOptionalPaymentMethod(value *string, field string) (*string, []res.FieldError) {
+	if value == nil {
+		return nil, nil
+	}
+
+	method := strings.TrimSpace(strings.ToLower(*value))
+	if method == "" {
+		return nil, nil
+	}
+	if !slices.Contains(models.PaymentMethods, method) {
+		return nil, []res.FieldError{res.Invalid(field, "must be one of: "+strings.Join(models.PaymentMethods, ", "))}
+	}
+
+	return &method, nil
+}
+
 func validateISODateRequired(field, value string) (string, []res.FieldError) {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -27,7 +27,7 @@ func validInvoiceInput() models.FEInvoiceIn {
 				Name:           "Line",
 				LineType:       "custom",
 				PricingMode:    "flat",
-				Quantity:       1,
+				Quantity:       models.WholeQuantity(1),
 				MinutesWorked:  nil,
 				UnitPriceMinor: 10000,
 				LineTotalMinor: 10000,
@@ -115,3 +115,42 @@ func TestValidateInvoiceCreate_SourceRevisionNoValidation(t *testing.T) {
 		t.Fatalf("expected validation error for invalid sourceRevisionNo")
 	}
 }
+
+func TestValidateInvoiceCreate_LineDiscount(t *testing.T) {
+	t.Run("accepts line total net of discount", func(t *testing.T) {
+		in := validInvoiceInput()
+		in.Lines[0].DiscountType = "percent"
+		in.Lines[0].DiscountRate = 1000
+		in.Lines[0].LineTotalMinor = 9000
+
+		got, errs := ValidateInvoiceCreate(in)
+		if len(errs) > 0 {
+			t.Fatalf("ValidateInvoiceCreate() errors = %v, want none", errs)
+		}
+		if got.Lines[0].DiscountMinor != 1000 {
+			t.Fatalf("DiscountMinor = %d, want 1000", got.Lines[0].DiscountMinor)
+		}
+	})
+
+	t.Run("rejects fixed discount above line amount", func(t *testing.T) {
+		in := validInvoiceInput()
+		in.Lines[0].DiscountType = "fixed"
+		in.Lines[0].DiscountMinor = 10001
+		in.Lines[0].LineTotalMinor = 0
+
+		_, errs := ValidateInvoiceCreate(in)
+		if !hasFieldError(errs, "lines[0].discountMinor") {
+			t.Fatalf("errors = %v, want lines[0].discountMinor", errs)
+		}
+	})
+
+	t.Run("rejects unknown discount type", func(t *testing.T) {
+		in := validInvoiceInput()
+		in.Lines[0].DiscountType = "bogus"
+
+		_, errs := ValidateInvoiceCreate(in)
+		if !hasFieldError(errs, "lines[0].discountType") {
+			t.Fatalf("errors = %v, want lines[0].discountType", errs)
+		}
+	})
+}
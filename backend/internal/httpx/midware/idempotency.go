@@ -0,0 +1,146 @@
+package midware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/idempotencyTx"
+)
+
+const idempotencyKeyMaxLen = 255
+
+// Idempotent returns middleware that makes POST requests carrying an
+// Idempotency-Key header safe to retry.
+//
+// The first request with a key runs and its response is stored for the
+// account for 24 hours. A repeat with the same method, path and body gets the
+// stored response back with Idempotent-Replayed: true, a repeat with a
+// different request is rejected with 422, and a repeat while the first is
+// still running gets 409. Server errors are not stored, so those can be
+// retried with the same key. Requests without the header pass straight
+// through. It must run after RequireAuth, since keys are scoped per account.
+func Idempotent(a *app.App) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > idempotencyKeyMaxLen {
+				res.Error(w, http.StatusBadRequest, "BAD_IDEMPOTENCY_KEY", "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				res.BadJSON(w)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			id, stored, err := idempotencyTx.Reserve(r.Context(), a.DB, key, idempotencyRequestHash(r, body), time.Now())
+			switch {
+			case errors.Is(err, idempotencyTx.ErrKeyReused):
+				res.Error(w, http.StatusUnprocessableEntity, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used for a different request")
+				return
+			case errors.Is(err, idempotencyTx.ErrInProgress):
+				res.Error(w, http.StatusConflict, "IDEMPOTENCY_IN_PROGRESS", "A request with this Idempotency-Key is still in progress")
+				return
+			case err != nil:
+				slog.ErrorContext(r.Context(), "reserve idempotency key failed", "err", err)
+				res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+				return
+			}
+
+			if stored != nil {
+				for name, values := range stored.Header {
+					w.Header()[name] = values
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.Status)
+				_, _ = w.Write(stored.Body)
+				return
+			}
+
+			// The request context may be cancelled by the time the handler
+			// returns; the reservation must still be settled.
+			settleCtx := context.WithoutCancel(r.Context())
+			rec := &responseRecorder{ResponseWriter: w}
+			settled := false
+			defer func() {
+				if !settled {
+					if err := idempotencyTx.Release(settleCtx, a.DB, id); err != nil {
+						slog.ErrorContext(settleCtx, "release idempotency key failed", "err", err)
+					}
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			if rec.status() >= http.StatusInternalServerError {
+				return
+			}
+			if err := idempotencyTx.Complete(settleCtx, a.DB, id, idempotencyTx.StoredResponse{
+				Status: rec.status(),
+				Header: rec.header,
+				Body:   rec.body.Bytes(),
+			}); err != nil {
+				slog.ErrorContext(settleCtx, "store idempotent response failed", "err", err)
+				return
+			}
+			settled = true
+		})
+	}
+}
+
+func idempotencyRequestHash(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method))
+	sum.Write([]byte{0})
+	sum.Write([]byte(r.URL.Path))
+	sum.Write([]byte{0})
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// responseRecorder passes a response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	code   int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(code int) {
+	if rec.code == 0 {
+		rec.code = code
+		rec.header = rec.ResponseWriter.Header().Clone()
+	}
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.code == 0 {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) status() int {
+	if rec.code == 0 {
+		return http.StatusOK
+	}
+	return rec.code
+}
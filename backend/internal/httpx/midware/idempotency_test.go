@@ -0,0 +1,105 @@
+package midware
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/db"
+)
+
+func newIdempotencyApp(t *testing.T) *app.App {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "idempotency.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	if err := db.Migrate(context.Background(), conn); err != nil {
+		t.Fatalf("migrate db: %v", err)
+	}
+
+	return &app.App{DB: conn}
+}
+
+func doIdempotentRequest(t *testing.T, handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/clients/1/invoice/7", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	req = req.WithContext(accountscope.WithAccountID(req.Context(), accountscope.DefaultAccountID))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotent_ReplaysStoredResponse(t *testing.T) {
+	a := newIdempotencyApp(t)
+
+	calls := 0
+	handler := Idempotent(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("ETag", `"1-a"`)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"receiptNo":1}`))
+	}))
+
+	first := doIdempotentRequest(t, handler, "key-1", `{"amountMinor":100}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusCreated)
+	}
+
+	second := doIdempotentRequest(t, handler, "key-1", `{"amountMinor":100}`)
+	if calls != 1 {
+		t.Fatalf("handler calls = %d, want 1", calls)
+	}
+	if second.Code != http.StatusCreated || second.Body.String() != `{"receiptNo":1}` {
+		t.Fatalf("replay = %d %q, want the first response", second.Code, second.Body.String())
+	}
+	if second.Header().Get("ETag") != `"1-a"` || second.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("replay headers = %v", second.Header())
+	}
+
+	mismatch := doIdempotentRequest(t, handler, "key-1", `{"amountMinor":200}`)
+	if mismatch.Code != http.StatusUnprocessableEntity || !strings.Contains(mismatch.Body.String(), "IDEMPOTENCY_KEY_REUSED") {
+		t.Fatalf("mismatch = %d %q, want IDEMPOTENCY_KEY_REUSED", mismatch.Code, mismatch.Body.String())
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls after mismatch = %d, want 1", calls)
+	}
+}
+
+func TestIdempotent_ServerErrorsCanBeRetried(t *testing.T) {
+	a := newIdempotencyApp(t)
+
+	calls := 0
+	handler := Idempotent(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	if first := doIdempotentRequest(t, handler, "key-2", `{}`); first.Code != http.StatusInternalServerError {
+		t.Fatalf("first status = %d, want %d", first.Code, http.StatusInternalServerError)
+	}
+	if retry := doIdempotentRequest(t, handler, "key-2", `{}`); retry.Code != http.StatusCreated {
+		t.Fatalf("retry status = %d, want %d", retry.Code, http.StatusCreated)
+	}
+	if calls != 2 {
+		t.Fatalf("handler calls = %d, want 2", calls)
+	}
+}
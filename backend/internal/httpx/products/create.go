@@ -11,6 +11,7 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/models"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/clientsTx"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/productsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
 func CreateProduct(a *app.App) http.HandlerFunc {
@@ -48,6 +49,10 @@ func CreateProduct(a *app.App) http.HandlerFunc {
 
 		created, err := productsTx.InsertTx(a, r.Context(), product)
 		if err != nil {
+			if errors.Is(err, settingsTx.ErrTaxRateNotFound) {
+				res.Validation(w, res.Invalid("taxRateId", "tax rate not found"))
+				return
+			}
 			slog.ErrorContext(r.Context(),
 				"create product failed",
 				"client_id", clientID,
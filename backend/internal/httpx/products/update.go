@@ -12,6 +12,7 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/models"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/clientsTx"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/productsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
 func UpdateProduct(a *app.App) http.HandlerFunc {
@@ -58,6 +59,10 @@ func UpdateProduct(a *app.App) http.HandlerFunc {
 
 		updated, err := productsTx.UpdateTx(a, r.Context(), productID, cmd)
 		if err != nil {
+			if errors.Is(err, settingsTx.ErrTaxRateNotFound) {
+				res.Validation(w, res.Invalid("taxRateId", "tax rate not found"))
+				return
+			}
 			if errors.Is(err, sql.ErrNoRows) {
 				res.NotFound(w, "product not found")
 				return
@@ -61,6 +61,28 @@ func ValidateCreate(in models.ProductCreateIn, routeClientID int64) (models.Prod
 		out.ProductName = productName
 	}
 
+	// ----- unit (optional) -----
+	if in.Unit != nil {
+		unit, fe := validate.Text(*in.Unit, validate.TextRules{
+			Field: "unit", Max: 20, SingleLine: true, Trim: true,
+		})
+		if len(fe) > 0 {
+			errs = append(errs, fe...)
+		} else {
+			out.Unit = unit
+		}
+	}
+
+	// ----- taxRateId (optional) -----
+	if in.TaxRateID != nil {
+		if *in.TaxRateID < 1 {
+			errs = append(errs, res.Invalid("taxRateId", "must be greater than 0"))
+		} else {
+			taxRateID := *in.TaxRateID
+			out.TaxRateID = &taxRateID
+		}
+	}
+
 	if out.ProductType == "style" && out.PricingMode == "hourly" {
 		errs = append(errs, res.Invalid("pricingMode", "must be 'flat' for style"))
 	}
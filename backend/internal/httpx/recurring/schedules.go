@@ -0,0 +1,158 @@
+package recurring
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/clientsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/recurringTx"
+)
+
+func ListSchedules(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+
+		schedules, err := recurringTx.List(r.Context(), a, clientID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "list recurring schedules failed", "client_id", clientID, "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, schedules)
+	}
+}
+
+func CreateSchedule(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		if !verifyClient(w, r, a, clientID) {
+			return
+		}
+
+		var dto models.RecurringScheduleIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateSchedule(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		out, err := recurringTx.Create(r.Context(), a, clientID, valid)
+		if err != nil {
+			handleScheduleWriteError(w, r, clientID, 0, "create", err)
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, out)
+	}
+}
+
+func UpdateSchedule(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		scheduleID, ok := params.ValidateParam(w, r, "scheduleID")
+		if !ok {
+			return
+		}
+		if !verifyClient(w, r, a, clientID) {
+			return
+		}
+
+		var dto models.RecurringScheduleIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+
+		valid, errs := ValidateSchedule(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		out, err := recurringTx.Update(r.Context(), a, clientID, scheduleID, valid)
+		if err != nil {
+			handleScheduleWriteError(w, r, clientID, scheduleID, "update", err)
+			return
+		}
+
+		res.JSON(w, http.StatusOK, out)
+	}
+}
+
+func DeleteSchedule(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		clientID, ok := params.ValidateParam(w, r, "clientID")
+		if !ok {
+			return
+		}
+		scheduleID, ok := params.ValidateParam(w, r, "scheduleID")
+		if !ok {
+			return
+		}
+
+		if err := recurringTx.Delete(r.Context(), a, clientID, scheduleID); err != nil {
+			handleScheduleWriteError(w, r, clientID, scheduleID, "delete", err)
+			return
+		}
+
+		res.NoContent(w)
+	}
+}
+
+func verifyClient(w http.ResponseWriter, r *http.Request, a *app.App, clientID int64) bool {
+	if err := clientsTx.VerifyClientID(r.Context(), a, clientID); err != nil {
+		if errors.Is(err, clientsTx.ErrClientNotFound) {
+			res.NotFound(w, "client not found")
+			return false
+		}
+
+		slog.ErrorContext(r.Context(), "verify client failed", "client_id", clientID, "err", err)
+		res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+		return false
+	}
+	return true
+}
+
+func handleScheduleWriteError(
+	w http.ResponseWriter,
+	r *http.Request,
+	clientID int64,
+	scheduleID int64,
+	action string,
+	err error,
+) {
+	switch {
+	case errors.Is(err, recurringTx.ErrScheduleNotFound):
+		res.NotFound(w, "recurring schedule not found")
+		return
+	case errors.Is(err, recurringTx.ErrSourceInvoiceNotFound):
+		res.Validation(w, res.Invalid("sourceBaseNumber", "invoice or revision not found for this client"))
+		return
+	}
+
+	slog.ErrorContext(r.Context(),
+		action+" recurring schedule failed",
+		"client_id", clientID,
+		"schedule_id", scheduleID,
+		"err", err,
+	)
+	res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+}
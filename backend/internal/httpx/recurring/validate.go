@@ -0,0 +1,69 @@
+package recurring
+
+import (
+	"strings"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/recurringTx"
+)
+
+func ValidateSchedule(in models.RecurringScheduleIn) (models.RecurringScheduleIn, []res.FieldError) {
+	var out models.RecurringScheduleIn
+	var errs []res.FieldError
+
+	if in.SourceBaseNumber < 1 {
+		errs = append(errs, res.Invalid("sourceBaseNumber", "must be greater than 0"))
+	} else {
+		out.SourceBaseNumber = in.SourceBaseNumber
+	}
+
+	if in.SourceRevisionNo != nil {
+		if *in.SourceRevisionNo < 1 {
+			errs = append(errs, res.Invalid("sourceRevisionNo", "must be greater than 0"))
+		} else {
+			revisionNo := *in.SourceRevisionNo
+			out.SourceRevisionNo = &revisionNo
+		}
+	}
+
+	cadence := strings.TrimSpace(strings.ToLower(in.Cadence))
+	switch cadence {
+	case "":
+		errs = append(errs, res.Required("cadence"))
+	case recurringTx.CadenceWeekly:
+		out.Cadence = cadence
+		if in.DayOfMonth != nil {
+			errs = append(errs, res.Invalid("dayOfMonth", "must be empty for weekly schedules"))
+		}
+	case recurringTx.CadenceMonthly, recurringTx.CadenceYearly:
+		out.Cadence = cadence
+		switch {
+		case in.DayOfMonth == nil:
+			errs = append(errs, res.Required("dayOfMonth"))
+		case *in.DayOfMonth < 1 || *in.DayOfMonth > 31:
+			errs = append(errs, res.Invalid("dayOfMonth", "must be between 1 and 31"))
+		default:
+			day := *in.DayOfMonth
+			out.DayOfMonth = &day
+		}
+	default:
+		errs = append(errs, res.Invalid("cadence", "must be one of: weekly, monthly, yearly"))
+	}
+
+	nextRunDate := strings.TrimSpace(in.NextRunDate)
+	if nextRunDate == "" {
+		errs = append(errs, res.Required("nextRunDate"))
+	} else if _, err := time.Parse("2006-01-02", nextRunDate); err != nil {
+		errs = append(errs, res.Invalid("nextRunDate", "must be a valid ISO date (YYYY-MM-DD)"))
+	} else {
+		out.NextRunDate = nextRunDate
+	}
+
+	out.AutoIssue = in.AutoIssue
+	active := in.Active == nil || *in.Active
+	out.Active = &active
+
+	return out, errs
+}
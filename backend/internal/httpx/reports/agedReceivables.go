@@ -0,0 +1,42 @@
+package reports
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/reportsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+)
+
+// AgedReceivables buckets outstanding invoice balances per client into
+// current/1-30/31-60/61-90/90+ days past due, as of today in the workspace time zone.
+func AgedReceivables(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID, err := accountscope.Require(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "aged receivables missing account scope", "err", err)
+			res.Error(w, http.StatusInternalServerError, "INTERNAL", "Failed to load report")
+			return
+		}
+
+		asOf, err := settingsTx.Today(r.Context(), a.DB, accountID, time.Now())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "aged receivables resolve date failed", "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		report, err := reportsTx.QueryAgedReceivables(r.Context(), a.DB, asOf)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "aged receivables query failed", "err", err, "as_of", asOf)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, report)
+	}
+}
@@ -0,0 +1,25 @@
+package reports
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/reportsTx"
+)
+
+// InvoiceNumberGaps lists missing invoice base numbers and template numbers
+// and why each one is missing, for proving the numbering is complete.
+func InvoiceNumberGaps(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := reportsTx.QueryInvoiceNumberGaps(r.Context(), a.DB)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "invoice number gaps query failed", "err", err)
+			res.Error(w, http.StatusInternalServerError, "DATABASE_ERROR", "Database error")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, report)
+	}
+}
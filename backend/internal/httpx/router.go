@@ -12,6 +12,8 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/httpx/invoice"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/midware"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/products"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/recurring"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/reports"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/settings"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/team"
 	"time"
@@ -65,6 +67,14 @@ func RegisterAllRouters(r chi.Router, a *app.App) {
 					r.Put("/", settings.PutLogo(a))
 					r.Delete("/", settings.DeleteLogo(a))
 				})
+				r.Route("/taxes", func(r chi.Router) {
+					r.Get("/", settings.ListTaxes(a))
+					r.Post("/", settings.CreateTax(a))
+					r.Route("/{taxRateID}", func(r chi.Router) {
+						r.Put("/", settings.UpdateTax(a))
+						r.Delete("/", settings.DeleteTax(a))
+					})
+				})
 			})
 
 			r.Route("/api/team", func(r chi.Router) {
@@ -77,8 +87,19 @@ func RegisterAllRouters(r chi.Router, a *app.App) {
 
 			r.Get("/api/edits", editor.HandleINVBookData(a))
 
+			r.Route("/api/audit-log", func(r chi.Router) {
+				r.Use(midware.RequireOwner)
+				r.Get("/", invoice.ListAuditLog(a))
+			})
+
+			r.Route("/api/reports", func(r chi.Router) {
+				r.Get("/aged-receivables", reports.AgedReceivables(a))
+				r.With(midware.RequireOwner).Get("/invoice-number-gaps", reports.InvoiceNumberGaps(a))
+			})
+
 			r.Route("/api/clients", func(r chi.Router) {
 				r.Use(midware.LimitBodyMaxSize(2 << 20)) // 2MB
+				r.Use(midware.Idempotent(a))
 				r.Post("/", clients.Create(a))
 				r.Get("/", clients.ListAll(a))
 
@@ -107,9 +128,48 @@ func RegisterAllRouters(r chi.Router, a *app.App) {
 							r.Delete("/", products.DeleteProduct(a))
 						})
 					})
+					// /api/clients/{clientID}/quotes/...
+					r.Route("/quotes", func(r chi.Router) {
+						r.Get("/", invoice.ListQuotes(a))
+						r.Get("/next-number", invoice.GetNextQuoteNumber(a))
+						r.Route("/{quoteNo}", func(r chi.Router) {
+							r.Get("/", invoice.GetQuote(a))
+							r.Post("/", invoice.CreateQuote(a))
+							r.Put("/", invoice.UpdateQuote(a))
+							r.Delete("/", invoice.DeleteQuote(a))
+							r.Patch("/status", invoice.PatchQuoteStatus(a))
+							r.Post("/convert", invoice.ConvertQuote(a))
+							r.Get("/pdf", invoice.GenerateQuotePDFHandler(a))
+							r.Get("/docx", invoice.GenerateQuoteDOCXHandler(a))
+						})
+					})
+					// /api/clients/{clientID}/recurring/...
+					r.Route("/recurring", func(r chi.Router) {
+						r.Get("/", recurring.ListSchedules(a))
+						r.Post("/", recurring.CreateSchedule(a))
+						r.Route("/{scheduleID}", func(r chi.Router) {
+							r.Patch("/", recurring.UpdateSchedule(a))
+							r.Delete("/", recurring.DeleteSchedule(a))
+						})
+					})
+					// /api/clients/{clientID}/payments/...
+					r.Route("/payments", func(r chi.Router) {
+						r.Get("/", invoice.ListClientPayments(a))
+						r.Post("/", invoice.CreateClientPayment(a))
+						r.Route("/{clientPaymentID}", func(r chi.Router) {
+							r.Get("/", invoice.GetClientPayment(a))
+							r.Post("/allocations", invoice.AllocateClientPayment(a))
+						})
+					})
+					// /api/clients/{clientID}/credit/...
+					r.Route("/credit", func(r chi.Router) {
+						r.Get("/", invoice.GetClientCredit(a))
+						r.Post("/apply", invoice.ApplyClientCredit(a))
+					})
 					// /api/clients/{clientID}/invoice/...
 					r.Route("/invoice", func(r chi.Router) {
 						r.Get("/", invoice.GetNextInvoiceNumber(a))
+						r.Get("/next", invoice.PreviewNextInvoiceNumber(a))
 						r.Route("/{baseNumber}", func(r chi.Router) {
 							r.Post("/", invoice.CreateInvoice(a))
 							r.Put("/", invoice.UpdateInvoice(a))
@@ -117,12 +177,36 @@ func RegisterAllRouters(r chi.Router, a *app.App) {
 							r.Patch("/status", invoice.PatchInvoiceStatus(a))
 							r.Post("/verify", invoice.VerifyInvoice())
 							r.With(midware.LimitInvoiceRevisionCreateByUser()).Post("/revisions", invoice.CreateRevision(a))
+							r.Get("/history", invoice.GetInvoiceHistory(a))
+							r.Get("/revisions/{revisionNo}/diff/{toRevisionNo}", invoice.GetInvoiceRevisionDiff(a))
+							r.Post("/duplicate", invoice.DuplicateInvoice(a))
+							r.Get("/late-charges", invoice.PreviewLateCharge(a))
+							r.With(midware.LimitInvoiceRevisionCreateByUser()).Post("/late-charges", invoice.CreateLateCharge(a))
 							r.Route("/revisions/{revisionNo}/receipts", func(r chi.Router) {
 								r.Post("/", invoice.CreatePaymentReceipt(a))
 								r.Patch("/{receiptNo}", invoice.UpdatePaymentReceipt(a))
 								r.Delete("/{receiptNo}", invoice.DeletePaymentReceipt(a))
 								r.Get("/{receiptNo}/pdf", invoice.GeneratePaymentReceiptPDFHandler(a))
 								r.Get("/{receiptNo}/docx", invoice.GeneratePaymentReceiptDOCXHandler(a))
+								r.Post("/{receiptNo}/refunds", invoice.CreateRefund(a))
+							})
+							r.Route("/deposits", func(r chi.Router) {
+								r.Get("/", invoice.ListDepositReceipts(a))
+								r.Post("/", invoice.CreateDepositReceipt(a))
+								r.Delete("/{depositNo}", invoice.DeleteDepositReceipt(a))
+								r.Get("/{depositNo}/pdf", invoice.GenerateDepositReceiptPDFHandler(a))
+								r.Get("/{depositNo}/docx", invoice.GenerateDepositReceiptDOCXHandler(a))
+							})
+							r.Route("/refunds", func(r chi.Router) {
+								r.Get("/", invoice.ListRefunds(a))
+								r.Get("/{refundNo}/pdf", invoice.GenerateRefundPDFHandler(a))
+								r.Get("/{refundNo}/docx", invoice.GenerateRefundDOCXHandler(a))
+							})
+							r.Route("/credit-notes", func(r chi.Router) {
+								r.Get("/", invoice.ListCreditNotes(a))
+								r.Post("/", invoice.CreateCreditNote(a))
+								r.Get("/{creditNoteNo}/pdf", invoice.GenerateCreditNotePDFHandler(a))
+								r.Get("/{creditNoteNo}/docx", invoice.GenerateCreditNoteDOCXHandler(a))
 							})
 							r.Get("/{revisionNo}/pdf", invoice.GeneratePDFHandler(a))
 							r.Post("/{revisionNo}/pdf/quick", invoice.QuickPDFHandler(a))
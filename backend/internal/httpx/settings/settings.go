@@ -3,14 +3,17 @@ package settings
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
 	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 	"github.com/viktorHadz/goInvoice26/internal/userscope"
 )
@@ -83,12 +86,54 @@ func Put(a *app.App) http.HandlerFunc {
 		if in.InvoicePrefix == "" {
 			in.InvoicePrefix = "INV-"
 		}
+		if _, ok := raw["quotePrefix"]; !ok {
+			in.QuotePrefix = current.QuotePrefix
+		}
+		if in.QuotePrefix == "" {
+			in.QuotePrefix = "QUO-"
+		}
+		if _, ok := raw["invoiceNumberTemplate"]; !ok {
+			in.InvoiceNumberTemplate = current.InvoiceNumberTemplate
+		}
+		in.InvoiceNumberTemplate = strings.TrimSpace(in.InvoiceNumberTemplate)
+		if err := invoiceformat.ValidateNumberTemplate(in.InvoiceNumberTemplate); err != nil {
+			res.Validation(w, res.Invalid("invoiceNumberTemplate", err.Error()))
+			return
+		}
 		if in.Currency == "" {
 			in.Currency = "GBP"
 		}
+		in.Currency = strings.ToUpper(strings.TrimSpace(in.Currency))
+		if !invoiceformat.SupportedCurrency(in.Currency) {
+			res.Validation(w, res.Invalid("currency", "is not a supported currency"))
+			return
+		}
 		if in.DateFormat == "" {
 			in.DateFormat = "dd/mm/yyyy"
 		}
+		if in.Timezone == "" {
+			in.Timezone = current.Timezone
+		}
+		if in.Timezone == "" {
+			in.Timezone = settingsTx.DefaultTimezone
+		}
+		if !settingsTx.ValidTimezone(in.Timezone) {
+			res.Validation(w, res.Invalid("timezone", "must be an IANA time zone such as Europe/London"))
+			return
+		}
+		if _, ok := raw["lateInterestRate"]; !ok {
+			in.LateInterestRate = current.LateInterestRate
+		}
+		if _, ok := raw["lateBaseRate"]; !ok {
+			in.LateBaseRate = current.LateBaseRate
+		}
+		if _, ok := raw["lateCompensationTiers"]; !ok {
+			in.LateCompensationTiers = current.LateCompensationTiers
+		}
+		if errs := validateLatePayment(in); len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
 		if _, ok := raw["showItemTypeHeaders"]; !ok {
 			in.ShowItemTypeHeaders = true
 		}
@@ -130,3 +175,35 @@ func Put(a *app.App) http.HandlerFunc {
 		res.JSON(w, http.StatusOK, cfg)
 	}
 }
+
+const maxLateCompensationTiers = 10
+
+func validateLatePayment(in models.Settings) []res.FieldError {
+	var errs []res.FieldError
+
+	if in.LateInterestRate < 0 || in.LateInterestRate > 10000 {
+		errs = append(errs, res.Invalid("lateInterestRate", "must be between 0 and 10000"))
+	}
+	if in.LateBaseRate < 0 || in.LateBaseRate > 10000 {
+		errs = append(errs, res.Invalid("lateBaseRate", "must be between 0 and 10000"))
+	}
+
+	if len(in.LateCompensationTiers) > maxLateCompensationTiers {
+		errs = append(errs, res.Invalid("lateCompensationTiers", fmt.Sprintf("must have at most %d tiers", maxLateCompensationTiers)))
+		return errs
+	}
+	for i, tier := range in.LateCompensationTiers {
+		field := fmt.Sprintf("lateCompensationTiers[%d]", i)
+		if tier.FromMinor < 0 {
+			errs = append(errs, res.Invalid(field+".fromMinor", "must be 0 or greater"))
+		}
+		if tier.AmountMinor < 0 {
+			errs = append(errs, res.Invalid(field+".amountMinor", "must be 0 or greater"))
+		}
+		if i > 0 && tier.FromMinor <= in.LateCompensationTiers[i-1].FromMinor {
+			errs = append(errs, res.Invalid(field+".fromMinor", "must be greater than the previous tier"))
+		}
+	}
+
+	return errs
+}
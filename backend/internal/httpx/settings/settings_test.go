@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -170,3 +171,34 @@ func TestDeleteLogo_RejectsMemberEdits(t *testing.T) {
 		t.Fatalf("body = %q, want SETTINGS_OWNER_ONLY", rec.Body.String())
 	}
 }
+
+func TestPut_KeepsQuotePrefixWhenOmitted(t *testing.T) {
+	a, cleanup := newSettingsApp(t)
+	defer cleanup()
+
+	put := func(body string) map[string]any {
+		t.Helper()
+
+		req := ownerRequest(t, http.MethodPut, "/api/settings")
+		req.Body = io.NopCloser(strings.NewReader(body))
+		rec := httptest.NewRecorder()
+
+		Put(a).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		var got map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return got
+	}
+
+	if got := put(`{"quotePrefix":"EST-"}`); got["quotePrefix"] != "EST-" {
+		t.Fatalf("quotePrefix = %v, want EST-", got["quotePrefix"])
+	}
+	if got := put(`{"invoicePrefix":"INV-"}`); got["quotePrefix"] != "EST-" {
+		t.Fatalf("quotePrefix after partial update = %v, want EST-", got["quotePrefix"])
+	}
+}
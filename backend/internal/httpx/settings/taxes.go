@@ -0,0 +1,191 @@
+package settings
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/params"
+	"github.com/viktorHadz/goInvoice26/internal/httpx/res"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+	"github.com/viktorHadz/goInvoice26/internal/userscope"
+	"github.com/viktorHadz/goInvoice26/internal/validate"
+)
+
+// ListTaxes returns the workspace tax rate registry.
+func ListTaxes(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID, err := accountscope.Require(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "list tax rates missing account scope", "err", err)
+			res.Error(w, http.StatusInternalServerError, "INTERNAL", "Failed to load tax rates")
+			return
+		}
+
+		rates, err := settingsTx.ListTaxRates(r.Context(), a.DB, accountID)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "list tax rates failed", "err", err, "account_id", accountID)
+			res.Error(w, http.StatusInternalServerError, "INTERNAL", "Failed to load tax rates")
+			return
+		}
+
+		res.JSON(w, http.StatusOK, rates)
+	}
+}
+
+func CreateTax(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accountID, ok := requireTaxOwner(w, r)
+		if !ok {
+			return
+		}
+
+		var dto models.TaxRateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+		valid, errs := ValidateTaxRate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		out, err := settingsTx.CreateTaxRate(r.Context(), a.DB, accountID, valid)
+		if err != nil {
+			handleTaxWriteError(w, r, 0, "create", err)
+			return
+		}
+
+		res.JSON(w, http.StatusCreated, out)
+	}
+}
+
+func UpdateTax(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taxRateID, ok := params.ValidateParam(w, r, "taxRateID")
+		if !ok {
+			return
+		}
+		accountID, ok := requireTaxOwner(w, r)
+		if !ok {
+			return
+		}
+
+		var dto models.TaxRateIn
+		if ok := res.DecodeJSON(w, r, &dto); !ok {
+			return
+		}
+		valid, errs := ValidateTaxRate(dto)
+		if len(errs) > 0 {
+			res.Validation(w, errs...)
+			return
+		}
+
+		out, err := settingsTx.UpdateTaxRate(r.Context(), a.DB, accountID, taxRateID, valid)
+		if err != nil {
+			handleTaxWriteError(w, r, taxRateID, "update", err)
+			return
+		}
+
+		res.JSON(w, http.StatusOK, out)
+	}
+}
+
+func DeleteTax(a *app.App) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		taxRateID, ok := params.ValidateParam(w, r, "taxRateID")
+		if !ok {
+			return
+		}
+		accountID, ok := requireTaxOwner(w, r)
+		if !ok {
+			return
+		}
+
+		if err := settingsTx.DeleteTaxRate(r.Context(), a.DB, accountID, taxRateID); err != nil {
+			handleTaxWriteError(w, r, taxRateID, "delete", err)
+			return
+		}
+
+		res.NoContent(w)
+	}
+}
+
+// ValidateTaxRate trims and checks a tax rate payload.
+func ValidateTaxRate(in models.TaxRateIn) (models.TaxRateIn, []res.FieldError) {
+	var out models.TaxRateIn
+	var errs []res.FieldError
+
+	name, fe := validate.Text(in.Name, validate.TextRules{
+		Field: "name", Required: true, Min: 1, Max: 60, SingleLine: true, Trim: true,
+	})
+	if len(fe) > 0 {
+		errs = append(errs, fe...)
+	} else {
+		out.Name = name
+	}
+
+	if in.RateBps < 0 || in.RateBps > 10000 {
+		errs = append(errs, res.Invalid("rateBps", "must be between 0 and 10000"))
+	} else {
+		out.RateBps = in.RateBps
+	}
+
+	out.EffectiveFrom, errs = validateTaxDate("effectiveFrom", in.EffectiveFrom, errs)
+	out.EffectiveTo, errs = validateTaxDate("effectiveTo", in.EffectiveTo, errs)
+	if out.EffectiveFrom != nil && out.EffectiveTo != nil && *out.EffectiveTo < *out.EffectiveFrom {
+		errs = append(errs, res.Invalid("effectiveTo", "must be on or after effectiveFrom"))
+	}
+
+	out.IsDefault = in.IsDefault
+
+	return out, errs
+}
+
+func validateTaxDate(field string, value *string, errs []res.FieldError) (*string, []res.FieldError) {
+	if value == nil {
+		return nil, errs
+	}
+	date := strings.TrimSpace(*value)
+	if date == "" {
+		return nil, errs
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return nil, append(errs, res.Invalid(field, "must be a valid ISO date (YYYY-MM-DD)"))
+	}
+	return &date, errs
+}
+
+func requireTaxOwner(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	if userscope.Role(r.Context()) != "owner" {
+		res.Error(w, http.StatusForbidden, "SETTINGS_OWNER_ONLY", "Only the workspace admin can edit settings")
+		return 0, false
+	}
+
+	accountID, err := accountscope.Require(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "tax rate write missing account scope", "err", err)
+		res.Error(w, http.StatusInternalServerError, "INTERNAL", "Failed to save tax rate")
+		return 0, false
+	}
+	return accountID, true
+}
+
+func handleTaxWriteError(w http.ResponseWriter, r *http.Request, taxRateID int64, action string, err error) {
+	switch {
+	case errors.Is(err, settingsTx.ErrTaxRateNotFound):
+		res.NotFound(w, "tax rate not found")
+		return
+	case errors.Is(err, settingsTx.ErrTaxRateNameTaken):
+		res.Validation(w, res.Invalid("name", "is already used by another tax rate"))
+		return
+	}
+
+	slog.ErrorContext(r.Context(), action+" tax rate failed", "tax_rate_id", taxRateID, "err", err)
+	res.Error(w, http.StatusInternalServerError, "INTERNAL", "Failed to save tax rate")
+}
@@ -0,0 +1,52 @@
+package settings
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+func TestCreateTax_RejectsMemberEdits(t *testing.T) {
+	a, cleanup := newSettingsApp(t)
+	defer cleanup()
+
+	req := memberRequest(t, http.MethodPost, "/api/settings/taxes")
+	rec := httptest.NewRecorder()
+
+	CreateTax(a).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if !strings.Contains(rec.Body.String(), "SETTINGS_OWNER_ONLY") {
+		t.Fatalf("body = %q, want SETTINGS_OWNER_ONLY", rec.Body.String())
+	}
+}
+
+func TestValidateTaxRate(t *testing.T) {
+	from := "2026-01-01"
+	to := "2025-12-31"
+	blank := " "
+
+	_, errs := ValidateTaxRate(models.TaxRateIn{Name: " ", RateBps: 10001, EffectiveFrom: &from, EffectiveTo: &to})
+	fields := make(map[string]bool, len(errs))
+	for _, fe := range errs {
+		fields[fe.Field] = true
+	}
+	for _, field := range []string{"name", "rateBps", "effectiveTo"} {
+		if !fields[field] {
+			t.Fatalf("ValidateTaxRate() errors = %+v, want one for %s", errs, field)
+		}
+	}
+
+	got, errs := ValidateTaxRate(models.TaxRateIn{Name: "  Standard  ", RateBps: 2000, EffectiveFrom: &from, EffectiveTo: &blank, IsDefault: true})
+	if len(errs) > 0 {
+		t.Fatalf("ValidateTaxRate() errors = %+v, want none", errs)
+	}
+	if got.Name != "Standard" || got.EffectiveFrom == nil || *got.EffectiveFrom != from || got.EffectiveTo != nil || !got.IsDefault {
+		t.Fatalf("ValidateTaxRate() = %+v, want trimmed name and an open-ended range", got)
+	}
+}
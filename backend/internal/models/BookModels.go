@@ -9,27 +9,47 @@ type INVBookRevision struct {
 }
 
 type INVBookInvoice struct {
-	ID                int64             `json:"id"`
-	ClientID          int64             `json:"clientId"`
-	ClientName        string            `json:"clientName"`
-	ClientCompanyName string            `json:"clientCompanyName"`
-	BaseNo            int               `json:"baseNo"`
-	Status            string            `json:"status"`
-	LatestRevisionNo  int               `json:"latestRevisionNo"`
-	IssueDate         string            `json:"issueDate"`
-	DueByDate         *string           `json:"dueByDate,omitempty"`
-	TotalMinor        int64             `json:"totalMinor"`
-	DepositMinor      int64             `json:"depositMinor"`
-	PaidMinor         int64             `json:"paidMinor"`
-	BalanceDueMinor   int64             `json:"balanceDueMinor"`
-	Revisions         []INVBookRevision `json:"revisions"`
+	ID                int64   `json:"id"`
+	ClientID          int64   `json:"clientId"`
+	ClientName        string  `json:"clientName"`
+	ClientCompanyName string  `json:"clientCompanyName"`
+	BaseNo            int     `json:"baseNo"`
+	NumberLabel       string  `json:"numberLabel,omitempty"`
+	Status            string  `json:"status"`
+	LatestRevisionNo  int     `json:"latestRevisionNo"`
+	IssueDate         string  `json:"issueDate"`
+	DueByDate         *string `json:"dueByDate,omitempty"`
+	TotalMinor        int64   `json:"totalMinor"`
+	DepositMinor      int64   `json:"depositMinor"`
+	PaidMinor         int64   `json:"paidMinor"`
+	CreditedMinor     int64   `json:"creditedMinor"`
+	BalanceDueMinor   int64   `json:"balanceDueMinor"`
+	// Currency is the invoice currency of the Minor amounts above.
+	Currency          string `json:"currency"`
+	ExchangeRateMicro int64  `json:"exchangeRateMicro"`
+	// TotalBaseMinor and BalanceDueBaseMinor are converted to the workspace
+	// currency at the invoice's exchange rate.
+	TotalBaseMinor      int64 `json:"totalBaseMinor"`
+	BalanceDueBaseMinor int64 `json:"balanceDueBaseMinor"`
+	// Overdue is derived: issued, balance left and due date before today (workspace time zone).
+	Overdue   bool              `json:"overdue"`
+	Revisions []INVBookRevision `json:"revisions"`
+}
+
+// INVBookTotals sums every non-void invoice matching the book filters, not
+// just the current page, in the workspace currency.
+type INVBookTotals struct {
+	TotalMinor      int64 `json:"totalMinor"`
+	BalanceDueMinor int64 `json:"balanceDueMinor"`
 }
 
 type INVBookOut struct {
-	Items   []INVBookInvoice `json:"items"`
-	Limit   int              `json:"limit"`
-	Offset  int              `json:"offset"`
-	Count   int              `json:"count"`
-	Total   int              `json:"total"`
-	HasMore bool             `json:"hasMore"`
+	Items        []INVBookInvoice `json:"items"`
+	Limit        int              `json:"limit"`
+	Offset       int              `json:"offset"`
+	Count        int              `json:"count"`
+	Total        int              `json:"total"`
+	HasMore      bool             `json:"hasMore"`
+	BaseCurrency string           `json:"baseCurrency"`
+	Totals       INVBookTotals    `json:"totals"`
 }
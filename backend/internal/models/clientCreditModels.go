@@ -0,0 +1,33 @@
+package models
+
+// Input received from frontend (clientID received from path params)
+type ClientCreditApplyIn struct {
+	BaseNumber  int64   `json:"baseNumber"`
+	AmountMinor int64   `json:"amountMinor"`
+	PaymentDate string  `json:"paymentDate"`
+	Label       *string `json:"label,omitempty"`
+}
+
+// ClientCreditEntryOut is one movement on a client's credit balance. Money
+// put on account is positive, credit applied to an invoice is negative.
+type ClientCreditEntryOut struct {
+	Kind        string `json:"kind"` // overpayment | credit_note | applied
+	BaseNumber  int64  `json:"baseNumber"`
+	RevisionNo  int64  `json:"revisionNo"`
+	ReceiptNo   *int64 `json:"receiptNo,omitempty"`
+	EntryDate   string `json:"entryDate"`
+	Currency    string `json:"currency"`
+	AmountMinor int64  `json:"amountMinor"`
+}
+
+// ClientCreditBalanceOut is the credit a client has on account in one currency.
+type ClientCreditBalanceOut struct {
+	Currency     string `json:"currency"`
+	BalanceMinor int64  `json:"balanceMinor"`
+}
+
+type ClientCreditOut struct {
+	ClientID int64                    `json:"clientId"`
+	Balances []ClientCreditBalanceOut `json:"balances"`
+	Entries  []ClientCreditEntryOut   `json:"entries"`
+}
@@ -1,19 +1,22 @@
 package models
 
 type Client struct {
-	ID          int64   `json:"id"`
-	Name        string  `json:"name"`
-	CompanyName string  `json:"companyName"`
-	Address     string  `json:"address"`
-	Email       string  `json:"email"`
-	CreatedAt   string  `json:"created_at"`
-	UpdatedAt   *string `json:"updated_at,omitempty"`
+	ID                 int64   `json:"id"`
+	Name               string  `json:"name"`
+	CompanyName        string  `json:"companyName"`
+	Address            string  `json:"address"`
+	Email              string  `json:"email"`
+	VATNumber          string  `json:"vatNumber"`
+	CreditBalanceMinor int64   `json:"creditBalanceMinor"`
+	CreatedAt          string  `json:"created_at"`
+	UpdatedAt          *string `json:"updated_at,omitempty"`
 }
 type CreateClient struct {
 	Name        string `json:"name" binding:"required"`
 	CompanyName string `json:"companyName"`
 	Address     string `json:"address"`
 	Email       string `json:"email"`
+	VATNumber   string `json:"vatNumber"`
 }
 
 type UpdateClient struct {
@@ -21,4 +24,5 @@ type UpdateClient struct {
 	CompanyName *string `json:"companyName"`
 	Address     *string `json:"address"`
 	Email       *string `json:"email"`
+	VATNumber   *string `json:"vatNumber"`
 }
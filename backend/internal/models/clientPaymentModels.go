@@ -0,0 +1,51 @@
+package models
+
+// ClientPaymentAllocationIn puts part of a client payment against one invoice.
+type ClientPaymentAllocationIn struct {
+	BaseNumber  int64 `json:"baseNumber"`
+	AmountMinor int64 `json:"amountMinor"`
+}
+
+// Input received from frontend (clientID received from path params)
+type ClientPaymentCreateIn struct {
+	AmountMinor   int64                       `json:"amountMinor"`
+	PaymentDate   string                      `json:"paymentDate"`
+	Label         *string                     `json:"label,omitempty"`
+	PaymentMethod *string                     `json:"paymentMethod,omitempty"`
+	Reference     *string                     `json:"reference,omitempty"`
+	AutoAllocate  bool                        `json:"autoAllocate"` // oldest open invoices first
+	Allocations   []ClientPaymentAllocationIn `json:"allocations,omitempty"`
+
+	// Currency is the ISO 4217 code the payment was received in. Empty uses
+	// the workspace currency. It is only allocated to invoices in the same
+	// currency.
+	Currency string `json:"currency,omitempty"`
+}
+
+// ClientPaymentAllocateIn allocates the unallocated remainder of a saved client payment.
+type ClientPaymentAllocateIn struct {
+	AutoAllocate bool                        `json:"autoAllocate"`
+	Allocations  []ClientPaymentAllocationIn `json:"allocations,omitempty"`
+}
+
+// ClientPaymentAllocationOut is the payment receipt an allocation created.
+type ClientPaymentAllocationOut struct {
+	BaseNumber  int64 `json:"baseNumber"`
+	RevisionNo  int64 `json:"revisionNo"`
+	ReceiptNo   int64 `json:"receiptNo"`
+	AmountMinor int64 `json:"amountMinor"`
+}
+
+type ClientPaymentOut struct {
+	ID               int64                        `json:"id"`
+	ClientID         int64                        `json:"clientId"`
+	AmountMinor      int64                        `json:"amountMinor"`
+	PaymentDate      string                       `json:"paymentDate"`
+	Label            *string                      `json:"label,omitempty"`
+	PaymentMethod    *string                      `json:"paymentMethod,omitempty"`
+	Reference        *string                      `json:"reference,omitempty"`
+	Currency         string                       `json:"currency"`
+	AllocatedMinor   int64                        `json:"allocatedMinor"`
+	UnallocatedMinor int64                        `json:"unallocatedMinor"`
+	Allocations      []ClientPaymentAllocationOut `json:"allocations"`
+}
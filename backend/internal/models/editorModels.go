@@ -1,6 +1,7 @@
 package models
 
 type InvoiceEditorResponse struct {
+	ETag     string                 `json:"etag"`
 	Status   string                 `json:"status"`
 	Totals   InvoiceEditorTotals    `json:"totals"`
 	Lines    []InvoiceEditorLine    `json:"lines"`
@@ -9,6 +10,7 @@ type InvoiceEditorResponse struct {
 
 type InvoiceEditorTotals struct {
 	BaseNumber        int64   `json:"baseNumber"`
+	NumberLabel       string  `json:"numberLabel,omitempty"`
 	RevisionNo        int64   `json:"revisionNo"`
 	IssueDate         string  `json:"issueDate"`
 	SupplyDate        *string `json:"supplyDate,omitempty"`
@@ -17,8 +19,12 @@ type InvoiceEditorTotals struct {
 	ClientCompanyName string  `json:"clientCompanyName"`
 	ClientAddress     string  `json:"clientAddress"`
 	ClientEmail       string  `json:"clientEmail"`
+	ClientVATNumber   string  `json:"clientVatNumber"`
 	Note              *string `json:"note,omitempty"`
+	Currency          string  `json:"currency"`
+	ExchangeRateMicro int64   `json:"exchangeRateMicro"`
 
+	VATTreatment  string `json:"vatTreatment"`
 	VATRate       int64  `json:"vatRate"`
 	VATAmountMin  int64  `json:"vatAmountMinor"`
 	DiscountType  string `json:"discountType"`
@@ -30,24 +36,42 @@ type InvoiceEditorTotals struct {
 	SubtotalMinor int64  `json:"subtotalMinor"`
 	TotalMinor    int64  `json:"totalMinor"`
 	PaidMinor     int64  `json:"paidMinor"`
+	CreditedMinor int64  `json:"creditedMinor"`
+
+	VATBreakdown []VATBand `json:"vatBreakdown"`
+
+	DeductionRate      int64    `json:"deductionRate"`
+	DeductionLineTypes []string `json:"deductionLineTypes"`
+	DeductionMinor     int64    `json:"deductionMinor"`
+
+	TaxRateID   *int64 `json:"taxRateId,omitempty"`
+	TaxRateName string `json:"taxRateName,omitempty"`
 }
 
 type InvoiceEditorLine struct {
-	ProductID     *int64  `json:"productId,omitempty"`
-	PricingMode   *string `json:"pricingMode,omitempty"`
-	MinutesWorked *int64  `json:"minutesWorked,omitempty"`
-	Name          string  `json:"name"`
-	LineType      string  `json:"lineType"`
-	Quantity      int64   `json:"quantity"`
-	UnitPriceMin  int64   `json:"unitPriceMinor"`
-	LineTotalMin  int64   `json:"lineTotalMinor"`
-	SortOrder     int64   `json:"sortOrder"`
+	ProductID     *int64   `json:"productId,omitempty"`
+	PricingMode   *string  `json:"pricingMode,omitempty"`
+	MinutesWorked *int64   `json:"minutesWorked,omitempty"`
+	Name          string   `json:"name"`
+	LineType      string   `json:"lineType"`
+	Quantity      Quantity `json:"quantity"`
+	Unit          string   `json:"unit,omitempty"`
+	UnitPriceMin  int64    `json:"unitPriceMinor"`
+	LineTotalMin  int64    `json:"lineTotalMinor"`
+	SortOrder     int64    `json:"sortOrder"`
+	VATRate       *int64   `json:"vatRate,omitempty"`
+	DiscountType  string   `json:"discountType"`
+	DiscountRate  int64    `json:"discountRate"`
+	DiscountMinor int64    `json:"discountMinor"`
 }
 
 type InvoiceEditorReceipt struct {
-	ID          int64   `json:"id"`
-	ReceiptNo   int64   `json:"receiptNo"`
-	PaymentDate string  `json:"paymentDate"`
-	AmountMinor int64   `json:"amountMinor"`
-	Label       *string `json:"label,omitempty"`
+	ID            int64   `json:"id"`
+	ReceiptNo     int64   `json:"receiptNo"`
+	PaymentDate   string  `json:"paymentDate"`
+	AmountMinor   int64   `json:"amountMinor"`
+	Label         *string `json:"label,omitempty"`
+	PaymentMethod *string `json:"paymentMethod,omitempty"`
+	Reference     *string `json:"reference,omitempty"`
+	RefundedMinor int64   `json:"refundedMinor"`
 }
@@ -18,18 +18,46 @@ type InvoiceCreateIn struct {
 	ClientAddress     string  `json:"clientAddress"`
 	ClientEmail       string  `json:"clientEmail"`
 	Note              *string `json:"note"`
+	// Currency is the ISO 4217 code the invoice is issued in. Empty uses the
+	// workspace currency.
+	Currency string `json:"currency,omitempty"`
+	// ExchangeRateMicro is how many workspace currency units one invoice
+	// currency unit is worth, times 1,000,000. It is required when Currency
+	// differs from the workspace currency and ignored otherwise.
+	ExchangeRateMicro int64 `json:"exchangeRateMicro,omitempty"`
+	// VATTreatment is one of standard, reverse_charge, exempt or outside_scope.
+	// Empty is standard. Every other treatment charges no VAT.
+	VATTreatment string `json:"vatTreatment,omitempty"`
+	// ClientVATNumber is required under the reverse charge. Empty uses the
+	// number saved on the client.
+	ClientVATNumber string `json:"clientVatNumber,omitempty"`
 }
 
 type LineCreateIn struct {
-	ProductID      *int64 `json:"productId"`
-	Name           string `json:"name"`
-	LineType       string `json:"lineType"`
-	PricingMode    string `json:"pricingMode"`
-	Quantity       int64  `json:"quantity"`
-	MinutesWorked  *int64 `json:"minutesWorked"`
-	UnitPriceMinor int64  `json:"unitPriceMinor"`
-	LineTotalMinor int64  `json:"lineTotalMinor"`
-	SortOrder      int64  `json:"sortOrder"`
+	ProductID      *int64   `json:"productId"`
+	Name           string   `json:"name"`
+	LineType       string   `json:"lineType"`
+	PricingMode    string   `json:"pricingMode"`
+	Quantity       Quantity `json:"quantity"`
+	Unit           string   `json:"unit,omitempty"`
+	MinutesWorked  *int64   `json:"minutesWorked"`
+	UnitPriceMinor int64    `json:"unitPriceMinor"`
+	LineTotalMinor int64    `json:"lineTotalMinor"`
+	SortOrder      int64    `json:"sortOrder"`
+	// VATRate overrides the invoice VAT rate for this line. Nil uses TotalsCreateIn.VATRate.
+	VATRate *int64 `json:"vatRate,omitempty"`
+
+	// Line discount, applied before the invoice-level discount. LineTotalMinor is net of it.
+	DiscountType  string `json:"discountType,omitempty"`
+	DiscountRate  int64  `json:"discountRate,omitempty"`
+	DiscountMinor int64  `json:"discountMinor,omitempty"`
+}
+
+// VATBand is the net amount (after discount) and VAT charged at one rate.
+type VATBand struct {
+	VATRate  int64 `json:"vatRate"`
+	NetMinor int64 `json:"netMinor"`
+	VatMinor int64 `json:"vatMinor"`
 }
 
 type TotalsCreateIn struct {
@@ -50,6 +78,33 @@ type TotalsCreateIn struct {
 	SubtotalMinor     int64 `json:"subtotalMinor"`
 	TotalMinor        int64 `json:"totalMinor"`
 	BalanceDue        int64 `json:"balanceDueMinor"`
+
+	// VATBreakdown is derived by the server; any submitted value is ignored.
+	VATBreakdown []VATBand `json:"vatBreakdown,omitempty"`
+
+	// Deduction is withheld by the client and paid to the tax authority on the
+	// supplier's behalf (UK CIS, withholding tax). DeductionRate applies to the
+	// net, after discount, of the lines whose type is in DeductionLineTypes, or
+	// of every line when it is empty. The invoice total is unchanged;
+	// PayableMinor is the total less DeductionMinor.
+	DeductionRate      int64    `json:"deductionRate"`
+	DeductionLineTypes []string `json:"deductionLineTypes,omitempty"`
+	DeductionMinor     int64    `json:"deductionMinor"`
+	PayableMinor       int64    `json:"payableMinor"`
+
+	// TaxRateID names the registry rate VATRate was taken from; VATRate must
+	// match it. TaxRateName is the name snapshotted onto the revision and is
+	// ignored on input.
+	TaxRateID   *int64 `json:"taxRateId,omitempty"`
+	TaxRateName string `json:"taxRateName,omitempty"`
+}
+
+// InvoiceDuplicateIn copies an invoice revision into a new draft.
+type InvoiceDuplicateIn struct {
+	RevisionNo *int64  `json:"revisionNo,omitempty"` // defaults to the current revision
+	ClientID   *int64  `json:"clientId,omitempty"`   // target client, defaults to the source client
+	IssueDate  *string `json:"issueDate,omitempty"`  // defaults to today
+	DueByDate  *string `json:"dueByDate,omitempty"`  // defaults to the source issue-to-due gap
 }
 
 type PaymentCreateIn struct {
@@ -58,15 +113,86 @@ type PaymentCreateIn struct {
 	Label       *string `json:"label,omitempty"`
 }
 
+// PaymentMethods lists the accepted receipt payment methods.
+var PaymentMethods = []string{"bank_transfer", "card", "cash", "cheque", "direct_debit", "other"}
+
 type PaymentReceiptCreateIn struct {
-	AmountMinor int64   `json:"amountMinor"`
-	PaymentDate string  `json:"paymentDate"`
-	Label       *string `json:"label,omitempty"`
+	AmountMinor   int64   `json:"amountMinor"`
+	PaymentDate   string  `json:"paymentDate"`
+	Label         *string `json:"label,omitempty"`
+	PaymentMethod *string `json:"paymentMethod,omitempty"` // one of PaymentMethods
+	Reference     *string `json:"reference,omitempty"`     // bank or card reference
 }
 
 type PaymentReceiptUpdateIn struct {
-	PaymentDate string  `json:"paymentDate"`
-	Label       *string `json:"label,omitempty"`
+	PaymentDate   string  `json:"paymentDate"`
+	Label         *string `json:"label,omitempty"`
+	PaymentMethod *string `json:"paymentMethod,omitempty"`
+	Reference     *string `json:"reference,omitempty"`
+}
+
+// RefundCreateIn reverses part or all of one payment receipt.
+type RefundCreateIn struct {
+	AmountMinor int64   `json:"amountMinor"`
+	RefundDate  string  `json:"refundDate"`
+	Reason      *string `json:"reason,omitempty"`
+}
+
+type RefundOut struct {
+	ID          int64   `json:"id"`
+	RefundNo    int64   `json:"refundNo"`
+	RevisionNo  int64   `json:"revisionNo"`
+	ReceiptNo   int64   `json:"receiptNo"`
+	RefundDate  string  `json:"refundDate"`
+	AmountMinor int64   `json:"amountMinor"`
+	Reason      *string `json:"reason,omitempty"`
+}
+
+// DepositReceiptOut is a received deposit on the invoice's current revision.
+type DepositReceiptOut struct {
+	ID            int64   `json:"id"`
+	DepositNo     int64   `json:"depositNo"`
+	RevisionNo    int64   `json:"revisionNo"`
+	PaymentDate   string  `json:"paymentDate"`
+	AmountMinor   int64   `json:"amountMinor"`
+	Label         *string `json:"label,omitempty"`
+	PaymentMethod *string `json:"paymentMethod,omitempty"`
+	Reference     *string `json:"reference,omitempty"`
+}
+
+type CreditNoteLineIn struct {
+	Name           string   `json:"name"`
+	LineType       string   `json:"lineType"`
+	Quantity       Quantity `json:"quantity"`
+	UnitPriceMinor int64    `json:"unitPriceMinor"`
+	LineTotalMinor int64    `json:"lineTotalMinor"`
+	SortOrder      int64    `json:"sortOrder"`
+	// VATRate overrides the credit note VAT rate for this line, so a line of
+	// a mixed-rate invoice is credited at the rate it was charged at.
+	VATRate *int64 `json:"vatRate,omitempty"`
+}
+
+type CreditNoteCreateIn struct {
+	RevisionNo *int64             `json:"revisionNo,omitempty"`
+	IssueDate  string             `json:"issueDate"`
+	Reason     *string            `json:"reason,omitempty"`
+	VATRate    *int64             `json:"vatRate,omitempty"`
+	Lines      []CreditNoteLineIn `json:"lines"`
+}
+
+type CreditNoteOut struct {
+	ID             int64              `json:"id"`
+	CreditNoteNo   int64              `json:"creditNoteNo"`
+	RevisionNo     int64              `json:"revisionNo"`
+	IssueDate      string             `json:"issueDate"`
+	Reason         *string            `json:"reason,omitempty"`
+	VATRate        int64              `json:"vatRate"`
+	SubtotalMinor  int64              `json:"subtotalMinor"`
+	VatAmountMinor int64              `json:"vatMinor"`
+	TotalMinor     int64              `json:"totalMinor"`
+	Lines          []CreditNoteLineIn `json:"lines"`
+
+	VATBreakdown []VATBand `json:"vatBreakdown"`
 }
 
 type InvoicePDFIssuer struct {
@@ -85,6 +211,7 @@ type InvoicePDFItem struct {
 	TimeWorked string
 	HourlyRate string
 	ItemTotal  string
+	Discount   string
 	SortOrder  int64
 }
 
@@ -94,6 +221,8 @@ type InvoicePDFData struct {
 	InvoiceNumberLabel   string
 	ReferenceNumberLabel string
 	ReceiptAmountMinor   int64
+	CreditedMinor        int64
+	DepositPaidMinor     int64 // received deposits, already included in Totals.PaidMinor
 	Currency             string
 	ShowItemTypeHeaders  bool
 
@@ -102,6 +231,10 @@ type InvoicePDFData struct {
 	DueDate    *string
 	Note       *string
 
+	// VATTreatment decides the VAT total label and the statement printed with
+	// the notes. Client.VATNumber is shown in the bill-to block.
+	VATTreatment string
+
 	Issuer InvoicePDFIssuer
 	Client CreateClient
 
@@ -112,4 +245,76 @@ type InvoicePDFData struct {
 	PaymentTerms   string
 	PaymentDetails string
 	NotesFooter    string
+
+	// Voided stamps the document VOID with the reason and date it was voided.
+	Voided     bool
+	VoidReason string
+	VoidedAt   string
+}
+
+// AuditLogEntryOut is one recorded change to an invoice or its payments.
+// The hashes fingerprint the invoice before and after the change.
+type AuditLogEntryOut struct {
+	ID         int64   `json:"id"`
+	UserID     *int64  `json:"userId,omitempty"`
+	ActorEmail *string `json:"actorEmail,omitempty"`
+	ClientID   int64   `json:"clientId"`
+	BaseNumber int64   `json:"baseNumber"`
+	Action     string  `json:"action"`
+	EntityID   *int64  `json:"entityId,omitempty"`
+	BeforeHash *string `json:"beforeHash,omitempty"`
+	AfterHash  *string `json:"afterHash,omitempty"`
+	RequestID  *string `json:"requestId,omitempty"`
+	CreatedAt  string  `json:"createdAt"`
+}
+
+// InvoiceHistoryEntryOut is one revision, payment receipt, refund or void in an invoice's history.
+type InvoiceHistoryEntryOut struct {
+	Type          string  `json:"type"` // revision/payment_receipt/refund/void
+	CreatedAt     string  `json:"createdAt"`
+	RevisionNo    *int64  `json:"revisionNo,omitempty"`
+	ReceiptNo     *int64  `json:"receiptNo,omitempty"`
+	IssueDate     *string `json:"issueDate,omitempty"`
+	DueByDate     *string `json:"dueByDate,omitempty"`
+	PaymentDate   *string `json:"paymentDate,omitempty"`
+	AmountMinor   *int64  `json:"amountMinor,omitempty"`
+	Label         *string `json:"label,omitempty"`
+	PaymentMethod *string `json:"paymentMethod,omitempty"`
+	Reference     *string `json:"reference,omitempty"`
+	RefundNo      *int64  `json:"refundNo,omitempty"`
+	VoidedBy      *string `json:"voidedBy,omitempty"`
+	// Diff is set on revision entries when the history is requested with ?diffs=true.
+	Diff *InvoiceRevisionDiffOut `json:"diff,omitempty"`
+}
+
+// RevisionFieldChangeOut is one field whose value differs between two revisions.
+type RevisionFieldChangeOut struct {
+	Section string `json:"section"` // header/client/discount/deposit/vat/totals/line
+	Field   string `json:"field"`
+	From    any    `json:"from"`
+	To      any    `json:"to"`
+}
+
+// RevisionLineChangeOut is one invoice line added, removed or changed between two revisions.
+type RevisionLineChangeOut struct {
+	Change        string                   `json:"change"` // added/removed/changed
+	ProductID     *int64                   `json:"productId,omitempty"`
+	Name          string                   `json:"name"`
+	FromSortOrder *int64                   `json:"fromSortOrder,omitempty"`
+	ToSortOrder   *int64                   `json:"toSortOrder,omitempty"`
+	Fields        []RevisionFieldChangeOut `json:"fields,omitempty"`
+}
+
+type InvoiceRevisionDiffOut struct {
+	FromRevisionNo int64                    `json:"fromRevisionNo"`
+	ToRevisionNo   int64                    `json:"toRevisionNo"`
+	Fields         []RevisionFieldChangeOut `json:"fields"`
+	Lines          []RevisionLineChangeOut  `json:"lines"`
+}
+
+// NextInvoiceNumberOut previews the number of the next invoice. NumberLabel
+// is empty when the account has no number template.
+type NextInvoiceNumberOut struct {
+	BaseNumber  int64  `json:"baseNumber"`
+	NumberLabel string `json:"numberLabel"`
 }
@@ -0,0 +1,25 @@
+package models
+
+// Input received from frontend (clientID and baseNumber received from path params)
+type LateChargeIn struct {
+	Mode string `json:"mode"` // revision/invoice
+}
+
+// LateChargeOut is the late payment charge accrued on an overdue invoice as of AsOf.
+type LateChargeOut struct {
+	AsOf              string `json:"asOf"`
+	DueByDate         string `json:"dueByDate"`
+	AccruedFrom       string `json:"accruedFrom"`
+	DaysCharged       int64  `json:"daysCharged"`
+	AnnualRateBps     int64  `json:"annualRateBps"`
+	DebtAtDueMinor    int64  `json:"debtAtDueMinor"`
+	OutstandingMinor  int64  `json:"outstandingMinor"`
+	InterestMinor     int64  `json:"interestMinor"`
+	CompensationMinor int64  `json:"compensationMinor"`
+	TotalMinor        int64  `json:"totalMinor"`
+
+	// Set once the charge is raised.
+	Mode                    string `json:"mode,omitempty"`
+	RevisionNo              *int64 `json:"revisionNo,omitempty"`
+	ChargeInvoiceBaseNumber *int64 `json:"chargeInvoiceBaseNumber,omitempty"`
+}
@@ -10,6 +10,8 @@ type Product struct {
 	FlatPriceMinor  *int64  `json:"flatPriceMinor,omitempty"` // 1000 --> £10
 	HourlyRateMinor *int64  `json:"hourlyRateMinor,omitempty"`
 	MinutesWorked   *int64  `json:"minutesWorked,omitempty"`
+	Unit            string  `json:"unit"` // pcs/m/day/kg, free text
+	TaxRateID       *int64  `json:"taxRateId,omitempty"`
 	ClientID        int64   `json:"clientId"`
 	CreatedAt       string  `json:"created_at"`
 	UpdatedAt       *string `json:"updated_at,omitempty"`
@@ -23,6 +25,8 @@ type ProductCreate struct {
 	FlatPriceMinor  *int64
 	HourlyRateMinor *int64
 	MinutesWorked   *int64
+	Unit            string
+	TaxRateID       *int64
 	ClientID        int64
 }
 
@@ -34,6 +38,8 @@ type ProductCreateIn struct {
 	FlatPrice     *json.Number `json:"flatPrice,omitempty"`     // 25.65
 	HourlyRate    *json.Number `json:"hourlyRate,omitempty"`    // 40.00
 	MinutesWorked *json.Number `json:"minutesWorked,omitempty"` // 120
+	Unit          *string      `json:"unit,omitempty"`          // "m"
+	TaxRateID     *int64       `json:"taxRateId,omitempty"`     // workspace tax rate
 }
 
 type ProductUpdate struct {
@@ -0,0 +1,122 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// QuantityScale is the number of stored units per whole unit (milli-units).
+const QuantityScale = 1000
+
+// Quantity is a line quantity in milli-units, so 2.5 metres is stored as 2500.
+//
+// In JSON it is a plain decimal number with at most three decimal places,
+// which keeps whole-number payloads ("quantity": 2) backwards compatible.
+type Quantity int64
+
+var errQuantityPrecision = errors.New("quantity must be a number with at most 3 decimal places")
+
+// WholeQuantity returns n whole units.
+func WholeQuantity(n int64) Quantity {
+	return Quantity(n * QuantityScale)
+}
+
+// ParseQuantity parses a decimal string such as "2", "2.5" or "0.125".
+func ParseQuantity(s string) (Quantity, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errQuantityPrecision
+	}
+
+	neg := false
+	if s[0] == '-' || s[0] == '+' {
+		neg = s[0] == '-'
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, errQuantityPrecision
+	}
+	if len(frac) > 3 {
+		return 0, errQuantityPrecision
+	}
+	for len(frac) < 3 {
+		frac += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	w, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || w < 0 {
+		return 0, errQuantityPrecision
+	}
+	f, err := strconv.ParseInt(frac, 10, 64)
+	if err != nil || f < 0 {
+		return 0, errQuantityPrecision
+	}
+
+	q := Quantity(w*QuantityScale + f)
+	if neg {
+		q = -q
+	}
+	return q, nil
+}
+
+// String formats the quantity without trailing zeros, e.g. "2", "2.5".
+func (q Quantity) String() string {
+	sign := ""
+	v := int64(q)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+
+	whole := v / QuantityScale
+	frac := v % QuantityScale
+	if frac == 0 {
+		return sign + strconv.FormatInt(whole, 10)
+	}
+
+	fracStr := strings.TrimRight(strconv.FormatInt(frac+QuantityScale, 10)[1:], "0")
+	return sign + strconv.FormatInt(whole, 10) + "." + fracStr
+}
+
+// Amount prices q units at unitMinor each, rounded to the nearest minor unit.
+func (q Quantity) Amount(unitMinor int64) int64 {
+	return int64(math.Round(float64(int64(q)*unitMinor) / QuantityScale))
+}
+
+// WholeUnitsCeil rounds the quantity up to whole units (minimum 1). It fills
+// the legacy integer quantity column.
+func (q Quantity) WholeUnitsCeil() int64 {
+	whole := (int64(q) + QuantityScale - 1) / QuantityScale
+	return max(whole, 1)
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	// Accept quoted numbers too; inputs bound to text fields often send them.
+	if len(data) >= 2 && data[0] == '"' && data[len(data)-1] == '"' {
+		data = data[1 : len(data)-1]
+	}
+
+	parsed, err := ParseQuantity(string(data))
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
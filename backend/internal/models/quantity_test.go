@@ -0,0 +1,48 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+func TestQuantity_JSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		in   string
+		want models.Quantity
+		out  string
+	}{
+		{in: `2`, want: 2000, out: `2`},
+		{in: `2.5`, want: 2500, out: `2.5`},
+		{in: `0.125`, want: 125, out: `0.125`},
+		{in: `"1.75"`, want: 1750, out: `1.75`},
+	}
+
+	for _, tc := range tests {
+		var q models.Quantity
+		if err := json.Unmarshal([]byte(tc.in), &q); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", tc.in, err)
+		}
+		if q != tc.want {
+			t.Fatalf("Unmarshal(%s) = %d, want %d", tc.in, q, tc.want)
+		}
+
+		out, err := json.Marshal(q)
+		if err != nil {
+			t.Fatalf("Marshal(%d) error = %v", q, err)
+		}
+		if string(out) != tc.out {
+			t.Fatalf("Marshal(%d) = %s, want %s", q, out, tc.out)
+		}
+	}
+}
+
+func TestQuantity_RejectsInvalidInput(t *testing.T) {
+	for _, in := range []string{`1.2345`, `"abc"`, `1e3`, `"."`} {
+		var q models.Quantity
+		if err := json.Unmarshal([]byte(in), &q); err == nil {
+			t.Fatalf("Unmarshal(%s) = %d, want error", in, q)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package models
+
+// QuoteIn mirrors FEInvoiceIn so quotes share line and totals maths with invoices.
+type QuoteIn struct {
+	Overview QuoteOverviewIn
+	Lines    []LineCreateIn
+	Totals   TotalsCreateIn
+}
+
+type QuoteOverviewIn struct {
+	ClientID          int64   `json:"clientId"`
+	QuoteNumber       int64   `json:"quoteNumber"`
+	IssueDate         string  `json:"issueDate"`
+	ValidUntil        *string `json:"validUntil"`
+	ClientName        string  `json:"clientName"`
+	ClientCompanyName string  `json:"clientCompanyName"`
+	ClientAddress     string  `json:"clientAddress"`
+	ClientEmail       string  `json:"clientEmail"`
+	Note              *string `json:"note"`
+	// Currency and ExchangeRateMicro work as on InvoiceCreateIn and carry
+	// over to the invoice the quote is converted into.
+	Currency          string `json:"currency,omitempty"`
+	ExchangeRateMicro int64  `json:"exchangeRateMicro,omitempty"`
+	// VATTreatment and ClientVATNumber work as on InvoiceCreateIn.
+	VATTreatment    string `json:"vatTreatment,omitempty"`
+	ClientVATNumber string `json:"clientVatNumber,omitempty"`
+}
+
+type QuoteStatusIn struct {
+	Status string `json:"status"`
+}
+
+type QuoteConvertIn struct {
+	IssueDate string  `json:"issueDate"`
+	DueByDate *string `json:"dueByDate,omitempty"`
+}
+
+type QuoteOut struct {
+	ID                         int64           `json:"id"`
+	Status                     string          `json:"status"`
+	Overview                   QuoteOverviewIn `json:"overview"`
+	Lines                      []LineCreateIn  `json:"lines"`
+	Totals                     TotalsCreateIn  `json:"totals"`
+	ConvertedInvoiceBaseNumber *int64          `json:"convertedInvoiceBaseNumber,omitempty"`
+	CreatedAt                  string          `json:"createdAt"`
+	UpdatedAt                  *string         `json:"updatedAt,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// Input received from frontend (clientID received from path params)
+type RecurringScheduleIn struct {
+	SourceBaseNumber int64  `json:"sourceBaseNumber"`
+	SourceRevisionNo *int64 `json:"sourceRevisionNo,omitempty"` // defaults to the current revision
+	Cadence          string `json:"cadence"`                    // weekly/monthly/yearly
+	DayOfMonth       *int64 `json:"dayOfMonth,omitempty"`       // monthly/yearly only, clamped to month end
+	NextRunDate      string `json:"nextRunDate"`
+	AutoIssue        bool   `json:"autoIssue"`
+	Active           *bool  `json:"active,omitempty"` // defaults to true
+}
+
+type RecurringScheduleOut struct {
+	ID                    int64   `json:"id"`
+	ClientID              int64   `json:"clientId"`
+	SourceBaseNumber      int64   `json:"sourceBaseNumber"`
+	SourceRevisionNo      int64   `json:"sourceRevisionNo"`
+	Cadence               string  `json:"cadence"`
+	DayOfMonth            *int64  `json:"dayOfMonth,omitempty"`
+	NextRunDate           string  `json:"nextRunDate"`
+	AutoIssue             bool    `json:"autoIssue"`
+	Active                bool    `json:"active"`
+	LastRunAt             *string `json:"lastRunAt,omitempty"`
+	LastInvoiceBaseNumber *int64  `json:"lastInvoiceBaseNumber,omitempty"`
+	CreatedAt             string  `json:"createdAt"`
+	UpdatedAt             *string `json:"updatedAt,omitempty"`
+}
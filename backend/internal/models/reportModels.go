@@ -0,0 +1,103 @@
+package models
+
+// AgedBuckets splits outstanding balances by how far past their due date they are.
+// Invoices without a due date, or not yet due, count as current.
+type AgedBuckets struct {
+	CurrentMinor    int64 `json:"currentMinor"`
+	Days1To30Minor  int64 `json:"days1To30Minor"`
+	Days31To60Minor int64 `json:"days31To60Minor"`
+	Days61To90Minor int64 `json:"days61To90Minor"`
+	Days90PlusMinor int64 `json:"days90PlusMinor"`
+	TotalMinor      int64 `json:"totalMinor"`
+}
+
+type AgedReceivablesClient struct {
+	ClientID          int64  `json:"clientId"`
+	ClientName        string `json:"clientName"`
+	ClientCompanyName string `json:"clientCompanyName"`
+	InvoiceCount      int64  `json:"invoiceCount"`
+	AgedBuckets
+}
+
+// AgedReceivablesReport amounts are in BaseCurrency, the workspace currency.
+// Invoices in other currencies are converted at their stored exchange rate.
+type AgedReceivablesReport struct {
+	AsOf         string                  `json:"asOf"`
+	BaseCurrency string                  `json:"baseCurrency"`
+	Clients      []AgedReceivablesClient `json:"clients"`
+	Totals       AgedBuckets             `json:"totals"`
+}
+
+// Invoice number gap reasons.
+const (
+	NumberGapDeletedDraft   = "deleted_draft"
+	NumberGapDeletedInvoice = "deleted_invoice"
+	NumberGapStartChanged   = "start_changed"
+	NumberGapUnknown        = "unknown"
+)
+
+// InvoiceNumberGapReport lists every base number between the first and the
+// last allocated one that has no invoice, with the reason when it is known.
+type InvoiceNumberGapReport struct {
+	FirstNumber  int64 `json:"firstNumber"`
+	LastNumber   int64 `json:"lastNumber"`
+	InvoiceCount int64 `json:"invoiceCount"`
+	// SequenceNext is invoice_number_seq.next_base_number. SequenceBehind is
+	// set when it is at or below a base number already in use.
+	SequenceNext   int64              `json:"sequenceNext"`
+	SequenceBehind bool               `json:"sequenceBehind"`
+	Gaps           []InvoiceNumberGap `json:"gaps"`
+
+	// Periods checks the template numbers customers see, one sequence per
+	// period. It is empty until the account uses a number template.
+	Periods []InvoiceNumberPeriod `json:"periods"`
+}
+
+// InvoiceNumberPeriod is one template sequence, e.g. "2026" for a template
+// that restarts yearly or "" for one that never does. Its numbers run from 1
+// to NextSeq-1.
+type InvoiceNumberPeriod struct {
+	Period       string                  `json:"period"`
+	NextSeq      int64                   `json:"nextSeq"`
+	InvoiceCount int64                   `json:"invoiceCount"`
+	Gaps         []InvoiceNumberLabelGap `json:"gaps"`
+}
+
+// InvoiceNumberLabelGap is a run of missing sequence numbers in a period
+// sharing one reason. Deleted invoices get a gap each and carry the label
+// they had; other labels are rendered from the current template.
+type InvoiceNumberLabelGap struct {
+	FromSeq   int64                  `json:"fromSeq"`
+	ToSeq     int64                  `json:"toSeq"`
+	FromLabel string                 `json:"fromLabel"`
+	ToLabel   string                 `json:"toLabel"`
+	Reason    string                 `json:"reason"`
+	Deletion  *InvoiceNumberDeletion `json:"deletion,omitempty"`
+}
+
+// InvoiceNumberGap is a run of missing base numbers sharing one reason.
+// Deleted invoices get a gap each.
+type InvoiceNumberGap struct {
+	FromNumber  int64                     `json:"fromNumber"`
+	ToNumber    int64                     `json:"toNumber"`
+	Reason      string                    `json:"reason"`
+	Deletion    *InvoiceNumberDeletion    `json:"deletion,omitempty"`
+	StartChange *InvoiceNumberStartChange `json:"startChange,omitempty"`
+}
+
+type InvoiceNumberDeletion struct {
+	ClientID       int64   `json:"clientId"`
+	NumberLabel    *string `json:"numberLabel,omitempty"`
+	Status         string  `json:"status"`
+	IssueDate      *string `json:"issueDate,omitempty"`
+	TotalMinor     *int64  `json:"totalMinor,omitempty"`
+	DeletedAt      string  `json:"deletedAt"`
+	DeletedByEmail *string `json:"deletedByEmail,omitempty"`
+}
+
+type InvoiceNumberStartChange struct {
+	FromNumber     int64   `json:"fromNumber"`
+	ToNumber       int64   `json:"toNumber"`
+	ChangedAt      string  `json:"changedAt"`
+	ChangedByEmail *string `json:"changedByEmail,omitempty"`
+}
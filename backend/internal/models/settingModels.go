@@ -1,21 +1,59 @@
 package models
 
 type Settings struct {
-	CompanyName                  string `json:"companyName"`
-	Email                        string `json:"email"`
-	Phone                        string `json:"phone"`
-	CompanyAddress               string `json:"companyAddress"`
-	InvoicePrefix                string `json:"invoicePrefix"`
-	Currency                     string `json:"currency"`
-	DateFormat                   string `json:"dateFormat"`
-	PaymentTerms                 string `json:"paymentTerms"`
-	PaymentDetails               string `json:"paymentDetails"`
-	NotesFooter                  string `json:"notesFooter"`
-	LogoURL                      string `json:"logoUrl"`
-	ShowItemTypeHeaders          bool   `json:"showItemTypeHeaders"`
-	StartingInvoiceNumber        int64  `json:"startingInvoiceNumber"`
-	CanEditStartingInvoiceNumber bool   `json:"canEditStartingInvoiceNumber"`
-	ReadOnly                     bool   `json:"readOnly"`
-	LogoAssetID                  int64  `json:"-"`
-	LogoStorageKey               string `json:"-"`
+	CompanyName    string `json:"companyName"`
+	Email          string `json:"email"`
+	Phone          string `json:"phone"`
+	CompanyAddress string `json:"companyAddress"`
+	InvoicePrefix  string `json:"invoicePrefix"`
+	QuotePrefix    string `json:"quotePrefix"`
+	// InvoiceNumberTemplate renders new invoice numbers, e.g. "INV-{YYYY}-{SEQ:4}".
+	// Empty keeps InvoicePrefix followed by the base number.
+	InvoiceNumberTemplate string `json:"invoiceNumberTemplate"`
+	Currency              string `json:"currency"`
+	DateFormat            string `json:"dateFormat"`
+	Timezone              string `json:"timezone"`
+	// Late payment: interest is charged at LateBaseRate + LateInterestRate (bps a year).
+	LateInterestRate             int64                  `json:"lateInterestRate"`
+	LateBaseRate                 int64                  `json:"lateBaseRate"`
+	LateCompensationTiers        []LateCompensationTier `json:"lateCompensationTiers"`
+	PaymentTerms                 string                 `json:"paymentTerms"`
+	PaymentDetails               string                 `json:"paymentDetails"`
+	NotesFooter                  string                 `json:"notesFooter"`
+	LogoURL                      string                 `json:"logoUrl"`
+	ShowItemTypeHeaders          bool                   `json:"showItemTypeHeaders"`
+	StartingInvoiceNumber        int64                  `json:"startingInvoiceNumber"`
+	CanEditStartingInvoiceNumber bool                   `json:"canEditStartingInvoiceNumber"`
+	ReadOnly                     bool                   `json:"readOnly"`
+	LogoAssetID                  int64                  `json:"-"`
+	LogoStorageKey               string                 `json:"-"`
+}
+
+// LateCompensationTier is the fixed compensation charged on an overdue debt of
+// at least FromMinor.
+type LateCompensationTier struct {
+	FromMinor   int64 `json:"fromMinor"`
+	AmountMinor int64 `json:"amountMinor"`
+}
+
+// TaxRate is a named VAT rate in the workspace registry. A nil EffectiveFrom
+// or EffectiveTo leaves that end of the range open.
+type TaxRate struct {
+	ID            int64   `json:"id"`
+	Name          string  `json:"name"`
+	RateBps       int64   `json:"rateBps"`
+	EffectiveFrom *string `json:"effectiveFrom,omitempty"`
+	EffectiveTo   *string `json:"effectiveTo,omitempty"`
+	IsDefault     bool    `json:"isDefault"`
+	CreatedAt     string  `json:"createdAt"`
+	UpdatedAt     *string `json:"updatedAt,omitempty"`
+}
+
+// TaxRateIn creates or replaces a tax rate. At most one rate is the default.
+type TaxRateIn struct {
+	Name          string  `json:"name"`
+	RateBps       int64   `json:"rateBps"`
+	EffectiveFrom *string `json:"effectiveFrom,omitempty"` // YYYY-MM-DD
+	EffectiveTo   *string `json:"effectiveTo,omitempty"`   // YYYY-MM-DD, inclusive
+	IsDefault     bool    `json:"isDefault"`
 }
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 )
 
 const (
@@ -24,10 +25,13 @@ const (
 	drawingNamespace            = "http://schemas.openxmlformats.org/drawingml/2006/main"
 	pictureNamespace            = "http://schemas.openxmlformats.org/drawingml/2006/picture"
 	wordDrawingNamespace        = "http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"
+	vmlNamespace                = "urn:schemas-microsoft-com:vml"
+	officeNamespace             = "urn:schemas-microsoft-com:office:office"
 	documentStylesRelID         = "rId1"
 	documentFooterRelID         = "rId2"
 	documentLogoRelID           = "rId3"
 	documentSettingsRelID       = "rId4"
+	documentHeaderRelID         = "rId5"
 	defaultLogoName             = "logo"
 	maxLogoWidthEMU       int64 = 1_900_000
 	maxLogoHeightEMU      int64 = 750_000
@@ -48,6 +52,7 @@ type paragraphOptions struct {
 	spacingBefore int
 	spacingAfter  int
 	topBorder     bool
+	color         string
 }
 
 type itemGroup struct {
@@ -77,22 +82,26 @@ func RenderDOCX(doc models.InvoicePDFData) ([]byte, error) {
 	logo := loadEmbeddedLogo(doc.Issuer.LogoPath)
 	footerLines := linesOf(doc.NotesFooter)
 	hasFooter := true
+	hasWatermark := doc.Voided
 
 	zw := zip.NewWriter(&out)
 	files := []archiveFile{
-		xmlFile("[Content_Types].xml", contentTypesXML(logo, hasFooter)),
+		xmlFile("[Content_Types].xml", contentTypesXML(logo, hasFooter, hasWatermark)),
 		xmlFile("_rels/.rels", rootRelationshipsXML()),
 		xmlFile("docProps/app.xml", appPropsXML()),
 		xmlFile("docProps/core.xml", corePropsXML(doc)),
-		xmlFile("word/document.xml", documentXML(doc, logo, hasFooter)),
+		xmlFile("word/document.xml", documentXML(doc, logo, hasFooter, hasWatermark)),
 		xmlFile("word/settings.xml", settingsXML()),
 		xmlFile("word/styles.xml", stylesXML()),
-		xmlFile("word/_rels/document.xml.rels", documentRelationshipsXML(logo, hasFooter)),
+		xmlFile("word/_rels/document.xml.rels", documentRelationshipsXML(logo, hasFooter, hasWatermark)),
 	}
 
 	if hasFooter {
 		files = append(files, xmlFile("word/footer1.xml", footerXML(footerLines)))
 	}
+	if hasWatermark {
+		files = append(files, xmlFile("word/header1.xml", voidWatermarkHeaderXML()))
+	}
 	if logo != nil {
 		files = append(files, archiveFile{
 			name: logo.archivePath,
@@ -121,7 +130,7 @@ func xmlFile(name, content string) archiveFile {
 	return archiveFile{name: name, data: []byte(content)}
 }
 
-func contentTypesXML(logo *embeddedImage, hasFooter bool) string {
+func contentTypesXML(logo *embeddedImage, hasFooter, hasWatermark bool) string {
 	var b strings.Builder
 
 	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
@@ -143,6 +152,9 @@ func contentTypesXML(logo *embeddedImage, hasFooter bool) string {
 	if hasFooter {
 		b.WriteString(`<Override PartName="/word/footer1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"/>`)
 	}
+	if hasWatermark {
+		b.WriteString(`<Override PartName="/word/header1.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"/>`)
+	}
 	b.WriteString(`</Types>`)
 
 	return b.String()
@@ -186,7 +198,7 @@ func corePropsXML(doc models.InvoicePDFData) string {
 </cp:coreProperties>`, escapeXML(title), now, now)
 }
 
-func documentRelationshipsXML(logo *embeddedImage, hasFooter bool) string {
+func documentRelationshipsXML(logo *embeddedImage, hasFooter, hasWatermark bool) string {
 	var b strings.Builder
 
 	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
@@ -201,6 +213,12 @@ func documentRelationshipsXML(logo *embeddedImage, hasFooter bool) string {
 			documentFooterRelID,
 		))
 	}
+	if hasWatermark {
+		b.WriteString(fmt.Sprintf(
+			`<Relationship Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/header" Target="header1.xml"/>`,
+			documentHeaderRelID,
+		))
+	}
 	if logo != nil {
 		target := strings.TrimPrefix(logo.archivePath, "word/")
 		b.WriteString(fmt.Sprintf(
@@ -243,7 +261,7 @@ func settingsXML() string {
 <w:settings xmlns:w="%s"><w:updateFields w:val="true"/></w:settings>`, wordNamespace)
 }
 
-func documentXML(doc models.InvoicePDFData, logo *embeddedImage, hasFooter bool) string {
+func documentXML(doc models.InvoicePDFData, logo *embeddedImage, hasFooter, hasWatermark bool) string {
 	var b strings.Builder
 
 	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`)
@@ -264,6 +282,16 @@ func documentXML(doc models.InvoicePDFData, logo *embeddedImage, hasFooter bool)
 		}
 	}
 
+	if doc.Voided {
+		b.WriteString(paragraph(voidNotice(doc), paragraphOptions{
+			align:         "right",
+			bold:          true,
+			size:          20,
+			spacingBefore: 80,
+			color:         "B02020",
+		}))
+	}
+
 	b.WriteString(paragraph(" ", paragraphOptions{spacingAfter: 120}))
 	b.WriteString(partyTableXML(doc))
 	b.WriteString(paragraph(" ", paragraphOptions{spacingAfter: 160}))
@@ -273,11 +301,17 @@ func documentXML(doc models.InvoicePDFData, logo *embeddedImage, hasFooter bool)
 	b.WriteString(sectionHeading("Summary"))
 	b.WriteString(summaryTableXML(doc))
 
-	if cleanPtr(doc.Note) != "" {
+	vatStatement := invoiceformat.VATTreatmentStatement(doc.VATTreatment)
+	if vatStatement != "" || cleanPtr(doc.Note) != "" {
 		b.WriteString(paragraph(" ", paragraphOptions{spacingAfter: 120}))
 		b.WriteString(sectionHeading("Notes"))
-		for _, line := range linesOf(*doc.Note) {
-			b.WriteString(paragraph(line, paragraphOptions{spacingAfter: 60}))
+		if vatStatement != "" {
+			b.WriteString(paragraph(vatStatement, paragraphOptions{spacingAfter: 60}))
+		}
+		if cleanPtr(doc.Note) != "" {
+			for _, line := range linesOf(*doc.Note) {
+				b.WriteString(paragraph(line, paragraphOptions{spacingAfter: 60}))
+			}
 		}
 	}
 
@@ -298,6 +332,9 @@ func documentXML(doc models.InvoicePDFData, logo *embeddedImage, hasFooter bool)
 	}
 
 	b.WriteString(`<w:sectPr>`)
+	if hasWatermark {
+		b.WriteString(fmt.Sprintf(`<w:headerReference w:type="default" r:id="%s"/>`, documentHeaderRelID))
+	}
 	if hasFooter {
 		b.WriteString(fmt.Sprintf(`<w:footerReference w:type="default" r:id="%s"/>`, documentFooterRelID))
 	}
@@ -309,6 +346,58 @@ func documentXML(doc models.InvoicePDFData, logo *embeddedImage, hasFooter bool)
 	return b.String()
 }
 
+// voidNotice states why and when a void invoice was voided.
+func voidNotice(doc models.InvoicePDFData) string {
+	notice := "VOID"
+	if voidedAt := clean(doc.VoidedAt); voidedAt != "" {
+		notice += " since " + voidedAt
+	}
+	if reason := clean(doc.VoidReason); reason != "" {
+		notice += ": " + reason
+	}
+	return notice
+}
+
+// voidWatermarkHeaderXML is a page header holding a diagonal "VOID" text
+// watermark. Word draws header shapes behind the body on every page, the same
+// way its own Insert Watermark does.
+func voidWatermarkHeaderXML() string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:hdr xmlns:w="%s" xmlns:v="%s" xmlns:o="%s">
+  <w:p>
+    <w:r>
+      <w:pict>
+        <v:shapetype id="_x0000_t136" coordsize="21600,21600" o:spt="136" adj="10800" path="m@7,l@8,m@5,21600l@6,21600e">
+          <v:formulas>
+            <v:f eqn="sum #0 0 10800"/>
+            <v:f eqn="prod #0 2 1"/>
+            <v:f eqn="sum 21600 0 @1"/>
+            <v:f eqn="sum 0 0 @2"/>
+            <v:f eqn="sum 21600 0 @3"/>
+            <v:f eqn="if @0 @3 0"/>
+            <v:f eqn="if @0 21600 @1"/>
+            <v:f eqn="if @0 0 @2"/>
+            <v:f eqn="if @0 @4 21600"/>
+            <v:f eqn="mid @5 @6"/>
+            <v:f eqn="mid @8 @5"/>
+            <v:f eqn="mid @7 @8"/>
+            <v:f eqn="mid @6 @7"/>
+            <v:f eqn="sum @6 0 @5"/>
+          </v:formulas>
+          <v:path textpathok="t" o:connecttype="custom" o:connectlocs="@9,0;@10,10800;@11,21600;@12,10800" o:connectangles="270,180,90,0"/>
+          <v:textpath on="t" fitshape="t"/>
+          <o:lock v:ext="edit" text="t" shapetype="t"/>
+        </v:shapetype>
+        <v:shape id="VoidWatermark" o:spid="_x0000_s2049" type="#_x0000_t136" style="position:absolute;margin-left:0;margin-top:0;width:468pt;height:156pt;rotation:315;z-index:-251657216;mso-position-horizontal:center;mso-position-horizontal-relative:margin;mso-position-vertical:center;mso-position-vertical-relative:margin" o:allowincell="f" fillcolor="#c82828" stroked="f">
+          <v:fill opacity=".22"/>
+          <v:textpath style="font-family:&quot;Calibri&quot;;font-size:1pt;font-weight:bold" string="VOID"/>
+        </v:shape>
+      </w:pict>
+    </w:r>
+  </w:p>
+</w:hdr>`, wordNamespace, vmlNamespace, officeNamespace)
+}
+
 func footerXML(lines []string) string {
 	var b strings.Builder
 
@@ -379,7 +468,7 @@ func headerTextParagraphs(doc models.InvoicePDFData) []string {
 	for _, row := range []string{
 		metaLine("Issued", doc.IssueAt),
 		metaLine("Supply", cleanPtr(doc.SupplyDate)),
-		metaLine("Due", cleanPtr(doc.DueDate)),
+		metaLine(dueDateLabel(doc), cleanPtr(doc.DueDate)),
 	} {
 		if row == "" {
 			continue
@@ -443,11 +532,14 @@ func paragraph(text string, opts paragraphOptions) string {
 	}
 
 	b.WriteString(`<w:r>`)
-	if opts.bold || opts.size > 0 {
+	if opts.bold || opts.size > 0 || opts.color != "" {
 		b.WriteString(`<w:rPr>`)
 		if opts.bold {
 			b.WriteString(`<w:b/>`)
 		}
+		if opts.color != "" {
+			b.WriteString(fmt.Sprintf(`<w:color w:val="%s"/>`, opts.color))
+		}
 		if opts.size > 0 {
 			b.WriteString(fmt.Sprintf(`<w:sz w:val="%d"/><w:szCs w:val="%d"/>`, opts.size, opts.size))
 		}
@@ -478,7 +570,11 @@ func partyTableXML(doc models.InvoicePDFData) string {
 		paragraph("BILL TO", paragraphOptions{bold: true, size: 20, spacingAfter: 20}),
 		paragraph(defaultText(leftName), paragraphOptions{bold: true, size: 22, spacingAfter: 40}),
 	}
-	for _, detail := range nonEmptyLines(joinAddressParts(doc.Client.Address), clean(doc.Client.Email)) {
+	vatNumber := clean(doc.Client.VATNumber)
+	if vatNumber != "" {
+		vatNumber = "VAT no. " + vatNumber
+	}
+	for _, detail := range nonEmptyLines(joinAddressParts(doc.Client.Address), clean(doc.Client.Email), vatNumber) {
 		leftParagraphs = append(leftParagraphs, paragraph(detail, paragraphOptions{spacingAfter: 20}))
 	}
 
@@ -507,7 +603,13 @@ func partyTableXML(doc models.InvoicePDFData) string {
 }
 
 func lineItemsTableXML(doc models.InvoicePDFData) string {
-	widths := []int{3600, 700, 900, 1500, 1300, 1500}
+	showDiscount := hasLineDiscounts(doc.Lines)
+	widths := []int{3400, 900, 900, 1500, 1300, 1500}
+	headings := []string{"Description", "Qty", "Time", "Rate", "Price", "Amount"}
+	if showDiscount {
+		widths = []int{2800, 900, 900, 1300, 1200, 1000, 1400}
+		headings = []string{"Description", "Qty", "Time", "Rate", "Price", "Disc.", "Amount"}
+	}
 
 	var b strings.Builder
 	b.WriteString(`<w:tbl>`)
@@ -524,7 +626,7 @@ func lineItemsTableXML(doc models.InvoicePDFData) string {
 	b.WriteString(`</w:tblGrid>`)
 
 	b.WriteString(`<w:tr>`)
-	for idx, heading := range []string{"Description", "Qty", "Time", "Rate", "Price", "Amount"} {
+	for idx, heading := range headings {
 		align := "left"
 		if idx > 0 {
 			align = "center"
@@ -574,7 +676,10 @@ func lineItemsTableXML(doc models.InvoicePDFData) string {
 			b.WriteString(tableCellXML([]string{paragraph(defaultText(clean(line.TimeWorked)), paragraphOptions{align: "center"})}, widths[2], false, false, 1))
 			b.WriteString(tableCellXML([]string{paragraph(defaultText(clean(line.HourlyRate)), paragraphOptions{align: "right"})}, widths[3], false, false, 1))
 			b.WriteString(tableCellXML([]string{paragraph(defaultText(clean(line.ItemPrice)), paragraphOptions{align: "right"})}, widths[4], false, false, 1))
-			b.WriteString(tableCellXML([]string{paragraph(defaultText(clean(line.ItemTotal)), paragraphOptions{align: "right"})}, widths[5], false, false, 1))
+			if showDiscount {
+				b.WriteString(tableCellXML([]string{paragraph(defaultText(clean(line.Discount)), paragraphOptions{align: "right"})}, widths[5], false, false, 1))
+			}
+			b.WriteString(tableCellXML([]string{paragraph(defaultText(clean(line.ItemTotal)), paragraphOptions{align: "right"})}, widths[len(widths)-1], false, false, 1))
 			b.WriteString(`</w:tr>`)
 		}
 	}
@@ -595,6 +700,15 @@ func lineItemsTableXML(doc models.InvoicePDFData) string {
 	return b.String()
 }
 
+func hasLineDiscounts(lines []models.InvoicePDFItem) bool {
+	for _, line := range lines {
+		if clean(line.Discount) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func summaryTableXML(doc models.InvoicePDFData) string {
 	rows := buildSummaryRows(doc)
 	widths := []int{4200, 1800}
@@ -698,9 +812,7 @@ func buildSummaryRows(doc models.InvoicePDFData) []summaryRow {
 			{label: "Payment Amount", value: formatMoney(doc.ReceiptAmountMinor, doc.Currency)},
 			{label: "Invoice Total", value: formatMoney(doc.Totals.TotalMinor, doc.Currency)},
 		}
-		if doc.Totals.DepositMinor > 0 {
-			rows = append(rows, summaryRow{label: "Requested Deposit", value: formatMoney(doc.Totals.DepositMinor, doc.Currency)})
-		}
+		rows = append(rows, buildDepositSummaryRows(doc, 1)...)
 		rows = append(rows, summaryRow{label: "Total Paid", value: formatMoney(doc.Totals.PaidMinor, doc.Currency)})
 		rows = append(rows, summaryRow{
 			label:     "Balance Due",
@@ -710,22 +822,72 @@ func buildSummaryRows(doc models.InvoicePDFData) []summaryRow {
 		return rows
 	}
 
+	if doc.DocumentKind == "deposit_receipt" {
+		return []summaryRow{
+			{label: "Deposit Amount", value: formatMoney(doc.ReceiptAmountMinor, doc.Currency)},
+			{label: "Invoice Total", value: formatMoney(doc.Totals.TotalMinor, doc.Currency)},
+			{label: "Deposit Requested", value: formatMoney(doc.Totals.DepositMinor, doc.Currency)},
+			{label: "Deposit Received", value: formatMoney(doc.DepositPaidMinor, doc.Currency)},
+			{label: "Total Paid", value: formatMoney(doc.Totals.PaidMinor, doc.Currency)},
+			{label: "Balance Due", value: formatMoney(doc.Totals.BalanceDue, doc.Currency), highlight: true},
+		}
+	}
+
+	if doc.DocumentKind == "refund" {
+		return []summaryRow{
+			{label: "Refund Amount", value: formatMoney(doc.ReceiptAmountMinor, doc.Currency)},
+			{label: "Invoice Total", value: formatMoney(doc.Totals.TotalMinor, doc.Currency)},
+			{label: "Total Paid", value: formatMoney(doc.Totals.PaidMinor, doc.Currency)},
+			{label: "Balance Due", value: formatMoney(doc.Totals.BalanceDue, doc.Currency), highlight: true},
+		}
+	}
+
+	if doc.DocumentKind == "credit_note" {
+		return []summaryRow{
+			{label: "Subtotal", value: formatMoney(doc.Totals.SubtotalMinor, doc.Currency)},
+			{label: "VAT", value: formatMoney(doc.Totals.VatAmountMinor, doc.Currency)},
+			{label: "Total Credit", value: formatMoney(doc.Totals.TotalMinor, doc.Currency), highlight: true},
+		}
+	}
+
+	if doc.DocumentKind == "quote" {
+		rows := []summaryRow{
+			{label: "Subtotal", value: formatMoney(doc.Totals.SubtotalMinor, doc.Currency)},
+		}
+		if doc.Totals.DiscountMinor > 0 {
+			rows = append(rows, summaryRow{label: "Discount", value: formatMoney(-doc.Totals.DiscountMinor, doc.Currency)})
+		}
+		rows = append(rows, buildVATSummaryRows(doc)...)
+		if doc.Totals.DepositMinor > 0 {
+			rows = append(rows, summaryRow{label: "Deposit on Acceptance", value: formatMoney(doc.Totals.DepositMinor, doc.Currency)})
+		}
+		return append(rows, summaryRow{
+			label:     "Quote Total",
+			value:     formatMoney(doc.Totals.TotalMinor, doc.Currency),
+			highlight: true,
+		})
+	}
+
 	rows := []summaryRow{
 		{label: "Subtotal", value: formatMoney(doc.Totals.SubtotalMinor, doc.Currency)},
-		{label: "VAT", value: formatMoney(doc.Totals.VatAmountMinor, doc.Currency)},
-		{label: "Total", value: formatMoney(doc.Totals.TotalMinor, doc.Currency)},
 	}
-
 	if doc.Totals.DiscountMinor > 0 {
-		rows = append(rows[:1], append([]summaryRow{
-			{label: "Discount", value: formatMoney(-doc.Totals.DiscountMinor, doc.Currency)},
-		}, rows[1:]...)...)
+		rows = append(rows, summaryRow{label: "Discount", value: formatMoney(-doc.Totals.DiscountMinor, doc.Currency)})
+	}
+	rows = append(rows, buildVATSummaryRows(doc)...)
+	rows = append(rows, summaryRow{label: "Total", value: formatMoney(doc.Totals.TotalMinor, doc.Currency)})
+	if doc.Totals.DeductionMinor > 0 {
+		rows = append(rows,
+			summaryRow{label: "Deduction " + invoiceformat.FormatRateBps(doc.Totals.DeductionRate), value: formatMoney(-doc.Totals.DeductionMinor, doc.Currency)},
+			summaryRow{label: "Amount Payable", value: formatMoney(doc.Totals.TotalMinor-doc.Totals.DeductionMinor, doc.Currency)},
+		)
 	}
-	if doc.Totals.DepositMinor > 0 {
-		rows = append(rows, summaryRow{label: "Requested Deposit", value: formatMoney(doc.Totals.DepositMinor, doc.Currency)})
+	rows = append(rows, buildDepositSummaryRows(doc, -1)...)
+	if paid := doc.Totals.PaidMinor - doc.DepositPaidMinor; paid > 0 {
+		rows = append(rows, summaryRow{label: "Paid", value: formatMoney(-paid, doc.Currency)})
 	}
-	if doc.Totals.PaidMinor > 0 {
-		rows = append(rows, summaryRow{label: "Paid", value: formatMoney(-doc.Totals.PaidMinor, doc.Currency)})
+	if doc.CreditedMinor > 0 {
+		rows = append(rows, summaryRow{label: "Credited", value: formatMoney(-doc.CreditedMinor, doc.Currency)})
 	}
 
 	rows = append(rows, summaryRow{
@@ -737,6 +899,49 @@ func buildSummaryRows(doc models.InvoicePDFData) []summaryRow {
 	return rows
 }
 
+// buildDepositSummaryRows shows the requested deposit until it has been fully
+// received, and what was received against it. Received deposits are signed
+// like the surrounding paid rows.
+func buildDepositSummaryRows(doc models.InvoicePDFData, sign int64) []summaryRow {
+	var rows []summaryRow
+	if doc.Totals.DepositMinor > 0 && doc.DepositPaidMinor < doc.Totals.DepositMinor {
+		rows = append(rows, summaryRow{label: "Deposit Requested", value: formatMoney(doc.Totals.DepositMinor, doc.Currency)})
+	}
+	if doc.DepositPaidMinor > 0 {
+		rows = append(rows, summaryRow{label: "Deposit Received", value: formatMoney(sign*doc.DepositPaidMinor, doc.Currency)})
+	}
+	return rows
+}
+
+// buildVATSummaryRows shows a single VAT row, or one row per rate when the
+// invoice mixes VAT rates.
+func buildVATSummaryRows(doc models.InvoicePDFData) []summaryRow {
+	bands := doc.Totals.VATBreakdown
+	if len(bands) <= 1 {
+		label := "VAT"
+		if treatment := invoiceformat.VATTreatmentLabel(doc.VATTreatment); treatment != "" {
+			label = "VAT (" + treatment + ")"
+		}
+		return []summaryRow{{label: label, value: formatMoney(doc.Totals.VatAmountMinor, doc.Currency)}}
+	}
+
+	rows := make([]summaryRow, 0, len(bands))
+	for _, band := range bands {
+		rows = append(rows, summaryRow{
+			label: fmt.Sprintf("VAT %s on %s", invoiceformat.FormatRateBps(band.VATRate), formatMoney(band.NetMinor, doc.Currency)),
+			value: formatMoney(band.VatMinor, doc.Currency),
+		})
+	}
+	return rows
+}
+
+func dueDateLabel(doc models.InvoicePDFData) string {
+	if doc.DocumentKind == "quote" {
+		return "Valid until"
+	}
+	return "Due"
+}
+
 func groupInvoiceLines(lines []models.InvoicePDFItem) []itemGroup {
 	sorted := append([]models.InvoicePDFItem(nil), lines...)
 	sort.SliceStable(sorted, func(i, j int) bool {
@@ -934,20 +1139,9 @@ func formatMoney(minorUnits int64, currency string) string {
 		minorUnits = -minorUnits
 	}
 
-	symbol := currencySymbol(currency)
+	symbol := invoiceformat.CurrencySymbol(currency)
 	major := minorUnits / 100
 	minor := minorUnits % 100
 
 	return fmt.Sprintf("%s%s%d.%02d", sign, symbol, major, minor)
 }
-
-func currencySymbol(code string) string {
-	switch code {
-	case "EUR":
-		return "€"
-	case "USD":
-		return "$"
-	default:
-		return "£"
-	}
-}
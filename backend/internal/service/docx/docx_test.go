@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 )
 
 func TestRenderDOCX_CreatesArchiveWithFooterAndEmbeddedLogo(t *testing.T) {
@@ -180,6 +181,99 @@ func TestRenderDOCX_UsesNoLineItemsFallback(t *testing.T) {
 	}
 }
 
+func TestRenderDOCX_StampsVoidInvoices(t *testing.T) {
+	doc := models.InvoicePDFData{
+		Title:              "Invoice",
+		InvoiceNumberLabel: "INV-9",
+		Currency:           "GBP",
+		IssueAt:            "26/03/2026",
+		Voided:             true,
+		VoidReason:         "Raised against the wrong client",
+		VoidedAt:           "28/03/2026",
+	}
+
+	data, err := RenderDOCX(doc)
+	if err != nil {
+		t.Fatalf("RenderDOCX() error = %v", err)
+	}
+
+	files := unzipFileMap(t, data)
+	headerXML, ok := files["word/header1.xml"]
+	if !ok {
+		t.Fatal("void invoice should have a watermark header")
+	}
+	if !strings.Contains(headerXML, `string="VOID"`) || !strings.Contains(headerXML, "rotation:315") {
+		t.Fatalf("header XML missing diagonal VOID watermark")
+	}
+	if !strings.Contains(files["word/document.xml"], "VOID since 28/03/2026: Raised against the wrong client") {
+		t.Fatalf("document XML missing void reason")
+	}
+	if !strings.Contains(files["word/document.xml"], `<w:headerReference w:type="default" r:id="`+documentHeaderRelID+`"/>`) {
+		t.Fatalf("document XML missing header reference")
+	}
+	if !strings.Contains(files["word/_rels/document.xml.rels"], `Target="header1.xml"`) {
+		t.Fatalf("document relationships missing header")
+	}
+	if !strings.Contains(files["[Content_Types].xml"], `/word/header1.xml`) {
+		t.Fatalf("content types missing header")
+	}
+
+	doc.Voided = false
+	data, err = RenderDOCX(doc)
+	if err != nil {
+		t.Fatalf("RenderDOCX() error = %v", err)
+	}
+	if _, ok := unzipFileMap(t, data)["word/header1.xml"]; ok {
+		t.Fatalf("live invoice should not have a watermark header")
+	}
+}
+
+func TestRenderDOCX_PrintsReverseChargeStatement(t *testing.T) {
+	doc := models.InvoicePDFData{
+		Title:              "Invoice",
+		InvoiceNumberLabel: "INV-10",
+		Currency:           "GBP",
+		IssueAt:            "26/03/2026",
+		VATTreatment:       invoiceformat.VATTreatmentReverseCharge,
+		Client:             models.CreateClient{Name: "Acme GmbH", VATNumber: "DE123456789"},
+	}
+
+	data, err := RenderDOCX(doc)
+	if err != nil {
+		t.Fatalf("RenderDOCX() error = %v", err)
+	}
+
+	documentXML := unzipFileMap(t, data)["word/document.xml"]
+	for _, want := range []string{
+		"VAT no. DE123456789",
+		"VAT (reverse charge)",
+		invoiceformat.VATTreatmentStatement(invoiceformat.VATTreatmentReverseCharge),
+	} {
+		if !strings.Contains(documentXML, want) {
+			t.Fatalf("document XML missing %q", want)
+		}
+	}
+}
+
+func TestLineItemsTableXML_ShowsDiscountColumnOnlyWhenUsed(t *testing.T) {
+	doc := models.InvoicePDFData{
+		Lines: []models.InvoicePDFItem{
+			{Name: "Coat", LineType: "style", Quantity: "1", ItemPrice: "£100.00", ItemTotal: "£100.00", SortOrder: 1},
+		},
+	}
+
+	if xml := lineItemsTableXML(doc); strings.Contains(xml, "Disc.") {
+		t.Fatalf("table without discounts should not render a discount column")
+	}
+
+	doc.Lines[0].Discount = "10%"
+	doc.Lines[0].ItemTotal = "£90.00"
+	xml := lineItemsTableXML(doc)
+	if !strings.Contains(xml, "Disc.") || !strings.Contains(xml, "10%") {
+		t.Fatalf("table with a discounted line should render the discount column")
+	}
+}
+
 func unzipFileMap(t *testing.T, data []byte) map[string]string {
 	t.Helper()
 
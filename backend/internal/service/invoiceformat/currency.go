@@ -0,0 +1,46 @@
+package invoiceformat
+
+import "strings"
+
+// DefaultCurrency is used when a stored currency is missing or unknown.
+const DefaultCurrency = "GBP"
+
+// currencySymbols lists the currencies documents can be issued in. Amounts
+// are held in minor units of 1/100, so only two-decimal currencies belong here.
+var currencySymbols = map[string]string{
+	"GBP": "£",
+	"EUR": "€",
+	"USD": "$",
+	"AUD": "A$",
+	"CAD": "C$",
+	"NZD": "NZ$",
+	"CHF": "CHF ",
+	"SEK": "SEK ",
+	"NOK": "NOK ",
+	"DKK": "DKK ",
+	"PLN": "PLN ",
+	"CZK": "CZK ",
+}
+
+// SupportedCurrency reports whether code is an ISO 4217 code documents can be
+// issued in.
+func SupportedCurrency(code string) bool {
+	_, ok := currencySymbols[code]
+	return ok
+}
+
+// NormalizeCurrency returns code upper-cased, or DefaultCurrency when it is
+// not supported.
+func NormalizeCurrency(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if !SupportedCurrency(code) {
+		return DefaultCurrency
+	}
+	return code
+}
+
+// CurrencySymbol renders the prefix shown before amounts, e.g. "EUR" -> "€"
+// and "CHF" -> "CHF ".
+func CurrencySymbol(code string) string {
+	return currencySymbols[NormalizeCurrency(code)]
+}
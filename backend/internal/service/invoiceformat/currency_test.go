@@ -0,0 +1,22 @@
+package invoiceformat
+
+import "testing"
+
+func TestCurrencySymbol(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "GBP", want: "£"},
+		{code: "eur", want: "€"},
+		{code: "CHF", want: "CHF "},
+		{code: "XYZ", want: "£"},
+		{code: "", want: "£"},
+	}
+
+	for _, tt := range tests {
+		if got := CurrencySymbol(tt.code); got != tt.want {
+			t.Fatalf("CurrencySymbol(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 )
 
 const defaultInvoicePrefix = "INV"
+const defaultQuotePrefix = "QUO"
 
 var trailingDashRegex = regexp.MustCompile(`-\s*$`)
 
@@ -19,13 +20,29 @@ func formatBaseLabel(prefix string, baseNumber int64) string {
 	return fmt.Sprintf("%s-%d", cleanPrefix, baseNumber)
 }
 
-func FormatInvoiceNumber(prefix string, baseNumber int64, revisionNo int64) string {
-	cleanPrefix := prefix
+// Numbering formats every number derived from one invoice. Label is the
+// number rendered from the account's number template when the invoice was
+// created; without one the base is PREFIX-N.
+type Numbering struct {
+	Prefix     string
+	BaseNumber int64
+	Label      string
+}
+
+func (n Numbering) base() string {
+	if n.Label != "" {
+		return n.Label
+	}
+
+	cleanPrefix := n.Prefix
 	if cleanPrefix == "" {
 		cleanPrefix = defaultInvoicePrefix
 	}
+	return formatBaseLabel(cleanPrefix, n.BaseNumber)
+}
 
-	baseLabel := formatBaseLabel(cleanPrefix, baseNumber)
+func (n Numbering) Invoice(revisionNo int64) string {
+	baseLabel := n.base()
 	if revisionNo <= 1 {
 		return baseLabel
 	}
@@ -33,16 +50,47 @@ func FormatInvoiceNumber(prefix string, baseNumber int64, revisionNo int64) stri
 	return fmt.Sprintf("%s.%d", baseLabel, revisionNo)
 }
 
+func (n Numbering) PaymentReceipt(revisionNo int64, receiptNo int64) string {
+	return fmt.Sprintf("%s-PR-%d", n.Invoice(revisionNo), max(receiptNo, 1))
+}
+
+func (n Numbering) CreditNote(creditNoteNo int64) string {
+	return fmt.Sprintf("%s-CN-%d", n.base(), max(creditNoteNo, 1))
+}
+
+func (n Numbering) Refund(refundNo int64) string {
+	return fmt.Sprintf("%s-RF-%d", n.base(), max(refundNo, 1))
+}
+
+func (n Numbering) DepositReceipt(depositNo int64) string {
+	return fmt.Sprintf("%s-DR-%d", n.base(), max(depositNo, 1))
+}
+
+func FormatInvoiceNumber(prefix string, baseNumber int64, revisionNo int64) string {
+	return Numbering{Prefix: prefix, BaseNumber: baseNumber}.Invoice(revisionNo)
+}
+
 func FormatPaymentReceiptNumber(prefix string, baseNumber int64, revisionNo int64, receiptNo int64) string {
+	return Numbering{Prefix: prefix, BaseNumber: baseNumber}.PaymentReceipt(revisionNo, receiptNo)
+}
+
+func FormatCreditNoteNumber(prefix string, baseNumber int64, creditNoteNo int64) string {
+	return Numbering{Prefix: prefix, BaseNumber: baseNumber}.CreditNote(creditNoteNo)
+}
+
+func FormatRefundNumber(prefix string, baseNumber int64, refundNo int64) string {
+	return Numbering{Prefix: prefix, BaseNumber: baseNumber}.Refund(refundNo)
+}
+
+func FormatDepositReceiptNumber(prefix string, baseNumber int64, depositNo int64) string {
+	return Numbering{Prefix: prefix, BaseNumber: baseNumber}.DepositReceipt(depositNo)
+}
+
+func FormatQuoteNumber(prefix string, quoteNumber int64) string {
 	cleanPrefix := prefix
 	if cleanPrefix == "" {
-		cleanPrefix = defaultInvoicePrefix
-	}
-
-	referenceLabel := FormatInvoiceNumber(cleanPrefix, baseNumber, revisionNo)
-	if receiptNo <= 1 {
-		return fmt.Sprintf("%s-PR-1", referenceLabel)
+		cleanPrefix = defaultQuotePrefix
 	}
 
-	return fmt.Sprintf("%s-PR-%d", referenceLabel, receiptNo)
+	return formatBaseLabel(cleanPrefix, quoteNumber)
 }
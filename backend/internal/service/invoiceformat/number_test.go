@@ -73,3 +73,115 @@ func TestFormatPaymentReceiptNumber(t *testing.T) {
 		})
 	}
 }
+
+func TestFormatCreditNoteNumber(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		baseNo       int64
+		creditNoteNo int64
+		want         string
+	}{
+		{name: "first credit note", prefix: "INV-", baseNo: 3, creditNoteNo: 1, want: "INV-3-CN-1"},
+		{name: "later credit note", prefix: "INV-", baseNo: 3, creditNoteNo: 4, want: "INV-3-CN-4"},
+		{name: "default prefix", prefix: "", baseNo: 3, creditNoteNo: 2, want: "INV-3-CN-2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FormatCreditNoteNumber(tc.prefix, tc.baseNo, tc.creditNoteNo)
+			if got != tc.want {
+				t.Fatalf("FormatCreditNoteNumber() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatRefundNumber(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		baseNo   int64
+		refundNo int64
+		want     string
+	}{
+		{name: "first refund", prefix: "INV-", baseNo: 3, refundNo: 1, want: "INV-3-RF-1"},
+		{name: "later refund", prefix: "INV-", baseNo: 3, refundNo: 2, want: "INV-3-RF-2"},
+		{name: "default prefix", prefix: "", baseNo: 3, refundNo: 2, want: "INV-3-RF-2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FormatRefundNumber(tc.prefix, tc.baseNo, tc.refundNo)
+			if got != tc.want {
+				t.Fatalf("FormatRefundNumber() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatDepositReceiptNumber(t *testing.T) {
+	tests := []struct {
+		name      string
+		prefix    string
+		baseNo    int64
+		depositNo int64
+		want      string
+	}{
+		{name: "first deposit", prefix: "INV-", baseNo: 3, depositNo: 1, want: "INV-3-DR-1"},
+		{name: "later deposit", prefix: "INV-", baseNo: 3, depositNo: 2, want: "INV-3-DR-2"},
+		{name: "default prefix", prefix: "", baseNo: 3, depositNo: 2, want: "INV-3-DR-2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FormatDepositReceiptNumber(tc.prefix, tc.baseNo, tc.depositNo)
+			if got != tc.want {
+				t.Fatalf("FormatDepositReceiptNumber() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormatQuoteNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		prefix  string
+		quoteNo int64
+		want    string
+	}{
+		{name: "prefix with dash", prefix: "QUO-", quoteNo: 12, want: "QUO-12"},
+		{name: "prefix without dash", prefix: "EST", quoteNo: 3, want: "EST-3"},
+		{name: "empty prefix uses default", prefix: "", quoteNo: 5, want: "QUO-5"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FormatQuoteNumber(tc.prefix, tc.quoteNo)
+			if got != tc.want {
+				t.Fatalf("FormatQuoteNumber() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNumbering_UsesTemplateLabel(t *testing.T) {
+	n := Numbering{Prefix: "INV-", BaseNumber: 42, Label: "INV-2026-0042"}
+
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{got: n.Invoice(1), want: "INV-2026-0042"},
+		{got: n.Invoice(2), want: "INV-2026-0042.2"},
+		{got: n.PaymentReceipt(2, 3), want: "INV-2026-0042.2-PR-3"},
+		{got: n.CreditNote(1), want: "INV-2026-0042-CN-1"},
+		{got: n.Refund(2), want: "INV-2026-0042-RF-2"},
+		{got: n.DepositReceipt(1), want: "INV-2026-0042-DR-1"},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Fatalf("label = %q, want %q", tc.got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package invoiceformat
+
+import "strings"
+
+var paymentMethodLabels = map[string]string{
+	"bank_transfer": "Bank transfer",
+	"card":          "Card",
+	"cash":          "Cash",
+	"cheque":        "Cheque",
+	"direct_debit":  "Direct debit",
+	"other":         "Other",
+}
+
+// FormatPaymentMethod renders a stored payment method for documents, e.g.
+// "bank_transfer" -> "Bank transfer". Unknown values are shown as stored.
+func FormatPaymentMethod(method string) string {
+	if label, ok := paymentMethodLabels[method]; ok {
+		return label
+	}
+	return strings.ReplaceAll(method, "_", " ")
+}
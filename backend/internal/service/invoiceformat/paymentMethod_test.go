@@ -0,0 +1,21 @@
+package invoiceformat
+
+import "testing"
+
+func TestFormatPaymentMethod(t *testing.T) {
+	tests := []struct {
+		method string
+		want   string
+	}{
+		{method: "bank_transfer", want: "Bank transfer"},
+		{method: "direct_debit", want: "Direct debit"},
+		{method: "cash", want: "Cash"},
+		{method: "crypto_wallet", want: "crypto wallet"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatPaymentMethod(tt.method); got != tt.want {
+			t.Fatalf("FormatPaymentMethod(%q) = %q, want %q", tt.method, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package invoiceformat
+
+import "strconv"
+
+// FormatRateBps renders a basis-point rate as a percentage, e.g. 2000 -> "20%"
+// and 1250 -> "12.5%".
+func FormatRateBps(bps int64) string {
+	return strconv.FormatFloat(float64(bps)/100, 'f', -1, 64) + "%"
+}
@@ -0,0 +1,22 @@
+package invoiceformat
+
+import "testing"
+
+func TestFormatRateBps(t *testing.T) {
+	tests := []struct {
+		bps  int64
+		want string
+	}{
+		{bps: 2000, want: "20%"},
+		{bps: 500, want: "5%"},
+		{bps: 1250, want: "12.5%"},
+		{bps: 0, want: "0%"},
+		{bps: 1, want: "0.01%"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatRateBps(tt.bps); got != tt.want {
+			t.Fatalf("FormatRateBps(%d) = %q, want %q", tt.bps, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package invoiceformat
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// NumberTemplateMaxLen caps the length of an invoice number template.
+const NumberTemplateMaxLen = 60
+
+// clientCodeMaxLen caps how much of the client name {CLIENT} renders.
+const clientCodeMaxLen = 8
+
+// numberTemplateToken matches {YYYY}, {YY}, {MM}, {CLIENT}, {SEQ} and {SEQ:n}.
+var numberTemplateToken = regexp.MustCompile(`\{([A-Z]+)(?::(\d+))?\}`)
+
+var (
+	errNumberTemplateTooLong   = fmt.Errorf("must be at most %d characters", NumberTemplateMaxLen)
+	errNumberTemplateSeq       = errors.New("must contain {SEQ} exactly once")
+	errNumberTemplateSeqWidth  = errors.New("{SEQ:n} width must be between 1 and 9")
+	errNumberTemplateCharacter = errors.New("may only contain letters, digits, '-', '_' and '/' outside tokens")
+)
+
+// NumberFields are the values an invoice number template is rendered with.
+type NumberFields struct {
+	// IssueDate is the invoice issue date as YYYY-MM-DD.
+	IssueDate string
+	Seq       int64
+	Client    string
+}
+
+// ValidateNumberTemplate checks an invoice number template. An empty template
+// is valid and keeps the PREFIX-N numbering.
+//
+// Tokens are {YYYY}, {YY} and {MM} from the issue date, {CLIENT} for a short
+// client code and {SEQ} or {SEQ:n} for the sequence, zero-padded to n digits.
+func ValidateNumberTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	if len(template) > NumberTemplateMaxLen {
+		return errNumberTemplateTooLong
+	}
+
+	seqCount := 0
+	for _, m := range numberTemplateToken.FindAllStringSubmatch(template, -1) {
+		switch m[1] {
+		case "YYYY", "YY", "MM", "CLIENT":
+			if m[2] != "" {
+				return fmt.Errorf("unknown token %s", m[0])
+			}
+		case "SEQ":
+			seqCount++
+			if m[2] != "" {
+				if width, err := strconv.Atoi(m[2]); err != nil || width < 1 || width > 9 {
+					return errNumberTemplateSeqWidth
+				}
+			}
+		default:
+			return fmt.Errorf("unknown token %s", m[0])
+		}
+	}
+	if seqCount != 1 {
+		return errNumberTemplateSeq
+	}
+
+	for _, r := range numberTemplateToken.ReplaceAllString(template, "") {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_' && r != '/' {
+			return errNumberTemplateCharacter
+		}
+	}
+
+	return nil
+}
+
+// NumberTemplatePeriod returns the period the sequence of template restarts
+// in for an invoice issued on issueDate (YYYY-MM-DD): "2026-03" when the
+// template shows the month, "2026" when it shows only the year, and "" when
+// the sequence never restarts.
+func NumberTemplatePeriod(template, issueDate string) string {
+	year, month := issueYearMonth(issueDate)
+	switch {
+	case strings.Contains(template, "{MM}"):
+		return year + "-" + month
+	case strings.Contains(template, "{YYYY}"), strings.Contains(template, "{YY}"):
+		return year
+	default:
+		return ""
+	}
+}
+
+// RenderNumberTemplate renders a template checked by ValidateNumberTemplate,
+// e.g. "INV-{YYYY}-{SEQ:4}" -> "INV-2026-0042".
+func RenderNumberTemplate(template string, f NumberFields) string {
+	year, month := issueYearMonth(f.IssueDate)
+
+	return numberTemplateToken.ReplaceAllStringFunc(template, func(token string) string {
+		m := numberTemplateToken.FindStringSubmatch(token)
+		switch m[1] {
+		case "YYYY":
+			return year
+		case "YY":
+			return year[len(year)-2:]
+		case "MM":
+			return month
+		case "CLIENT":
+			return ClientCode(f.Client)
+		case "SEQ":
+			width, _ := strconv.Atoi(m[2])
+			return fmt.Sprintf("%0*d", width, f.Seq)
+		default:
+			return token
+		}
+	})
+}
+
+// ClientCode renders {CLIENT}: the client name upper-cased, with everything
+// but letters and digits dropped, cut to 8 characters.
+func ClientCode(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if r > unicode.MaxASCII || (!unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+			continue
+		}
+		b.WriteRune(r)
+		if b.Len() == clientCodeMaxLen {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "CLIENT"
+	}
+	return b.String()
+}
+
+func issueYearMonth(issueDate string) (year, month string) {
+	year, month = "0000", "00"
+	if len(issueDate) >= len("2006-01") {
+		year, month = issueDate[:4], issueDate[5:7]
+	}
+	return year, month
+}
@@ -0,0 +1,90 @@
+package invoiceformat
+
+import "testing"
+
+func TestRenderNumberTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		fields   NumberFields
+		want     string
+	}{
+		{
+			name:     "yearly padded sequence",
+			template: "INV-{YYYY}-{SEQ:4}",
+			fields:   NumberFields{IssueDate: "2026-03-14", Seq: 42},
+			want:     "INV-2026-0042",
+		},
+		{
+			name:     "short year and month",
+			template: "{YY}{MM}/{SEQ}",
+			fields:   NumberFields{IssueDate: "2026-03-14", Seq: 7},
+			want:     "2603/7",
+		},
+		{
+			name:     "sequence wider than padding",
+			template: "{SEQ:2}",
+			fields:   NumberFields{IssueDate: "2026-03-14", Seq: 1234},
+			want:     "1234",
+		},
+		{
+			name:     "client code",
+			template: "{CLIENT}-{SEQ:3}",
+			fields:   NumberFields{IssueDate: "2026-03-14", Seq: 5, Client: "Acme & Sons Ltd."},
+			want:     "ACMESONS-005",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := RenderNumberTemplate(tc.template, tc.fields)
+			if got != tc.want {
+				t.Fatalf("RenderNumberTemplate() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNumberTemplatePeriod(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{template: "INV-{YYYY}-{SEQ:4}", want: "2026"},
+		{template: "{YY}-{SEQ}", want: "2026"},
+		{template: "{YYYY}{MM}-{SEQ}", want: "2026-03"},
+		{template: "{CLIENT}-{SEQ}", want: ""},
+	}
+
+	for _, tc := range tests {
+		if got := NumberTemplatePeriod(tc.template, "2026-03-14"); got != tc.want {
+			t.Fatalf("NumberTemplatePeriod(%q) = %q, want %q", tc.template, got, tc.want)
+		}
+	}
+}
+
+func TestValidateNumberTemplate(t *testing.T) {
+	valid := []string{"", "INV-{YYYY}-{SEQ:4}", "{CLIENT}/{YY}{MM}/{SEQ}"}
+	for _, template := range valid {
+		if err := ValidateNumberTemplate(template); err != nil {
+			t.Fatalf("ValidateNumberTemplate(%q) = %v, want nil", template, err)
+		}
+	}
+
+	invalid := []string{
+		"INV-{YYYY}",
+		"{SEQ}-{SEQ}",
+		"{SEQ:0}",
+		"{SEQ:10}",
+		"{DD}-{SEQ}",
+		"{YYYY:2}-{SEQ}",
+		"INV {SEQ}",
+		"INV.{SEQ}",
+		"{seq}",
+	}
+	for _, template := range invalid {
+		if err := ValidateNumberTemplate(template); err == nil {
+			t.Fatalf("ValidateNumberTemplate(%q) = nil, want error", template)
+		}
+	}
+}
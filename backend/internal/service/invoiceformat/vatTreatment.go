@@ -0,0 +1,72 @@
+package invoiceformat
+
+import "strings"
+
+// VAT treatments an invoice revision can be issued under. Anything other than
+// VATTreatmentStandard charges no VAT.
+const (
+	VATTreatmentStandard      = "standard"
+	VATTreatmentReverseCharge = "reverse_charge"
+	VATTreatmentExempt        = "exempt"
+	VATTreatmentOutsideScope  = "outside_scope"
+)
+
+var vatTreatments = map[string]struct {
+	label     string
+	statement string
+}{
+	VATTreatmentStandard: {},
+	VATTreatmentReverseCharge: {
+		label:     "reverse charge",
+		statement: "Reverse charge: the customer is liable to account for the VAT on this supply (Article 196, Council Directive 2006/112/EC).",
+	},
+	VATTreatmentExempt: {
+		label:     "exempt",
+		statement: "Exempt from VAT: no VAT has been charged on this supply.",
+	},
+	VATTreatmentOutsideScope: {
+		label:     "outside the scope",
+		statement: "Outside the scope of VAT: no VAT has been charged on this supply.",
+	},
+}
+
+// NormalizeVATTreatment trims and lower-cases treatment. Empty becomes
+// VATTreatmentStandard.
+func NormalizeVATTreatment(treatment string) string {
+	treatment = strings.ToLower(strings.TrimSpace(treatment))
+	if treatment == "" {
+		return VATTreatmentStandard
+	}
+	return treatment
+}
+
+// ValidVATTreatment reports whether treatment is a known VAT treatment.
+func ValidVATTreatment(treatment string) bool {
+	_, ok := vatTreatments[treatment]
+	return ok
+}
+
+// ChargesVAT reports whether invoices under treatment charge VAT.
+// Unknown treatments are treated as standard.
+func ChargesVAT(treatment string) bool {
+	return vatTreatments[treatment].statement == ""
+}
+
+// RequiresClientVATNumber reports whether treatment is only valid when the
+// client's VAT number is on the invoice. The customer accounts for the VAT
+// under the reverse charge, so their registration must be shown.
+func RequiresClientVATNumber(treatment string) bool {
+	return treatment == VATTreatmentReverseCharge
+}
+
+// VATTreatmentLabel is the short form shown next to the VAT total, e.g.
+// "reverse charge". Standard and unknown treatments have none.
+func VATTreatmentLabel(treatment string) string {
+	return vatTreatments[treatment].label
+}
+
+// VATTreatmentStatement is the wording an invoice must carry to explain why
+// no VAT was charged. Standard and unknown treatments have none.
+func VATTreatmentStatement(treatment string) string {
+	return vatTreatments[treatment].statement
+}
@@ -0,0 +1,55 @@
+package invoiceformat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeVATTreatment(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "", want: VATTreatmentStandard},
+		{in: "  Reverse_Charge ", want: VATTreatmentReverseCharge},
+		{in: "exempt", want: VATTreatmentExempt},
+		{in: "zero", want: "zero"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeVATTreatment(tt.in); got != tt.want {
+			t.Fatalf("NormalizeVATTreatment(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if ValidVATTreatment("zero") {
+		t.Fatal("expected unknown treatment to be invalid")
+	}
+}
+
+func TestVATTreatmentStatement(t *testing.T) {
+	if got := VATTreatmentStatement(VATTreatmentStandard); got != "" {
+		t.Fatalf("expected no statement for standard VAT, got %q", got)
+	}
+	if !ChargesVAT(VATTreatmentStandard) {
+		t.Fatal("expected standard VAT to be charged")
+	}
+
+	for _, treatment := range []string{VATTreatmentReverseCharge, VATTreatmentExempt, VATTreatmentOutsideScope} {
+		if !ValidVATTreatment(treatment) {
+			t.Fatalf("expected %q to be valid", treatment)
+		}
+		if ChargesVAT(treatment) {
+			t.Fatalf("expected %q to charge no VAT", treatment)
+		}
+		if VATTreatmentStatement(treatment) == "" || VATTreatmentLabel(treatment) == "" {
+			t.Fatalf("expected %q to have a label and statement", treatment)
+		}
+	}
+
+	if !strings.Contains(VATTreatmentStatement(VATTreatmentReverseCharge), "Article 196") {
+		t.Fatalf("unexpected reverse charge statement %q", VATTreatmentStatement(VATTreatmentReverseCharge))
+	}
+	if !RequiresClientVATNumber(VATTreatmentReverseCharge) || RequiresClientVATNumber(VATTreatmentExempt) {
+		t.Fatal("expected only the reverse charge to require the client VAT number")
+	}
+}
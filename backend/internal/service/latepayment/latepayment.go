@@ -0,0 +1,141 @@
+// Package latepayment works out statutory late payment charges on overdue
+// invoices: simple daily interest on the unpaid debt plus a one-off fixed
+// compensation sum picked from the workspace tiers.
+package latepayment
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// ErrInvalidDate is returned when a date is not YYYY-MM-DD.
+var ErrInvalidDate = errors.New("late payment dates must be YYYY-MM-DD")
+
+// Reduction lowers the outstanding debt from Date onwards (a receipt or credit
+// note). A negative amount, such as a refund, raises it again.
+type Reduction struct {
+	Date        string
+	AmountMinor int64
+}
+
+// Input describes one overdue invoice.
+type Input struct {
+	PrincipalMinor int64
+	DueDate        string
+	// AccrueFrom is the last day already charged; interest accrues from the
+	// day after. Empty means the due date.
+	AccrueFrom    string
+	AsOf          string
+	AnnualRateBps int64
+	Reductions    []Reduction
+}
+
+// Result is the interest accrued over (AccrueFrom, AsOf].
+type Result struct {
+	AccruedFrom      string
+	Days             int64
+	DebtAtDueMinor   int64
+	OutstandingMinor int64
+	InterestMinor    int64
+}
+
+// AccruedInterest charges simple interest for each day the debt stays unpaid
+// after AccrueFrom, at AnnualRateBps over a 365-day year. A reduction dated D
+// stops interest on that amount from D.
+func AccruedInterest(in Input) (Result, error) {
+	due, err := time.Parse(dateLayout, in.DueDate)
+	if err != nil {
+		return Result{}, ErrInvalidDate
+	}
+	asOf, err := time.Parse(dateLayout, in.AsOf)
+	if err != nil {
+		return Result{}, ErrInvalidDate
+	}
+	from := due
+	if in.AccrueFrom != "" {
+		f, err := time.Parse(dateLayout, in.AccrueFrom)
+		if err != nil {
+			return Result{}, ErrInvalidDate
+		}
+		if f.After(from) {
+			from = f
+		}
+	}
+
+	type step struct {
+		at     time.Time
+		amount int64
+	}
+	steps := make([]step, 0, len(in.Reductions))
+	for _, r := range in.Reductions {
+		at, err := time.Parse(dateLayout, r.Date)
+		if err != nil {
+			return Result{}, ErrInvalidDate
+		}
+		steps = append(steps, step{at: at, amount: r.AmountMinor})
+	}
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].at.Before(steps[j].at) })
+
+	out := Result{AccruedFrom: from.Format(dateLayout)}
+
+	balance := in.PrincipalMinor
+	debtAtDue := in.PrincipalMinor
+	var balanceDays int64 // sum of balance * days, in minor-unit days
+
+	// Day d bears interest on the balance left after reductions dated on or before d.
+	chargeFrom := from.AddDate(0, 0, 1)
+	accrue := func(until time.Time) {
+		days := daysBetween(chargeFrom, until)
+		if days > 0 && balance > 0 {
+			balanceDays += balance * days
+			out.Days += days
+		}
+		if until.After(chargeFrom) {
+			chargeFrom = until
+		}
+	}
+
+	for _, st := range steps {
+		if !st.at.After(due) {
+			debtAtDue -= st.amount
+		}
+		if st.at.After(asOf) {
+			break
+		}
+		if st.at.After(from) {
+			accrue(st.at)
+		}
+		balance -= st.amount
+	}
+	accrue(asOf.AddDate(0, 0, 1))
+
+	out.DebtAtDueMinor = max(debtAtDue, 0)
+	out.OutstandingMinor = max(balance, 0)
+	out.InterestMinor = int64(math.Round(float64(balanceDays) * float64(in.AnnualRateBps) / (10000.0 * 365.0)))
+	return out, nil
+}
+
+func daysBetween(from, to time.Time) int64 {
+	return int64(to.Sub(from).Hours() / 24)
+}
+
+// Compensation returns the fixed sum for a debt: the amount of the highest
+// tier whose FromMinor the debt reaches. Tiers must be sorted ascending.
+func Compensation(tiers []models.LateCompensationTier, debtMinor int64) int64 {
+	if debtMinor <= 0 {
+		return 0
+	}
+	var amount int64
+	for _, tier := range tiers {
+		if debtMinor >= tier.FromMinor {
+			amount = tier.AmountMinor
+		}
+	}
+	return amount
+}
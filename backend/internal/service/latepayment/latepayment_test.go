@@ -0,0 +1,95 @@
+package latepayment
+
+import (
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+func TestAccruedInterest(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           Input
+		wantDays     int64
+		wantInterest int64
+		wantOutstand int64
+	}{
+		{
+			// 10,000.00 at 12.25% for 30 days = 100.68
+			name:         "whole balance",
+			in:           Input{PrincipalMinor: 1000000, DueDate: "2026-03-01", AsOf: "2026-03-31", AnnualRateBps: 1225},
+			wantDays:     30,
+			wantInterest: 10068,
+			wantOutstand: 1000000,
+		},
+		{
+			name: "part payment stops interest on that amount",
+			in: Input{
+				PrincipalMinor: 365000, DueDate: "2026-03-01", AsOf: "2026-03-21", AnnualRateBps: 10000,
+				Reductions: []Reduction{{Date: "2026-03-11", AmountMinor: 182500}},
+			},
+			// 3650.00 for 9 days (2nd-10th) then 1825.00 for 11 days (11th-21st), at 100%.
+			wantDays:     20,
+			wantInterest: 9000 + 5500,
+			wantOutstand: 182500,
+		},
+		{
+			name: "payment before due date lowers the debt",
+			in: Input{
+				PrincipalMinor: 365000, DueDate: "2026-03-01", AsOf: "2026-03-11", AnnualRateBps: 10000,
+				Reductions: []Reduction{{Date: "2026-02-20", AmountMinor: 65000}},
+			},
+			wantDays:     10,
+			wantInterest: 8219,
+			wantOutstand: 300000,
+		},
+		{
+			name:         "resumes after last charge",
+			in:           Input{PrincipalMinor: 365000, DueDate: "2026-03-01", AccrueFrom: "2026-03-06", AsOf: "2026-03-11", AnnualRateBps: 10000},
+			wantDays:     5,
+			wantInterest: 5000,
+			wantOutstand: 365000,
+		},
+		{
+			name:         "not yet overdue",
+			in:           Input{PrincipalMinor: 365000, DueDate: "2026-03-01", AsOf: "2026-03-01", AnnualRateBps: 10000},
+			wantOutstand: 365000,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := AccruedInterest(tc.in)
+			if err != nil {
+				t.Fatalf("AccruedInterest() error = %v", err)
+			}
+			if got.Days != tc.wantDays || got.InterestMinor != tc.wantInterest || got.OutstandingMinor != tc.wantOutstand {
+				t.Fatalf("AccruedInterest() = %+v, want days %d interest %d outstanding %d",
+					got, tc.wantDays, tc.wantInterest, tc.wantOutstand)
+			}
+		})
+	}
+}
+
+func TestCompensation(t *testing.T) {
+	tiers := []models.LateCompensationTier{
+		{FromMinor: 0, AmountMinor: 4000},
+		{FromMinor: 100000, AmountMinor: 7000},
+		{FromMinor: 1000000, AmountMinor: 10000},
+	}
+
+	tests := []struct {
+		debt int64
+		want int64
+	}{
+		{debt: 0, want: 0},
+		{debt: 99999, want: 4000},
+		{debt: 100000, want: 7000},
+		{debt: 2500000, want: 10000},
+	}
+	for _, tc := range tests {
+		if got := Compensation(tiers, tc.debt); got != tc.want {
+			t.Fatalf("Compensation(%d) = %d, want %d", tc.debt, got, tc.want)
+		}
+	}
+}
@@ -16,18 +16,20 @@ import (
 	"github.com/johnfercher/maroto/v2/pkg/components/text"
 	"github.com/johnfercher/maroto/v2/pkg/config"
 	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/extension"
 	"github.com/johnfercher/maroto/v2/pkg/consts/orientation"
 	"github.com/johnfercher/maroto/v2/pkg/consts/pagesize"
 	"github.com/johnfercher/maroto/v2/pkg/core"
 	"github.com/johnfercher/maroto/v2/pkg/props"
 
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 )
 
 type MarotoRenderer struct{}
 
 func (m *MarotoRenderer) RenderPDF(ctx context.Context, doc models.InvoicePDFData) ([]byte, error) {
-	cfg := config.NewBuilder().
+	builder := config.NewBuilder().
 		WithOrientation(orientation.Vertical).
 		WithPageSize(pagesize.A4).
 		WithLeftMargin(14).
@@ -39,16 +41,23 @@ func (m *MarotoRenderer) RenderPDF(ctx context.Context, doc models.InvoicePDFDat
 			Place:   props.LeftBottom,
 			Size:    invoiceTheme.text.footer.Size,
 			Color:   invoiceTheme.text.footer.Color,
-		}).
-		Build()
+		})
+	if doc.Voided {
+		watermark, err := voidWatermark()
+		if err != nil {
+			return nil, fmt.Errorf("void watermark: %w", err)
+		}
+		builder = builder.WithBackgroundImage(watermark, extension.Png)
+	}
 
-	mr := maroto.New(cfg)
+	mr := maroto.New(builder.Build())
 
 	if err := registerFooter(mr, doc); err != nil {
 		return nil, fmt.Errorf("register footer: %w", err)
 	}
 
 	renderHeader(mr, doc)
+	renderVoidNotice(mr, doc)
 	renderMeta(mr, doc)
 	renderItemTable(mr, doc)
 	renderClosingBlocks(mr, doc)
@@ -109,11 +118,32 @@ func renderHeader(mr core.Maroto, doc models.InvoicePDFData) {
 	if supplyDate != "" {
 		renderHeaderMetaRow(mr, hasLogo, headerMetaValue("Supply", supplyDate))
 	}
-	renderHeaderMetaRow(mr, hasLogo, headerMetaValue("Due", dueDate))
+	renderHeaderMetaRow(mr, hasLogo, headerMetaValue(dueDateLabel(doc), dueDate))
 
 	mr.AddRow(invoiceTheme.space.lg)
 }
 
+// renderVoidNotice states why and when a void invoice was voided, under the
+// header where it cannot be missed.
+func renderVoidNotice(mr core.Maroto, doc models.InvoicePDFData) {
+	if !doc.Voided {
+		return
+	}
+
+	notice := "VOID"
+	if voidedAt := clean(doc.VoidedAt); voidedAt != "" {
+		notice += " since " + voidedAt
+	}
+	if reason := clean(doc.VoidReason); reason != "" {
+		notice += ": " + reason
+	}
+
+	mr.AddRow(invoiceTheme.row.voidNotice,
+		text.NewCol(12, notice, invoiceTheme.text.void),
+	)
+	mr.AddRow(invoiceTheme.space.md)
+}
+
 func renderMeta(mr core.Maroto, doc models.InvoicePDFData) {
 	clientName := clean(doc.Client.CompanyName)
 	if clientName == "" {
@@ -121,6 +151,9 @@ func renderMeta(mr core.Maroto, doc models.InvoicePDFData) {
 	}
 
 	left := buildPartyBlock("BILL TO", clientName, doc.Client.Address, clean(doc.Client.Email), "")
+	if vatNumber := clean(doc.Client.VATNumber); vatNumber != "" {
+		left.details = append(left.details, "VAT no. "+vatNumber)
+	}
 	right := buildPartyBlock("ISSUED BY", clean(doc.Issuer.CompanyName), doc.Issuer.CompanyAddress, clean(doc.Issuer.Email), clean(doc.Issuer.Phone))
 
 	leftRows := left.rows()
@@ -134,17 +167,28 @@ func renderMeta(mr core.Maroto, doc models.InvoicePDFData) {
 func renderItemTable(mr core.Maroto, doc models.InvoicePDFData) {
 	renderSectionLabel(mr, "Line Items")
 
+	showDiscount := hasLineDiscounts(doc.Lines)
+	descSize := 6
+	if showDiscount {
+		descSize = 5
+	}
+
+	headerCols := []core.Col{
+		text.NewCol(descSize, "Description", invoiceTheme.tableHeaderText(align.Left)),
+		text.NewCol(1, "Qty", invoiceTheme.tableHeaderText(align.Center)),
+		text.NewCol(1, "Time", invoiceTheme.tableHeaderText(align.Center)),
+		text.NewCol(1, "Rate", invoiceTheme.tableHeaderText(align.Right)),
+		text.NewCol(1, "Price", invoiceTheme.tableHeaderText(align.Right)),
+	}
+	if showDiscount {
+		headerCols = append(headerCols, text.NewCol(1, "Disc.", invoiceTheme.tableHeaderText(align.Right)))
+	}
+	headerCols = append(headerCols, text.NewCol(2, "Amount", invoiceTheme.tableHeaderText(align.Right)))
+
 	mr.AddRows(
 		row.New(invoiceTheme.row.tableHeader).
 			WithStyle(invoiceTheme.cell.tableHeader).
-			Add(
-				text.NewCol(6, "Description", invoiceTheme.tableHeaderText(align.Left)),
-				text.NewCol(1, "Qty", invoiceTheme.tableHeaderText(align.Center)),
-				text.NewCol(1, "Time", invoiceTheme.tableHeaderText(align.Center)),
-				text.NewCol(1, "Rate", invoiceTheme.tableHeaderText(align.Right)),
-				text.NewCol(1, "Price", invoiceTheme.tableHeaderText(align.Right)),
-				text.NewCol(2, "Amount", invoiceTheme.tableHeaderText(align.Right)),
-			),
+			Add(headerCols...),
 	)
 	renderFullDivider(mr, invoiceTheme.line.divider)
 
@@ -173,14 +217,18 @@ func renderItemTable(mr core.Maroto, doc models.InvoicePDFData) {
 		}
 
 		for i, ln := range group.Lines {
-			mr.AddAutoRow(
-				text.NewCol(6, clean(ln.Name), invoiceTheme.tableCellText(align.Left)),
+			cols := []core.Col{
+				text.NewCol(descSize, clean(ln.Name), invoiceTheme.tableCellText(align.Left)),
 				text.NewCol(1, clean(ln.Quantity), invoiceTheme.tableCellText(align.Center)),
 				text.NewCol(1, clean(ln.TimeWorked), invoiceTheme.tableCellText(align.Center)),
 				text.NewCol(1, clean(ln.HourlyRate), invoiceTheme.tableCellText(align.Right)),
 				text.NewCol(1, clean(ln.ItemPrice), invoiceTheme.tableCellText(align.Right)),
-				text.NewCol(2, clean(ln.ItemTotal), invoiceTheme.tableCellText(align.Right)),
-			)
+			}
+			if showDiscount {
+				cols = append(cols, text.NewCol(1, clean(ln.Discount), invoiceTheme.tableCellText(align.Right)))
+			}
+			cols = append(cols, text.NewCol(2, clean(ln.ItemTotal), invoiceTheme.tableCellText(align.Right)))
+			mr.AddAutoRow(cols...)
 			renderedRows++
 
 			if doc.ShowItemTypeHeaders && i < len(group.Lines)-1 {
@@ -197,7 +245,7 @@ func renderItemTable(mr core.Maroto, doc models.InvoicePDFData) {
 
 func renderClosingBlocks(mr core.Maroto, doc models.InvoicePDFData) {
 	totalRows := buildTotalRows(doc)
-	noteRows := buildNoteRows(doc.Note)
+	noteRows := append(buildVATStatementRows(doc.VATTreatment), buildNoteRows(doc.Note)...)
 	paymentSections := buildPaymentSections(doc)
 
 	renderTotalsBlock(mr, totalRows, noteRows)
@@ -311,15 +359,23 @@ func buildNoteRows(note *string) []styledTextLine {
 	return rows
 }
 
+// buildVATStatementRows is the statement explaining why no VAT was charged,
+// printed ahead of the invoice note.
+func buildVATStatementRows(treatment string) []styledTextLine {
+	statement := invoiceformat.VATTreatmentStatement(treatment)
+	if statement == "" {
+		return nil
+	}
+	return []styledTextLine{{text: statement, style: invoiceTheme.text.noteBody}}
+}
+
 func buildTotalRows(doc models.InvoicePDFData) []totalLine {
 	if doc.DocumentKind == "payment_receipt" {
 		rows := []totalLine{
 			newTotalLine("Payment Amount", formatMoney(doc.ReceiptAmountMinor, doc.Currency)),
 			newTotalLine("Invoice Total", formatMoney(doc.Totals.TotalMinor, doc.Currency)),
 		}
-		if doc.Totals.DepositMinor > 0 {
-			rows = append(rows, newTotalLine("Requested Deposit", formatMoney(doc.Totals.DepositMinor, doc.Currency)))
-		}
+		rows = append(rows, buildDepositTotalLines(doc, 1)...)
 		rows = append(rows, newTotalLine("Total Paid", formatMoney(doc.Totals.PaidMinor, doc.Currency)))
 		rows = append(rows, totalLine{
 			label:      "Balance Due",
@@ -332,6 +388,77 @@ func buildTotalRows(doc models.InvoicePDFData) []totalLine {
 		return rows
 	}
 
+	if doc.DocumentKind == "deposit_receipt" {
+		return []totalLine{
+			newTotalLine("Deposit Amount", formatMoney(doc.ReceiptAmountMinor, doc.Currency)),
+			newTotalLine("Invoice Total", formatMoney(doc.Totals.TotalMinor, doc.Currency)),
+			newTotalLine("Deposit Requested", formatMoney(doc.Totals.DepositMinor, doc.Currency)),
+			newTotalLine("Deposit Received", formatMoney(doc.DepositPaidMinor, doc.Currency)),
+			newTotalLine("Total Paid", formatMoney(doc.Totals.PaidMinor, doc.Currency)),
+			{
+				label:      "Balance Due",
+				value:      formatMoney(doc.Totals.BalanceDue, doc.Currency),
+				labelStyle: invoiceTheme.balanceLabelText(),
+				valueStyle: invoiceTheme.balanceValueText(),
+				cellStyle:  invoiceTheme.cell.balance,
+				ruleAbove:  true,
+			},
+		}
+	}
+
+	if doc.DocumentKind == "refund" {
+		return []totalLine{
+			newTotalLine("Refund Amount", formatMoney(doc.ReceiptAmountMinor, doc.Currency)),
+			newTotalLine("Invoice Total", formatMoney(doc.Totals.TotalMinor, doc.Currency)),
+			newTotalLine("Total Paid", formatMoney(doc.Totals.PaidMinor, doc.Currency)),
+			{
+				label:      "Balance Due",
+				value:      formatMoney(doc.Totals.BalanceDue, doc.Currency),
+				labelStyle: invoiceTheme.balanceLabelText(),
+				valueStyle: invoiceTheme.balanceValueText(),
+				cellStyle:  invoiceTheme.cell.balance,
+				ruleAbove:  true,
+			},
+		}
+	}
+
+	if doc.DocumentKind == "credit_note" {
+		return []totalLine{
+			newTotalLine("Subtotal", formatMoney(doc.Totals.SubtotalMinor, doc.Currency)),
+			newTotalLine("VAT", formatMoney(doc.Totals.VatAmountMinor, doc.Currency)),
+			{
+				label:      "Total Credit",
+				value:      formatMoney(doc.Totals.TotalMinor, doc.Currency),
+				labelStyle: invoiceTheme.balanceLabelText(),
+				valueStyle: invoiceTheme.balanceValueText(),
+				cellStyle:  invoiceTheme.cell.balance,
+				ruleAbove:  true,
+			},
+		}
+	}
+
+	if doc.DocumentKind == "quote" {
+		rows := []totalLine{
+			newTotalLine("Subtotal", formatMoney(doc.Totals.SubtotalMinor, doc.Currency)),
+		}
+		if doc.Totals.DiscountMinor > 0 {
+			rows = append(rows, newTotalLine("Discount", formatMoney(-doc.Totals.DiscountMinor, doc.Currency)))
+		}
+		rows = append(rows, buildVATTotalLines(doc)...)
+		if doc.Totals.DepositMinor > 0 {
+			rows = append(rows, newTotalLine("Deposit on Acceptance", formatMoney(doc.Totals.DepositMinor, doc.Currency)))
+		}
+		rows = append(rows, totalLine{
+			label:      "Quote Total",
+			value:      formatMoney(doc.Totals.TotalMinor, doc.Currency),
+			labelStyle: invoiceTheme.balanceLabelText(),
+			valueStyle: invoiceTheme.balanceValueText(),
+			cellStyle:  invoiceTheme.cell.balance,
+			ruleAbove:  true,
+		})
+		return rows
+	}
+
 	rows := []totalLine{
 		newTotalLine("Subtotal", formatMoney(doc.Totals.SubtotalMinor, doc.Currency)),
 	}
@@ -340,16 +467,21 @@ func buildTotalRows(doc models.InvoicePDFData) []totalLine {
 		rows = append(rows, newTotalLine("Discount", formatMoney(-doc.Totals.DiscountMinor, doc.Currency)))
 	}
 
-	rows = append(rows,
-		newTotalLine("VAT", formatMoney(doc.Totals.VatAmountMinor, doc.Currency)),
-		newTotalLine("Total", formatMoney(doc.Totals.TotalMinor, doc.Currency)),
-	)
+	rows = append(rows, buildVATTotalLines(doc)...)
+	rows = append(rows, newTotalLine("Total", formatMoney(doc.Totals.TotalMinor, doc.Currency)))
+	if doc.Totals.DeductionMinor > 0 {
+		rows = append(rows,
+			newTotalLine(deductionLabel(doc.Totals.DeductionRate), formatMoney(-doc.Totals.DeductionMinor, doc.Currency)),
+			newTotalLine("Amount Payable", formatMoney(doc.Totals.TotalMinor-doc.Totals.DeductionMinor, doc.Currency)),
+		)
+	}
 
-	if doc.Totals.DepositMinor > 0 {
-		rows = append(rows, newTotalLine("Requested Deposit", formatMoney(doc.Totals.DepositMinor, doc.Currency)))
+	rows = append(rows, buildDepositTotalLines(doc, -1)...)
+	if paid := doc.Totals.PaidMinor - doc.DepositPaidMinor; paid > 0 {
+		rows = append(rows, newTotalLine("Paid", formatMoney(-paid, doc.Currency)))
 	}
-	if doc.Totals.PaidMinor > 0 {
-		rows = append(rows, newTotalLine("Paid", formatMoney(-doc.Totals.PaidMinor, doc.Currency)))
+	if doc.CreditedMinor > 0 {
+		rows = append(rows, newTotalLine("Credited", formatMoney(-doc.CreditedMinor, doc.Currency)))
 	}
 
 	rows = append(rows, totalLine{
@@ -364,6 +496,58 @@ func buildTotalRows(doc models.InvoicePDFData) []totalLine {
 	return rows
 }
 
+// buildDepositTotalLines shows the requested deposit until it has been fully
+// received, and what was received against it. Received deposits are signed
+// like the surrounding paid rows.
+func buildDepositTotalLines(doc models.InvoicePDFData, sign int64) []totalLine {
+	var rows []totalLine
+	if doc.Totals.DepositMinor > 0 && doc.DepositPaidMinor < doc.Totals.DepositMinor {
+		rows = append(rows, newTotalLine("Deposit Requested", formatMoney(doc.Totals.DepositMinor, doc.Currency)))
+	}
+	if doc.DepositPaidMinor > 0 {
+		rows = append(rows, newTotalLine("Deposit Received", formatMoney(sign*doc.DepositPaidMinor, doc.Currency)))
+	}
+	return rows
+}
+
+// buildVATTotalLines shows a single VAT row, or one row per rate when the
+// invoice mixes VAT rates.
+func buildVATTotalLines(doc models.InvoicePDFData) []totalLine {
+	bands := doc.Totals.VATBreakdown
+	if len(bands) <= 1 {
+		return []totalLine{newTotalLine(vatTotalLabel(doc.VATTreatment), formatMoney(doc.Totals.VatAmountMinor, doc.Currency))}
+	}
+
+	rows := make([]totalLine, 0, len(bands))
+	for _, band := range bands {
+		label := fmt.Sprintf("VAT %s on %s", invoiceformat.FormatRateBps(band.VATRate), formatMoney(band.NetMinor, doc.Currency))
+		rows = append(rows, newTotalLine(label, formatMoney(band.VatMinor, doc.Currency)))
+	}
+	return rows
+}
+
+// deductionLabel names the row for the amount the client withholds, e.g.
+// "Deduction 20%".
+func deductionLabel(rateBps int64) string {
+	return "Deduction " + invoiceformat.FormatRateBps(rateBps)
+}
+
+// vatTotalLabel names the VAT row, e.g. "VAT (reverse charge)" when the
+// treatment charges no VAT.
+func vatTotalLabel(treatment string) string {
+	if label := invoiceformat.VATTreatmentLabel(treatment); label != "" {
+		return "VAT (" + label + ")"
+	}
+	return "VAT"
+}
+
+func dueDateLabel(doc models.InvoicePDFData) string {
+	if doc.DocumentKind == "quote" {
+		return "Valid until"
+	}
+	return "Due"
+}
+
 func newTotalLine(label, value string) totalLine {
 	return totalLine{
 		label:      label,
@@ -640,6 +824,15 @@ func groupInvoicePDFItems(lines []models.InvoicePDFItem) []itemGroup {
 	return groups
 }
 
+func hasLineDiscounts(lines []models.InvoicePDFItem) bool {
+	for _, ln := range lines {
+		if clean(ln.Discount) != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func resolveLocalLogoPath(v string) (string, bool) {
 	v = strings.TrimSpace(v)
 	if v == "" {
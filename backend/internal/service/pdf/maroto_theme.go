@@ -29,6 +29,7 @@ type marotoTheme struct {
 		paymentLabel props.Text
 		paymentBody  props.Text
 		footer       props.Text
+		void         props.Text
 	}
 	line struct {
 		soft    props.Line
@@ -59,6 +60,7 @@ type marotoTheme struct {
 		tableHeader  float64
 		groupLabel   float64
 		footerRule   float64
+		voidNotice   float64
 	}
 }
 
@@ -72,6 +74,7 @@ func newMarotoTheme() marotoTheme {
 	ruleStrong := &props.Color{Red: 188, Green: 188, Blue: 188}
 	panelSoft := &props.Color{Red: 244, Green: 244, Blue: 244}
 	panelStrong := &props.Color{Red: 235, Green: 235, Blue: 235}
+	alert := &props.Color{Red: 176, Green: 32, Blue: 32}
 
 	t.text.title = props.Text{
 		Size:  25,
@@ -215,6 +218,13 @@ func newMarotoTheme() marotoTheme {
 		Color: inkBody,
 		Top:   0.9,
 	}
+	t.text.void = props.Text{
+		Size:  10,
+		Style: fontstyle.Bold,
+		Align: align.Right,
+		Color: alert,
+		Top:   1,
+	}
 
 	t.line.soft = props.Line{Color: ruleSoft, Thickness: 0.14, OffsetPercent: 50, SizePercent: 100}
 	t.line.divider = props.Line{Color: ruleSoft, Thickness: 0.24, OffsetPercent: 50, SizePercent: 100}
@@ -238,6 +248,7 @@ func newMarotoTheme() marotoTheme {
 	t.row.headerText = 5.4
 	t.row.headerMeta = 4.8
 	t.row.sectionLabel = 4.5
+	t.row.voidNotice = 6.5
 	t.row.tableHeader = 7.5
 	t.row.groupLabel = 5
 	t.row.footerRule = 1.6
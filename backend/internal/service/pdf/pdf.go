@@ -11,6 +11,7 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 	"github.com/viktorHadz/goInvoice26/internal/service/storage"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/quoteTx"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
@@ -54,22 +55,24 @@ func BuildInvoiceFromDB(
 		return models.InvoicePDFData{}, fmt.Errorf("get settings: %w", err)
 	}
 
+	currency := invoiceCurrency(overview, settings)
 	lines := make([]models.InvoicePDFItem, 0, len(rawItems))
 	for _, it := range rawItems {
 		pricingMode := ""
 		if it.PricingMode != nil {
 			pricingMode = *it.PricingMode
 		}
-		pricing := buildInvoicePDFPricing(pricingMode, it.UnitPriceMin, it.MinutesWorked, settings.Currency)
+		pricing := buildInvoicePDFPricing(pricingMode, it.UnitPriceMin, it.MinutesWorked, currency)
 
 		lines = append(lines, models.InvoicePDFItem{
 			Name:       it.Name,
 			LineType:   it.LineType,
-			Quantity:   formatQuantity(it.Quantity),
+			Quantity:   formatQuantity(it.Quantity, it.Unit),
 			ItemPrice:  pricing.itemPrice,
 			TimeWorked: pricing.timeWorked,
 			HourlyRate: pricing.hourlyRate,
-			ItemTotal:  formatMoney(it.LineTotalMin, settings.Currency),
+			ItemTotal:  formatMoney(it.LineTotalMin, currency),
+			Discount:   formatLineDiscount(it.DiscountType, it.DiscountRate, it.DiscountMinor, currency),
 			SortOrder:  it.SortOrder,
 		})
 	}
@@ -110,8 +113,7 @@ func BuildPaymentReceiptFromDB(
 		SELECT COALESCE(SUM(amount_minor), 0)
 		FROM payments
 		WHERE applied_in_revision_id = ?
-		  AND payment_type = 'payment'
-		  AND receipt_no <= ?;
+		  AND (payment_type = 'deposit' OR receipt_no <= ?);
 	`, receipt.AppliedRevisionID, receipt.ReceiptNo).Scan(&paidUpToReceipt); err != nil {
 		return models.InvoicePDFData{}, fmt.Errorf("sum payment receipts to receipt number: %w", err)
 	}
@@ -119,32 +121,142 @@ func BuildPaymentReceiptFromDB(
 	return buildPaymentReceiptPDFData(overview, receipt, paidUpToReceipt, settings), nil
 }
 
+func BuildDepositReceiptFromDB(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNo int64,
+	depositNo int64,
+) (models.InvoicePDFData, error) {
+	receipt, err := invoiceTx.QueryDepositReceiptByNumber(ctx, db, clientID, baseNo, depositNo)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get deposit receipt: %w", err)
+	}
+
+	overview, err := invoiceTx.QueryInvoiceSummary(ctx, db, clientID, baseNo, receipt.AppliedRevisionNo)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get deposit receipt invoice summary: %w", err)
+	}
+
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get account scope: %w", err)
+	}
+
+	settings, err := settingsTx.Get(ctx, db, accountID)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get settings: %w", err)
+	}
+
+	// Deposits up to this one, plus payment receipts taken no later than it.
+	var depositsUpToReceipt, paidUpToReceipt int64
+	if err := db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE(SUM(CASE WHEN payment_type = 'deposit' THEN amount_minor ELSE 0 END), 0),
+			COALESCE(SUM(amount_minor), 0)
+		FROM payments
+		WHERE applied_in_revision_id = ?
+		  AND (
+			(payment_type = 'deposit' AND receipt_no <= ?)
+			OR (payment_type = 'payment' AND payment_date <= ?)
+		  );
+	`, receipt.AppliedRevisionID, receipt.ReceiptNo, receipt.PaymentDate).Scan(&depositsUpToReceipt, &paidUpToReceipt); err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("sum payments to deposit receipt number: %w", err)
+	}
+
+	return buildDepositReceiptPDFData(overview, receipt, depositsUpToReceipt, paidUpToReceipt, settings), nil
+}
+
+func BuildCreditNoteFromDB(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNo int64,
+	creditNoteNo int64,
+) (models.InvoicePDFData, error) {
+	note, err := invoiceTx.QueryCreditNoteByNumber(ctx, db, clientID, baseNo, creditNoteNo)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get credit note: %w", err)
+	}
+
+	overview, err := invoiceTx.QueryInvoiceSummary(ctx, db, clientID, baseNo, note.RevisionNo)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get credit note invoice summary: %w", err)
+	}
+
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get account scope: %w", err)
+	}
+
+	settings, err := settingsTx.Get(ctx, db, accountID)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get settings: %w", err)
+	}
+
+	return buildCreditNotePDFData(overview, note, settings), nil
+}
+
+func BuildRefundFromDB(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNo int64,
+	refundNo int64,
+) (models.InvoicePDFData, error) {
+	refund, err := invoiceTx.QueryRefundByNumber(ctx, db, clientID, baseNo, refundNo)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get refund: %w", err)
+	}
+
+	overview, err := invoiceTx.QueryInvoiceSummary(ctx, db, clientID, baseNo, refund.AppliedRevisionNo)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get refund invoice summary: %w", err)
+	}
+
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get account scope: %w", err)
+	}
+
+	settings, err := settingsTx.Get(ctx, db, accountID)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get settings: %w", err)
+	}
+
+	var paidAfterRefund int64
+	if err := db.QueryRowContext(ctx, `
+		SELECT
+			COALESCE((
+				SELECT SUM(amount_minor)
+				FROM payments
+				WHERE applied_in_revision_id = ?
+				  AND payment_type IN ('payment','deposit')
+			), 0) - COALESCE((
+				SELECT SUM(amount_minor)
+				FROM payment_refunds
+				WHERE applied_in_revision_id = ?
+				  AND refund_no <= ?
+			), 0);
+	`, refund.AppliedRevisionID, refund.AppliedRevisionID, refund.RefundNo).Scan(&paidAfterRefund); err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("sum payments net of refunds to refund number: %w", err)
+	}
+
+	return buildRefundPDFData(overview, refund, paidAfterRefund, settings), nil
+}
+
 // BuildQuickInvoice builds a PDF from in-memory invoice data without saving to DB.
 func BuildQuickInvoice(
 	invoice models.FEInvoiceIn,
 	settings models.Settings,
 	revisionNo int64,
+	numberLabel string,
 ) models.InvoicePDFData {
-	lines := make([]models.InvoicePDFItem, 0, len(invoice.Lines))
-	for _, line := range invoice.Lines {
-		pricing := buildInvoicePDFPricing(
-			line.PricingMode,
-			line.UnitPriceMinor,
-			line.MinutesWorked,
-			settings.Currency,
-		)
-
-		lines = append(lines, models.InvoicePDFItem{
-			Name:       line.Name,
-			LineType:   line.LineType,
-			Quantity:   formatQuantity(line.Quantity),
-			ItemPrice:  pricing.itemPrice,
-			TimeWorked: pricing.timeWorked,
-			HourlyRate: pricing.hourlyRate,
-			ItemTotal:  formatMoney(line.LineTotalMinor, settings.Currency),
-			SortOrder:  line.SortOrder,
-		})
+	currency := settings.Currency
+	if invoice.Overview.Currency != "" {
+		currency = invoice.Overview.Currency
 	}
+	lines := buildPDFItemsFromLines(invoice.Lines, invoiceformat.NormalizeCurrency(currency))
 
 	var dueByDate sql.NullString
 	if invoice.Overview.DueByDate != nil {
@@ -164,6 +276,7 @@ func BuildQuickInvoice(
 
 	overview := &invoiceTx.InvoiceOverviewTotals{
 		BaseNumber:        invoice.Overview.BaseNumber,
+		NumberLabel:       sql.NullString{String: numberLabel, Valid: numberLabel != ""},
 		RevisionNo:        revisionNo,
 		IssueDate:         invoice.Overview.IssueDate,
 		SupplyDate:        nullStringFromPointer(invoice.Overview.SupplyDate),
@@ -172,8 +285,11 @@ func BuildQuickInvoice(
 		ClientCompanyName: invoice.Overview.ClientCompanyName,
 		ClientAddress:     invoice.Overview.ClientAddress,
 		ClientEmail:       invoice.Overview.ClientEmail,
+		ClientVATNumber:   invoice.Overview.ClientVATNumber,
 		Note:              note,
+		Currency:          invoice.Overview.Currency,
 
+		VATTreatment:  invoice.Overview.VATTreatment,
 		VATRate:       invoice.Totals.VATRate,
 		VATAmountMin:  invoice.Totals.VatAmountMinor,
 		DiscountType:  invoice.Totals.DiscountType,
@@ -185,11 +301,67 @@ func BuildQuickInvoice(
 		SubtotalMinor: invoice.Totals.SubtotalMinor,
 		TotalMinor:    invoice.Totals.TotalMinor,
 		PaidMinor:     invoice.Totals.PaidMinor,
+		VATBreakdown:  invoiceTx.VATBreakdownOrDefault(invoice.Totals),
+
+		DeductionRate:      invoice.Totals.DeductionRate,
+		DeductionLineTypes: invoice.Totals.DeductionLineTypes,
+		DeductionMinor:     invoice.Totals.DeductionMinor,
 	}
 
 	return buildInvoicePDFData(overview, lines, settings)
 }
 
+// BuildQuoteFromDB builds a quote document through the invoice builder so
+// quotes and invoices share one layout.
+func BuildQuoteFromDB(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	quoteNo int64,
+) (models.InvoicePDFData, error) {
+	quote, err := quoteTx.Get(ctx, db, clientID, quoteNo)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get quote: %w", err)
+	}
+
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get account scope: %w", err)
+	}
+
+	settings, err := settingsTx.Get(ctx, db, accountID)
+	if err != nil {
+		return models.InvoicePDFData{}, fmt.Errorf("get settings: %w", err)
+	}
+
+	return buildQuotePDFData(quote, settings), nil
+}
+
+func buildPDFItemsFromLines(in []models.LineCreateIn, currency string) []models.InvoicePDFItem {
+	lines := make([]models.InvoicePDFItem, 0, len(in))
+	for _, line := range in {
+		pricing := buildInvoicePDFPricing(
+			line.PricingMode,
+			line.UnitPriceMinor,
+			line.MinutesWorked,
+			currency,
+		)
+
+		lines = append(lines, models.InvoicePDFItem{
+			Name:       line.Name,
+			LineType:   line.LineType,
+			Quantity:   formatQuantity(line.Quantity, line.Unit),
+			ItemPrice:  pricing.itemPrice,
+			TimeWorked: pricing.timeWorked,
+			HourlyRate: pricing.hourlyRate,
+			ItemTotal:  formatMoney(line.LineTotalMinor, currency),
+			Discount:   formatLineDiscount(line.DiscountType, line.DiscountRate, line.DiscountMinor, currency),
+			SortOrder:  line.SortOrder,
+		})
+	}
+	return lines
+}
+
 func buildInvoicePDFData(
 	o *invoiceTx.InvoiceOverviewTotals,
 	lines []models.InvoicePDFItem,
@@ -213,7 +385,7 @@ func buildInvoicePDFData(
 	}
 
 	subtotalAfterDisc := o.SubtotalMinor - o.DiscountMinor
-	balanceDue := o.TotalMinor - o.PaidMinor
+	balanceDue := o.TotalMinor - o.DeductionMinor - o.PaidMinor - o.CreditedMinor
 	if balanceDue < 0 {
 		balanceDue = 0
 	}
@@ -223,11 +395,19 @@ func buildInvoicePDFData(
 		logoPath = storage.NewLocalStore(storage.DefaultRootDir).Path(s.LogoStorageKey)
 	}
 
+	voided := o.Status == "void"
+	voidedAt := ""
+	if voided && len(o.VoidedAt.String) >= len("2006-01-02") {
+		voidedAt = formatDate(o.VoidedAt.String[:len("2006-01-02")], s.DateFormat)
+	}
+
 	return models.InvoicePDFData{
 		DocumentKind:        "invoice",
 		Title:               "Invoice",
-		InvoiceNumberLabel:  invoiceformat.FormatInvoiceNumber(s.InvoicePrefix, o.BaseNumber, o.RevisionNo),
-		Currency:            fallbackCurrency(s.Currency),
+		InvoiceNumberLabel:  invoiceNumbering(o, s).Invoice(o.RevisionNo),
+		CreditedMinor:       o.CreditedMinor,
+		DepositPaidMinor:    o.DepositPaidMinor,
+		Currency:            invoiceCurrency(o, s),
 		ShowItemTypeHeaders: s.ShowItemTypeHeaders,
 
 		IssueAt:    formatDate(o.IssueDate, s.DateFormat),
@@ -235,6 +415,8 @@ func buildInvoicePDFData(
 		DueDate:    dueDate,
 		Note:       note,
 
+		VATTreatment: o.VATTreatment,
+
 		Issuer: models.InvoicePDFIssuer{
 			CompanyName:    s.CompanyName,
 			Email:          s.Email,
@@ -247,6 +429,7 @@ func buildInvoicePDFData(
 			CompanyName: o.ClientCompanyName,
 			Address:     o.ClientAddress,
 			Email:       o.ClientEmail,
+			VATNumber:   o.ClientVATNumber,
 		},
 		Lines: lines,
 		Totals: models.TotalsCreateIn{
@@ -263,10 +446,20 @@ func buildInvoicePDFData(
 			SubtotalMinor:     o.SubtotalMinor,
 			TotalMinor:        o.TotalMinor,
 			BalanceDue:        balanceDue,
+			VATBreakdown:      o.VATBreakdown,
+
+			DeductionRate:      o.DeductionRate,
+			DeductionLineTypes: o.DeductionLineTypes,
+			DeductionMinor:     o.DeductionMinor,
+			PayableMinor:       o.TotalMinor - o.DeductionMinor,
 		},
 		PaymentTerms:   s.PaymentTerms,
 		PaymentDetails: s.PaymentDetails,
 		NotesFooter:    s.NotesFooter,
+
+		Voided:     voided,
+		VoidReason: o.VoidReason.String,
+		VoidedAt:   voidedAt,
 	}
 }
 
@@ -276,10 +469,12 @@ func buildPaymentReceiptPDFData(
 	paidUpToReceipt int64,
 	s models.Settings,
 ) models.InvoicePDFData {
-	referenceNumberLabel := invoiceformat.FormatInvoiceNumber(s.InvoicePrefix, o.BaseNumber, receipt.AppliedRevisionNo)
-	receiptNumberLabel := invoiceformat.FormatPaymentReceiptNumber(s.InvoicePrefix, o.BaseNumber, receipt.AppliedRevisionNo, receipt.ReceiptNo)
+	currency := invoiceCurrency(o, s)
+	numbering := invoiceNumbering(o, s)
+	referenceNumberLabel := numbering.Invoice(receipt.AppliedRevisionNo)
+	receiptNumberLabel := numbering.PaymentReceipt(receipt.AppliedRevisionNo, receipt.ReceiptNo)
 
-	balanceDue := o.TotalMinor - paidUpToReceipt
+	balanceDue := o.TotalMinor - o.DeductionMinor - paidUpToReceipt
 	if balanceDue < 0 {
 		balanceDue = 0
 	}
@@ -294,8 +489,8 @@ func buildPaymentReceiptPDFData(
 			Name:      fmt.Sprintf("Payment received for %s", referenceNumberLabel),
 			LineType:  "custom",
 			Quantity:  "1",
-			ItemPrice: formatMoney(receipt.AmountMinor, s.Currency),
-			ItemTotal: formatMoney(receipt.AmountMinor, s.Currency),
+			ItemPrice: formatMoney(receipt.AmountMinor, currency),
+			ItemTotal: formatMoney(receipt.AmountMinor, currency),
 			SortOrder: 1,
 		},
 	}
@@ -307,6 +502,12 @@ func buildPaymentReceiptPDFData(
 	}
 
 	paymentDetails := fmt.Sprintf("Reference invoice: %s", referenceNumberLabel)
+	if receipt.PaymentMethod.Valid && receipt.PaymentMethod.String != "" {
+		paymentDetails += "\nPayment method: " + invoiceformat.FormatPaymentMethod(receipt.PaymentMethod.String)
+	}
+	if receipt.Reference.Valid && receipt.Reference.String != "" {
+		paymentDetails += "\nPayment reference: " + receipt.Reference.String
+	}
 	if note != nil {
 		paymentDetails += "\nReceipt note: " + *note
 	}
@@ -317,7 +518,8 @@ func buildPaymentReceiptPDFData(
 		InvoiceNumberLabel:   receiptNumberLabel,
 		ReferenceNumberLabel: referenceNumberLabel,
 		ReceiptAmountMinor:   receipt.AmountMinor,
-		Currency:             fallbackCurrency(s.Currency),
+		DepositPaidMinor:     o.DepositPaidMinor,
+		Currency:             currency,
 		ShowItemTypeHeaders:  false,
 
 		IssueAt: formatDate(receipt.PaymentDate, s.DateFormat),
@@ -335,6 +537,7 @@ func buildPaymentReceiptPDFData(
 			CompanyName: o.ClientCompanyName,
 			Address:     o.ClientAddress,
 			Email:       o.ClientEmail,
+			VATNumber:   o.ClientVATNumber,
 		},
 		Lines: lines,
 		Totals: models.TotalsCreateIn{
@@ -351,6 +554,282 @@ func buildPaymentReceiptPDFData(
 	}
 }
 
+func buildDepositReceiptPDFData(
+	o *invoiceTx.InvoiceOverviewTotals,
+	receipt *invoiceTx.PaymentReceiptRow,
+	depositsUpToReceipt int64,
+	paidUpToReceipt int64,
+	s models.Settings,
+) models.InvoicePDFData {
+	currency := invoiceCurrency(o, s)
+	numbering := invoiceNumbering(o, s)
+	referenceNumberLabel := numbering.Invoice(receipt.AppliedRevisionNo)
+	depositNumberLabel := numbering.DepositReceipt(receipt.ReceiptNo)
+
+	balanceDue := max(o.TotalMinor-o.DeductionMinor-paidUpToReceipt, 0)
+
+	logoPath := ""
+	if s.LogoStorageKey != "" {
+		logoPath = storage.NewLocalStore(storage.DefaultRootDir).Path(s.LogoStorageKey)
+	}
+
+	lines := []models.InvoicePDFItem{
+		{
+			Name:      fmt.Sprintf("Deposit received for %s", referenceNumberLabel),
+			LineType:  "custom",
+			Quantity:  "1",
+			ItemPrice: formatMoney(receipt.AmountMinor, currency),
+			ItemTotal: formatMoney(receipt.AmountMinor, currency),
+			SortOrder: 1,
+		},
+	}
+
+	var note *string
+	if receipt.Label.Valid && receipt.Label.String != "" {
+		value := receipt.Label.String
+		note = &value
+	}
+
+	paymentDetails := fmt.Sprintf("Reference invoice: %s", referenceNumberLabel)
+	if receipt.PaymentMethod.Valid && receipt.PaymentMethod.String != "" {
+		paymentDetails += "\nPayment method: " + invoiceformat.FormatPaymentMethod(receipt.PaymentMethod.String)
+	}
+	if receipt.Reference.Valid && receipt.Reference.String != "" {
+		paymentDetails += "\nPayment reference: " + receipt.Reference.String
+	}
+	if note != nil {
+		paymentDetails += "\nReceipt note: " + *note
+	}
+
+	return models.InvoicePDFData{
+		DocumentKind:         "deposit_receipt",
+		Title:                "Deposit Receipt",
+		InvoiceNumberLabel:   depositNumberLabel,
+		ReferenceNumberLabel: referenceNumberLabel,
+		ReceiptAmountMinor:   receipt.AmountMinor,
+		DepositPaidMinor:     depositsUpToReceipt,
+		Currency:             currency,
+		ShowItemTypeHeaders:  false,
+
+		IssueAt: formatDate(receipt.PaymentDate, s.DateFormat),
+		Note:    note,
+
+		Issuer: models.InvoicePDFIssuer{
+			CompanyName:    s.CompanyName,
+			Email:          s.Email,
+			Phone:          s.Phone,
+			CompanyAddress: s.CompanyAddress,
+			LogoPath:       logoPath,
+		},
+		Client: models.CreateClient{
+			Name:        o.ClientName,
+			CompanyName: o.ClientCompanyName,
+			Address:     o.ClientAddress,
+			Email:       o.ClientEmail,
+			VATNumber:   o.ClientVATNumber,
+		},
+		Lines: lines,
+		Totals: models.TotalsCreateIn{
+			DepositType:   o.DepositType,
+			DepositRate:   o.DepositRate,
+			DepositMinor:  o.DepositMinor,
+			PaidMinor:     paidUpToReceipt,
+			SubtotalMinor: receipt.AmountMinor,
+			TotalMinor:    o.TotalMinor,
+			BalanceDue:    balanceDue,
+		},
+		PaymentDetails: paymentDetails,
+		NotesFooter:    s.NotesFooter,
+	}
+}
+
+func buildRefundPDFData(
+	o *invoiceTx.InvoiceOverviewTotals,
+	refund *invoiceTx.RefundRow,
+	paidAfterRefund int64,
+	s models.Settings,
+) models.InvoicePDFData {
+	currency := invoiceCurrency(o, s)
+	numbering := invoiceNumbering(o, s)
+	referenceNumberLabel := numbering.Invoice(refund.AppliedRevisionNo)
+	receiptNumberLabel := numbering.PaymentReceipt(refund.AppliedRevisionNo, refund.ReceiptNo)
+	refundNumberLabel := numbering.Refund(refund.RefundNo)
+
+	balanceDue := max(o.TotalMinor-o.DeductionMinor-paidAfterRefund-o.CreditedMinor, 0)
+
+	logoPath := ""
+	if s.LogoStorageKey != "" {
+		logoPath = storage.NewLocalStore(storage.DefaultRootDir).Path(s.LogoStorageKey)
+	}
+
+	lines := []models.InvoicePDFItem{
+		{
+			Name:      fmt.Sprintf("Refund of payment %s", receiptNumberLabel),
+			LineType:  "custom",
+			Quantity:  "1",
+			ItemPrice: formatMoney(refund.AmountMinor, currency),
+			ItemTotal: formatMoney(refund.AmountMinor, currency),
+			SortOrder: 1,
+		},
+	}
+
+	var reason *string
+	if refund.Reason.Valid && refund.Reason.String != "" {
+		value := refund.Reason.String
+		reason = &value
+	}
+
+	paymentDetails := fmt.Sprintf("Reference invoice: %s\nRefunded receipt: %s", referenceNumberLabel, receiptNumberLabel)
+
+	return models.InvoicePDFData{
+		DocumentKind:         "refund",
+		Title:                "Refund",
+		InvoiceNumberLabel:   refundNumberLabel,
+		ReferenceNumberLabel: referenceNumberLabel,
+		ReceiptAmountMinor:   refund.AmountMinor,
+		Currency:             currency,
+		ShowItemTypeHeaders:  false,
+
+		IssueAt: formatDate(refund.RefundDate, s.DateFormat),
+		Note:    reason,
+
+		Issuer: models.InvoicePDFIssuer{
+			CompanyName:    s.CompanyName,
+			Email:          s.Email,
+			Phone:          s.Phone,
+			CompanyAddress: s.CompanyAddress,
+			LogoPath:       logoPath,
+		},
+		Client: models.CreateClient{
+			Name:        o.ClientName,
+			CompanyName: o.ClientCompanyName,
+			Address:     o.ClientAddress,
+			Email:       o.ClientEmail,
+			VATNumber:   o.ClientVATNumber,
+		},
+		Lines: lines,
+		Totals: models.TotalsCreateIn{
+			PaidMinor:     paidAfterRefund,
+			SubtotalMinor: refund.AmountMinor,
+			TotalMinor:    o.TotalMinor,
+			BalanceDue:    balanceDue,
+		},
+		PaymentDetails: paymentDetails,
+		NotesFooter:    s.NotesFooter,
+	}
+}
+
+func buildCreditNotePDFData(
+	o *invoiceTx.InvoiceOverviewTotals,
+	note *invoiceTx.CreditNoteRow,
+	s models.Settings,
+) models.InvoicePDFData {
+	currency := invoiceCurrency(o, s)
+	numbering := invoiceNumbering(o, s)
+	referenceNumberLabel := numbering.Invoice(note.RevisionNo)
+	creditNoteNumberLabel := numbering.CreditNote(note.CreditNoteNo)
+
+	logoPath := ""
+	if s.LogoStorageKey != "" {
+		logoPath = storage.NewLocalStore(storage.DefaultRootDir).Path(s.LogoStorageKey)
+	}
+
+	lines := make([]models.InvoicePDFItem, 0, len(note.Lines))
+	for _, it := range note.Lines {
+		lines = append(lines, models.InvoicePDFItem{
+			Name:      it.Name,
+			LineType:  it.LineType,
+			Quantity:  formatQuantity(it.Quantity, ""),
+			ItemPrice: formatMoney(it.UnitPriceMinor, currency),
+			ItemTotal: formatMoney(it.LineTotalMinor, currency),
+			SortOrder: it.SortOrder,
+		})
+	}
+
+	var reason *string
+	if note.Reason.Valid && note.Reason.String != "" {
+		value := note.Reason.String
+		reason = &value
+	}
+
+	return models.InvoicePDFData{
+		DocumentKind:         "credit_note",
+		Title:                "Credit Note",
+		InvoiceNumberLabel:   creditNoteNumberLabel,
+		ReferenceNumberLabel: referenceNumberLabel,
+		Currency:             currency,
+		ShowItemTypeHeaders:  s.ShowItemTypeHeaders,
+
+		IssueAt: formatDate(note.IssueDate, s.DateFormat),
+		Note:    reason,
+
+		Issuer: models.InvoicePDFIssuer{
+			CompanyName:    s.CompanyName,
+			Email:          s.Email,
+			Phone:          s.Phone,
+			CompanyAddress: s.CompanyAddress,
+			LogoPath:       logoPath,
+		},
+		Client: models.CreateClient{
+			Name:        o.ClientName,
+			CompanyName: o.ClientCompanyName,
+			Address:     o.ClientAddress,
+			Email:       o.ClientEmail,
+			VATNumber:   o.ClientVATNumber,
+		},
+		Lines: lines,
+		Totals: models.TotalsCreateIn{
+			VATRate:        note.VATRate,
+			VatAmountMinor: note.VatAmountMinor,
+			SubtotalMinor:  note.SubtotalMinor,
+			TotalMinor:     note.TotalMinor,
+			VATBreakdown:   note.VATBreakdown,
+		},
+		PaymentDetails: fmt.Sprintf("Credited against invoice: %s", referenceNumberLabel),
+		NotesFooter:    s.NotesFooter,
+	}
+}
+
+func buildQuotePDFData(q *models.QuoteOut, s models.Settings) models.InvoicePDFData {
+	ov := q.Overview
+	overview := &invoiceTx.InvoiceOverviewTotals{
+		BaseNumber:        ov.QuoteNumber,
+		IssueDate:         ov.IssueDate,
+		DueByDate:         nullStringFromPointer(ov.ValidUntil),
+		ClientName:        ov.ClientName,
+		ClientCompanyName: ov.ClientCompanyName,
+		ClientAddress:     ov.ClientAddress,
+		ClientEmail:       ov.ClientEmail,
+		ClientVATNumber:   ov.ClientVATNumber,
+		Note:              nullStringFromPointer(ov.Note),
+		Currency:          ov.Currency,
+		ExchangeRateMicro: ov.ExchangeRateMicro,
+		VATTreatment:      ov.VATTreatment,
+
+		VATRate:       q.Totals.VATRate,
+		VATAmountMin:  q.Totals.VatAmountMinor,
+		DiscountType:  q.Totals.DiscountType,
+		DiscountRate:  q.Totals.DiscountRate,
+		DiscountMinor: q.Totals.DiscountMinor,
+		DepositType:   q.Totals.DepositType,
+		DepositRate:   q.Totals.DepositRate,
+		DepositMinor:  q.Totals.DepositMinor,
+		SubtotalMinor: q.Totals.SubtotalMinor,
+		TotalMinor:    q.Totals.TotalMinor,
+		VATBreakdown:  invoiceTx.VATBreakdownOrDefault(q.Totals),
+
+		DeductionRate:      q.Totals.DeductionRate,
+		DeductionLineTypes: q.Totals.DeductionLineTypes,
+		DeductionMinor:     q.Totals.DeductionMinor,
+	}
+
+	doc := buildInvoicePDFData(overview, buildPDFItemsFromLines(q.Lines, invoiceCurrency(overview, s)), s)
+	doc.DocumentKind = "quote"
+	doc.Title = "Quote"
+	doc.InvoiceNumberLabel = invoiceformat.FormatQuoteNumber(s.QuotePrefix, ov.QuoteNumber)
+	return doc
+}
+
 func nullStringFromPointer(value *string) sql.NullString {
 	if value == nil || *value == "" {
 		return sql.NullString{}
@@ -362,15 +841,25 @@ func nullStringFromPointer(value *string) sql.NullString {
 	}
 }
 
-func fallbackCurrency(v string) string {
-	switch v {
-	case "EUR", "USD", "GBP":
-		return v
-	default:
-		return "GBP"
+// invoiceNumbering formats the numbers of o, preferring the label it was
+// given by the account's number template.
+func invoiceNumbering(o *invoiceTx.InvoiceOverviewTotals, s models.Settings) invoiceformat.Numbering {
+	return invoiceformat.Numbering{
+		Prefix:     s.InvoicePrefix,
+		BaseNumber: o.BaseNumber,
+		Label:      o.NumberLabel.String,
 	}
 }
 
+// invoiceCurrency is the currency the invoice was issued in. Invoices saved
+// before currencies were stored per revision use the workspace currency.
+func invoiceCurrency(o *invoiceTx.InvoiceOverviewTotals, s models.Settings) string {
+	if o.Currency != "" {
+		return invoiceformat.NormalizeCurrency(o.Currency)
+	}
+	return invoiceformat.NormalizeCurrency(s.Currency)
+}
+
 func formatMoney(minorUnits int64, currency string) string {
 	sign := ""
 	if minorUnits < 0 {
@@ -378,26 +867,31 @@ func formatMoney(minorUnits int64, currency string) string {
 		minorUnits = -minorUnits
 	}
 
-	symbol := currencySymbol(currency)
+	symbol := invoiceformat.CurrencySymbol(currency)
 	major := minorUnits / 100
 	minor := minorUnits % 100
 
 	return fmt.Sprintf("%s%s%d.%02d", sign, symbol, major, minor)
 }
 
-func currencySymbol(code string) string {
-	switch code {
-	case "EUR":
-		return "€"
-	case "USD":
-		return "$"
-	default:
-		return "£"
+// formatLineDiscount renders a line discount for the item table, or "" when
+// the line has none.
+func formatLineDiscount(discountType string, rate, minor int64, currency string) string {
+	if minor <= 0 {
+		return ""
 	}
+	if discountType == "percent" {
+		return invoiceformat.FormatRateBps(rate)
+	}
+	return formatMoney(-minor, currency)
 }
 
-func formatQuantity(qty int64) string {
-	return fmt.Sprintf("%d", qty)
+// formatQuantity renders a milli-unit quantity with its unit, e.g. "2.5 m".
+func formatQuantity(qty models.Quantity, unit string) string {
+	if unit == "" {
+		return qty.String()
+	}
+	return qty.String() + " " + unit
 }
 
 func formatDate(input string, dateFormat string) string {
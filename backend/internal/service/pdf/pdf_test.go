@@ -2,11 +2,13 @@ package pdf
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
 )
 
@@ -39,6 +41,121 @@ func TestBuildInvoicePDFData_InvoiceNumberLabelUsesDisplayMapping(t *testing.T)
 	}
 }
 
+func TestBuildInvoicePDFData_UsesInvoiceCurrency(t *testing.T) {
+	overview := &invoiceTx.InvoiceOverviewTotals{
+		BaseNumber:        7,
+		RevisionNo:        1,
+		IssueDate:         "2026-03-25",
+		Currency:          "EUR",
+		ExchangeRateMicro: 855_000,
+	}
+	settings := models.Settings{InvoicePrefix: "INV-", DateFormat: "dd/mm/yyyy", Currency: "GBP"}
+
+	if doc := buildInvoicePDFData(overview, nil, settings); doc.Currency != "EUR" {
+		t.Fatalf("currency = %q, want the invoice currency EUR", doc.Currency)
+	}
+
+	overview.Currency = ""
+	if doc := buildInvoicePDFData(overview, nil, settings); doc.Currency != "GBP" {
+		t.Fatalf("currency = %q, want the workspace currency GBP", doc.Currency)
+	}
+}
+
+func TestBuildInvoicePDFData_CarriesVoidReason(t *testing.T) {
+	overview := &invoiceTx.InvoiceOverviewTotals{
+		BaseNumber: 7,
+		RevisionNo: 1,
+		IssueDate:  "2026-03-25",
+		Status:     "void",
+		VoidReason: sql.NullString{String: "Raised against the wrong client", Valid: true},
+		VoidedAt:   sql.NullString{String: "2026-03-28T09:15:00.000Z", Valid: true},
+	}
+	settings := models.Settings{InvoicePrefix: "INV-", DateFormat: "dd/mm/yyyy", Currency: "GBP"}
+
+	doc := buildInvoicePDFData(overview, nil, settings)
+	if !doc.Voided || doc.VoidReason != "Raised against the wrong client" || doc.VoidedAt != "28/03/2026" {
+		t.Fatalf("void fields = %v %q %q", doc.Voided, doc.VoidReason, doc.VoidedAt)
+	}
+
+	overview.Status = "issued"
+	if doc := buildInvoicePDFData(overview, nil, settings); doc.Voided {
+		t.Fatalf("issued invoice should not be marked void")
+	}
+}
+
+func TestBuildPaymentReceiptPDFData_ShowsMethodAndReference(t *testing.T) {
+	overview := &invoiceTx.InvoiceOverviewTotals{
+		BaseNumber: 9,
+		RevisionNo: 1,
+		IssueDate:  "2026-03-25",
+		ClientName: "Client",
+		TotalMinor: 5000,
+	}
+	receipt := &invoiceTx.PaymentReceiptRow{
+		BaseNumber:        9,
+		ReceiptNo:         1,
+		PaymentDate:       "2026-03-30",
+		AmountMinor:       2000,
+		PaymentMethod:     sql.NullString{String: "bank_transfer", Valid: true},
+		Reference:         sql.NullString{String: "ACME-0042", Valid: true},
+		AppliedRevisionNo: 1,
+	}
+	settings := models.Settings{InvoicePrefix: "INV-", DateFormat: "dd/mm/yyyy", Currency: "GBP"}
+
+	doc := buildPaymentReceiptPDFData(overview, receipt, 2000, settings)
+	for _, want := range []string{"Payment method: Bank transfer", "Payment reference: ACME-0042"} {
+		if !strings.Contains(doc.PaymentDetails, want) {
+			t.Fatalf("PaymentDetails = %q, want it to contain %q", doc.PaymentDetails, want)
+		}
+	}
+
+	receipt.PaymentMethod = sql.NullString{}
+	receipt.Reference = sql.NullString{}
+	doc = buildPaymentReceiptPDFData(overview, receipt, 2000, settings)
+	if strings.Contains(doc.PaymentDetails, "Payment method") || strings.Contains(doc.PaymentDetails, "Payment reference") {
+		t.Fatalf("PaymentDetails = %q, want no method or reference rows when unset", doc.PaymentDetails)
+	}
+}
+
+func TestBuildRefundPDFData_ReferencesReceiptAndReopensBalance(t *testing.T) {
+	overview := &invoiceTx.InvoiceOverviewTotals{
+		BaseNumber: 3,
+		RevisionNo: 2,
+		IssueDate:  "2026-03-25",
+		ClientName: "Client",
+		TotalMinor: 5000,
+	}
+	refund := &invoiceTx.RefundRow{
+		BaseNumber:        3,
+		RefundNo:          1,
+		ReceiptNo:         2,
+		RefundDate:        "2026-04-02",
+		AmountMinor:       1500,
+		Reason:            sql.NullString{String: "Returned goods", Valid: true},
+		AppliedRevisionNo: 2,
+	}
+	settings := models.Settings{InvoicePrefix: "INV-", DateFormat: "dd/mm/yyyy", Currency: "GBP"}
+
+	doc := buildRefundPDFData(overview, refund, 3500, settings)
+	if doc.DocumentKind != "refund" || doc.InvoiceNumberLabel != "INV-3-RF-1" {
+		t.Fatalf("document = %q %q, want refund INV-3-RF-1", doc.DocumentKind, doc.InvoiceNumberLabel)
+	}
+	if !strings.Contains(doc.PaymentDetails, "Refunded receipt: INV-3.2-PR-2") {
+		t.Fatalf("PaymentDetails = %q, want the refunded receipt number", doc.PaymentDetails)
+	}
+	if doc.Totals.BalanceDue != 1500 || doc.ReceiptAmountMinor != 1500 {
+		t.Fatalf("balance/refund = %d/%d, want 1500/1500", doc.Totals.BalanceDue, doc.ReceiptAmountMinor)
+	}
+	if doc.Note == nil || *doc.Note != "Returned goods" {
+		t.Fatalf("Note = %v, want refund reason", doc.Note)
+	}
+
+	rows := buildTotalRows(doc)
+	if rows[0].label != "Refund Amount" || rows[0].value != "£15.00" {
+		t.Fatalf("first row = %q %q, want Refund Amount £15.00", rows[0].label, rows[0].value)
+	}
+}
+
 func TestBuildPartyBlock_NormalizesAddressCommas(t *testing.T) {
 	block := buildPartyBlock(
 		"ISSUED BY",
@@ -94,7 +211,7 @@ func TestBuildTotalRows_UsesASCIIHyphenForNegativeSummaryValues(t *testing.T) {
 
 	for label, want := range map[string]string{
 		"Discount":          "-£5.00",
-		"Requested Deposit": "£15.00",
+		"Deposit Requested": "£15.00",
 		"Paid":              "-£20.00",
 	} {
 		if got := valuesByLabel[label]; got != want {
@@ -103,6 +220,159 @@ func TestBuildTotalRows_UsesASCIIHyphenForNegativeSummaryValues(t *testing.T) {
 	}
 }
 
+func TestBuildTotalRows_CreditNoteShowsTotalCredit(t *testing.T) {
+	rows := buildTotalRows(models.InvoicePDFData{
+		DocumentKind: "credit_note",
+		Currency:     "GBP",
+		Totals: models.TotalsCreateIn{
+			SubtotalMinor:  10000,
+			VatAmountMinor: 2000,
+			TotalMinor:     12000,
+		},
+	})
+
+	if len(rows) != 3 {
+		t.Fatalf("buildTotalRows() len = %d, want 3", len(rows))
+	}
+	last := rows[len(rows)-1]
+	if last.label != "Total Credit" || last.value != "£120.00" {
+		t.Fatalf("last row = %q %q, want %q %q", last.label, last.value, "Total Credit", "£120.00")
+	}
+}
+
+func TestBuildTotalRows_ShowsVATRowPerRate(t *testing.T) {
+	rows := buildTotalRows(models.InvoicePDFData{
+		Currency: "GBP",
+		Totals: models.TotalsCreateIn{
+			SubtotalMinor:  15000,
+			VatAmountMinor: 2000,
+			TotalMinor:     17000,
+			BalanceDue:     17000,
+			VATBreakdown: []models.VATBand{
+				{VATRate: 2000, NetMinor: 10000, VatMinor: 2000},
+				{VATRate: 0, NetMinor: 5000, VatMinor: 0},
+			},
+		},
+	})
+
+	valuesByLabel := make(map[string]string, len(rows))
+	for _, row := range rows {
+		valuesByLabel[row.label] = row.value
+	}
+
+	if _, ok := valuesByLabel["VAT"]; ok {
+		t.Fatalf("buildTotalRows() kept single VAT row for mixed rates")
+	}
+	for label, want := range map[string]string{
+		"VAT 20% on £100.00": "£20.00",
+		"VAT 0% on £50.00":   "£0.00",
+	} {
+		if got := valuesByLabel[label]; got != want {
+			t.Fatalf("%s value = %q, want %q", label, got, want)
+		}
+	}
+}
+
+func TestBuildTotalRows_LabelsReverseChargeAndPrintsStatement(t *testing.T) {
+	doc := models.InvoicePDFData{
+		Currency:     "GBP",
+		VATTreatment: invoiceformat.VATTreatmentReverseCharge,
+		Totals: models.TotalsCreateIn{
+			SubtotalMinor: 10000,
+			TotalMinor:    10000,
+			BalanceDue:    10000,
+			VATBreakdown:  []models.VATBand{{VATRate: 0, NetMinor: 10000}},
+		},
+	}
+
+	rows := buildTotalRows(doc)
+	found := false
+	for _, row := range rows {
+		if row.label == "VAT (reverse charge)" && row.value == "£0.00" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("buildTotalRows() = %+v, want a reverse charge VAT row", rows)
+	}
+
+	statement := buildVATStatementRows(doc.VATTreatment)
+	if len(statement) != 1 || statement[0].text != invoiceformat.VATTreatmentStatement(invoiceformat.VATTreatmentReverseCharge) {
+		t.Fatalf("buildVATStatementRows() = %+v, want the reverse charge statement", statement)
+	}
+	if rows := buildVATStatementRows(invoiceformat.VATTreatmentStandard); len(rows) != 0 {
+		t.Fatalf("buildVATStatementRows(standard) = %+v, want none", rows)
+	}
+}
+
+func TestBuildTotalRows_ShowsDeductionAndAmountPayable(t *testing.T) {
+	rows := buildTotalRows(models.InvoicePDFData{
+		Currency: "GBP",
+		Totals: models.TotalsCreateIn{
+			SubtotalMinor:  10000,
+			TotalMinor:     10000,
+			DeductionRate:  2000,
+			DeductionMinor: 2000,
+			PayableMinor:   8000,
+			BalanceDue:     8000,
+		},
+	})
+
+	valuesByLabel := make(map[string]string, len(rows))
+	for _, row := range rows {
+		valuesByLabel[row.label] = row.value
+	}
+	for label, want := range map[string]string{
+		"Total":          "£100.00",
+		"Deduction 20%":  "-£20.00",
+		"Amount Payable": "£80.00",
+	} {
+		if got := valuesByLabel[label]; got != want {
+			t.Fatalf("%s value = %q, want %q (rows %+v)", label, got, want, rows)
+		}
+	}
+}
+
+func TestBuildQuotePDFData_UsesQuoteNumberAndValidity(t *testing.T) {
+	validUntil := "2026-05-01"
+	quote := &models.QuoteOut{
+		Overview: models.QuoteOverviewIn{
+			QuoteNumber: 12,
+			IssueDate:   "2026-04-01",
+			ValidUntil:  &validUntil,
+			ClientName:  "Client",
+		},
+		Totals: models.TotalsCreateIn{
+			SubtotalMinor:  10000,
+			VatAmountMinor: 2000,
+			TotalMinor:     12000,
+		},
+	}
+
+	doc := buildQuotePDFData(quote, models.Settings{
+		InvoicePrefix: "INV-",
+		QuotePrefix:   "QUO-",
+		DateFormat:    "dd/mm/yyyy",
+		Currency:      "GBP",
+	})
+
+	if doc.DocumentKind != "quote" || doc.InvoiceNumberLabel != "QUO-12" {
+		t.Fatalf("quote doc = %q %q, want quote QUO-12", doc.DocumentKind, doc.InvoiceNumberLabel)
+	}
+	if doc.DueDate == nil || *doc.DueDate != "01/05/2026" {
+		t.Fatalf("quote valid until = %v, want 01/05/2026", doc.DueDate)
+	}
+	if got := dueDateLabel(doc); got != "Valid until" {
+		t.Fatalf("dueDateLabel() = %q, want %q", got, "Valid until")
+	}
+
+	rows := buildTotalRows(doc)
+	last := rows[len(rows)-1]
+	if last.label != "Quote Total" || last.value != "£120.00" {
+		t.Fatalf("last row = %q %q, want %q %q", last.label, last.value, "Quote Total", "£120.00")
+	}
+}
+
 func TestFormatDurationMinutes(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -125,6 +395,27 @@ func TestFormatDurationMinutes(t *testing.T) {
 	}
 }
 
+func TestFormatQuantity(t *testing.T) {
+	tests := []struct {
+		name string
+		qty  models.Quantity
+		unit string
+		want string
+	}{
+		{name: "whole without unit", qty: models.WholeQuantity(2), want: "2"},
+		{name: "fraction with unit", qty: 2500, unit: "m", want: "2.5 m"},
+		{name: "three decimals", qty: 125, unit: "kg", want: "0.125 kg"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := formatQuantity(tc.qty, tc.unit); got != tc.want {
+				t.Fatalf("formatQuantity(%d, %q) = %q, want %q", tc.qty, tc.unit, got, tc.want)
+			}
+		})
+	}
+}
+
 func TestBuildInvoicePDFPricing(t *testing.T) {
 	minutes := int64(90)
 
@@ -172,7 +463,7 @@ func TestBuildQuickInvoice_HourlyLineIncludesPricingColumns(t *testing.T) {
 					Name:           "Hourly sample",
 					LineType:       "sample",
 					PricingMode:    "hourly",
-					Quantity:       1,
+					Quantity:       models.WholeQuantity(1),
 					MinutesWorked:  &minutes,
 					UnitPriceMinor: 6000,
 					LineTotalMinor: 9000,
@@ -182,7 +473,7 @@ func TestBuildQuickInvoice_HourlyLineIncludesPricingColumns(t *testing.T) {
 					Name:           "Flat line",
 					LineType:       "custom",
 					PricingMode:    "flat",
-					Quantity:       1,
+					Quantity:       models.WholeQuantity(1),
 					UnitPriceMinor: 2500,
 					LineTotalMinor: 2500,
 					SortOrder:      2,
@@ -195,6 +486,7 @@ func TestBuildQuickInvoice_HourlyLineIncludesPricingColumns(t *testing.T) {
 			Currency:      "GBP",
 		},
 		1,
+		"",
 	)
 
 	if len(doc.Lines) != 2 {
@@ -294,6 +586,32 @@ func TestMarotoRenderer_RenderPDF(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "void invoice",
+			doc: models.InvoicePDFData{
+				Title:              "Invoice",
+				InvoiceNumberLabel: "INV-9",
+				Currency:           "GBP",
+				IssueAt:            "26/03/2026",
+				Voided:             true,
+				VoidReason:         "Raised against the wrong client",
+				VoidedAt:           "28/03/2026",
+				Issuer: models.InvoicePDFIssuer{
+					CompanyName: "North Studio Ltd",
+				},
+				Client: models.CreateClient{
+					Name: "Client",
+				},
+				Lines: []models.InvoicePDFItem{
+					{Name: "Consulting", Quantity: "1", ItemPrice: "£100.00", TimeWorked: "—", HourlyRate: "—", ItemTotal: "£100.00", SortOrder: 1},
+				},
+				Totals: models.TotalsCreateIn{
+					SubtotalMinor: 10000,
+					TotalMinor:    10000,
+					BalanceDue:    10000,
+				},
+			},
+		},
 		{
 			name: "empty line items",
 			doc: models.InvoicePDFData{
@@ -0,0 +1,99 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// The watermark covers the A4 content area (182 x 271 mm) at 5 px per mm.
+const (
+	voidWatermarkWidth  = 910
+	voidWatermarkHeight = 1355
+	voidWatermarkSize   = 280
+)
+
+var voidWatermarkColor = color.NRGBA{R: 200, G: 40, B: 40, A: 56}
+
+var (
+	voidWatermarkOnce  sync.Once
+	voidWatermarkBytes []byte
+	voidWatermarkErr   error
+)
+
+// voidWatermark returns a transparent PNG with "VOID" drawn corner to corner
+// across it. It is drawn once and reused.
+func voidWatermark() ([]byte, error) {
+	voidWatermarkOnce.Do(func() {
+		voidWatermarkBytes, voidWatermarkErr = drawVoidWatermark()
+	})
+	return voidWatermarkBytes, voidWatermarkErr
+}
+
+func drawVoidWatermark() ([]byte, error) {
+	ttf, err := opentype.Parse(gobold.TTF)
+	if err != nil {
+		return nil, fmt.Errorf("parse watermark font: %w", err)
+	}
+	face, err := opentype.NewFace(ttf, &opentype.FaceOptions{
+		Size:    voidWatermarkSize,
+		DPI:     72,
+		Hinting: font.HintingNone,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load watermark font: %w", err)
+	}
+	defer face.Close()
+
+	// Draw the word level first, then rotate it onto the page.
+	metrics := face.Metrics()
+	wordWidth := font.MeasureString(face, "VOID").Ceil()
+	wordHeight := (metrics.Ascent + metrics.Descent).Ceil()
+	word := image.NewAlpha(image.Rect(0, 0, wordWidth, wordHeight))
+	drawer := font.Drawer{
+		Dst:  word,
+		Src:  image.Opaque,
+		Face: face,
+		Dot:  fixed.P(0, metrics.Ascent.Ceil()),
+	}
+	drawer.DrawString("VOID")
+
+	out := image.NewNRGBA(image.Rect(0, 0, voidWatermarkWidth, voidWatermarkHeight))
+	sin, cos := math.Sincos(math.Atan2(voidWatermarkHeight, voidWatermarkWidth))
+	pageCX, pageCY := float64(voidWatermarkWidth)/2, float64(voidWatermarkHeight)/2
+	wordCX, wordCY := float64(wordWidth)/2, float64(wordHeight)/2
+
+	for y := 0; y < voidWatermarkHeight; y++ {
+		for x := 0; x < voidWatermarkWidth; x++ {
+			dx, dy := float64(x)+0.5-pageCX, float64(y)+0.5-pageCY
+			// Inverse of a rotation rising from bottom left to top right.
+			u := int(math.Floor(dx*cos - dy*sin + wordCX))
+			v := int(math.Floor(dx*sin + dy*cos + wordCY))
+			if u < 0 || v < 0 || u >= wordWidth || v >= wordHeight {
+				continue
+			}
+			coverage := word.AlphaAt(u, v).A
+			if coverage == 0 {
+				continue
+			}
+			c := voidWatermarkColor
+			c.A = uint8(uint16(c.A) * uint16(coverage) / 255)
+			out.SetNRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, fmt.Errorf("encode watermark: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,106 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/recurringTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+)
+
+const DefaultInterval = 15 * time.Minute
+
+// Worker periodically turns due recurring schedules into invoices.
+type Worker struct {
+	db       *sql.DB
+	interval time.Duration
+	now      func() time.Time
+}
+
+func NewWorker(db *sql.DB, interval time.Duration) *Worker {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Worker{
+		db:       db,
+		interval: interval,
+		now:      time.Now,
+	}
+}
+
+// Start runs one pass immediately and then one per interval until ctx is done.
+func (w *Worker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// latestZone is the furthest-ahead UTC offset in use, so its date is the
+// latest date any workspace can have reached.
+var latestZone = time.FixedZone("UTC+14", 14*60*60)
+
+// RunDue runs every schedule due today, in its workspace's time zone, once. A
+// failing schedule is logged and retried on the next pass; it never blocks the
+// others.
+func (w *Worker) RunDue(ctx context.Context) {
+	now := w.now()
+	horizon := now.In(latestZone).Format("2006-01-02")
+
+	due, err := recurringTx.ListDueSchedules(ctx, w.db, horizon)
+	if err != nil {
+		slog.ErrorContext(ctx, "list due recurring schedules failed", "err", err)
+		return
+	}
+
+	todayByAccount := make(map[int64]string)
+	for _, s := range due {
+		scoped := accountscope.WithAccountID(ctx, s.AccountID)
+
+		today, ok := todayByAccount[s.AccountID]
+		if !ok {
+			today, err = settingsTx.Today(scoped, w.db, s.AccountID, now)
+			if err != nil {
+				slog.ErrorContext(ctx, "resolve recurring run date failed",
+					"account_id", s.AccountID,
+					"err", err,
+				)
+				continue
+			}
+			todayByAccount[s.AccountID] = today
+		}
+
+		result, err := recurringTx.RunSchedule(scoped, w.db, s.ID, today)
+		if err != nil {
+			if errors.Is(err, recurringTx.ErrScheduleNotDue) {
+				continue
+			}
+			slog.ErrorContext(ctx, "run recurring schedule failed",
+				"account_id", s.AccountID,
+				"schedule_id", s.ID,
+				"err", err,
+			)
+			continue
+		}
+
+		slog.InfoContext(ctx, "recurring invoice generated",
+			"account_id", s.AccountID,
+			"schedule_id", s.ID,
+			"base_number", result.BaseNumber,
+			"status", result.Status,
+			"next_run_date", result.NextRunDate,
+		)
+	}
+}
@@ -0,0 +1,130 @@
+package recurring
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/db"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/recurringTx"
+)
+
+func newWorkerApp(t *testing.T) *app.App {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "recurring.sqlite"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	if err := db.Migrate(context.Background(), conn); err != nil {
+		t.Fatalf("migrate db: %v", err)
+	}
+	return &app.App{DB: conn}
+}
+
+func createDueSchedule(t *testing.T, ctx context.Context, a *app.App, nextRunDate string) int64 {
+	t.Helper()
+
+	res, err := a.DB.Exec(`INSERT INTO clients (name, address) VALUES ('Acme', '1 High Street')`)
+	if err != nil {
+		t.Fatalf("insert client: %v", err)
+	}
+	clientID, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("client lastInsertId: %v", err)
+	}
+
+	_, _, err = invoiceTx.Create(ctx, a, &models.FEInvoiceIn{
+		Overview: models.InvoiceCreateIn{
+			ClientID:   clientID,
+			BaseNumber: 1,
+			IssueDate:  "2026-02-28",
+			ClientName: "Acme",
+		},
+		Lines: []models.LineCreateIn{
+			{
+				Name:           "Retainer",
+				LineType:       "custom",
+				PricingMode:    "flat",
+				Quantity:       models.WholeQuantity(1),
+				UnitPriceMinor: 1000,
+				LineTotalMinor: 1000,
+				SortOrder:      1,
+			},
+		},
+		Totals: models.TotalsCreateIn{
+			DiscountType:      "none",
+			DepositType:       "none",
+			SubtotalMinor:     1000,
+			SubtotalAfterDisc: 1000,
+			TotalMinor:        1000,
+			BalanceDue:        1000,
+		},
+	})
+	if err != nil {
+		t.Fatalf("create source invoice: %v", err)
+	}
+
+	day := int64(31)
+	schedule, err := recurringTx.Create(ctx, a, clientID, models.RecurringScheduleIn{
+		SourceBaseNumber: 1,
+		Cadence:          recurringTx.CadenceMonthly,
+		DayOfMonth:       &day,
+		NextRunDate:      nextRunDate,
+	})
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+	return schedule.ID
+}
+
+func TestRunDue_UsesWorkspaceTimezone(t *testing.T) {
+	// 12:00 UTC on 30 March is already 31 March in Auckland.
+	now := time.Date(2026, 3, 30, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		timezone string
+		wantNext string
+	}{
+		{name: "workspace already on run date", timezone: "Pacific/Auckland", wantNext: "2026-04-30"},
+		{name: "workspace still before run date", timezone: "UTC", wantNext: "2026-03-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+			a := newWorkerApp(t)
+
+			if _, err := a.DB.Exec(`
+				INSERT INTO account_settings (account_id, timezone) VALUES (?, ?)
+				ON CONFLICT(account_id) DO UPDATE SET timezone = excluded.timezone
+			`, accountscope.DefaultAccountID, tt.timezone); err != nil {
+				t.Fatalf("set timezone: %v", err)
+			}
+			scheduleID := createDueSchedule(t, ctx, a, "2026-03-31")
+
+			w := NewWorker(a.DB, time.Minute)
+			w.now = func() time.Time { return now }
+			w.RunDue(context.Background())
+
+			var next string
+			if err := a.DB.QueryRow(`SELECT next_run_date FROM recurring_schedules WHERE id = ?`, scheduleID).Scan(&next); err != nil {
+				t.Fatalf("load schedule: %v", err)
+			}
+			if next != tt.wantNext {
+				t.Fatalf("next_run_date = %q, want %q", next, tt.wantNext)
+			}
+		})
+	}
+}
@@ -16,9 +16,9 @@ func Insert(ctx context.Context, a *app.App, c *models.CreateClient) (int64, err
 	}
 
 	res, err := a.DB.ExecContext(ctx, `
-    INSERT INTO clients (account_id, name, company_name, address, email)
-    VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))
-  `, accountID, c.Name, c.CompanyName, c.Address, c.Email)
+    INSERT INTO clients (account_id, name, company_name, address, email, vat_number)
+    VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))
+  `, accountID, c.Name, c.CompanyName, c.Address, c.Email, c.VATNumber)
 
 	if err != nil {
 		return 0, err
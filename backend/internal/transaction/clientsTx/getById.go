@@ -19,17 +19,24 @@ func GetByID(ctx context.Context, a *app.App, id int64) (models.Client, error) {
 	var c models.Client
 	err = a.DB.QueryRowContext(ctx, `
 		SELECT
-			id,
-			name,
-			COALESCE(company_name, '') AS companyName,
-			COALESCE(address, '')      AS address,
-			COALESCE(email, '')        AS email,
-			created_at,
-			updated_at
-		FROM clients
-		WHERE id = ?
-		  AND account_id = ?
-	`, id, accountID).Scan(&c.ID, &c.Name, &c.CompanyName, &c.Address, &c.Email, &c.CreatedAt, &c.UpdatedAt)
+			c.id,
+			c.name,
+			COALESCE(c.company_name, '')  AS companyName,
+			COALESCE(c.address, '')       AS address,
+			COALESCE(c.email, '')         AS email,
+			COALESCE(c.vat_number, '')    AS vatNumber,
+			COALESCE(cb.balance_minor, 0) AS creditBalanceMinor,
+			c.created_at,
+			c.updated_at
+		FROM clients c
+		LEFT JOIN account_settings s
+			ON s.account_id = c.account_id
+		LEFT JOIN client_credit_balances cb
+			ON cb.client_id = c.id
+			AND cb.currency = COALESCE(s.currency, 'GBP')
+		WHERE c.id = ?
+		  AND c.account_id = ?
+	`, id, accountID).Scan(&c.ID, &c.Name, &c.CompanyName, &c.Address, &c.Email, &c.VATNumber, &c.CreditBalanceMinor, &c.CreatedAt, &c.UpdatedAt)
 
 	return c, err
 }
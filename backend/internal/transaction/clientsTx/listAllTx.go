@@ -8,6 +8,8 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/models"
 )
 
+// ListClients returns the account's clients, newest first. CreditBalanceMinor
+// is the credit each has on account in the workspace currency.
 func ListClients(a *app.App, ctx context.Context) ([]models.Client, error) {
 	accountID, err := accountscope.Require(ctx)
 	if err != nil {
@@ -16,16 +18,23 @@ func ListClients(a *app.App, ctx context.Context) ([]models.Client, error) {
 
 	rows, err := a.DB.QueryContext(ctx, `
 		SELECT
-			id,
-			name,
-			COALESCE(company_name, '') AS companyName,
-			COALESCE(address, '')      AS address,
-			COALESCE(email, '')        AS email,
-			created_at,
-			updated_at
-		FROM clients
-		WHERE account_id = ?
-		ORDER BY id DESC
+			c.id,
+			c.name,
+			COALESCE(c.company_name, '')  AS companyName,
+			COALESCE(c.address, '')       AS address,
+			COALESCE(c.email, '')         AS email,
+			COALESCE(c.vat_number, '')    AS vatNumber,
+			COALESCE(cb.balance_minor, 0) AS creditBalanceMinor,
+			c.created_at,
+			c.updated_at
+		FROM clients c
+		LEFT JOIN account_settings s
+			ON s.account_id = c.account_id
+		LEFT JOIN client_credit_balances cb
+			ON cb.client_id = c.id
+			AND cb.currency = COALESCE(s.currency, 'GBP')
+		WHERE c.account_id = ?
+		ORDER BY c.id DESC
 	`, accountID)
 	if err != nil {
 		return nil, err
@@ -36,7 +45,7 @@ func ListClients(a *app.App, ctx context.Context) ([]models.Client, error) {
 	for rows.Next() {
 		var c models.Client
 		if err := rows.Scan(
-			&c.ID, &c.Name, &c.CompanyName, &c.Address, &c.Email, &c.CreatedAt, &c.UpdatedAt,
+			&c.ID, &c.Name, &c.CompanyName, &c.Address, &c.Email, &c.VATNumber, &c.CreditBalanceMinor, &c.CreatedAt, &c.UpdatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -37,6 +37,10 @@ func UpdateClient(ctx context.Context, a *app.App, id int64, input models.Update
 		setParts = append(setParts, "email = NULLIF(?, '')")
 		args = append(args, *input.Email)
 	}
+	if input.VATNumber != nil {
+		setParts = append(setParts, "vat_number = NULLIF(?, '')")
+		args = append(args, *input.VATNumber)
+	}
 
 	if len(setParts) == 0 {
 		return 0, errors.New("no fields to update")
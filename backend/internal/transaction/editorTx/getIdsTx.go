@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
@@ -15,6 +16,11 @@ type InvoiceBookPageFilters struct {
 	SortBy        string
 	SortDirection string
 	PaymentState  string
+	// Overdue keeps only issued invoices with a balance left after their due date.
+	Overdue bool
+	// Today is the workspace-local date (YYYY-MM-DD) used to derive overdue.
+	// Empty means today in UTC.
+	Today string
 }
 
 func normalizeInvoiceBookPageFilters(filters InvoiceBookPageFilters) InvoiceBookPageFilters {
@@ -23,6 +29,12 @@ func normalizeInvoiceBookPageFilters(filters InvoiceBookPageFilters) InvoiceBook
 		SortBy:        "date",
 		SortDirection: "desc",
 		PaymentState:  "all",
+		Overdue:       filters.Overdue,
+		Today:         filters.Today,
+	}
+
+	if _, err := time.Parse("2006-01-02", out.Today); err != nil {
+		out.Today = time.Now().UTC().Format("2006-01-02")
 	}
 
 	if filters.SortBy == "balance" {
@@ -42,20 +54,23 @@ func normalizeInvoiceBookPageFilters(filters InvoiceBookPageFilters) InvoiceBook
 }
 
 func invoiceBookWhereClause(filters InvoiceBookPageFilters) string {
+	var conds []string
+
 	switch filters.PaymentState {
 	case "paid":
-		return `
-		WHERE status <> 'void'
-			AND balance_due_minor <= 0
-		`
+		conds = append(conds, "status <> 'void'", "balance_due_minor <= 0")
 	case "unpaid":
-		return `
-		WHERE status <> 'void'
-			AND balance_due_minor > 0
-		`
-	default:
+		conds = append(conds, "status <> 'void'", "balance_due_minor > 0")
+	}
+
+	if filters.Overdue {
+		conds = append(conds, "overdue = 1")
+	}
+
+	if len(conds) == 0 {
 		return ""
 	}
+	return "WHERE " + strings.Join(conds, "\n\t\t\tAND ")
 }
 
 func invoiceBookOrderClause(filters InvoiceBookPageFilters) string {
@@ -66,7 +81,7 @@ func invoiceBookOrderClause(filters InvoiceBookPageFilters) string {
 
 	if filters.SortBy == "balance" {
 		return fmt.Sprintf(
-			"ORDER BY balance_due_minor %s, issue_date DESC, base_number DESC",
+			"ORDER BY balance_due_base_minor %s, issue_date DESC, base_number DESC",
 			direction,
 		)
 	}
@@ -76,8 +91,8 @@ func invoiceBookOrderClause(filters InvoiceBookPageFilters) string {
 
 func invoiceBookBaseCTE(accountID int64, filters InvoiceBookPageFilters) (string, []any) {
 	clientWhere := "WHERE i.account_id = ?"
-	args := make([]any, 0, 2)
-	args = append(args, accountID)
+	args := make([]any, 0, 3)
+	args = append(args, filters.Today, accountID)
 	if filters.ClientID > 0 {
 		clientWhere += " AND i.client_id = ?"
 		args = append(args, filters.ClientID)
@@ -86,37 +101,63 @@ func invoiceBookBaseCTE(accountID int64, filters InvoiceBookPageFilters) (string
 	baseCTE := fmt.Sprintf(`
 		WITH paid_totals AS (
 			SELECT
-				p.applied_in_revision_id,
-				COALESCE(SUM(p.amount_minor), 0) AS paid_minor
-			FROM payments p
-			WHERE p.payment_type = 'payment'
-			GROUP BY p.applied_in_revision_id
+				rp.revision_id AS applied_in_revision_id,
+				rp.paid_minor
+			FROM invoice_revision_paid rp
 		),
-		invoice_page_rows AS (
+		credit_totals AS (
+			SELECT
+				cn.invoice_id,
+				COALESCE(SUM(cn.total_minor), 0) AS credited_minor
+			FROM credit_notes cn
+			GROUP BY cn.invoice_id
+		),
+		invoice_book_base AS (
 			SELECT
 				i.id,
 				i.client_id,
 				cur.client_name,
 				cur.client_company_name,
 				i.base_number,
+				COALESCE(i.number_label, '') AS number_label,
 				i.status,
 				cur.revision_no,
 				cur.issue_date,
 				cur.due_by_date,
 				cur.total_minor,
 				cur.deposit_minor,
+				cur.currency,
+				cur.exchange_rate_micro,
 				COALESCE(pt.paid_minor, 0) AS paid_minor,
+				COALESCE(ct.credited_minor, 0) AS credited_minor,
+				CASE
+					WHEN cur.total_minor - cur.deduction_minor - COALESCE(pt.paid_minor, 0) - COALESCE(ct.credited_minor, 0) > 0
+						THEN cur.total_minor - cur.deduction_minor - COALESCE(pt.paid_minor, 0) - COALESCE(ct.credited_minor, 0)
+					ELSE 0
+				END AS balance_due_minor,
 				CASE
-					WHEN cur.total_minor - COALESCE(pt.paid_minor, 0) > 0
-						THEN cur.total_minor - COALESCE(pt.paid_minor, 0)
+					WHEN i.status = 'issued'
+						AND cur.due_by_date IS NOT NULL
+						AND cur.due_by_date < ?
+						AND cur.total_minor - cur.deduction_minor - COALESCE(pt.paid_minor, 0) - COALESCE(ct.credited_minor, 0) > 0
+						THEN 1
 					ELSE 0
-				END AS balance_due_minor
+				END AS overdue
 			FROM invoices i
 			JOIN invoice_revisions cur
 				ON cur.id = i.current_revision_id
 			LEFT JOIN paid_totals pt
 				ON pt.applied_in_revision_id = cur.id
+			LEFT JOIN credit_totals ct
+				ON ct.invoice_id = i.id
 			%s
+		),
+		invoice_page_rows AS (
+			SELECT
+				b.*,
+				CAST(ROUND(b.total_minor * b.exchange_rate_micro / 1000000.0) AS INTEGER) AS total_base_minor,
+				CAST(ROUND(b.balance_due_minor * b.exchange_rate_micro / 1000000.0) AS INTEGER) AS balance_due_base_minor
+			FROM invoice_book_base b
 		)
 	`, clientWhere)
 
@@ -150,13 +191,30 @@ func QueryInvoiceBookPage(
 	baseCTE, baseArgs := invoiceBookBaseCTE(accountID, filters)
 	whereClause := invoiceBookWhereClause(filters)
 
-	var total int
+	var baseCurrency string
+	if err := a.DB.QueryRowContext(ctx, `
+		SELECT COALESCE((
+			SELECT currency
+			FROM account_settings
+			WHERE account_id = ?
+		), 'GBP');
+	`, accountID).Scan(&baseCurrency); err != nil {
+		return models.INVBookOut{}, fmt.Errorf("load workspace currency: %w", err)
+	}
+
+	var (
+		total  int
+		totals models.INVBookTotals
+	)
 	countSQL := baseCTE + fmt.Sprintf(`
-		SELECT COUNT(*)
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN status <> 'void' THEN total_base_minor ELSE 0 END), 0),
+			COALESCE(SUM(CASE WHEN status <> 'void' THEN balance_due_base_minor ELSE 0 END), 0)
 		FROM invoice_page_rows
 		%s;
 	`, whereClause)
-	if err := a.DB.QueryRowContext(ctx, countSQL, baseArgs...).Scan(&total); err != nil {
+	if err := a.DB.QueryRowContext(ctx, countSQL, baseArgs...).Scan(&total, &totals.TotalMinor, &totals.BalanceDueMinor); err != nil {
 		return models.INVBookOut{}, fmt.Errorf("count invoices: %w", err)
 	}
 
@@ -167,6 +225,7 @@ func QueryInvoiceBookPage(
 			client_name,
 			client_company_name,
 			base_number,
+			number_label,
 			status,
 			revision_no,
 			issue_date,
@@ -174,7 +233,13 @@ func QueryInvoiceBookPage(
 			total_minor,
 			deposit_minor,
 			paid_minor,
-			balance_due_minor
+			credited_minor,
+			balance_due_minor,
+			currency,
+			exchange_rate_micro,
+			total_base_minor,
+			balance_due_base_minor,
+			overdue
 		FROM invoice_page_rows
 		%s
 		%s
@@ -200,6 +265,7 @@ func QueryInvoiceBookPage(
 			&item.ClientName,
 			&item.ClientCompanyName,
 			&item.BaseNo,
+			&item.NumberLabel,
 			&item.Status,
 			&item.LatestRevisionNo,
 			&item.IssueDate,
@@ -207,7 +273,13 @@ func QueryInvoiceBookPage(
 			&item.TotalMinor,
 			&item.DepositMinor,
 			&item.PaidMinor,
+			&item.CreditedMinor,
 			&item.BalanceDueMinor,
+			&item.Currency,
+			&item.ExchangeRateMicro,
+			&item.TotalBaseMinor,
+			&item.BalanceDueBaseMinor,
+			&item.Overdue,
 		); err != nil {
 			return models.INVBookOut{}, fmt.Errorf("scan paged invoice row: %w", err)
 		}
@@ -225,12 +297,14 @@ func QueryInvoiceBookPage(
 
 	if len(items) == 0 {
 		return models.INVBookOut{
-			Items:   []models.INVBookInvoice{},
-			Limit:   limit,
-			Offset:  offset,
-			Count:   0,
-			Total:   total,
-			HasMore: false,
+			Items:        []models.INVBookInvoice{},
+			Limit:        limit,
+			Offset:       offset,
+			Count:        0,
+			Total:        total,
+			HasMore:      false,
+			BaseCurrency: baseCurrency,
+			Totals:       totals,
 		}, nil
 	}
 
@@ -297,11 +371,13 @@ func QueryInvoiceBookPage(
 	count := len(items)
 
 	return models.INVBookOut{
-		Items:   items,
-		Limit:   limit,
-		Offset:  offset,
-		Count:   count,
-		Total:   total,
-		HasMore: offset+count < total,
+		Items:        items,
+		Limit:        limit,
+		Offset:       offset,
+		Count:        count,
+		Total:        total,
+		HasMore:      offset+count < total,
+		BaseCurrency: baseCurrency,
+		Totals:       totals,
 	}, nil
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
 	"github.com/viktorHadz/goInvoice26/internal/db"
+	"github.com/viktorHadz/goInvoice26/internal/models"
 	"github.com/viktorHadz/goInvoice26/internal/transaction/editorTx"
 )
 
@@ -320,3 +321,87 @@ func TestQueryInvoiceBookPage_IsScopedPerAccount(t *testing.T) {
 		t.Fatalf("second account page = %+v, want only base 601", secondPage)
 	}
 }
+
+func TestQueryInvoiceBookPage_DerivesAndFiltersOverdue(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	// every helper invoice is due 2026-04-10
+	clientID := insertClient(t, a)
+	insertInvoiceBookInvoice(t, a, clientID, 701, "issued", 1, "2026-03-10", 5000, 0, 1000)
+	insertInvoiceBookInvoice(t, a, clientID, 702, "paid", 1, "2026-03-11", 6000, 0, 6000)
+	insertInvoiceBookInvoice(t, a, clientID, 703, "draft", 1, "2026-03-12", 3000, 0, 0)
+
+	onDueDate, err := editorTx.QueryInvoiceBookPage(a, ctx, clientID, 10, 0, editorTx.InvoiceBookPageFilters{
+		Today: "2026-04-10",
+	})
+	if err != nil {
+		t.Fatalf("QueryInvoiceBookPage on due date: %v", err)
+	}
+	for _, item := range onDueDate.Items {
+		if item.Overdue {
+			t.Fatalf("invoice %d overdue on its due date", item.BaseNo)
+		}
+	}
+
+	got, err := editorTx.QueryInvoiceBookPage(a, ctx, clientID, 10, 0, editorTx.InvoiceBookPageFilters{
+		Overdue: true,
+		Today:   "2026-04-11",
+	})
+	if err != nil {
+		t.Fatalf("QueryInvoiceBookPage overdue: %v", err)
+	}
+	if got.Total != 1 || len(got.Items) != 1 {
+		t.Fatalf("overdue total = %d, want 1", got.Total)
+	}
+	if got.Items[0].BaseNo != 701 || !got.Items[0].Overdue {
+		t.Fatalf("overdue item = %+v, want base 701 flagged overdue", got.Items[0])
+	}
+}
+
+func TestQueryInvoiceBookPage_ConvertsTotalsToWorkspaceCurrency(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceBookInvoice(t, a, clientID, 301, "issued", 1, "2026-03-20", 10000, 0, 0)
+	euroID := insertInvoiceBookInvoice(t, a, clientID, 302, "issued", 1, "2026-03-21", 9000, 0, 1000)
+	insertInvoiceBookInvoice(t, a, clientID, 303, "void", 1, "2026-03-22", 50000, 0, 0)
+
+	if _, err := a.DB.Exec(`
+		UPDATE invoice_revisions
+		SET currency = 'EUR', exchange_rate_micro = 850000
+		WHERE invoice_id = ?
+	`, euroID); err != nil {
+		t.Fatalf("set invoice currency: %v", err)
+	}
+
+	got, err := editorTx.QueryInvoiceBookPage(a, ctx, clientID, 10, 0, editorTx.InvoiceBookPageFilters{
+		SortBy: "balance",
+	})
+	if err != nil {
+		t.Fatalf("QueryInvoiceBookPage: %v", err)
+	}
+
+	if got.BaseCurrency != "GBP" {
+		t.Fatalf("base currency = %q, want GBP", got.BaseCurrency)
+	}
+	if got.Totals.TotalMinor != 10000+7650 || got.Totals.BalanceDueMinor != 10000+6800 {
+		t.Fatalf("totals = %+v, want total 17650 and balance 16800", got.Totals)
+	}
+
+	var euro *models.INVBookInvoice
+	for i := range got.Items {
+		if got.Items[i].BaseNo == 302 {
+			euro = &got.Items[i]
+		}
+	}
+	if euro == nil {
+		t.Fatalf("items = %+v, want invoice 302", got.Items)
+	}
+	if euro.Currency != "EUR" || euro.BalanceDueMinor != 8000 || euro.BalanceDueBaseMinor != 6800 || euro.TotalBaseMinor != 7650 {
+		t.Fatalf("EUR invoice = %+v", *euro)
+	}
+}
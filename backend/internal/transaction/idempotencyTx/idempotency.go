@@ -0,0 +1,152 @@
+package idempotencyTx
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+)
+
+const (
+	// Retention is how long a key and its stored response are kept.
+	Retention = 24 * time.Hour
+
+	timestampLayout = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+var (
+	// ErrKeyReused is returned when a key comes back with a different request.
+	ErrKeyReused = errors.New("idempotency key was already used for a different request")
+	// ErrInProgress is returned when the first request with a key has not finished yet.
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// StoredResponse is the response recorded for a key.
+type StoredResponse struct {
+	Status int
+	Header map[string][]string
+	Body   []byte
+}
+
+// Reserve claims key for the request identified by requestHash.
+//
+// The first request gets the id of a pending reservation to Complete or
+// Release once it has run. A repeat of a completed request gets its stored
+// response instead. Expired keys are swept first, so a key can be reused
+// after Retention.
+func Reserve(ctx context.Context, db *sql.DB, key, requestHash string, now time.Time) (int64, *StoredResponse, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE expires_at <= ?;
+	`, formatTimestamp(now)); err != nil {
+		return 0, nil, fmt.Errorf("sweep expired idempotency keys: %w", err)
+	}
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO idempotency_keys (
+			account_id,
+			idempotency_key,
+			request_hash,
+			expires_at
+		)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (account_id, idempotency_key) DO NOTHING
+		RETURNING id;
+	`, accountID, key, requestHash, formatTimestamp(now.Add(Retention))).Scan(&id)
+	if err == nil {
+		if err := tx.Commit(); err != nil {
+			return 0, nil, fmt.Errorf("commit idempotency key: %w", err)
+		}
+		return id, nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, fmt.Errorf("insert idempotency key: %w", err)
+	}
+
+	var (
+		storedHash string
+		status     sql.NullInt64
+		header     sql.NullString
+		body       []byte
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT request_hash, response_status, response_headers, response_body
+		FROM idempotency_keys
+		WHERE account_id = ?
+		  AND idempotency_key = ?;
+	`, accountID, key).Scan(&storedHash, &status, &header, &body); err != nil {
+		return 0, nil, fmt.Errorf("load idempotency key: %w", err)
+	}
+	if storedHash != requestHash {
+		return 0, nil, ErrKeyReused
+	}
+	if !status.Valid {
+		return 0, nil, ErrInProgress
+	}
+
+	stored := &StoredResponse{
+		Status: int(status.Int64),
+		Body:   body,
+	}
+	if header.Valid && header.String != "" {
+		if err := json.Unmarshal([]byte(header.String), &stored.Header); err != nil {
+			return 0, nil, fmt.Errorf("decode stored response headers: %w", err)
+		}
+	}
+
+	return 0, stored, nil
+}
+
+// Complete stores the response for a reservation made by Reserve.
+func Complete(ctx context.Context, db *sql.DB, id int64, resp StoredResponse) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return fmt.Errorf("encode response headers: %w", err)
+	}
+
+	if _, err := db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET response_status = ?,
+			response_headers = ?,
+			response_body = ?
+		WHERE id = ?;
+	`, resp.Status, string(header), resp.Body, id); err != nil {
+		return fmt.Errorf("store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+// Release drops a pending reservation so the request can be retried with the
+// same key, e.g. after a server error.
+func Release(ctx context.Context, db *sql.DB, id int64) error {
+	if _, err := db.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE id = ?
+		  AND response_status IS NULL;
+	`, id); err != nil {
+		return fmt.Errorf("release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func formatTimestamp(ts time.Time) string {
+	return ts.UTC().Format(timestampLayout)
+}
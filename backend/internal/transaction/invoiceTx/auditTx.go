@@ -0,0 +1,360 @@
+package invoiceTx
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/userscope"
+)
+
+// Audit log actions.
+const (
+	AuditInvoiceCreated  = "invoice_created"
+	AuditDraftUpdated    = "draft_updated"
+	AuditRevisionCreated = "revision_created"
+	AuditStatusChanged   = "status_changed"
+	AuditReceiptCreated  = "receipt_created"
+	AuditReceiptUpdated  = "receipt_updated"
+	AuditReceiptDeleted  = "receipt_deleted"
+	AuditDepositCreated  = "deposit_created"
+	AuditDepositDeleted  = "deposit_deleted"
+	AuditRefundCreated   = "refund_created"
+	AuditInvoiceDeleted  = "invoice_deleted"
+
+	AuditCreditNoteCreated = "credit_note_created"
+)
+
+// AuditLogRow is one recorded change to an invoice or its payments.
+type AuditLogRow struct {
+	ID         int64
+	UserID     sql.NullInt64
+	ActorEmail sql.NullString
+	InvoiceID  int64
+	ClientID   int64
+	BaseNumber int64
+	Action     string
+	EntityID   sql.NullInt64
+	BeforeHash sql.NullString
+	AfterHash  sql.NullString
+	RequestID  sql.NullString
+	CreatedAt  string
+}
+
+// AuditLogFilters narrows the audit log. ClientID and BaseNumber select one
+// invoice; UserID selects one actor. BeforeID pages back from an entry.
+type AuditLogFilters struct {
+	ClientID   int64
+	BaseNumber int64
+	UserID     int64
+	BeforeID   int64
+	Limit      int
+}
+
+const defaultAuditLogLimit = 100
+
+// invoiceSnapshotHash hashes the invoice status and void record, its current
+// revision with lines, the payments and refunds recorded on that revision, and
+// the invoice's credit notes. It returns NULL when the invoice does not exist
+// yet.
+func invoiceSnapshotHash(ctx context.Context, tx *sql.Tx, invoiceID int64) (sql.NullString, error) {
+	var snapshot sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT json_object(
+			'status', i.status,
+			'voidReason', i.void_reason,
+			'voidedAt', i.voided_at,
+			'voidedByUserId', i.voided_by_user_id,
+			'voidedByEmail', i.voided_by_email,
+			'currentRevisionId', i.current_revision_id,
+			'revision', (
+				SELECT json_object(
+					'revisionNo', r.revision_no,
+					'issueDate', r.issue_date,
+					'supplyDate', r.supply_date,
+					'dueByDate', r.due_by_date,
+					'clientName', r.client_name,
+					'clientCompanyName', r.client_company_name,
+					'clientAddress', r.client_address,
+					'clientEmail', r.client_email,
+					'clientVatNumber', r.client_vat_number,
+					'note', r.note,
+					'currency', r.currency,
+					'exchangeRateMicro', r.exchange_rate_micro,
+					'vatTreatment', r.vat_treatment,
+					'vatRate', r.vat_rate,
+					'taxRateId', r.tax_rate_id,
+					'taxRateName', r.tax_rate_name,
+					'discountType', r.discount_type,
+					'discountRate', r.discount_rate,
+					'discountMinor', r.discount_minor,
+					'depositType', r.deposit_type,
+					'depositRate', r.deposit_rate,
+					'depositMinor', r.deposit_minor,
+					'deductionRate', r.deduction_rate,
+					'deductionLineTypes', r.deduction_line_types,
+					'deductionMinor', r.deduction_minor,
+					'subtotalMinor', r.subtotal_minor,
+					'vatAmountMinor', r.vat_amount_minor,
+					'totalMinor', r.total_minor
+				)
+				FROM invoice_revisions r
+				WHERE r.id = i.current_revision_id
+			),
+			'items', (
+				SELECT json_group_array(json_object(
+					'sortOrder', it.sort_order,
+					'productId', it.product_id,
+					'name', it.name,
+					'lineType', it.line_type,
+					'pricingMode', it.pricing_mode,
+					'quantity', COALESCE(it.quantity_milli, it.quantity * 1000),
+					'unit', it.unit,
+					'minutesWorked', it.minutes_worked,
+					'unitPriceMinor', it.unit_price_minor,
+					'lineTotalMinor', it.line_total_minor,
+					'vatRate', it.vat_rate,
+					'discountType', it.discount_type,
+					'discountRate', it.discount_rate,
+					'discountMinor', it.discount_minor
+				))
+				FROM (
+					SELECT *
+					FROM invoice_items
+					WHERE invoice_revision_id = i.current_revision_id
+					ORDER BY sort_order ASC, id ASC
+				) it
+			),
+			'payments', (
+				SELECT json_group_array(json_object(
+					'id', p.id,
+					'paymentType', p.payment_type,
+					'receiptNo', p.receipt_no,
+					'amountMinor', p.amount_minor,
+					'paymentDate', p.payment_date,
+					'label', p.label,
+					'paymentMethod', p.payment_method,
+					'reference', p.reference
+				))
+				FROM (
+					SELECT *
+					FROM payments
+					WHERE applied_in_revision_id = i.current_revision_id
+					ORDER BY id ASC
+				) p
+			),
+			'refunds', (
+				SELECT json_group_array(json_object(
+					'id', rf.id,
+					'paymentId', rf.payment_id,
+					'amountMinor', rf.amount_minor,
+					'refundDate', rf.refund_date
+				))
+				FROM (
+					SELECT *
+					FROM payment_refunds
+					WHERE applied_in_revision_id = i.current_revision_id
+					ORDER BY id ASC
+				) rf
+			),
+			'creditNotes', (
+				SELECT json_group_array(json_object(
+					'id', cn.id,
+					'creditNoteNo', cn.credit_note_no,
+					'revisionId', cn.invoice_revision_id,
+					'issueDate', cn.issue_date,
+					'totalMinor', cn.total_minor
+				))
+				FROM (
+					SELECT *
+					FROM credit_notes
+					WHERE invoice_id = i.id
+					ORDER BY id ASC
+				) cn
+			)
+		)
+		FROM invoices i
+		WHERE i.id = ?;
+	`, invoiceID).Scan(&snapshot)
+	if errors.Is(err, sql.ErrNoRows) {
+		return sql.NullString{}, nil
+	}
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("load invoice audit snapshot: %w", err)
+	}
+	if !snapshot.Valid {
+		return sql.NullString{}, nil
+	}
+
+	sum := sha256.Sum256([]byte(snapshot.String))
+	return sql.NullString{String: hex.EncodeToString(sum[:]), Valid: true}, nil
+}
+
+// writeInvoiceAudit appends one audit entry for a change to invoiceID,
+// hashing the invoice as it stands now for the after snapshot. It must run in
+// the transaction that made the change.
+func writeInvoiceAudit(
+	ctx context.Context,
+	tx *sql.Tx,
+	invoiceID int64,
+	action string,
+	entityID int64,
+	beforeHash sql.NullString,
+) error {
+	afterHash, err := invoiceSnapshotHash(ctx, tx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	return insertInvoiceAudit(ctx, tx, invoiceID, action, entityID, beforeHash, afterHash)
+}
+
+// insertInvoiceAudit appends one audit entry for invoiceID with the given
+// snapshots. The invoice must still exist, so a deletion is recorded before
+// the row goes.
+func insertInvoiceAudit(
+	ctx context.Context,
+	tx *sql.Tx,
+	invoiceID int64,
+	action string,
+	entityID int64,
+	beforeHash sql.NullString,
+	afterHash sql.NullString,
+) error {
+	userID, actorEmail := auditActor(ctx)
+
+	var requestID any
+	if id := strings.TrimSpace(middleware.GetReqID(ctx)); id != "" {
+		requestID = id
+	}
+
+	var entity any
+	if entityID > 0 {
+		entity = entityID
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO audit_log (
+			account_id,
+			user_id,
+			actor_email,
+			invoice_id,
+			client_id,
+			base_number,
+			action,
+			entity_id,
+			before_hash,
+			after_hash,
+			request_id
+		)
+		SELECT i.account_id, ?, ?, i.id, i.client_id, i.base_number, ?, ?, ?, ?, ?
+		FROM invoices i
+		WHERE i.id = ?;
+	`, userID, actorEmail, action, entity, beforeHash, afterHash, requestID, invoiceID); err != nil {
+		return fmt.Errorf("write %s audit entry: %w", action, err)
+	}
+
+	return nil
+}
+
+// auditActor returns the user ID and email of the principal in ctx as SQL
+// arguments, NULL when unknown.
+func auditActor(ctx context.Context) (userID, email any) {
+	if principal, ok := userscope.PrincipalFromContext(ctx); ok {
+		userID = principal.UserID
+		if v := strings.TrimSpace(principal.Email); v != "" {
+			email = v
+		}
+	}
+	return userID, email
+}
+
+// QueryAuditLog returns audit entries for the account, newest first.
+func QueryAuditLog(ctx context.Context, db *sql.DB, filters AuditLogFilters) ([]AuditLogRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"account_id = ?"}
+	args := []any{accountID}
+	if filters.ClientID > 0 {
+		where = append(where, "client_id = ?")
+		args = append(args, filters.ClientID)
+	}
+	if filters.BaseNumber > 0 {
+		where = append(where, "base_number = ?")
+		args = append(args, filters.BaseNumber)
+	}
+	if filters.UserID > 0 {
+		where = append(where, "user_id = ?")
+		args = append(args, filters.UserID)
+	}
+	if filters.BeforeID > 0 {
+		where = append(where, "id < ?")
+		args = append(args, filters.BeforeID)
+	}
+
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = defaultAuditLogLimit
+	}
+	args = append(args, limit)
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			id,
+			user_id,
+			actor_email,
+			invoice_id,
+			client_id,
+			base_number,
+			action,
+			entity_id,
+			before_hash,
+			after_hash,
+			request_id,
+			created_at
+		FROM audit_log
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id DESC
+		LIMIT ?;
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]AuditLogRow, 0)
+	for rows.Next() {
+		var row AuditLogRow
+		if err := rows.Scan(
+			&row.ID,
+			&row.UserID,
+			&row.ActorEmail,
+			&row.InvoiceID,
+			&row.ClientID,
+			&row.BaseNumber,
+			&row.Action,
+			&row.EntityID,
+			&row.BeforeHash,
+			&row.AfterHash,
+			&row.RequestID,
+			&row.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan audit log row: %w", err)
+		}
+		out = append(out, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit log rows: %w", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,135 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/userscope"
+)
+
+func TestAuditLog_RecordsReceiptMutationsWithActorAndHashes(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	ctx = userscope.WithPrincipal(ctx, userscope.Principal{
+		UserID:    42,
+		AccountID: accountscope.DefaultAccountID,
+		Email:     "owner@example.com",
+		Role:      "owner",
+	})
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "req-audit-1")
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 920, "issued")
+
+	_, _, receiptNo, err := invoiceTx.CreatePaymentReceipt(ctx, a, clientID, 920, 1, &models.PaymentReceiptCreateIn{
+		AmountMinor: 200,
+		PaymentDate: "2026-04-01",
+	})
+	if err != nil {
+		t.Fatalf("CreatePaymentReceipt: %v", err)
+	}
+	if _, err := invoiceTx.UpdatePaymentReceiptMetadata(ctx, a, clientID, 920, 1, receiptNo, &models.PaymentReceiptUpdateIn{
+		PaymentDate: "2026-04-02",
+	}); err != nil {
+		t.Fatalf("UpdatePaymentReceiptMetadata: %v", err)
+	}
+	if _, err := invoiceTx.DeletePaymentReceipt(ctx, a, clientID, 920, 1, receiptNo); err != nil {
+		t.Fatalf("DeletePaymentReceipt: %v", err)
+	}
+	if err := invoiceTx.UpdateInvoiceStatus(ctx, a, clientID, 920, "void"); err != nil {
+		t.Fatalf("UpdateInvoiceStatus: %v", err)
+	}
+
+	entries, err := invoiceTx.QueryAuditLog(ctx, a.DB, invoiceTx.AuditLogFilters{BaseNumber: 920})
+	if err != nil {
+		t.Fatalf("QueryAuditLog: %v", err)
+	}
+	wantActions := []string{
+		invoiceTx.AuditStatusChanged,
+		invoiceTx.AuditReceiptDeleted,
+		invoiceTx.AuditReceiptUpdated,
+		invoiceTx.AuditReceiptCreated,
+	}
+	if len(entries) != len(wantActions) {
+		t.Fatalf("audit entries = %+v, want %d", entries, len(wantActions))
+	}
+	for i, entry := range entries {
+		if entry.Action != wantActions[i] {
+			t.Fatalf("entry %d action = %q, want %q", i, entry.Action, wantActions[i])
+		}
+		if entry.UserID.Int64 != 42 || entry.ActorEmail.String != "owner@example.com" || entry.RequestID.String != "req-audit-1" {
+			t.Fatalf("entry %d actor = %+v", i, entry)
+		}
+		if !entry.BeforeHash.Valid || !entry.AfterHash.Valid || entry.BeforeHash.String == entry.AfterHash.String {
+			t.Fatalf("entry %d hashes = %+v/%+v, want two different hashes", i, entry.BeforeHash, entry.AfterHash)
+		}
+		if i > 0 && entries[i].AfterHash.String != entries[i-1].BeforeHash.String {
+			t.Fatalf("entry %d after hash does not chain into the next entry", i)
+		}
+	}
+
+	byOtherUser, err := invoiceTx.QueryAuditLog(ctx, a.DB, invoiceTx.AuditLogFilters{UserID: 7})
+	if err != nil {
+		t.Fatalf("QueryAuditLog(other user): %v", err)
+	}
+	if len(byOtherUser) != 0 {
+		t.Fatalf("other user entries = %d, want 0", len(byOtherUser))
+	}
+
+	if _, err := a.DB.Exec(`UPDATE audit_log SET action = 'receipt_created'`); err == nil {
+		t.Fatal("expected audit log update to be rejected")
+	}
+	if _, err := a.DB.Exec(`DELETE FROM audit_log`); err == nil {
+		t.Fatal("expected audit log delete to be rejected")
+	}
+}
+
+func TestAuditLog_SnapshotHashCoversInvoiceFields(t *testing.T) {
+	tests := []struct {
+		name string
+		edit string
+	}{
+		{name: "void record", edit: `UPDATE invoices SET void_reason = 'Raised in error', voided_by_email = 'owner@example.com' WHERE id = ?`},
+		{name: "currency", edit: `UPDATE invoice_revisions SET currency = 'EUR', exchange_rate_micro = 850000 WHERE invoice_id = ?`},
+		{name: "vat treatment", edit: `UPDATE invoice_revisions SET vat_treatment = 'reverse_charge', client_vat_number = 'DE123456789' WHERE invoice_id = ?`},
+		{name: "deduction", edit: `UPDATE invoice_revisions SET deduction_rate = 2000, deduction_line_types = 'labour', deduction_minor = 200 WHERE invoice_id = ?`},
+		{name: "tax rate name", edit: `UPDATE invoice_revisions SET tax_rate_name = 'Standard 20%' WHERE invoice_id = ?`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+			a, cleanup := newTestApp(t)
+			defer cleanup()
+
+			clientID := insertClient(t, a)
+			invoiceID := insertInvoiceGraph(t, a, clientID, 930, "issued")
+
+			if err := invoiceTx.UpdateInvoiceStatus(ctx, a, clientID, 930, "issued"); err != nil {
+				t.Fatalf("UpdateInvoiceStatus(before edit): %v", err)
+			}
+			if _, err := a.DB.Exec(tt.edit, invoiceID); err != nil {
+				t.Fatalf("edit invoice: %v", err)
+			}
+			if err := invoiceTx.UpdateInvoiceStatus(ctx, a, clientID, 930, "issued"); err != nil {
+				t.Fatalf("UpdateInvoiceStatus(after edit): %v", err)
+			}
+
+			entries, err := invoiceTx.QueryAuditLog(ctx, a.DB, invoiceTx.AuditLogFilters{BaseNumber: 930})
+			if err != nil {
+				t.Fatalf("QueryAuditLog: %v", err)
+			}
+			if len(entries) != 2 {
+				t.Fatalf("audit entries = %d, want 2", len(entries))
+			}
+			if entries[0].BeforeHash.String == entries[1].AfterHash.String {
+				t.Fatalf("snapshot hash did not change after editing %s", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+var (
+	// ErrInsufficientClientCredit is returned when applying more credit than the client has on account.
+	ErrInsufficientClientCredit = errors.New("client credit balance is too low")
+	// ErrCreditExceedsBalanceDue is returned when applying more credit than the invoice still owes.
+	ErrCreditExceedsBalanceDue = errors.New("applied credit cannot exceed the invoice balance due")
+)
+
+const defaultClientCreditLabel = "Account credit"
+
+// Client credit entry kinds.
+const (
+	ClientCreditOverpayment = "overpayment"
+	ClientCreditCreditNote  = "credit_note"
+	ClientCreditApplied     = "applied"
+)
+
+// ClientCreditEntryRow is one movement on a client's credit balance.
+// AmountMinor is positive for money put on account and negative when applied.
+type ClientCreditEntryRow struct {
+	Kind        string
+	BaseNumber  int64
+	RevisionNo  int64
+	ReceiptNo   sql.NullInt64
+	EntryDate   string
+	Currency    string
+	AmountMinor int64
+}
+
+// ClientCreditBalanceRow is a client's credit balance in one currency.
+type ClientCreditBalanceRow struct {
+	Currency     string
+	BalanceMinor int64
+}
+
+// ClientCreditStatement is a client's credit balances, one per currency the
+// client has been invoiced in, and the entries behind them.
+type ClientCreditStatement struct {
+	ClientID int64
+	Balances []ClientCreditBalanceRow
+	Entries  []ClientCreditEntryRow
+}
+
+// QueryClientCredit returns the client's credit balances with their entries,
+// oldest first.
+//
+// Credit is derived rather than stored: any non-void invoice that received more
+// in payments and credit notes than its current total puts the excess on
+// account, and receipts applied from credit take it off again. Each currency
+// keeps its own balance.
+func QueryClientCredit(ctx context.Context, db *sql.DB, clientID int64) (*ClientCreditStatement, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := db.QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM clients WHERE id = ? AND account_id = ?);
+	`, clientID, accountID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check client: %w", err)
+	}
+	if !exists {
+		return nil, ErrInvoiceNotFound
+	}
+
+	out := &ClientCreditStatement{
+		ClientID: clientID,
+		Balances: make([]ClientCreditBalanceRow, 0),
+		Entries:  make([]ClientCreditEntryRow, 0),
+	}
+
+	balances, err := queryClientCreditBalances(ctx, db, accountID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	out.Balances = append(out.Balances, balances...)
+
+	credits, err := queryClientCreditSources(ctx, db, accountID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	out.Entries = append(out.Entries, credits...)
+
+	applied, err := queryClientCreditApplied(ctx, db, accountID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	out.Entries = append(out.Entries, applied...)
+
+	sort.SliceStable(out.Entries, func(i, j int) bool {
+		return out.Entries[i].EntryDate < out.Entries[j].EntryDate
+	})
+
+	return out, nil
+}
+
+// ApplyClientCredit pays part of an invoice from the client's credit balance
+// in the invoice's currency. The payment is recorded as a receipt on the
+// invoice's current revision.
+func ApplyClientCredit(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	canonical *models.ClientCreditApplyIn,
+) (invoiceID, paymentID, receiptNo int64, err error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		status     string
+		revisionNo int64
+		dueMinor   int64
+		currency   string
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT b.status, b.revision_no, b.balance_due_minor, b.currency
+		FROM invoice_book_rows b
+		JOIN invoices i
+			ON i.id = b.id
+		WHERE i.account_id = ?
+		  AND b.client_id = ?
+		  AND b.base_number = ?;
+	`, accountID, clientID, canonical.BaseNumber).Scan(&status, &revisionNo, &dueMinor, &currency)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, 0, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("load invoice balance due: %w", err)
+	}
+	if status == "void" {
+		return 0, 0, 0, ErrInvoiceVoidForReceipt
+	}
+
+	var balanceMinor int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT balance_minor
+		FROM client_credit_balances
+		WHERE account_id = ?
+		  AND client_id = ?
+		  AND currency = ?;
+	`, accountID, clientID, currency).Scan(&balanceMinor)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, 0, fmt.Errorf("load client credit balance: %w", err)
+	}
+	if canonical.AmountMinor > balanceMinor {
+		return 0, 0, 0, ErrInsufficientClientCredit
+	}
+	if canonical.AmountMinor > dueMinor {
+		return 0, 0, 0, ErrCreditExceedsBalanceDue
+	}
+
+	label := defaultClientCreditLabel
+	if canonical.Label != nil {
+		label = *canonical.Label
+	}
+	receipt := models.PaymentReceiptCreateIn{
+		AmountMinor: canonical.AmountMinor,
+		PaymentDate: canonical.PaymentDate,
+		Label:       &label,
+	}
+	invoiceID, paymentID, receiptNo, err = createPaymentReceiptInTx(ctx, tx, clientID, canonical.BaseNumber, revisionNo, &receipt, receiptSource{fromClientCredit: true})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commit client credit: %w", err)
+	}
+
+	return invoiceID, paymentID, receiptNo, nil
+}
+
+func queryClientCreditBalances(ctx context.Context, db *sql.DB, accountID, clientID int64) ([]ClientCreditBalanceRow, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT currency, balance_minor
+		FROM client_credit_balances
+		WHERE account_id = ?
+		  AND client_id = ?
+		ORDER BY currency ASC;
+	`, accountID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("query client credit balances: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]ClientCreditBalanceRow, 0)
+	for rows.Next() {
+		var balance ClientCreditBalanceRow
+		if err := rows.Scan(&balance.Currency, &balance.BalanceMinor); err != nil {
+			return nil, fmt.Errorf("scan client credit balance: %w", err)
+		}
+		out = append(out, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate client credit balances: %w", err)
+	}
+
+	return out, nil
+}
+
+// queryClientCreditSources splits each invoice's excess into the overpaid part
+// and the part left over from credit notes.
+func queryClientCreditSources(ctx context.Context, db *sql.DB, accountID, clientID int64) ([]ClientCreditEntryRow, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			b.base_number,
+			b.revision_no,
+			b.currency,
+			b.total_minor - b.deduction_minor AS payable_minor,
+			b.paid_minor,
+			b.credited_minor,
+			COALESCE((
+				SELECT MAX(p.payment_date)
+				FROM payments p
+				WHERE p.applied_in_revision_id = b.current_revision_id
+				  AND p.payment_type = 'payment'
+			), b.issue_date) AS last_payment_date,
+			COALESCE((
+				SELECT MAX(cn.issue_date)
+				FROM credit_notes cn
+				WHERE cn.invoice_id = b.id
+			), b.issue_date) AS last_credit_note_date
+		FROM invoice_book_rows b
+		JOIN invoices i
+			ON i.id = b.id
+		WHERE i.account_id = ?
+		  AND b.client_id = ?
+		  AND b.status <> 'void'
+		  AND b.paid_minor + b.credited_minor > b.total_minor - b.deduction_minor
+		ORDER BY b.base_number ASC;
+	`, accountID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("query client credit sources: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]ClientCreditEntryRow, 0)
+	for rows.Next() {
+		var (
+			baseNumber, revisionNo               int64
+			currency                             string
+			payableMinor, paidMinor, creditMinor int64
+			paymentDate, creditNoteDate          string
+		)
+		if err := rows.Scan(&baseNumber, &revisionNo, &currency, &payableMinor, &paidMinor, &creditMinor, &paymentDate, &creditNoteDate); err != nil {
+			return nil, fmt.Errorf("scan client credit source: %w", err)
+		}
+
+		excess := paidMinor + creditMinor - payableMinor
+		overpaid := min(excess, max(paidMinor-payableMinor, 0))
+		if overpaid > 0 {
+			out = append(out, ClientCreditEntryRow{
+				Kind:        ClientCreditOverpayment,
+				BaseNumber:  baseNumber,
+				RevisionNo:  revisionNo,
+				EntryDate:   paymentDate,
+				Currency:    currency,
+				AmountMinor: overpaid,
+			})
+		}
+		if credited := excess - overpaid; credited > 0 {
+			out = append(out, ClientCreditEntryRow{
+				Kind:        ClientCreditCreditNote,
+				BaseNumber:  baseNumber,
+				RevisionNo:  revisionNo,
+				EntryDate:   creditNoteDate,
+				Currency:    currency,
+				AmountMinor: credited,
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate client credit sources: %w", err)
+	}
+
+	return out, nil
+}
+
+func queryClientCreditApplied(ctx context.Context, db *sql.DB, accountID, clientID int64) ([]ClientCreditEntryRow, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			i.base_number,
+			r.revision_no,
+			p.receipt_no,
+			p.payment_date,
+			r.currency,
+			p.amount_minor
+		FROM payments p
+		JOIN invoices i
+			ON i.current_revision_id = p.applied_in_revision_id
+		JOIN invoice_revisions r
+			ON r.id = p.applied_in_revision_id
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND p.payment_type = 'payment'
+		  AND p.from_client_credit = 1
+		ORDER BY p.payment_date ASC, p.id ASC;
+	`, accountID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("query applied client credit: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]ClientCreditEntryRow, 0)
+	for rows.Next() {
+		entry := ClientCreditEntryRow{Kind: ClientCreditApplied}
+		if err := rows.Scan(&entry.BaseNumber, &entry.RevisionNo, &entry.ReceiptNo, &entry.EntryDate, &entry.Currency, &entry.AmountMinor); err != nil {
+			return nil, fmt.Errorf("scan applied client credit: %w", err)
+		}
+		entry.AmountMinor = -entry.AmountMinor
+		out = append(out, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate applied client credit: %w", err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/clientsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestApplyClientCredit_MovesOverpaymentToAnotherInvoice(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	overpaidID := insertInvoiceGraph(t, a, clientID, 801, "issued")
+	openID := insertInvoiceGraph(t, a, clientID, 802, "issued")
+
+	if _, _, _, err := invoiceTx.CreatePaymentReceipt(ctx, a, clientID, 801, 1, &models.PaymentReceiptCreateIn{
+		AmountMinor: 1150,
+		PaymentDate: "2026-04-01",
+	}); err != nil {
+		t.Fatalf("CreatePaymentReceipt: %v", err)
+	}
+	assertInvoiceStatus(t, a, overpaidID, "paid")
+
+	clients, err := clientsTx.ListClients(a, ctx)
+	if err != nil {
+		t.Fatalf("ListClients: %v", err)
+	}
+	if len(clients) != 1 || clients[0].CreditBalanceMinor != 250 {
+		t.Fatalf("clients = %+v, want one client with 250 credit", clients)
+	}
+
+	_, _, _, err = invoiceTx.ApplyClientCredit(ctx, a, clientID, &models.ClientCreditApplyIn{
+		BaseNumber:  802,
+		AmountMinor: 251,
+		PaymentDate: "2026-04-03",
+	})
+	if !errors.Is(err, invoiceTx.ErrInsufficientClientCredit) {
+		t.Fatalf("ApplyClientCredit(over balance) error = %v, want %v", err, invoiceTx.ErrInsufficientClientCredit)
+	}
+
+	if _, _, _, err := invoiceTx.ApplyClientCredit(ctx, a, clientID, &models.ClientCreditApplyIn{
+		BaseNumber:  802,
+		AmountMinor: 200,
+		PaymentDate: "2026-04-03",
+	}); err != nil {
+		t.Fatalf("ApplyClientCredit: %v", err)
+	}
+	assertInvoiceStatus(t, a, openID, "issued")
+
+	statement, err := invoiceTx.QueryClientCredit(ctx, a.DB, clientID)
+	if err != nil {
+		t.Fatalf("QueryClientCredit: %v", err)
+	}
+	if len(statement.Balances) != 1 || statement.Balances[0].Currency != "GBP" || statement.Balances[0].BalanceMinor != 50 {
+		t.Fatalf("Balances = %+v, want 50 GBP", statement.Balances)
+	}
+	if len(statement.Entries) != 2 {
+		t.Fatalf("entries = %+v, want 2", statement.Entries)
+	}
+	if got := statement.Entries[0]; got.Kind != invoiceTx.ClientCreditOverpayment || got.BaseNumber != 801 || got.AmountMinor != 250 {
+		t.Fatalf("first entry = %+v, want 250 overpaid on invoice 801", got)
+	}
+	if got := statement.Entries[1]; got.Kind != invoiceTx.ClientCreditApplied || got.BaseNumber != 802 || got.AmountMinor != -200 {
+		t.Fatalf("second entry = %+v, want 200 applied to invoice 802", got)
+	}
+}
+
+func TestClientCredit_KeepsCurrenciesApart(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	euroID := insertInvoiceGraph(t, a, clientID, 811, "issued")
+	insertInvoiceGraph(t, a, clientID, 812, "issued")
+	if _, err := a.DB.Exec(`
+		UPDATE invoice_revisions
+		SET currency = 'EUR', exchange_rate_micro = 850000
+		WHERE invoice_id = ?
+	`, euroID); err != nil {
+		t.Fatalf("set invoice currency: %v", err)
+	}
+
+	if _, _, _, err := invoiceTx.CreatePaymentReceipt(ctx, a, clientID, 811, 1, &models.PaymentReceiptCreateIn{
+		AmountMinor: 1200,
+		PaymentDate: "2026-04-01",
+	}); err != nil {
+		t.Fatalf("CreatePaymentReceipt: %v", err)
+	}
+
+	statement, err := invoiceTx.QueryClientCredit(ctx, a.DB, clientID)
+	if err != nil {
+		t.Fatalf("QueryClientCredit: %v", err)
+	}
+	balances := make(map[string]int64)
+	for _, balance := range statement.Balances {
+		balances[balance.Currency] = balance.BalanceMinor
+	}
+	if balances["EUR"] != 300 || balances["GBP"] != 0 {
+		t.Fatalf("Balances = %+v, want 300 EUR and no GBP credit", statement.Balances)
+	}
+
+	clients, err := clientsTx.ListClients(a, ctx)
+	if err != nil {
+		t.Fatalf("ListClients: %v", err)
+	}
+	if len(clients) != 1 || clients[0].CreditBalanceMinor != 0 {
+		t.Fatalf("clients = %+v, want no credit in the workspace currency", clients)
+	}
+
+	_, _, _, err = invoiceTx.ApplyClientCredit(ctx, a, clientID, &models.ClientCreditApplyIn{
+		BaseNumber:  812,
+		AmountMinor: 100,
+		PaymentDate: "2026-04-03",
+	})
+	if !errors.Is(err, invoiceTx.ErrInsufficientClientCredit) {
+		t.Fatalf("ApplyClientCredit(other currency) error = %v, want %v", err, invoiceTx.ErrInsufficientClientCredit)
+	}
+}
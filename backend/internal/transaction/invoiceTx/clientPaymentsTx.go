@@ -0,0 +1,462 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
+)
+
+var (
+	ErrClientPaymentNotFound = errors.New("client payment not found")
+	// ErrAllocationExceedsPayment is returned when allocations add up to more than the unallocated amount.
+	ErrAllocationExceedsPayment = errors.New("allocations cannot exceed the unallocated client payment amount")
+	// ErrAllocationCurrencyMismatch is returned when a payment is allocated to an invoice in another currency.
+	ErrAllocationCurrencyMismatch = errors.New("client payment and invoice currencies differ")
+)
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ClientPaymentRow is one client payment with the receipts allocated from it.
+type ClientPaymentRow struct {
+	ID             int64
+	ClientID       int64
+	AmountMinor    int64
+	PaymentDate    string
+	Label          sql.NullString
+	PaymentMethod  sql.NullString
+	Reference      sql.NullString
+	Currency       string
+	AllocatedMinor int64
+	Allocations    []ClientPaymentAllocationRow
+}
+
+// ClientPaymentAllocationRow is a payment receipt created from a client payment.
+type ClientPaymentAllocationRow struct {
+	BaseNumber  int64
+	RevisionNo  int64
+	ReceiptNo   int64
+	AmountMinor int64
+}
+
+// CreateClientPayment saves one payment from a client and allocates it across
+// the client's invoices in its currency, in the same transaction. Each
+// allocation becomes a payment receipt on the invoice's current revision.
+func CreateClientPayment(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	canonical *models.ClientPaymentCreateIn,
+) (clientPaymentID int64, allocations []ClientPaymentAllocationRow, err error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := assertClientBelongsToAccount(ctx, tx, accountID, clientID); err != nil {
+		return 0, nil, err
+	}
+
+	currency := canonical.Currency
+	if currency == "" {
+		if currency, err = workspaceCurrency(ctx, tx, accountID); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO client_payments (
+			account_id,
+			client_id,
+			amount_minor,
+			payment_date,
+			label,
+			payment_method,
+			reference,
+			currency
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`, accountID, clientID, canonical.AmountMinor, canonical.PaymentDate,
+		normalizedOptionalString(canonical.Label),
+		normalizedOptionalString(canonical.PaymentMethod),
+		normalizedOptionalString(canonical.Reference),
+		invoiceformat.NormalizeCurrency(currency),
+	).Scan(&clientPaymentID); err != nil {
+		return 0, nil, fmt.Errorf("insert client payment: %w", err)
+	}
+
+	header, err := loadClientPayment(ctx, tx, accountID, clientID, clientPaymentID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	allocations, err = allocateClientPaymentInTx(ctx, tx, accountID, *header, canonical.AutoAllocate, canonical.Allocations)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("commit client payment: %w", err)
+	}
+
+	return clientPaymentID, allocations, nil
+}
+
+// AllocateClientPayment allocates what is left of a saved client payment.
+func AllocateClientPayment(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	clientPaymentID int64,
+	canonical *models.ClientPaymentAllocateIn,
+) ([]ClientPaymentAllocationRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	header, err := loadClientPayment(ctx, tx, accountID, clientID, clientPaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	allocations, err := allocateClientPaymentInTx(ctx, tx, accountID, *header, canonical.AutoAllocate, canonical.Allocations)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit client payment allocation: %w", err)
+	}
+
+	return allocations, nil
+}
+
+// ListClientPayments returns a client's payments, newest first, with their
+// allocations and unallocated remainder.
+func ListClientPayments(ctx context.Context, db *sql.DB, clientID int64) ([]ClientPaymentRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, clientPaymentSelectSQL+`
+		WHERE cp.account_id = ?
+		  AND cp.client_id = ?
+		ORDER BY cp.payment_date DESC, cp.id DESC;
+	`, accountID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("query client payments: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]ClientPaymentRow, 0)
+	for rows.Next() {
+		payment, err := scanClientPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, payment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate client payments: %w", err)
+	}
+
+	for i := range out {
+		allocations, err := queryClientPaymentAllocations(ctx, db, out[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		out[i].Allocations = allocations
+	}
+
+	return out, nil
+}
+
+// QueryClientPayment returns one client payment with its allocations.
+func QueryClientPayment(ctx context.Context, db *sql.DB, clientID, clientPaymentID int64) (*ClientPaymentRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadClientPayment(ctx, db, accountID, clientID, clientPaymentID)
+}
+
+// allocateClientPaymentInTx turns allocations into payment receipts, either
+// as given or oldest open invoice first, capped by the unallocated amount.
+// Only invoices in the payment's currency can take an allocation.
+func allocateClientPaymentInTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	accountID int64,
+	header ClientPaymentRow,
+	autoAllocate bool,
+	requested []models.ClientPaymentAllocationIn,
+) ([]ClientPaymentAllocationRow, error) {
+	allocated, err := sumClientPaymentAllocated(ctx, tx, header.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := header.AmountMinor - allocated
+
+	type target struct {
+		baseNumber  int64
+		revisionNo  int64
+		amountMinor int64
+	}
+	var targets []target
+
+	if autoAllocate {
+		open, err := queryOpenInvoicesOldestFirst(ctx, tx, accountID, header.ClientID, header.Currency)
+		if err != nil {
+			return nil, err
+		}
+		for _, inv := range open {
+			if remaining <= 0 {
+				break
+			}
+			amount := min(inv.AmountMinor, remaining)
+			targets = append(targets, target{baseNumber: inv.BaseNumber, revisionNo: inv.RevisionNo, amountMinor: amount})
+			remaining -= amount
+		}
+	} else {
+		var total int64
+		for _, alloc := range requested {
+			total += alloc.AmountMinor
+		}
+		if total > remaining {
+			return nil, ErrAllocationExceedsPayment
+		}
+		for _, alloc := range requested {
+			var (
+				revisionNo int64
+				currency   string
+			)
+			err := tx.QueryRowContext(ctx, `
+				SELECT r.revision_no, r.currency
+				FROM invoices i
+				JOIN invoice_revisions r
+					ON r.id = i.current_revision_id
+				WHERE i.account_id = ? AND i.client_id = ? AND i.base_number = ?;
+			`, accountID, header.ClientID, alloc.BaseNumber).Scan(&revisionNo, &currency)
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrInvoiceNotFound
+			}
+			if err != nil {
+				return nil, fmt.Errorf("load allocation invoice revision: %w", err)
+			}
+			if currency != header.Currency {
+				return nil, ErrAllocationCurrencyMismatch
+			}
+			targets = append(targets, target{baseNumber: alloc.BaseNumber, revisionNo: revisionNo, amountMinor: alloc.AmountMinor})
+		}
+	}
+
+	out := make([]ClientPaymentAllocationRow, 0, len(targets))
+	for _, t := range targets {
+		receipt := models.PaymentReceiptCreateIn{
+			AmountMinor:   t.amountMinor,
+			PaymentDate:   header.PaymentDate,
+			Label:         ptrFromNullString(header.Label),
+			PaymentMethod: ptrFromNullString(header.PaymentMethod),
+			Reference:     ptrFromNullString(header.Reference),
+		}
+		clientPaymentID := header.ID
+		_, _, receiptNo, err := createPaymentReceiptInTx(ctx, tx, header.ClientID, t.baseNumber, t.revisionNo, &receipt, receiptSource{clientPaymentID: &clientPaymentID})
+		if err != nil {
+			return nil, fmt.Errorf("allocate client payment to invoice %d: %w", t.baseNumber, err)
+		}
+		out = append(out, ClientPaymentAllocationRow{
+			BaseNumber:  t.baseNumber,
+			RevisionNo:  t.revisionNo,
+			ReceiptNo:   receiptNo,
+			AmountMinor: t.amountMinor,
+		})
+	}
+
+	return out, nil
+}
+
+// queryOpenInvoicesOldestFirst lists a client's issued invoices in currency
+// with a balance left, oldest first. AmountMinor is the balance still due.
+func queryOpenInvoicesOldestFirst(ctx context.Context, tx *sql.Tx, accountID, clientID int64, currency string) ([]ClientPaymentAllocationRow, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT b.base_number, b.revision_no, b.balance_due_minor
+		FROM invoice_book_rows b
+		JOIN invoices i
+			ON i.id = b.id
+		WHERE i.account_id = ?
+		  AND b.client_id = ?
+		  AND b.currency = ?
+		  AND b.status = 'issued'
+		  AND b.balance_due_minor > 0
+		ORDER BY b.issue_date ASC, b.base_number ASC;
+	`, accountID, clientID, currency)
+	if err != nil {
+		return nil, fmt.Errorf("query open invoices: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]ClientPaymentAllocationRow, 0)
+	for rows.Next() {
+		var inv ClientPaymentAllocationRow
+		if err := rows.Scan(&inv.BaseNumber, &inv.RevisionNo, &inv.AmountMinor); err != nil {
+			return nil, fmt.Errorf("scan open invoice: %w", err)
+		}
+		out = append(out, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate open invoices: %w", err)
+	}
+
+	return out, nil
+}
+
+// Allocations count only receipts on each invoice's current revision, so
+// snapshots carried into later revisions are not double counted.
+const clientPaymentSelectSQL = `
+	SELECT
+		cp.id,
+		cp.client_id,
+		cp.amount_minor,
+		cp.payment_date,
+		cp.label,
+		cp.payment_method,
+		cp.reference,
+		cp.currency,
+		COALESCE((
+			SELECT SUM(p.amount_minor)
+			FROM payments p
+			JOIN invoices i
+				ON i.current_revision_id = p.applied_in_revision_id
+			WHERE p.client_payment_id = cp.id
+			  AND p.payment_type = 'payment'
+		), 0) AS allocated_minor
+	FROM client_payments cp
+`
+
+func loadClientPayment(ctx context.Context, q queryer, accountID, clientID, clientPaymentID int64) (*ClientPaymentRow, error) {
+	row := q.QueryRowContext(ctx, clientPaymentSelectSQL+`
+		WHERE cp.account_id = ?
+		  AND cp.client_id = ?
+		  AND cp.id = ?;
+	`, accountID, clientID, clientPaymentID)
+
+	payment, err := scanClientPayment(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrClientPaymentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	allocations, err := queryClientPaymentAllocations(ctx, q, payment.ID)
+	if err != nil {
+		return nil, err
+	}
+	payment.Allocations = allocations
+
+	return &payment, nil
+}
+
+func scanClientPayment(row rowScanner) (ClientPaymentRow, error) {
+	var out ClientPaymentRow
+	err := row.Scan(
+		&out.ID,
+		&out.ClientID,
+		&out.AmountMinor,
+		&out.PaymentDate,
+		&out.Label,
+		&out.PaymentMethod,
+		&out.Reference,
+		&out.Currency,
+		&out.AllocatedMinor,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ClientPaymentRow{}, err
+	}
+	if err != nil {
+		return ClientPaymentRow{}, fmt.Errorf("scan client payment: %w", err)
+	}
+	return out, nil
+}
+
+func queryClientPaymentAllocations(ctx context.Context, q queryer, clientPaymentID int64) ([]ClientPaymentAllocationRow, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT
+			i.base_number,
+			r.revision_no,
+			p.receipt_no,
+			p.amount_minor
+		FROM payments p
+		JOIN invoices i
+			ON i.current_revision_id = p.applied_in_revision_id
+		JOIN invoice_revisions r
+			ON r.id = p.applied_in_revision_id
+		WHERE p.client_payment_id = ?
+		  AND p.payment_type = 'payment'
+		ORDER BY p.id ASC;
+	`, clientPaymentID)
+	if err != nil {
+		return nil, fmt.Errorf("query client payment allocations: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]ClientPaymentAllocationRow, 0)
+	for rows.Next() {
+		var alloc ClientPaymentAllocationRow
+		if err := rows.Scan(&alloc.BaseNumber, &alloc.RevisionNo, &alloc.ReceiptNo, &alloc.AmountMinor); err != nil {
+			return nil, fmt.Errorf("scan client payment allocation: %w", err)
+		}
+		out = append(out, alloc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate client payment allocations: %w", err)
+	}
+
+	return out, nil
+}
+
+func sumClientPaymentAllocated(ctx context.Context, tx *sql.Tx, clientPaymentID int64) (int64, error) {
+	var allocated int64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(p.amount_minor), 0)
+		FROM payments p
+		JOIN invoices i
+			ON i.current_revision_id = p.applied_in_revision_id
+		WHERE p.client_payment_id = ?
+		  AND p.payment_type = 'payment';
+	`, clientPaymentID).Scan(&allocated); err != nil {
+		return 0, fmt.Errorf("sum client payment allocations: %w", err)
+	}
+	return allocated, nil
+}
+
+func ptrFromNullString(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	return &v.String
+}
@@ -0,0 +1,122 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreateClientPayment_AutoAllocatesOldestFirstAndKeepsRemainder(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	newerID := insertInvoiceGraph(t, a, clientID, 701, "issued")
+	olderID := insertInvoiceGraph(t, a, clientID, 702, "issued")
+	if _, err := a.DB.Exec(`
+		UPDATE invoice_revisions
+		SET issue_date = '2026-03-01'
+		WHERE invoice_id = ?;
+	`, olderID); err != nil {
+		t.Fatalf("backdate invoice: %v", err)
+	}
+
+	method := "bank_transfer"
+	clientPaymentID, allocations, err := invoiceTx.CreateClientPayment(ctx, a, clientID, &models.ClientPaymentCreateIn{
+		AmountMinor:   1200,
+		PaymentDate:   "2026-04-02",
+		PaymentMethod: &method,
+		AutoAllocate:  true,
+	})
+	if err != nil {
+		t.Fatalf("CreateClientPayment: %v", err)
+	}
+	if len(allocations) != 2 {
+		t.Fatalf("allocations = %d, want 2", len(allocations))
+	}
+	if allocations[0].BaseNumber != 702 || allocations[0].AmountMinor != 900 {
+		t.Fatalf("first allocation = %+v, want 900 to invoice 702", allocations[0])
+	}
+	if allocations[1].BaseNumber != 701 || allocations[1].AmountMinor != 300 {
+		t.Fatalf("second allocation = %+v, want 300 to invoice 701", allocations[1])
+	}
+	assertInvoiceStatus(t, a, olderID, "paid")
+	assertInvoiceStatus(t, a, newerID, "issued")
+
+	var receiptMethod string
+	if err := a.DB.QueryRow(`
+		SELECT payment_method
+		FROM payments
+		WHERE client_payment_id = ? AND invoice_id = ?;
+	`, clientPaymentID, olderID).Scan(&receiptMethod); err != nil {
+		t.Fatalf("load allocated receipt: %v", err)
+	}
+	if receiptMethod != method {
+		t.Fatalf("receipt payment_method = %q, want %q", receiptMethod, method)
+	}
+
+	payment, err := invoiceTx.QueryClientPayment(ctx, a.DB, clientID, clientPaymentID)
+	if err != nil {
+		t.Fatalf("QueryClientPayment: %v", err)
+	}
+	if payment.AllocatedMinor != 1200 {
+		t.Fatalf("AllocatedMinor = %d, want 1200", payment.AllocatedMinor)
+	}
+}
+
+func TestAllocateClientPayment_RejectsOverAllocationAndReportsRemainder(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 710, "issued")
+	insertInvoiceGraph(t, a, clientID, 711, "issued")
+
+	clientPaymentID, _, err := invoiceTx.CreateClientPayment(ctx, a, clientID, &models.ClientPaymentCreateIn{
+		AmountMinor: 1000,
+		PaymentDate: "2026-04-02",
+		Allocations: []models.ClientPaymentAllocationIn{
+			{BaseNumber: 710, AmountMinor: 400},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateClientPayment: %v", err)
+	}
+
+	_, err = invoiceTx.AllocateClientPayment(ctx, a, clientID, clientPaymentID, &models.ClientPaymentAllocateIn{
+		Allocations: []models.ClientPaymentAllocationIn{
+			{BaseNumber: 711, AmountMinor: 601},
+		},
+	})
+	if !errors.Is(err, invoiceTx.ErrAllocationExceedsPayment) {
+		t.Fatalf("AllocateClientPayment(over) error = %v, want %v", err, invoiceTx.ErrAllocationExceedsPayment)
+	}
+
+	if _, err := invoiceTx.AllocateClientPayment(ctx, a, clientID, clientPaymentID, &models.ClientPaymentAllocateIn{
+		Allocations: []models.ClientPaymentAllocationIn{
+			{BaseNumber: 711, AmountMinor: 250},
+		},
+	}); err != nil {
+		t.Fatalf("AllocateClientPayment: %v", err)
+	}
+
+	payments, err := invoiceTx.ListClientPayments(ctx, a.DB, clientID)
+	if err != nil {
+		t.Fatalf("ListClientPayments: %v", err)
+	}
+	if len(payments) != 1 {
+		t.Fatalf("client payments = %d, want 1", len(payments))
+	}
+	if got := payments[0].AmountMinor - payments[0].AllocatedMinor; got != 350 {
+		t.Fatalf("unallocated = %d, want 350", got)
+	}
+	if len(payments[0].Allocations) != 2 {
+		t.Fatalf("allocations = %d, want 2", len(payments[0].Allocations))
+	}
+}
@@ -15,10 +15,10 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/dberr"
 	"github.com/viktorHadz/goInvoice26/internal/models"
 )
 
@@ -35,6 +35,61 @@ func Create(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn) (inv
 	}
 	defer tx.Rollback()
 
+	invoiceID, revisionID, err = createInTx(ctx, tx, accountID, canonical)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("commit: %w", err)
+	}
+	return invoiceID, revisionID, nil
+}
+
+// CreateNext inserts a new invoice under the next free base number for the
+// account and returns the number it allocated.
+func CreateNext(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn) (invoiceID, revisionID, baseNumber int64, err error) {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer tx.Rollback()
+
+	invoiceID, revisionID, baseNumber, err = CreateNextInTx(ctx, tx, canonical)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commit: %w", err)
+	}
+	return invoiceID, revisionID, baseNumber, nil
+}
+
+// CreateNextInTx allocates the next base number for the account and inserts
+// the invoice through the same path as [Create]. The caller owns tx.
+//
+// Overview.BaseNumber is overwritten with the allocated number.
+func CreateNextInTx(ctx context.Context, tx *sql.Tx, canonical *models.FEInvoiceIn) (invoiceID, revisionID, baseNumber int64, err error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	baseNumber, err = nextBaseNumber(ctx, tx, accountID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	canonical.Overview.BaseNumber = baseNumber
+
+	invoiceID, revisionID, err = createInTx(ctx, tx, accountID, canonical)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return invoiceID, revisionID, baseNumber, nil
+}
+
+func createInTx(ctx context.Context, tx *sql.Tx, accountID int64, canonical *models.FEInvoiceIn) (invoiceID, revisionID int64, err error) {
 	ov := &canonical.Overview
 
 	if err := assertClientBelongsToAccount(ctx, tx, accountID, ov.ClientID); err != nil {
@@ -48,12 +103,17 @@ func Create(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn) (inv
 		return 0, 0, fmt.Errorf("ensure invoice sequence row: %w", err)
 	}
 
+	number, err := allocateTemplateNumber(ctx, tx, accountID, ov.IssueDate, numberClientName(ov))
+	if err != nil {
+		return 0, 0, err
+	}
+
 	if err := tx.QueryRowContext(ctx, `
-		INSERT INTO invoices (account_id, client_id, base_number, status)
-		VALUES (?, ?, ?, 'draft')
+		INSERT INTO invoices (account_id, client_id, base_number, status, number_label, number_period, number_seq)
+		VALUES (?, ?, ?, 'draft', NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0))
 		RETURNING id;
-	`, accountID, ov.ClientID, ov.BaseNumber).Scan(&invoiceID); err != nil {
-		if isUniqueViolation(err) {
+	`, accountID, ov.ClientID, ov.BaseNumber, number.Label, number.Period, number.Seq).Scan(&invoiceID); err != nil {
+		if dberr.IsUniqueViolation(err) {
 			return 0, 0, fmt.Errorf("invoice base_number %d already exists: %w", ov.BaseNumber, err)
 		}
 		return 0, 0, fmt.Errorf("insert invoice: %w", err)
@@ -76,9 +136,10 @@ func Create(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn) (inv
 		return 0, 0, fmt.Errorf("sync invoice_number_seq: %w", err)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return 0, 0, fmt.Errorf("commit: %w", err)
+	if err := finishInvoiceMutation(ctx, tx, invoiceID, AuditInvoiceCreated, revisionID, sql.NullString{}); err != nil {
+		return 0, 0, err
 	}
+
 	return invoiceID, revisionID, nil
 }
 
@@ -93,6 +154,19 @@ func CreateRevision(ctx context.Context, a *app.App, canonical *models.FEInvoice
 	}
 	defer tx.Rollback()
 
+	invoiceID, revisionID, revisionNo, err = createRevisionInTx(ctx, tx, canonical)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commit: %w", err)
+	}
+
+	return invoiceID, revisionID, revisionNo, nil
+}
+
+func createRevisionInTx(ctx context.Context, tx *sql.Tx, canonical *models.FEInvoiceIn) (invoiceID, revisionID, revisionNo int64, err error) {
 	ov := &canonical.Overview
 	accountID, err := accountscope.Require(ctx)
 	if err != nil {
@@ -113,6 +187,13 @@ func CreateRevision(ctx context.Context, a *app.App, canonical *models.FEInvoice
 	if err := assertRevisionAllowed(invStatus); err != nil {
 		return 0, 0, 0, err
 	}
+	if err := assertIfMatch(ctx, tx, invoiceID); err != nil {
+		return 0, 0, 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, invoiceID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
 
 	if err := tx.QueryRowContext(ctx, `
 		SELECT COALESCE(MAX(revision_no), 0) + 1
@@ -164,18 +245,9 @@ func CreateRevision(ctx context.Context, a *app.App, canonical *models.FEInvoice
 		return 0, 0, 0, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return 0, 0, 0, fmt.Errorf("commit: %w", err)
+	if err := finishInvoiceMutation(ctx, tx, invoiceID, AuditRevisionCreated, revisionID, beforeHash); err != nil {
+		return 0, 0, 0, err
 	}
 
 	return invoiceID, revisionID, revisionNo, nil
 }
-
-// isUniqueViolation returns true if the error is a SQLite unique constraint violation.
-func isUniqueViolation(err error) bool {
-	if err == nil {
-		return false
-	}
-	msg := err.Error()
-	return strings.Contains(msg, "UNIQUE") || strings.Contains(msg, "unique")
-}
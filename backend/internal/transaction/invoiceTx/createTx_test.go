@@ -80,3 +80,28 @@ func TestCreate_AllowsSameBaseNumberAcrossAccounts(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateNext_AllocatesAfterExistingInvoices(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 41, "issued")
+
+	_, _, baseNumber, err := invoiceTx.CreateNext(ctx, a, draftUpdatePayload(clientID, 0, 1000, 0, "Copied line"))
+	if err != nil {
+		t.Fatalf("CreateNext: %v", err)
+	}
+	if baseNumber != 42 {
+		t.Fatalf("baseNumber = %d, want 42", baseNumber)
+	}
+
+	revisionNo, err := invoiceTx.QueryCurrentRevisionNo(ctx, a.DB, clientID, baseNumber)
+	if err != nil {
+		t.Fatalf("QueryCurrentRevisionNo: %v", err)
+	}
+	if revisionNo != 1 {
+		t.Fatalf("revisionNo = %d, want 1", revisionNo)
+	}
+}
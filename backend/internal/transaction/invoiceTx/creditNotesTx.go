@@ -0,0 +1,453 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+var (
+	// ErrInvoiceDraftForCreditNote is returned when crediting an invoice that was never issued.
+	ErrInvoiceDraftForCreditNote = errors.New("invoice is draft; issue it before raising credit notes")
+	// ErrInvoiceVoidForCreditNote is returned when crediting a void invoice.
+	ErrInvoiceVoidForCreditNote = errors.New("invoice is void; credit notes are not allowed")
+	// ErrCreditNoteExceedsInvoice is returned when total credit would exceed what the referenced revision leaves payable.
+	ErrCreditNoteExceedsInvoice = errors.New("credit notes cannot exceed the invoice total")
+	// ErrCreditNoteTotalInvalid is returned when a credit note totals to zero.
+	ErrCreditNoteTotalInvalid = errors.New("credit note total must be greater than 0")
+	ErrCreditNoteNotFound     = errors.New("credit note not found")
+)
+
+// CreditNoteRow is a DB/query row for one credit note and its lines.
+type CreditNoteRow struct {
+	ID             int64
+	InvoiceID      int64
+	BaseNumber     int64
+	CreditNoteNo   int64
+	RevisionID     int64
+	RevisionNo     int64
+	IssueDate      string
+	Reason         sql.NullString
+	VATRate        int64
+	SubtotalMinor  int64
+	VatAmountMinor int64
+	TotalMinor     int64
+	Lines          []CreditNoteItemRow
+	// VATBreakdown is derived from the lines, each at its own VAT rate or
+	// else VATRate.
+	VATBreakdown []models.VATBand
+}
+
+type CreditNoteItemRow struct {
+	Name           string
+	LineType       string
+	Quantity       models.Quantity
+	UnitPriceMinor int64
+	LineTotalMinor int64
+	SortOrder      int64
+	VATRate        *int64
+}
+
+// CreateCreditNote credits an issued invoice against one of its revisions.
+//
+// Totals are computed here from the validated lines so the VAT rate can default
+// to the referenced revision; lines with their own rate are taxed at it. Credit
+// is capped at the revision total less its deduction. The invoice status is
+// re-synced and the change audited afterwards.
+func CreateCreditNote(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	baseNumber int64,
+	canonical *models.CreditNoteCreateIn,
+) (invoiceID, creditNoteID, creditNoteNo, totalMinor int64, err error) {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	invoiceID, status, err := LoadInvoiceIDAndStatus(ctx, tx, clientID, baseNumber)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	switch status {
+	case "draft":
+		return 0, 0, 0, 0, ErrInvoiceDraftForCreditNote
+	case "void":
+		return 0, 0, 0, 0, ErrInvoiceVoidForCreditNote
+	}
+
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, invoiceID)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	var (
+		revisionID           int64
+		revisionVATRate      int64
+		revisionPayableMinor int64
+	)
+	revisionQuery := `
+		SELECT r.id, r.vat_rate, r.total_minor - r.deduction_minor
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		WHERE i.id = ?;
+	`
+	revisionArgs := []any{invoiceID}
+	if canonical.RevisionNo != nil {
+		revisionQuery = `
+			SELECT id, vat_rate, total_minor - deduction_minor
+			FROM invoice_revisions
+			WHERE invoice_id = ?
+			  AND revision_no = ?;
+		`
+		revisionArgs = append(revisionArgs, *canonical.RevisionNo)
+	}
+	err = tx.QueryRowContext(ctx, revisionQuery, revisionArgs...).Scan(&revisionID, &revisionVATRate, &revisionPayableMinor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, 0, 0, ErrSourceRevisionInvalid
+	}
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("load credit note revision: %w", err)
+	}
+
+	vatRate := revisionVATRate
+	if canonical.VATRate != nil {
+		vatRate = *canonical.VATRate
+	}
+	netByRate := make(map[int64]int64)
+	for _, ln := range canonical.Lines {
+		netByRate[creditNoteLineRate(ln.VATRate, vatRate)] += ln.Quantity.Amount(ln.UnitPriceMinor)
+	}
+	subtotalMinor, vatMinor, totalMinor := creditNoteTotals(creditNoteVATBreakdown(netByRate))
+	if totalMinor <= 0 {
+		return 0, 0, 0, 0, ErrCreditNoteTotalInvalid
+	}
+
+	creditedMinor, err := sumCreditNotesByInvoice(ctx, tx, invoiceID)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if creditedMinor+totalMinor > revisionPayableMinor {
+		return 0, 0, 0, 0, ErrCreditNoteExceedsInvoice
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(credit_note_no), 0) + 1
+		FROM credit_notes
+		WHERE invoice_id = ?;
+	`, invoiceID).Scan(&creditNoteNo); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("next credit note number: %w", err)
+	}
+
+	var reason any
+	if canonical.Reason != nil {
+		reason = *canonical.Reason
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO credit_notes (
+			invoice_id,
+			invoice_revision_id,
+			credit_note_no,
+			issue_date,
+			reason,
+			vat_rate,
+			subtotal_minor,
+			vat_amount_minor,
+			total_minor
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`, invoiceID, revisionID, creditNoteNo, canonical.IssueDate, reason, vatRate, subtotalMinor, vatMinor, totalMinor).Scan(&creditNoteID); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("insert credit note: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO credit_note_items (
+			credit_note_id,
+			name,
+			line_type,
+			quantity,
+			quantity_milli,
+			unit_price_minor,
+			line_total_minor,
+			sort_order,
+			vat_rate
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`)
+	if err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("prepare credit note items: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, ln := range canonical.Lines {
+		if _, err := stmt.ExecContext(ctx,
+			creditNoteID,
+			ln.Name,
+			ln.LineType,
+			ln.Quantity.WholeUnitsCeil(),
+			ln.Quantity,
+			ln.UnitPriceMinor,
+			ln.Quantity.Amount(ln.UnitPriceMinor),
+			ln.SortOrder,
+			ln.VATRate,
+		); err != nil {
+			return 0, 0, 0, 0, fmt.Errorf("insert credit note item: %w", err)
+		}
+	}
+
+	if err := syncInvoiceStatusForCurrentRevision(ctx, tx, invoiceID); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	if err := finishInvoiceMutation(ctx, tx, invoiceID, AuditCreditNoteCreated, creditNoteID, beforeHash); err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, 0, fmt.Errorf("commit credit note: %w", err)
+	}
+
+	return invoiceID, creditNoteID, creditNoteNo, totalMinor, nil
+}
+
+// ListCreditNotes returns every credit note raised against an invoice, oldest first.
+func ListCreditNotes(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+) ([]CreditNoteRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, creditNoteSelectSQL+`
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?
+		ORDER BY cn.credit_note_no ASC;
+	`, accountID, clientID, baseNumber)
+	if err != nil {
+		return nil, fmt.Errorf("query credit notes: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]CreditNoteRow, 0)
+	for rows.Next() {
+		note, err := scanCreditNote(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, note)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate credit notes: %w", err)
+	}
+
+	for i := range out {
+		lines, err := queryCreditNoteItems(ctx, db, out[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		out[i].Lines = lines
+		out[i].VATBreakdown = creditNoteRowVATBreakdown(&out[i])
+	}
+
+	return out, nil
+}
+
+// QueryCreditNoteByNumber returns one credit note with its lines.
+func QueryCreditNoteByNumber(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+	creditNoteNo int64,
+) (*CreditNoteRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRowContext(ctx, creditNoteSelectSQL+`
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?
+		  AND cn.credit_note_no = ?;
+	`, accountID, clientID, baseNumber, creditNoteNo)
+
+	note, err := scanCreditNote(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrCreditNoteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines, err := queryCreditNoteItems(ctx, db, note.ID)
+	if err != nil {
+		return nil, err
+	}
+	note.Lines = lines
+	note.VATBreakdown = creditNoteRowVATBreakdown(&note)
+
+	return &note, nil
+}
+
+const creditNoteSelectSQL = `
+	SELECT
+		cn.id,
+		i.id,
+		i.base_number,
+		cn.credit_note_no,
+		r.id,
+		r.revision_no,
+		cn.issue_date,
+		cn.reason,
+		cn.vat_rate,
+		cn.subtotal_minor,
+		cn.vat_amount_minor,
+		cn.total_minor
+	FROM credit_notes cn
+	JOIN invoices i
+		ON i.id = cn.invoice_id
+	JOIN invoice_revisions r
+		ON r.id = cn.invoice_revision_id
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCreditNote(row rowScanner) (CreditNoteRow, error) {
+	var out CreditNoteRow
+	err := row.Scan(
+		&out.ID,
+		&out.InvoiceID,
+		&out.BaseNumber,
+		&out.CreditNoteNo,
+		&out.RevisionID,
+		&out.RevisionNo,
+		&out.IssueDate,
+		&out.Reason,
+		&out.VATRate,
+		&out.SubtotalMinor,
+		&out.VatAmountMinor,
+		&out.TotalMinor,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return CreditNoteRow{}, err
+	}
+	if err != nil {
+		return CreditNoteRow{}, fmt.Errorf("scan credit note: %w", err)
+	}
+	return out, nil
+}
+
+func queryCreditNoteItems(ctx context.Context, db *sql.DB, creditNoteID int64) ([]CreditNoteItemRow, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			name,
+			line_type,
+			COALESCE(quantity_milli, quantity * 1000),
+			unit_price_minor,
+			line_total_minor,
+			sort_order,
+			vat_rate
+		FROM credit_note_items
+		WHERE credit_note_id = ?
+		ORDER BY sort_order ASC;
+	`, creditNoteID)
+	if err != nil {
+		return nil, fmt.Errorf("query credit note items: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]CreditNoteItemRow, 0)
+	for rows.Next() {
+		var item CreditNoteItemRow
+		if err := rows.Scan(
+			&item.Name,
+			&item.LineType,
+			&item.Quantity,
+			&item.UnitPriceMinor,
+			&item.LineTotalMinor,
+			&item.SortOrder,
+			&item.VATRate,
+		); err != nil {
+			return nil, fmt.Errorf("scan credit note item: %w", err)
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate credit note items: %w", err)
+	}
+
+	return out, nil
+}
+
+func sumCreditNotesByInvoice(ctx context.Context, tx *sql.Tx, invoiceID int64) (int64, error) {
+	var credited int64
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(total_minor), 0)
+		FROM credit_notes
+		WHERE invoice_id = ?
+	`, invoiceID).Scan(&credited); err != nil {
+		return 0, fmt.Errorf("sum credit notes: %w", err)
+	}
+	return credited, nil
+}
+
+// creditNoteTotals adds up the VAT bands of a credit note.
+func creditNoteTotals(bands []models.VATBand) (subtotalMinor, vatMinor, totalMinor int64) {
+	for _, band := range bands {
+		subtotalMinor += band.NetMinor
+		vatMinor += band.VatMinor
+	}
+	return subtotalMinor, vatMinor, subtotalMinor + vatMinor
+}
+
+// creditNoteVATBreakdown mirrors the invoice VAT rounding: VAT is rounded once
+// per rate, round(net * bps / 10000). Bands are ordered by rate, highest first.
+func creditNoteVATBreakdown(netByRate map[int64]int64) []models.VATBand {
+	bands := make([]models.VATBand, 0, len(netByRate))
+	for rate, net := range netByRate {
+		bands = append(bands, models.VATBand{
+			VATRate:  rate,
+			NetMinor: net,
+			VatMinor: max(int64(math.Round(float64(net*rate)/10000.0)), 0),
+		})
+	}
+	sort.Slice(bands, func(i, j int) bool { return bands[i].VATRate > bands[j].VATRate })
+	return bands
+}
+
+// creditNoteRowVATBreakdown rebuilds the VAT bands of a saved credit note.
+func creditNoteRowVATBreakdown(note *CreditNoteRow) []models.VATBand {
+	netByRate := make(map[int64]int64)
+	for _, ln := range note.Lines {
+		netByRate[creditNoteLineRate(ln.VATRate, note.VATRate)] += ln.LineTotalMinor
+	}
+	return creditNoteVATBreakdown(netByRate)
+}
+
+// creditNoteLineRate is the VAT rate of a credit note line: its own, or else
+// the credit note's.
+func creditNoteLineRate(lineRate *int64, vatRate int64) int64 {
+	if lineRate != nil {
+		return *lineRate
+	}
+	return vatRate
+}
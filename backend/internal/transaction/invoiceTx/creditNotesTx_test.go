@@ -0,0 +1,247 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func creditNotePayload(unitPriceMinor int64) *models.CreditNoteCreateIn {
+	return &models.CreditNoteCreateIn{
+		IssueDate: "2026-04-01",
+		Lines: []models.CreditNoteLineIn{
+			{
+				Name:           "Returned goods",
+				LineType:       "custom",
+				Quantity:       models.WholeQuantity(1),
+				UnitPriceMinor: unitPriceMinor,
+				LineTotalMinor: unitPriceMinor,
+				SortOrder:      1,
+			},
+		},
+	}
+}
+
+func TestCreateCreditNote_SettlesInvoiceAndNumbersSequentially(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	invoiceID := insertInvoiceGraph(t, a, clientID, 410, "issued")
+
+	_, _, firstNo, _, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 410, creditNotePayload(400))
+	if err != nil {
+		t.Fatalf("CreateCreditNote(first): %v", err)
+	}
+	_, _, secondNo, totalMinor, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 410, creditNotePayload(500))
+	if err != nil {
+		t.Fatalf("CreateCreditNote(second): %v", err)
+	}
+
+	if firstNo != 1 || secondNo != 2 {
+		t.Fatalf("credit note numbers = %d, %d, want 1, 2", firstNo, secondNo)
+	}
+	if totalMinor != 500 {
+		t.Fatalf("totalMinor = %d, want 500", totalMinor)
+	}
+
+	var status string
+	if err := a.DB.QueryRow(`SELECT status FROM invoices WHERE id = ?`, invoiceID).Scan(&status); err != nil {
+		t.Fatalf("load invoice status: %v", err)
+	}
+	if status != "paid" {
+		t.Fatalf("status = %q, want paid once payments and credits cover the total", status)
+	}
+
+	var balance int64
+	if err := a.DB.QueryRow(`SELECT balance_due_minor FROM invoice_book_rows WHERE id = ?`, invoiceID).Scan(&balance); err != nil {
+		t.Fatalf("load book balance: %v", err)
+	}
+	if balance != 0 {
+		t.Fatalf("balance_due_minor = %d, want 0", balance)
+	}
+}
+
+func TestCreateCreditNote_RejectsCreditAboveInvoiceTotal(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 420, "issued")
+
+	_, _, _, _, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 420, creditNotePayload(1001))
+	if !errors.Is(err, invoiceTx.ErrCreditNoteExceedsInvoice) {
+		t.Fatalf("CreateCreditNote() error = %v, want %v", err, invoiceTx.ErrCreditNoteExceedsInvoice)
+	}
+}
+
+func TestCreateCreditNote_RejectsDraftAndVoidInvoices(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+
+	tests := []struct {
+		name    string
+		status  string
+		wantErr error
+	}{
+		{name: "draft", status: "draft", wantErr: invoiceTx.ErrInvoiceDraftForCreditNote},
+		{name: "void", status: "void", wantErr: invoiceTx.ErrInvoiceVoidForCreditNote},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, cleanup := newTestApp(t)
+			defer cleanup()
+
+			clientID := insertClient(t, a)
+			insertInvoiceGraph(t, a, clientID, 430, tt.status)
+
+			_, _, _, _, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 430, creditNotePayload(100))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateCreditNote() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateCreditNote_TaxesEachLineAtItsOwnRate(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 440, "issued")
+
+	standard := int64(2000)
+	reduced := int64(500)
+	payload := &models.CreditNoteCreateIn{
+		IssueDate: "2026-04-01",
+		Lines: []models.CreditNoteLineIn{
+			{
+				Name:           "Returned hours",
+				LineType:       "custom",
+				Quantity:       models.Quantity(2500),
+				UnitPriceMinor: 200,
+				LineTotalMinor: 500,
+				SortOrder:      1,
+				VATRate:        &standard,
+			},
+			{
+				Name:           "Returned books",
+				LineType:       "custom",
+				Quantity:       models.WholeQuantity(1),
+				UnitPriceMinor: 200,
+				LineTotalMinor: 200,
+				SortOrder:      2,
+				VATRate:        &reduced,
+			},
+		},
+	}
+
+	_, _, _, totalMinor, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 440, payload)
+	if err != nil {
+		t.Fatalf("CreateCreditNote: %v", err)
+	}
+	if totalMinor != 810 {
+		t.Fatalf("totalMinor = %d, want 810", totalMinor)
+	}
+
+	notes, err := invoiceTx.ListCreditNotes(ctx, a.DB, clientID, 440)
+	if err != nil {
+		t.Fatalf("ListCreditNotes: %v", err)
+	}
+	if len(notes) != 1 {
+		t.Fatalf("len(notes) = %d, want 1", len(notes))
+	}
+
+	note := notes[0]
+	if note.SubtotalMinor != 700 || note.VatAmountMinor != 110 {
+		t.Fatalf("subtotal, vat = %d, %d, want 700, 110", note.SubtotalMinor, note.VatAmountMinor)
+	}
+	if got := note.Lines[0].Quantity; got != models.Quantity(2500) {
+		t.Fatalf("line quantity = %s, want 2.5", got)
+	}
+	want := []models.VATBand{
+		{VATRate: 2000, NetMinor: 500, VatMinor: 100},
+		{VATRate: 500, NetMinor: 200, VatMinor: 10},
+	}
+	if len(note.VATBreakdown) != len(want) {
+		t.Fatalf("VATBreakdown = %+v, want %+v", note.VATBreakdown, want)
+	}
+	for i := range want {
+		if note.VATBreakdown[i] != want[i] {
+			t.Fatalf("VATBreakdown[%d] = %+v, want %+v", i, note.VATBreakdown[i], want[i])
+		}
+	}
+}
+
+func TestCreateCreditNote_CapsCreditAtPayableAfterDeduction(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	invoiceID := insertInvoiceGraph(t, a, clientID, 450, "issued")
+	if _, err := a.DB.Exec(`
+		UPDATE invoice_revisions
+		SET deduction_rate = 2000, deduction_line_types = 'custom', deduction_minor = 200
+		WHERE invoice_id = ?
+	`, invoiceID); err != nil {
+		t.Fatalf("set deduction: %v", err)
+	}
+
+	_, _, _, _, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 450, creditNotePayload(900))
+	if !errors.Is(err, invoiceTx.ErrCreditNoteExceedsInvoice) {
+		t.Fatalf("CreateCreditNote(900) error = %v, want %v", err, invoiceTx.ErrCreditNoteExceedsInvoice)
+	}
+	if _, _, _, _, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 450, creditNotePayload(800)); err != nil {
+		t.Fatalf("CreateCreditNote(800): %v", err)
+	}
+}
+
+func TestCreateCreditNote_AuditsAndMovesETag(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 460, "issued")
+
+	before, err := invoiceTx.QueryInvoiceETag(ctx, a.DB, clientID, 460)
+	if err != nil {
+		t.Fatalf("QueryInvoiceETag before: %v", err)
+	}
+
+	_, creditNoteID, _, _, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 460, creditNotePayload(300))
+	if err != nil {
+		t.Fatalf("CreateCreditNote: %v", err)
+	}
+
+	after, err := invoiceTx.QueryInvoiceETag(ctx, a.DB, clientID, 460)
+	if err != nil {
+		t.Fatalf("QueryInvoiceETag after: %v", err)
+	}
+	if after == before {
+		t.Fatalf("etag unchanged after credit note: %s", after)
+	}
+
+	entries, err := invoiceTx.QueryAuditLog(ctx, a.DB, invoiceTx.AuditLogFilters{BaseNumber: 460})
+	if err != nil {
+		t.Fatalf("QueryAuditLog: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %+v, want 1", entries)
+	}
+	entry := entries[0]
+	if entry.Action != invoiceTx.AuditCreditNoteCreated || !entry.EntityID.Valid || entry.EntityID.Int64 != creditNoteID {
+		t.Fatalf("audit entry = %+v, want %s for credit note %d", entry, invoiceTx.AuditCreditNoteCreated, creditNoteID)
+	}
+	if entry.BeforeHash.String == entry.AfterHash.String {
+		t.Fatalf("audit hashes unchanged: %s", entry.AfterHash.String)
+	}
+}
@@ -0,0 +1,77 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
+)
+
+// ExchangeRateScale is the exchange rate of an invoice issued in the
+// workspace currency. Stored rates are fixed-point with this many parts per unit.
+const ExchangeRateScale int64 = 1_000_000
+
+// ErrExchangeRateRequired is returned when an invoice in a foreign currency
+// is saved without an exchange rate to the workspace currency.
+var ErrExchangeRateRequired = errors.New("exchange rate is required for an invoice in a foreign currency")
+
+// resolveRevisionCurrency returns the currency and exchange rate to store on a
+// revision of invoiceID. An empty currency means the workspace currency, and
+// an invoice in the workspace currency always has a rate of ExchangeRateScale.
+func resolveRevisionCurrency(ctx context.Context, tx *sql.Tx, invoiceID int64, ov *models.InvoiceCreateIn) (string, int64, error) {
+	var base string
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(s.currency, '')
+		FROM invoices i
+		LEFT JOIN account_settings s
+			ON s.account_id = i.account_id
+		WHERE i.id = ?;
+	`, invoiceID).Scan(&base); err != nil {
+		return "", 0, fmt.Errorf("load workspace currency: %w", err)
+	}
+	return pickCurrency(invoiceformat.NormalizeCurrency(base), ov.Currency, ov.ExchangeRateMicro)
+}
+
+// ResolveCurrency returns the currency and exchange rate to store on a
+// document of the account priced in currency, following the same rules as
+// invoice revisions. Quotes use it so they convert into a valid invoice.
+func ResolveCurrency(ctx context.Context, tx *sql.Tx, accountID int64, currency string, exchangeRateMicro int64) (string, int64, error) {
+	base, err := workspaceCurrency(ctx, tx, accountID)
+	if err != nil {
+		return "", 0, err
+	}
+	return pickCurrency(base, currency, exchangeRateMicro)
+}
+
+func pickCurrency(base, requested string, exchangeRateMicro int64) (string, int64, error) {
+	currency := base
+	if requested != "" {
+		currency = invoiceformat.NormalizeCurrency(requested)
+	}
+	if currency == base {
+		return currency, ExchangeRateScale, nil
+	}
+	if exchangeRateMicro <= 0 {
+		return "", 0, ErrExchangeRateRequired
+	}
+
+	return currency, exchangeRateMicro, nil
+}
+
+// workspaceCurrency returns the account's currency, GBP when it has no
+// settings yet.
+func workspaceCurrency(ctx context.Context, q queryer, accountID int64) (string, error) {
+	var currency string
+	err := q.QueryRowContext(ctx, `
+		SELECT currency
+		FROM account_settings
+		WHERE account_id = ?;
+	`, accountID).Scan(&currency)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("load workspace currency: %w", err)
+	}
+	return invoiceformat.NormalizeCurrency(currency), nil
+}
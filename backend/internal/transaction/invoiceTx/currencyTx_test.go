@@ -0,0 +1,49 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreate_StoresInvoiceCurrencyAndExchangeRate(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+
+	foreign := draftUpdatePayload(clientID, 1, 1000, 0, "Consulting")
+	foreign.Overview.Currency = "EUR"
+	if _, _, err := invoiceTx.Create(ctx, a, foreign); !errors.Is(err, invoiceTx.ErrExchangeRateRequired) {
+		t.Fatalf("Create without rate error = %v, want %v", err, invoiceTx.ErrExchangeRateRequired)
+	}
+
+	foreign.Overview.ExchangeRateMicro = 855_000
+	if _, _, err := invoiceTx.Create(ctx, a, foreign); err != nil {
+		t.Fatalf("Create EUR: %v", err)
+	}
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary EUR: %v", err)
+	}
+	if summary.Currency != "EUR" || summary.ExchangeRateMicro != 855_000 {
+		t.Fatalf("EUR invoice currency = %q at %d, want EUR at 855000", summary.Currency, summary.ExchangeRateMicro)
+	}
+
+	home := draftUpdatePayload(clientID, 2, 1000, 0, "Consulting")
+	home.Overview.ExchangeRateMicro = 2_000_000
+	if _, _, err := invoiceTx.Create(ctx, a, home); err != nil {
+		t.Fatalf("Create GBP: %v", err)
+	}
+	summary, err = invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 2, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary GBP: %v", err)
+	}
+	if summary.Currency != "GBP" || summary.ExchangeRateMicro != invoiceTx.ExchangeRateScale {
+		t.Fatalf("GBP invoice currency = %q at %d, want the workspace currency at par", summary.Currency, summary.ExchangeRateMicro)
+	}
+}
@@ -0,0 +1,52 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreate_StoresDeductionAndReducesBookBalance(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+
+	inv := draftUpdatePayload(clientID, 1, 10000, 0, "Labour")
+	inv.Totals.DeductionRate = 2000
+	inv.Totals.DeductionLineTypes = []string{"custom", "style"}
+	inv.Totals.DeductionMinor = 2000
+	inv.Totals.PayableMinor = 8000
+	inv.Totals.BalanceDue = 8000
+	if _, _, err := invoiceTx.Create(ctx, a, inv); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary: %v", err)
+	}
+	if summary.DeductionRate != 2000 || summary.DeductionMinor != 2000 {
+		t.Fatalf("stored deduction = (rate %d, minor %d), want (2000, 2000)", summary.DeductionRate, summary.DeductionMinor)
+	}
+	if want := []string{"custom", "style"}; !reflect.DeepEqual(summary.DeductionLineTypes, want) {
+		t.Fatalf("stored deduction line types = %v, want %v", summary.DeductionLineTypes, want)
+	}
+	if summary.TotalMinor != 10000 {
+		t.Fatalf("stored total = %d, want 10000", summary.TotalMinor)
+	}
+
+	var balance int64
+	if err := a.DB.QueryRowContext(ctx,
+		`SELECT balance_due_minor FROM invoice_book_rows WHERE client_id = ? AND base_number = 1`, clientID,
+	).Scan(&balance); err != nil {
+		t.Fatalf("query book balance: %v", err)
+	}
+	if balance != 8000 {
+		t.Fatalf("book balance = %d, want 8000", balance)
+	}
+}
@@ -11,8 +11,15 @@ import (
 
 var (
 	ErrInvoiceDeleteVoid = errors.New("void invoices cannot be deleted")
+	// ErrInvoiceDeleteCredited is returned when credit notes or refunds were
+	// raised against the invoice. Those documents must keep their invoice, so
+	// it has to be voided instead.
+	ErrInvoiceDeleteCredited = errors.New("invoices with credit notes or refunds cannot be deleted")
 )
 
+// Delete removes an invoice and everything under it, leaving a tombstone so
+// the invoice number gap report can explain the missing number and an audit
+// entry recording who deleted it.
 func Delete(ctx context.Context, a *app.App, clientID, baseNumber int64) error {
 	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
@@ -30,6 +37,51 @@ func Delete(ctx context.Context, a *app.App, clientID, baseNumber int64) error {
 		return ErrInvoiceDeleteVoid
 	}
 
+	var credited bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM credit_notes WHERE invoice_id = ?)
+			OR EXISTS (SELECT 1 FROM payment_refunds WHERE invoice_id = ?);
+	`, invoiceID, invoiceID).Scan(&credited); err != nil {
+		return fmt.Errorf("check invoice credit notes and refunds: %w", err)
+	}
+	if credited {
+		return ErrInvoiceDeleteCredited
+	}
+
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, invoiceID)
+	if err != nil {
+		return err
+	}
+	if err := insertInvoiceAudit(ctx, tx, invoiceID, AuditInvoiceDeleted, 0, beforeHash, sql.NullString{}); err != nil {
+		return err
+	}
+
+	userID, email := auditActor(ctx)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO invoice_tombstones (
+			account_id,
+			invoice_id,
+			client_id,
+			base_number,
+			number_label,
+			number_period,
+			number_seq,
+			status,
+			issue_date,
+			total_minor,
+			deleted_by_user_id,
+			deleted_by_email
+		)
+		SELECT i.account_id, i.id, i.client_id, i.base_number, i.number_label, i.number_period, i.number_seq, i.status, r.issue_date, r.total_minor, ?, ?
+		FROM invoices i
+		LEFT JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		WHERE i.id = ?;
+	`, userID, email, invoiceID); err != nil {
+		return fmt.Errorf("write invoice tombstone: %w", err)
+	}
+
 	res, err := tx.ExecContext(ctx, `
 		DELETE FROM invoices
 		WHERE id = ?
@@ -246,3 +246,62 @@ func TestDelete_RejectsVoidInvoices(t *testing.T) {
 		t.Fatalf("invoice count = %d, want 1", count)
 	}
 }
+
+func TestDelete_RejectsInvoicesWithRefunds(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	invoiceID := insertInvoiceGraph(t, a, clientID, 404, "paid")
+
+	if _, err := a.DB.Exec(`
+		INSERT INTO payment_refunds (invoice_id, payment_id, applied_in_revision_id, refund_no, amount_minor, refund_date)
+		SELECT p.invoice_id, p.id, p.applied_in_revision_id, 1, 50, '2026-03-29'
+		FROM payments p
+		WHERE p.invoice_id = ?
+	`, invoiceID); err != nil {
+		t.Fatalf("insert refund: %v", err)
+	}
+
+	err := invoiceTx.Delete(ctx, a, clientID, 404)
+	if !errors.Is(err, invoiceTx.ErrInvoiceDeleteCredited) {
+		t.Fatalf("Delete() error = %v, want %v", err, invoiceTx.ErrInvoiceDeleteCredited)
+	}
+
+	if count := countRows(t, a, "invoices", invoiceID); count != 1 {
+		t.Fatalf("invoice count = %d, want 1", count)
+	}
+}
+
+func TestDelete_WritesAuditEntry(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	invoiceID := insertInvoiceGraph(t, a, clientID, 505, "issued")
+
+	if err := invoiceTx.Delete(ctx, a, clientID, 505); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	var (
+		baseNumber int64
+		beforeHash sql.NullString
+		afterHash  sql.NullString
+	)
+	if err := a.DB.QueryRow(`
+		SELECT base_number, before_hash, after_hash
+		FROM audit_log
+		WHERE invoice_id = ? AND action = ?
+	`, invoiceID, invoiceTx.AuditInvoiceDeleted).Scan(&baseNumber, &beforeHash, &afterHash); err != nil {
+		t.Fatalf("load delete audit entry: %v", err)
+	}
+	if baseNumber != 505 {
+		t.Fatalf("audit base number = %d, want 505", baseNumber)
+	}
+	if !beforeHash.Valid || afterHash.Valid {
+		t.Fatalf("audit hashes = %v/%v, want before only", beforeHash, afterHash)
+	}
+}
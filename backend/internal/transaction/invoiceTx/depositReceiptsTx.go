@@ -0,0 +1,334 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+var (
+	// ErrNoDepositRequested is returned when recording a deposit on a revision that does not request one.
+	ErrNoDepositRequested = errors.New("invoice revision does not request a deposit")
+	// ErrDepositExceedsRequested is returned when received deposits would exceed the requested deposit.
+	ErrDepositExceedsRequested = errors.New("received deposits cannot exceed the requested deposit")
+	ErrDepositReceiptNotFound  = errors.New("deposit receipt not found")
+)
+
+// CreateDepositReceipt records that part or all of the requested deposit was
+// received against the invoice's current revision.
+//
+// Deposit receipts are payments rows with payment_type 'deposit'. They are
+// numbered per invoice in receipt_no and, like every received payment, count
+// toward paid (see the invoice_revision_paid view).
+func CreateDepositReceipt(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	baseNumber int64,
+	canonical *models.PaymentReceiptCreateIn,
+) (invoiceID, paymentID, depositNo int64, err error) {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadCurrentDepositState(ctx, tx, clientID, baseNumber)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := assertReceiptMutationAllowed(state.InvoiceStatus); err != nil {
+		return 0, 0, 0, err
+	}
+	if state.DepositMinor <= 0 {
+		return 0, 0, 0, ErrNoDepositRequested
+	}
+	if state.DepositPaidMinor+canonical.AmountMinor > state.DepositMinor {
+		return 0, 0, 0, ErrDepositExceedsRequested
+	}
+	if err := assertIfMatch(ctx, tx, state.InvoiceID); err != nil {
+		return 0, 0, 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, state.InvoiceID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(receipt_no), 0) + 1
+		FROM payments
+		WHERE invoice_id = ?
+		  AND payment_type = 'deposit';
+	`, state.InvoiceID).Scan(&depositNo); err != nil {
+		return 0, 0, 0, fmt.Errorf("next deposit receipt number: %w", err)
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO payments (
+			invoice_id,
+			receipt_no,
+			payment_type,
+			amount_minor,
+			payment_date,
+			applied_in_revision_id,
+			label,
+			payment_method,
+			reference
+		)
+		VALUES (?, ?, 'deposit', ?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`, state.InvoiceID, depositNo, canonical.AmountMinor, canonical.PaymentDate, state.RevisionID,
+		normalizedOptionalString(canonical.Label),
+		normalizedOptionalString(canonical.PaymentMethod),
+		normalizedOptionalString(canonical.Reference),
+	).Scan(&paymentID); err != nil {
+		return 0, 0, 0, fmt.Errorf("insert deposit receipt: %w", err)
+	}
+
+	if err := syncInvoiceStatusForCurrentRevision(ctx, tx, state.InvoiceID); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := finishInvoiceMutation(ctx, tx, state.InvoiceID, AuditDepositCreated, paymentID, beforeHash); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commit deposit receipt: %w", err)
+	}
+
+	return state.InvoiceID, paymentID, depositNo, nil
+}
+
+// DeleteDepositReceipt removes a deposit receipt from the current revision.
+// Snapshots carried by earlier revisions are left untouched.
+func DeleteDepositReceipt(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	baseNumber int64,
+	depositNo int64,
+) (paymentID int64, err error) {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadCurrentDepositState(ctx, tx, clientID, baseNumber)
+	if err != nil {
+		return 0, err
+	}
+	if err := assertReceiptMutationAllowed(state.InvoiceStatus); err != nil {
+		return 0, err
+	}
+	if err := assertIfMatch(ctx, tx, state.InvoiceID); err != nil {
+		return 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, state.InvoiceID)
+	if err != nil {
+		return 0, err
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM payments
+		WHERE applied_in_revision_id = ?
+		  AND receipt_no = ?
+		  AND payment_type = 'deposit'
+		RETURNING id;
+	`, state.RevisionID, depositNo).Scan(&paymentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDepositReceiptNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("delete deposit receipt: %w", err)
+	}
+
+	if err := syncInvoiceStatusForCurrentRevision(ctx, tx, state.InvoiceID); err != nil {
+		return 0, err
+	}
+
+	if err := finishInvoiceMutation(ctx, tx, state.InvoiceID, AuditDepositDeleted, paymentID, beforeHash); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit deposit receipt delete: %w", err)
+	}
+
+	return paymentID, nil
+}
+
+// ListDepositReceipts returns the deposit receipts on the invoice's current revision.
+func ListDepositReceipts(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+) ([]PaymentReceiptRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, depositReceiptSelectSQL+`
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		JOIN payments p
+			ON p.applied_in_revision_id = r.id
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?
+		  AND p.payment_type = 'deposit'
+		ORDER BY p.receipt_no ASC;
+	`, accountID, clientID, baseNumber)
+	if err != nil {
+		return nil, fmt.Errorf("query deposit receipts: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]PaymentReceiptRow, 0)
+	for rows.Next() {
+		receipt, err := scanDepositReceipt(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, receipt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate deposit receipts: %w", err)
+	}
+
+	return out, nil
+}
+
+// QueryDepositReceiptByNumber returns one deposit receipt from the latest
+// revision that carries it.
+func QueryDepositReceiptByNumber(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+	depositNo int64,
+) (*PaymentReceiptRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRowContext(ctx, depositReceiptSelectSQL+`
+		JOIN payments p
+			ON p.invoice_id = i.id
+		JOIN invoice_revisions r
+			ON r.id = p.applied_in_revision_id
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?
+		  AND p.receipt_no = ?
+		  AND p.payment_type = 'deposit'
+		ORDER BY r.revision_no DESC
+		LIMIT 1;
+	`, accountID, clientID, baseNumber, depositNo)
+
+	receipt, err := scanDepositReceipt(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDepositReceiptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
+
+type depositState struct {
+	InvoiceID        int64
+	InvoiceStatus    string
+	RevisionID       int64
+	DepositMinor     int64
+	DepositPaidMinor int64
+}
+
+func loadCurrentDepositState(ctx context.Context, tx *sql.Tx, clientID, baseNumber int64) (depositState, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return depositState{}, err
+	}
+
+	var state depositState
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			i.id,
+			i.status,
+			r.id,
+			r.deposit_minor,
+			rp.deposit_paid_minor
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		JOIN invoice_revision_paid rp
+			ON rp.revision_id = r.id
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?;
+	`, accountID, clientID, baseNumber).Scan(
+		&state.InvoiceID,
+		&state.InvoiceStatus,
+		&state.RevisionID,
+		&state.DepositMinor,
+		&state.DepositPaidMinor,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return depositState{}, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return depositState{}, fmt.Errorf("load invoice deposit state: %w", err)
+	}
+
+	return state, nil
+}
+
+const depositReceiptSelectSQL = `
+	SELECT
+		p.id,
+		i.id,
+		i.base_number,
+		p.receipt_no,
+		p.payment_date,
+		p.amount_minor,
+		p.label,
+		p.payment_method,
+		p.reference,
+		p.applied_in_revision_id,
+		r.revision_no
+	FROM invoices i
+`
+
+func scanDepositReceipt(row rowScanner) (PaymentReceiptRow, error) {
+	var out PaymentReceiptRow
+	err := row.Scan(
+		&out.ID,
+		&out.InvoiceID,
+		&out.BaseNumber,
+		&out.ReceiptNo,
+		&out.PaymentDate,
+		&out.AmountMinor,
+		&out.Label,
+		&out.PaymentMethod,
+		&out.Reference,
+		&out.AppliedRevisionID,
+		&out.AppliedRevisionNo,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return PaymentReceiptRow{}, err
+	}
+	if err != nil {
+		return PaymentReceiptRow{}, fmt.Errorf("scan deposit receipt: %w", err)
+	}
+	return out, nil
+}
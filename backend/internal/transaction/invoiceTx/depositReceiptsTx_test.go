@@ -0,0 +1,92 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreateDepositReceipt_CountsTowardPaidAndNumbersSeparately(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	invoiceID := insertInvoiceGraph(t, a, clientID, 901, "issued")
+
+	_, _, _, err := invoiceTx.CreateDepositReceipt(ctx, a, clientID, 901, &models.PaymentReceiptCreateIn{
+		AmountMinor: 100,
+		PaymentDate: "2026-03-28",
+	})
+	if !errors.Is(err, invoiceTx.ErrNoDepositRequested) {
+		t.Fatalf("CreateDepositReceipt(no deposit) error = %v, want %v", err, invoiceTx.ErrNoDepositRequested)
+	}
+
+	if _, err := a.DB.Exec(`
+		UPDATE invoice_revisions
+		SET deposit_type = 'fixed', deposit_minor = 300
+		WHERE invoice_id = ?
+	`, invoiceID); err != nil {
+		t.Fatalf("request deposit: %v", err)
+	}
+
+	_, _, _, err = invoiceTx.CreateDepositReceipt(ctx, a, clientID, 901, &models.PaymentReceiptCreateIn{
+		AmountMinor: 301,
+		PaymentDate: "2026-03-28",
+	})
+	if !errors.Is(err, invoiceTx.ErrDepositExceedsRequested) {
+		t.Fatalf("CreateDepositReceipt(over request) error = %v, want %v", err, invoiceTx.ErrDepositExceedsRequested)
+	}
+
+	_, _, depositNo, err := invoiceTx.CreateDepositReceipt(ctx, a, clientID, 901, &models.PaymentReceiptCreateIn{
+		AmountMinor: 300,
+		PaymentDate: "2026-03-28",
+	})
+	if err != nil {
+		t.Fatalf("CreateDepositReceipt: %v", err)
+	}
+	if depositNo != 1 {
+		t.Fatalf("depositNo = %d, want 1", depositNo)
+	}
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 901, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary: %v", err)
+	}
+	if summary.PaidMinor != 400 || summary.DepositPaidMinor != 300 {
+		t.Fatalf("paid = %d, deposit paid = %d, want 400 and 300", summary.PaidMinor, summary.DepositPaidMinor)
+	}
+
+	_, _, receiptNo, err := invoiceTx.CreatePaymentReceipt(ctx, a, clientID, 901, 1, &models.PaymentReceiptCreateIn{
+		AmountMinor: 600,
+		PaymentDate: "2026-04-02",
+	})
+	if err != nil {
+		t.Fatalf("CreatePaymentReceipt: %v", err)
+	}
+	if receiptNo != 1 {
+		t.Fatalf("receiptNo = %d, want 1", receiptNo)
+	}
+	assertInvoiceStatus(t, a, invoiceID, "paid")
+
+	deposits, err := invoiceTx.ListDepositReceipts(ctx, a.DB, clientID, 901)
+	if err != nil {
+		t.Fatalf("ListDepositReceipts: %v", err)
+	}
+	if len(deposits) != 1 || deposits[0].ReceiptNo != 1 || deposits[0].AmountMinor != 300 {
+		t.Fatalf("deposits = %+v, want one 300 deposit numbered 1", deposits)
+	}
+
+	if _, err := invoiceTx.DeleteDepositReceipt(ctx, a, clientID, 901, 1); err != nil {
+		t.Fatalf("DeleteDepositReceipt: %v", err)
+	}
+	assertInvoiceStatus(t, a, invoiceID, "issued")
+
+	if _, err := invoiceTx.DeleteDepositReceipt(ctx, a, clientID, 901, 1); !errors.Is(err, invoiceTx.ErrDepositReceiptNotFound) {
+		t.Fatalf("DeleteDepositReceipt(again) error = %v, want %v", err, invoiceTx.ErrDepositReceiptNotFound)
+	}
+}
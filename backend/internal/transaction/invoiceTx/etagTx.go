@@ -0,0 +1,117 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+)
+
+// ErrETagMismatch is returned when an If-Match precondition no longer matches the invoice.
+var ErrETagMismatch = errors.New("invoice changed since it was loaded")
+
+type ifMatchKey struct{}
+
+// WithIfMatch attaches an If-Match header value to ctx. Invoice and receipt
+// mutations compare it against the invoice's ETag inside their transaction;
+// without one they run unconditionally.
+func WithIfMatch(ctx context.Context, ifMatch string) context.Context {
+	return context.WithValue(ctx, ifMatchKey{}, strings.TrimSpace(ifMatch))
+}
+
+// QueryInvoiceETag returns the strong ETag for an invoice: its current
+// revision id and when that revision last changed.
+func QueryInvoiceETag(ctx context.Context, db *sql.DB, clientID, baseNumber int64) (string, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		revisionID int64
+		changedAt  string
+	)
+	err = db.QueryRowContext(ctx, `
+		SELECT r.id, COALESCE(r.updated_at, r.created_at)
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?;
+	`, accountID, clientID, baseNumber).Scan(&revisionID, &changedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrInvoiceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("load invoice etag: %w", err)
+	}
+
+	return formatInvoiceETag(revisionID, changedAt), nil
+}
+
+func formatInvoiceETag(revisionID int64, changedAt string) string {
+	return fmt.Sprintf(`"%d-%s"`, revisionID, changedAt)
+}
+
+// assertIfMatch checks the If-Match value on ctx, if any, against the
+// invoice's current ETag.
+func assertIfMatch(ctx context.Context, tx *sql.Tx, invoiceID int64) error {
+	ifMatch, _ := ctx.Value(ifMatchKey{}).(string)
+	if ifMatch == "" || ifMatch == "*" {
+		return nil
+	}
+
+	var (
+		revisionID int64
+		changedAt  string
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT r.id, COALESCE(r.updated_at, r.created_at)
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		WHERE i.id = ?;
+	`, invoiceID).Scan(&revisionID, &changedAt); err != nil {
+		return fmt.Errorf("load invoice etag: %w", err)
+	}
+
+	// If-Match uses strong comparison, so a weak W/ tag never matches.
+	current := formatInvoiceETag(revisionID, changedAt)
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(candidate) == current {
+			return nil
+		}
+	}
+
+	return ErrETagMismatch
+}
+
+// finishInvoiceMutation moves the invoice's ETag on and records the change in
+// the audit log. Every invoice and receipt mutation ends with it. The touch is
+// at least a millisecond past the previous stamp, so edits landing in the same
+// millisecond still get distinct ETags.
+func finishInvoiceMutation(
+	ctx context.Context,
+	tx *sql.Tx,
+	invoiceID int64,
+	action string,
+	entityID int64,
+	beforeHash sql.NullString,
+) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoice_revisions
+		SET updated_at = strftime('%Y-%m-%dT%H:%M:%fZ', MAX(
+			julianday('now'),
+			julianday(COALESCE(updated_at, created_at)) + 1.0 / 86400000
+		))
+		WHERE id = (SELECT current_revision_id FROM invoices WHERE id = ?);
+	`, invoiceID); err != nil {
+		return fmt.Errorf("touch current invoice revision: %w", err)
+	}
+
+	return writeInvoiceAudit(ctx, tx, invoiceID, action, entityID, beforeHash)
+}
@@ -0,0 +1,90 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestUpdateDraft_RejectsStaleIfMatch(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 930, "draft")
+
+	loaded, err := invoiceTx.QueryInvoiceETag(ctx, a.DB, clientID, 930)
+	if err != nil {
+		t.Fatalf("QueryInvoiceETag: %v", err)
+	}
+
+	if _, _, err := invoiceTx.UpdateDraft(invoiceTx.WithIfMatch(ctx, loaded), a, draftUpdatePayload(clientID, 930, 150, 100, "First editor")); err != nil {
+		t.Fatalf("UpdateDraft first editor: %v", err)
+	}
+
+	_, _, err = invoiceTx.UpdateDraft(invoiceTx.WithIfMatch(ctx, loaded), a, draftUpdatePayload(clientID, 930, 175, 100, "Second editor"))
+	if !errors.Is(err, invoiceTx.ErrETagMismatch) {
+		t.Fatalf("UpdateDraft second editor error = %v, want %v", err, invoiceTx.ErrETagMismatch)
+	}
+
+	lines, err := invoiceTx.QueryInvoiceLines(ctx, a.DB, clientID, 930, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceLines: %v", err)
+	}
+	if len(lines) != 1 || lines[0].Name != "First editor" {
+		t.Fatalf("lines = %+v, want the first editor's line kept", lines)
+	}
+}
+
+func TestInvoiceETag_ChangesOnEveryReceiptMutation(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 931, "issued")
+
+	before, err := invoiceTx.QueryInvoiceETag(ctx, a.DB, clientID, 931)
+	if err != nil {
+		t.Fatalf("QueryInvoiceETag: %v", err)
+	}
+
+	_, _, receiptNo, err := invoiceTx.CreatePaymentReceipt(invoiceTx.WithIfMatch(ctx, before), a, clientID, 931, 1, &models.PaymentReceiptCreateIn{
+		AmountMinor: 200,
+		PaymentDate: "2026-04-01",
+	})
+	if err != nil {
+		t.Fatalf("CreatePaymentReceipt: %v", err)
+	}
+
+	afterCreate, err := invoiceTx.QueryInvoiceETag(ctx, a.DB, clientID, 931)
+	if err != nil {
+		t.Fatalf("QueryInvoiceETag after create: %v", err)
+	}
+	if afterCreate == before {
+		t.Fatalf("etag unchanged after receipt create: %s", afterCreate)
+	}
+
+	if _, err := invoiceTx.DeletePaymentReceipt(invoiceTx.WithIfMatch(ctx, before), a, clientID, 931, 1, receiptNo); !errors.Is(err, invoiceTx.ErrETagMismatch) {
+		t.Fatalf("DeletePaymentReceipt stale error = %v, want %v", err, invoiceTx.ErrETagMismatch)
+	}
+	if _, err := invoiceTx.DeletePaymentReceipt(invoiceTx.WithIfMatch(ctx, "W/"+afterCreate), a, clientID, 931, 1, receiptNo); !errors.Is(err, invoiceTx.ErrETagMismatch) {
+		t.Fatalf("DeletePaymentReceipt weak error = %v, want %v", err, invoiceTx.ErrETagMismatch)
+	}
+	if _, err := invoiceTx.DeletePaymentReceipt(invoiceTx.WithIfMatch(ctx, afterCreate), a, clientID, 931, 1, receiptNo); err != nil {
+		t.Fatalf("DeletePaymentReceipt current: %v", err)
+	}
+
+	afterDelete, err := invoiceTx.QueryInvoiceETag(ctx, a.DB, clientID, 931)
+	if err != nil {
+		t.Fatalf("QueryInvoiceETag after delete: %v", err)
+	}
+	if afterDelete == afterCreate {
+		t.Fatalf("etag unchanged after receipt delete: %s", afterDelete)
+	}
+}
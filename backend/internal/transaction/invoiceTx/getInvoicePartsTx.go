@@ -3,9 +3,12 @@ package invoiceTx
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
 )
 
 // ItemLine is a DB/query row for invoice items.
@@ -18,11 +21,16 @@ type ItemLine struct {
 	PricingMode   *string
 	Name          string
 	LineType      string
-	Quantity      int64
+	Quantity      models.Quantity
+	Unit          string
 	MinutesWorked *int64
 	UnitPriceMin  int64
 	LineTotalMin  int64
 	SortOrder     int64
+	VATRate       *int64
+	DiscountType  string
+	DiscountRate  int64
+	DiscountMinor int64
 }
 
 // InvoiceOverviewTotals is a DB/query row for invoice overview and totals.
@@ -31,8 +39,10 @@ type ItemLine struct {
 // Do not send it directly in JSON responses.
 // Map it to an API response model in the handler layer.
 type InvoiceOverviewTotals struct {
-	Status            string
-	BaseNumber        int64
+	Status     string
+	BaseNumber int64
+	// NumberLabel is set when the invoice was numbered from a template.
+	NumberLabel       sql.NullString
 	RevisionNo        int64
 	IssueDate         string
 	SupplyDate        sql.NullString
@@ -41,8 +51,21 @@ type InvoiceOverviewTotals struct {
 	ClientCompanyName string
 	ClientAddress     string
 	ClientEmail       string
+	ClientVATNumber   string
 	Note              sql.NullString
+	// Currency is the ISO 4217 code the revision is issued in.
+	Currency string
+	// ExchangeRateMicro converts Currency to the workspace currency; see
+	// [ExchangeRateScale].
+	ExchangeRateMicro int64
+
+	// Void fields are only set once the invoice has been voided.
+	VoidReason    sql.NullString
+	VoidedAt      sql.NullString
+	VoidedByEmail sql.NullString
 
+	// VATTreatment is one of the invoiceformat.VATTreatment values.
+	VATTreatment  string
 	VATRate       int64
 	VATAmountMin  int64
 	DiscountType  string
@@ -54,14 +77,56 @@ type InvoiceOverviewTotals struct {
 	SubtotalMinor int64
 	TotalMinor    int64
 	PaidMinor     int64
+	CreditedMinor int64
+	// The deduction withheld by the client; see models.TotalsCreateIn.
+	DeductionRate      int64
+	DeductionLineTypes []string
+	DeductionMinor     int64
+	// TaxRateID is the registry rate VATRate came from, if any; TaxRateName is
+	// its name as snapshotted onto the revision.
+	TaxRateID   sql.NullInt64
+	TaxRateName string
+	// DepositPaidMinor is the part of PaidMinor received as deposit receipts.
+	DepositPaidMinor int64
+
+	// VATBreakdown always holds at least one band; older revisions fall back
+	// to a single band at VATRate.
+	VATBreakdown []models.VATBand
 }
 
 type ReceiptRow struct {
-	ID          int64
-	ReceiptNo   int64
-	AmountMinor int64
-	PaymentDate string
-	Label       sql.NullString
+	ID            int64
+	ReceiptNo     int64
+	AmountMinor   int64
+	PaymentDate   string
+	Label         sql.NullString
+	PaymentMethod sql.NullString
+	Reference     sql.NullString
+	RefundedMinor int64
+}
+
+// QueryCurrentRevisionNo returns the revision number an invoice currently points at.
+func QueryCurrentRevisionNo(ctx context.Context, db *sql.DB, clientID, baseNumber int64) (int64, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var revisionNo int64
+	err = db.QueryRowContext(ctx, `
+		SELECT r.revision_no
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		WHERE i.account_id = ? AND i.client_id = ? AND i.base_number = ?
+	`, accountID, clientID, baseNumber).Scan(&revisionNo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("load current revision: %w", err)
+	}
+	return revisionNo, nil
 }
 
 // QueryInvoiceSummary returns the DB/query row for one invoice revision.
@@ -69,7 +134,9 @@ type ReceiptRow struct {
 // The returned value is an internal backend shape.
 // Handlers should map it to an API response model before sending via res.JSON.
 //
-// PaidMinor is aggregated from the selected revision snapshot.
+// PaidMinor is aggregated from the selected revision snapshot and includes
+// received deposits.
+// CreditedMinor sums every credit note raised against the invoice.
 func QueryInvoiceSummary(
 	ctx context.Context,
 	db *sql.DB,
@@ -84,8 +151,10 @@ func QueryInvoiceSummary(
 
 	query := `
 		SELECT
+			r.id,
 			i.status,
 			i.base_number,
+			i.number_label,
 			r.revision_no,
 			r.issue_date,
 			r.supply_date,
@@ -94,50 +163,100 @@ func QueryInvoiceSummary(
 			r.client_company_name,
 			r.client_address,
 			r.client_email,
+			r.client_vat_number,
 			r.note,
+			r.currency,
+			r.exchange_rate_micro,
+			i.void_reason,
+			i.voided_at,
+			i.voided_by_email,
+			r.vat_treatment,
 			r.vat_rate,
 			r.vat_amount_minor,
+			r.tax_rate_id,
+			r.tax_rate_name,
 			r.discount_type,
 			r.discount_rate,
 			r.discount_minor,
 			r.deposit_type,
 			r.deposit_rate,
 			r.deposit_minor,
+			r.deduction_rate,
+			r.deduction_line_types,
+			r.deduction_minor,
 			r.subtotal_minor,
 			r.total_minor,
+			rp.paid_minor,
+			rp.deposit_paid_minor,
 			COALESCE(
 				(
-					SELECT SUM(p.amount_minor)
-					FROM payments p
-					WHERE p.applied_in_revision_id = r.id
-						AND p.payment_type = 'payment'
+					SELECT SUM(cn.total_minor)
+					FROM credit_notes cn
+					WHERE cn.invoice_id = i.id
 				), 0
-			) AS paid_minor
+			) AS credited_minor
 		FROM invoices i
 		JOIN invoice_revisions r
 			ON r.invoice_id = i.id AND r.revision_no = ?
+		JOIN invoice_revision_paid rp
+			ON rp.revision_id = r.id
 		WHERE i.account_id = ? AND i.base_number = ? AND i.client_id = ?
 	`
 
-	var o InvoiceOverviewTotals
+	var (
+		o                  InvoiceOverviewTotals
+		revisionID         int64
+		deductionLineTypes string
+	)
 	err = db.QueryRowContext(ctx, query, revisionNo, accountID, baseNumber, clientID).Scan(
+		&revisionID,
 		&o.Status,
-		&o.BaseNumber, &o.RevisionNo,
+		&o.BaseNumber, &o.NumberLabel, &o.RevisionNo,
 		&o.IssueDate, &o.SupplyDate, &o.DueByDate,
-		&o.ClientName, &o.ClientCompanyName, &o.ClientAddress, &o.ClientEmail,
+		&o.ClientName, &o.ClientCompanyName, &o.ClientAddress, &o.ClientEmail, &o.ClientVATNumber,
 		&o.Note,
-		&o.VATRate, &o.VATAmountMin,
+		&o.Currency, &o.ExchangeRateMicro,
+		&o.VoidReason, &o.VoidedAt, &o.VoidedByEmail,
+		&o.VATTreatment, &o.VATRate, &o.VATAmountMin,
+		&o.TaxRateID, &o.TaxRateName,
 		&o.DiscountType, &o.DiscountRate, &o.DiscountMinor,
 		&o.DepositType, &o.DepositRate, &o.DepositMinor,
+		&o.DeductionRate, &deductionLineTypes, &o.DeductionMinor,
 		&o.SubtotalMinor, &o.TotalMinor,
 		&o.PaidMinor,
+		&o.DepositPaidMinor,
+		&o.CreditedMinor,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("GetInvoiceSummary() => %w,\nrevisionNumber: %v,\nbaseNumber: %v,\nclientID: %v", err, revisionNo, baseNumber, clientID)
 	}
+
+	o.DeductionLineTypes = SplitDeductionLineTypes(deductionLineTypes)
+
+	bands, err := queryRevisionVATBreakdown(ctx, db, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	o.VATBreakdown = VATBreakdownOrDefault(models.TotalsCreateIn{
+		VATRate:        o.VATRate,
+		VatAmountMinor: o.VATAmountMin,
+		DiscountMinor:  o.DiscountMinor,
+		SubtotalMinor:  o.SubtotalMinor,
+		VATBreakdown:   bands,
+	})
+
 	return &o, nil
 }
 
+// SplitDeductionLineTypes reads the stored comma-separated deduction line
+// types. Empty means the deduction applies to every line.
+func SplitDeductionLineTypes(stored string) []string {
+	if stored == "" {
+		return nil
+	}
+	return strings.Split(stored, ",")
+}
+
 func QueryInvoiceReceiptsForRevision(
 	ctx context.Context,
 	db *sql.DB,
@@ -156,7 +275,14 @@ func QueryInvoiceReceiptsForRevision(
 			p.receipt_no,
 			p.amount_minor,
 			p.payment_date,
-			p.label
+			p.label,
+			p.payment_method,
+			p.reference,
+			COALESCE((
+				SELECT SUM(rf.amount_minor)
+				FROM payment_refunds rf
+				WHERE rf.payment_id = p.id
+			), 0) AS refunded_minor
 		FROM invoices i
 		JOIN invoice_revisions r
 			ON r.invoice_id = i.id AND r.revision_no = ?
@@ -189,6 +315,9 @@ func QueryInvoiceReceiptsForRevision(
 			&p.AmountMinor,
 			&p.PaymentDate,
 			&p.Label,
+			&p.PaymentMethod,
+			&p.Reference,
+			&p.RefundedMinor,
 		); err != nil {
 			return nil, fmt.Errorf("scan receipt row: %w", err)
 		}
@@ -226,9 +355,14 @@ func QueryInvoiceLines(
 			it.minutes_worked,
 			it.name,
 			it.line_type,
-			it.quantity,
+			COALESCE(it.quantity_milli, it.quantity * 1000),
+			it.unit,
 			it.unit_price_minor,
-			it.line_total_minor
+			it.line_total_minor,
+			it.vat_rate,
+			it.discount_type,
+			it.discount_rate,
+			it.discount_minor
 		FROM invoices i
 		JOIN invoice_revisions r
 			ON r.invoice_id = i.id AND r.revision_no = ?
@@ -256,8 +390,13 @@ func QueryInvoiceLines(
 			&item.Name,
 			&item.LineType,
 			&item.Quantity,
+			&item.Unit,
 			&item.UnitPriceMin,
 			&item.LineTotalMin,
+			&item.VATRate,
+			&item.DiscountType,
+			&item.DiscountRate,
+			&item.DiscountMinor,
 		); err != nil {
 			return nil, fmt.Errorf("scan item: %w", err)
 		}
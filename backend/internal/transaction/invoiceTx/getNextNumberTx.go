@@ -2,6 +2,7 @@ package invoiceTx
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/viktorHadz/goInvoice26/internal/accountscope"
@@ -26,14 +27,32 @@ func GetSuggestedNextBaseNumber(ctx context.Context, a *app.App) (int64, error)
 	}
 
 	var next int64
-	err = a.DB.QueryRowContext(ctx, `
-		SELECT MAX(
-			COALESCE((SELECT next_base_number FROM invoice_number_seq WHERE account_id = ?), 1),
-			COALESCE((SELECT MAX(base_number) FROM invoices WHERE account_id = ?), 0) + 1
-		)
-	`, accountID, accountID).Scan(&next)
-	if err != nil {
+	if err := a.DB.QueryRowContext(ctx, nextBaseNumberSQL, accountID, accountID).Scan(&next); err != nil {
 		return 0, fmt.Errorf("get suggested next base number: %w", err)
 	}
 	return next, nil
 }
+
+const nextBaseNumberSQL = `
+	SELECT MAX(
+		COALESCE((SELECT next_base_number FROM invoice_number_seq WHERE account_id = ?), 1),
+		COALESCE((SELECT MAX(base_number) FROM invoices WHERE account_id = ?), 0) + 1
+	)
+`
+
+// nextBaseNumber reads the next free base number inside tx. The sequence is
+// advanced by the insert that consumes it, so callers must insert in the same tx.
+func nextBaseNumber(ctx context.Context, tx *sql.Tx, accountID int64) (int64, error) {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO invoice_number_seq (account_id, next_base_number)
+		VALUES (?, 1);
+	`, accountID); err != nil {
+		return 0, fmt.Errorf("ensure invoice sequence row: %w", err)
+	}
+
+	var next int64
+	if err := tx.QueryRowContext(ctx, nextBaseNumberSQL, accountID, accountID).Scan(&next); err != nil {
+		return 0, fmt.Errorf("allocate next base number: %w", err)
+	}
+	return next, nil
+}
@@ -3,6 +3,7 @@ package invoiceTx
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -10,17 +11,39 @@ import (
 )
 
 type InvoiceHistoryRow struct {
-	ID          int64
-	InvoiceID   int64
-	Type        string
-	CreatedAt   string
-	RevisionNo  sql.NullInt64
-	ReceiptNo   sql.NullInt64
-	IssueDate   sql.NullString
-	DueByDate   sql.NullString
-	PaymentDate sql.NullString
-	AmountMinor sql.NullInt64
-	Label       sql.NullString
+	ID            int64
+	InvoiceID     int64
+	Type          string
+	CreatedAt     string
+	RevisionNo    sql.NullInt64
+	ReceiptNo     sql.NullInt64
+	IssueDate     sql.NullString
+	DueByDate     sql.NullString
+	PaymentDate   sql.NullString
+	AmountMinor   sql.NullInt64
+	Label         sql.NullString
+	PaymentMethod sql.NullString
+	Reference     sql.NullString
+	RefundNo      sql.NullInt64
+	// VoidedBy is who voided the invoice, on void entries.
+	VoidedBy sql.NullString
+	// Diff is what changed from the previous revision. It is only set on
+	// revision entries when InvoiceHistoryFilters.IncludeRevisionDiffs is on.
+	Diff *RevisionDiff
+}
+
+// InvoiceHistoryFilters narrows invoice history. A PaymentMethod keeps only
+// payment receipts recorded with that method.
+type InvoiceHistoryFilters struct {
+	PaymentMethod        string
+	IncludeRevisionDiffs bool
+}
+
+func (f InvoiceHistoryFilters) where() (string, []any) {
+	if f.PaymentMethod == "" {
+		return "", nil
+	}
+	return "WHERE entry_type = 'payment_receipt' AND payment_method = ?", []any{f.PaymentMethod}
 }
 
 func QueryInvoiceHistory(
@@ -28,13 +51,15 @@ func QueryInvoiceHistory(
 	db *sql.DB,
 	clientID int64,
 	baseNumber int64,
+	filters InvoiceHistoryFilters,
 ) ([]InvoiceHistoryRow, error) {
 	accountID, err := accountscope.Require(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	rows, err := db.QueryContext(ctx, `
+	where, whereArgs := filters.where()
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
 		WITH target_invoice AS (
 			SELECT id
 			FROM invoices
@@ -51,7 +76,11 @@ func QueryInvoiceHistory(
 			due_by_date,
 			payment_date,
 			amount_minor,
-			label
+			label,
+			payment_method,
+			reference,
+			refund_no,
+			voided_by
 		FROM (
 			SELECT
 				r.id AS entry_id,
@@ -64,7 +93,11 @@ func QueryInvoiceHistory(
 				r.due_by_date,
 				NULL AS payment_date,
 				NULL AS amount_minor,
-				NULL AS label
+				NULL AS label,
+				NULL AS payment_method,
+				NULL AS reference,
+				NULL AS refund_no,
+				NULL AS voided_by
 			FROM invoice_revisions r
 			JOIN target_invoice ti
 				ON ti.id = r.invoice_id
@@ -82,31 +115,108 @@ func QueryInvoiceHistory(
 				NULL AS due_by_date,
 				p.payment_date,
 				p.amount_minor,
-				p.label
+				p.label,
+				p.payment_method,
+				p.reference,
+				NULL AS refund_no,
+				NULL AS voided_by
 			FROM payments p
 			JOIN target_invoice ti
 				ON ti.id = p.invoice_id
 			WHERE p.payment_type = 'payment'
+
+			UNION ALL
+
+			SELECT
+				rf.id AS entry_id,
+				rf.invoice_id,
+				'refund' AS entry_type,
+				rf.created_at,
+				NULL AS revision_no,
+				p.receipt_no,
+				NULL AS issue_date,
+				NULL AS due_by_date,
+				rf.refund_date AS payment_date,
+				rf.amount_minor,
+				rf.reason AS label,
+				NULL AS payment_method,
+				NULL AS reference,
+				rf.refund_no,
+				NULL AS voided_by
+			FROM payment_refunds rf
+			JOIN payments p
+				ON p.id = rf.payment_id
+			JOIN target_invoice ti
+				ON ti.id = rf.invoice_id
+
+			UNION ALL
+
+			SELECT
+				i.id AS entry_id,
+				i.id AS invoice_id,
+				'void' AS entry_type,
+				i.voided_at AS created_at,
+				NULL AS revision_no,
+				NULL AS receipt_no,
+				NULL AS issue_date,
+				NULL AS due_by_date,
+				NULL AS payment_date,
+				NULL AS amount_minor,
+				i.void_reason AS label,
+				NULL AS payment_method,
+				NULL AS reference,
+				NULL AS refund_no,
+				i.voided_by_email AS voided_by
+			FROM invoices i
+			JOIN target_invoice ti
+				ON ti.id = i.id
+			WHERE i.voided_at IS NOT NULL
 		)
+		%s
 		ORDER BY created_at ASC, entry_type ASC, entry_id ASC;
-	`, accountID, clientID, baseNumber)
+	`, where), append([]any{accountID, clientID, baseNumber}, whereArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("query invoice history: %w", err)
 	}
 	defer rows.Close()
 
-	return scanInvoiceHistoryRows(rows)
+	items, err := scanInvoiceHistoryRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	if !filters.IncludeRevisionDiffs {
+		return items, nil
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.Type != "revision" || !item.RevisionNo.Valid || item.RevisionNo.Int64 <= 1 {
+			continue
+		}
+		diff, err := QueryRevisionDiff(ctx, db, clientID, baseNumber, item.RevisionNo.Int64-1, item.RevisionNo.Int64)
+		if errors.Is(err, ErrRevisionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		item.Diff = diff
+	}
+
+	return items, nil
 }
 
 func QueryInvoiceHistoryForInvoices(
 	ctx context.Context,
 	db *sql.DB,
 	invoiceIDs []int64,
+	filters InvoiceHistoryFilters,
 ) ([]InvoiceHistoryRow, error) {
 	if len(invoiceIDs) == 0 {
 		return []InvoiceHistoryRow{}, nil
 	}
 
+	where, whereArgs := filters.where()
 	placeholders := make([]string, 0, len(invoiceIDs))
 	args := make([]any, 0, len(invoiceIDs))
 	for _, id := range invoiceIDs {
@@ -114,6 +224,7 @@ func QueryInvoiceHistoryForInvoices(
 		args = append(args, id)
 	}
 
+	inList := strings.Join(placeholders, ",")
 	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
 		SELECT
 			entry_id,
@@ -126,7 +237,11 @@ func QueryInvoiceHistoryForInvoices(
 			due_by_date,
 			payment_date,
 			amount_minor,
-			label
+			label,
+			payment_method,
+			reference,
+			refund_no,
+			voided_by
 		FROM (
 			SELECT
 				r.id AS entry_id,
@@ -139,7 +254,11 @@ func QueryInvoiceHistoryForInvoices(
 				r.due_by_date,
 				NULL AS payment_date,
 				NULL AS amount_minor,
-				NULL AS label
+				NULL AS label,
+				NULL AS payment_method,
+				NULL AS reference,
+				NULL AS refund_no,
+				NULL AS voided_by
 			FROM invoice_revisions r
 			WHERE r.invoice_id IN (%s)
 
@@ -156,13 +275,63 @@ func QueryInvoiceHistoryForInvoices(
 				NULL AS due_by_date,
 				p.payment_date,
 				p.amount_minor,
-				p.label
+				p.label,
+				p.payment_method,
+				p.reference,
+				NULL AS refund_no,
+				NULL AS voided_by
 			FROM payments p
 			WHERE p.invoice_id IN (%s)
 			  AND p.payment_type = 'payment'
+
+			UNION ALL
+
+			SELECT
+				rf.id AS entry_id,
+				rf.invoice_id,
+				'refund' AS entry_type,
+				rf.created_at,
+				NULL AS revision_no,
+				p.receipt_no,
+				NULL AS issue_date,
+				NULL AS due_by_date,
+				rf.refund_date AS payment_date,
+				rf.amount_minor,
+				rf.reason AS label,
+				NULL AS payment_method,
+				NULL AS reference,
+				rf.refund_no,
+				NULL AS voided_by
+			FROM payment_refunds rf
+			JOIN payments p
+				ON p.id = rf.payment_id
+			WHERE rf.invoice_id IN (%s)
+
+			UNION ALL
+
+			SELECT
+				i.id AS entry_id,
+				i.id AS invoice_id,
+				'void' AS entry_type,
+				i.voided_at AS created_at,
+				NULL AS revision_no,
+				NULL AS receipt_no,
+				NULL AS issue_date,
+				NULL AS due_by_date,
+				NULL AS payment_date,
+				NULL AS amount_minor,
+				i.void_reason AS label,
+				NULL AS payment_method,
+				NULL AS reference,
+				NULL AS refund_no,
+				i.voided_by_email AS voided_by
+			FROM invoices i
+			WHERE i.id IN (%s)
+			  AND i.voided_at IS NOT NULL
 		)
+		%s
 		ORDER BY invoice_id DESC, created_at ASC, entry_type ASC, entry_id ASC;
-	`, strings.Join(placeholders, ","), strings.Join(placeholders, ",")), append(args, args...)...)
+	`, inList, inList, inList, inList, where), append(append(append(append(args, args...), args...), args...), whereArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("query invoice history for page: %w", err)
 	}
@@ -187,6 +356,10 @@ func scanInvoiceHistoryRows(rows *sql.Rows) ([]InvoiceHistoryRow, error) {
 			&item.PaymentDate,
 			&item.AmountMinor,
 			&item.Label,
+			&item.PaymentMethod,
+			&item.Reference,
+			&item.RefundNo,
+			&item.VoidedBy,
 		); err != nil {
 			return nil, fmt.Errorf("scan invoice history row: %w", err)
 		}
@@ -0,0 +1,74 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestQueryInvoiceHistory_FiltersReceiptsByPaymentMethod(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 610, "issued")
+
+	card := "card"
+	reference := "AUTH-7781"
+	if _, _, _, err := invoiceTx.CreatePaymentReceipt(ctx, a, clientID, 610, 1, &models.PaymentReceiptCreateIn{
+		AmountMinor:   300,
+		PaymentDate:   "2026-04-02",
+		PaymentMethod: &card,
+		Reference:     &reference,
+	}); err != nil {
+		t.Fatalf("CreatePaymentReceipt: %v", err)
+	}
+
+	all, err := invoiceTx.QueryInvoiceHistory(ctx, a.DB, clientID, 610, invoiceTx.InvoiceHistoryFilters{})
+	if err != nil {
+		t.Fatalf("QueryInvoiceHistory(all): %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("history entries = %d, want revision plus two receipts", len(all))
+	}
+
+	byCard, err := invoiceTx.QueryInvoiceHistory(ctx, a.DB, clientID, 610, invoiceTx.InvoiceHistoryFilters{PaymentMethod: "card"})
+	if err != nil {
+		t.Fatalf("QueryInvoiceHistory(card): %v", err)
+	}
+	if len(byCard) != 1 {
+		t.Fatalf("card entries = %d, want 1", len(byCard))
+	}
+	got := byCard[0]
+	if got.Type != "payment_receipt" || got.PaymentMethod.String != "card" || got.Reference.String != reference || got.AmountMinor.Int64 != 300 {
+		t.Fatalf("card entry = %+v", got)
+	}
+
+	cash := "cash"
+	if _, err := invoiceTx.UpdatePaymentReceiptMetadata(ctx, a, clientID, 610, 1, got.ReceiptNo.Int64, &models.PaymentReceiptUpdateIn{
+		PaymentDate:   "2026-04-02",
+		PaymentMethod: &cash,
+	}); err != nil {
+		t.Fatalf("UpdatePaymentReceiptMetadata: %v", err)
+	}
+
+	receipt, err := invoiceTx.QueryPaymentReceiptByNumber(ctx, a.DB, clientID, 610, 1, got.ReceiptNo.Int64)
+	if err != nil {
+		t.Fatalf("QueryPaymentReceiptByNumber: %v", err)
+	}
+	if receipt.PaymentMethod.String != "cash" || receipt.Reference.Valid {
+		t.Fatalf("updated receipt method/reference = %+v/%+v, want cash and no reference", receipt.PaymentMethod, receipt.Reference)
+	}
+
+	byCard, err = invoiceTx.QueryInvoiceHistoryForInvoices(ctx, a.DB, []int64{got.InvoiceID}, invoiceTx.InvoiceHistoryFilters{PaymentMethod: "card"})
+	if err != nil {
+		t.Fatalf("QueryInvoiceHistoryForInvoices(card): %v", err)
+	}
+	if len(byCard) != 0 {
+		t.Fatalf("card entries after update = %d, want 0", len(byCard))
+	}
+}
@@ -0,0 +1,271 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/dberr"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+const (
+	LateChargeModeRevision = "revision"
+	LateChargeModeInvoice  = "invoice"
+)
+
+// ErrLateChargeExists is returned when the invoice was already charged up to the same day or later.
+var ErrLateChargeExists = errors.New("late payment charges already raised for this period")
+
+// DatedAmount is a receipt or credit note that lowers an invoice balance from
+// Date; refunds are negative.
+type DatedAmount struct {
+	Date        string
+	AmountMinor int64
+}
+
+// LateChargeState is everything the late payment calculator needs for one invoice.
+//
+// TotalMinor is what the client owes, net of any deduction they withhold.
+// ChargedMinor sums charges already added to the invoice as revision lines, so
+// interest is never charged on interest. LastChargedUntil is empty until the
+// first charge is raised.
+type LateChargeState struct {
+	InvoiceID           int64
+	Status              string
+	RevisionNo          int64
+	DueByDate           sql.NullString
+	TotalMinor          int64
+	ChargedMinor        int64
+	LastChargedUntil    string
+	CompensationCharged bool
+	Reductions          []DatedAmount
+}
+
+// LateCharge is one raised late payment charge.
+type LateCharge struct {
+	Mode              string
+	AccruedFrom       string
+	ChargedUntil      string
+	AnnualRateBps     int64
+	InterestMinor     int64
+	CompensationMinor int64
+}
+
+// QueryLateChargeState loads the current revision of an invoice together with
+// its receipts, credit notes and previously raised late charges.
+func QueryLateChargeState(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+) (*LateChargeState, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		st         LateChargeState
+		revisionID int64
+	)
+	err = db.QueryRowContext(ctx, `
+		SELECT
+			i.id,
+			i.status,
+			r.id,
+			r.revision_no,
+			r.due_by_date,
+			r.total_minor - r.deduction_minor,
+			COALESCE((
+				SELECT SUM(lc.interest_minor + lc.compensation_minor)
+				FROM invoice_late_charges lc
+				WHERE lc.invoice_id = i.id
+				  AND lc.mode = 'revision'
+			), 0),
+			COALESCE((
+				SELECT MAX(lc.charged_until)
+				FROM invoice_late_charges lc
+				WHERE lc.invoice_id = i.id
+			), ''),
+			EXISTS(
+				SELECT 1
+				FROM invoice_late_charges lc
+				WHERE lc.invoice_id = i.id
+				  AND lc.compensation_minor > 0
+			)
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		WHERE i.account_id = ? AND i.client_id = ? AND i.base_number = ?
+	`, accountID, clientID, baseNumber).Scan(
+		&st.InvoiceID,
+		&st.Status,
+		&revisionID,
+		&st.RevisionNo,
+		&st.DueByDate,
+		&st.TotalMinor,
+		&st.ChargedMinor,
+		&st.LastChargedUntil,
+		&st.CompensationCharged,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load late charge state: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT payment_date, amount_minor
+		FROM payments
+		WHERE applied_in_revision_id = ?
+		  AND payment_type IN ('payment','deposit')
+		UNION ALL
+		SELECT refund_date, -amount_minor
+		FROM payment_refunds
+		WHERE applied_in_revision_id = ?
+		UNION ALL
+		SELECT issue_date, total_minor
+		FROM credit_notes
+		WHERE invoice_id = ?
+		ORDER BY 1 ASC
+	`, revisionID, revisionID, st.InvoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("load late charge reductions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var red DatedAmount
+		if err := rows.Scan(&red.Date, &red.AmountMinor); err != nil {
+			return nil, fmt.Errorf("scan late charge reduction: %w", err)
+		}
+		st.Reductions = append(st.Reductions, red)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("late charge reduction rows: %w", err)
+	}
+
+	return &st, nil
+}
+
+// CreateLateChargeRevision appends a revision carrying the charge lines and
+// records the charge in the same transaction.
+func CreateLateChargeRevision(
+	ctx context.Context,
+	a *app.App,
+	canonical *models.FEInvoiceIn,
+	charge LateCharge,
+) (invoiceID, revisionID, revisionNo int64, err error) {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	invoiceID, revisionID, revisionNo, err = createRevisionInTx(ctx, tx, canonical)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := insertLateCharge(ctx, tx, invoiceID, charge, revisionID, nil); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commit late charge revision: %w", err)
+	}
+
+	return invoiceID, revisionID, revisionNo, nil
+}
+
+// CreateLateChargeInvoice raises a separate draft invoice for the charge
+// against sourceBaseNumber and records the charge in the same transaction.
+func CreateLateChargeInvoice(
+	ctx context.Context,
+	a *app.App,
+	sourceBaseNumber int64,
+	canonical *models.FEInvoiceIn,
+	charge LateCharge,
+) (invoiceID, baseNumber int64, err error) {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	sourceID, status, err := LoadInvoiceIDAndStatus(ctx, tx, canonical.Overview.ClientID, sourceBaseNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := assertRevisionAllowed(status); err != nil {
+		return 0, 0, err
+	}
+
+	invoiceID, _, baseNumber, err = CreateNextInTx(ctx, tx, canonical)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := insertLateCharge(ctx, tx, sourceID, charge, 0, &invoiceID); err != nil {
+		return 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("commit late charge invoice: %w", err)
+	}
+
+	return invoiceID, baseNumber, nil
+}
+
+func insertLateCharge(
+	ctx context.Context,
+	tx *sql.Tx,
+	invoiceID int64,
+	charge LateCharge,
+	revisionID int64,
+	chargeInvoiceID *int64,
+) error {
+	var lastChargedUntil string
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(charged_until), '')
+		FROM invoice_late_charges
+		WHERE invoice_id = ?;
+	`, invoiceID).Scan(&lastChargedUntil); err != nil {
+		return fmt.Errorf("load last late charge: %w", err)
+	}
+	if lastChargedUntil >= charge.ChargedUntil {
+		return ErrLateChargeExists
+	}
+
+	var revision any
+	if revisionID > 0 {
+		revision = revisionID
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO invoice_late_charges (
+			invoice_id,
+			mode,
+			accrued_from,
+			charged_until,
+			annual_rate_bps,
+			interest_minor,
+			compensation_minor,
+			revision_id,
+			charge_invoice_id
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`, invoiceID, charge.Mode, charge.AccruedFrom, charge.ChargedUntil, charge.AnnualRateBps,
+		charge.InterestMinor, charge.CompensationMinor, revision, chargeInvoiceID); err != nil {
+		if dberr.IsUniqueViolation(err) {
+			return ErrLateChargeExists
+		}
+		return fmt.Errorf("insert late charge: %w", err)
+	}
+
+	return nil
+}
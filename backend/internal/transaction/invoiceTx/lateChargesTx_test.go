@@ -0,0 +1,109 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestLateCharges_RecordsChargesAndTracksState(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 510, "issued")
+	if _, _, _, _, err := invoiceTx.CreateCreditNote(ctx, a, clientID, 510, creditNotePayload(200)); err != nil {
+		t.Fatalf("CreateCreditNote: %v", err)
+	}
+
+	st, err := invoiceTx.QueryLateChargeState(ctx, a.DB, clientID, 510)
+	if err != nil {
+		t.Fatalf("QueryLateChargeState(initial): %v", err)
+	}
+	if st.Status != "issued" || st.RevisionNo != 1 || st.DueByDate.String != "2026-04-10" || st.TotalMinor != 1000 {
+		t.Fatalf("initial state = %+v", st)
+	}
+	if st.ChargedMinor != 0 || st.LastChargedUntil != "" || st.CompensationCharged {
+		t.Fatalf("initial charge state = %+v, want nothing charged", st)
+	}
+	wantReductions := []invoiceTx.DatedAmount{
+		{Date: "2026-03-28", AmountMinor: 100},
+		{Date: "2026-04-01", AmountMinor: 200},
+	}
+	if len(st.Reductions) != len(wantReductions) {
+		t.Fatalf("reductions = %+v, want %+v", st.Reductions, wantReductions)
+	}
+	for i, want := range wantReductions {
+		if st.Reductions[i] != want {
+			t.Fatalf("reduction[%d] = %+v, want %+v", i, st.Reductions[i], want)
+		}
+	}
+
+	interest := invoiceTx.LateCharge{
+		Mode:          invoiceTx.LateChargeModeRevision,
+		AccruedFrom:   "2026-04-10",
+		ChargedUntil:  "2026-05-10",
+		AnnualRateBps: 800,
+		InterestMinor: 50,
+	}
+	_, _, revisionNo, err := invoiceTx.CreateLateChargeRevision(ctx, a, draftUpdatePayload(clientID, 510, 1050, 100, "With interest"), interest)
+	if err != nil {
+		t.Fatalf("CreateLateChargeRevision: %v", err)
+	}
+	if revisionNo != 2 {
+		t.Fatalf("revisionNo = %d, want 2", revisionNo)
+	}
+
+	_, _, _, err = invoiceTx.CreateLateChargeRevision(ctx, a, draftUpdatePayload(clientID, 510, 1050, 100, "Again"), interest)
+	if !errors.Is(err, invoiceTx.ErrLateChargeExists) {
+		t.Fatalf("CreateLateChargeRevision(repeat) error = %v, want %v", err, invoiceTx.ErrLateChargeExists)
+	}
+
+	compensation := invoiceTx.LateCharge{
+		Mode:              invoiceTx.LateChargeModeInvoice,
+		AccruedFrom:       "2026-05-10",
+		ChargedUntil:      "2026-06-01",
+		AnnualRateBps:     800,
+		InterestMinor:     0,
+		CompensationMinor: 4000,
+	}
+	chargeInvoiceID, chargeBase, err := invoiceTx.CreateLateChargeInvoice(ctx, a, 510, draftUpdatePayload(clientID, 0, 4000, 0, "Late payment compensation"), compensation)
+	if err != nil {
+		t.Fatalf("CreateLateChargeInvoice: %v", err)
+	}
+	if chargeBase == 510 || chargeInvoiceID == 0 {
+		t.Fatalf("charge invoice = (%d, %d), want a new invoice", chargeInvoiceID, chargeBase)
+	}
+
+	st, err = invoiceTx.QueryLateChargeState(ctx, a.DB, clientID, 510)
+	if err != nil {
+		t.Fatalf("QueryLateChargeState(after): %v", err)
+	}
+	if st.RevisionNo != 2 || st.TotalMinor != 1050 {
+		t.Fatalf("after state revision/total = %d/%d, want 2/1050", st.RevisionNo, st.TotalMinor)
+	}
+	if st.ChargedMinor != 50 {
+		t.Fatalf("ChargedMinor = %d, want only the revision charge (50)", st.ChargedMinor)
+	}
+	if st.LastChargedUntil != "2026-06-01" || !st.CompensationCharged {
+		t.Fatalf("after charge state = %+v", st)
+	}
+	if len(st.Reductions) != 2 {
+		t.Fatalf("reductions after revision = %+v, want receipt carried over plus credit note", st.Reductions)
+	}
+
+	if _, err := a.DB.Exec(`DELETE FROM invoices WHERE id = ?`, chargeInvoiceID); err != nil {
+		t.Fatalf("delete charge invoice: %v", err)
+	}
+	st, err = invoiceTx.QueryLateChargeState(ctx, a.DB, clientID, 510)
+	if err != nil {
+		t.Fatalf("QueryLateChargeState(after delete): %v", err)
+	}
+	if st.LastChargedUntil != "2026-05-10" || st.CompensationCharged {
+		t.Fatalf("deleting the charge invoice should drop its charge, got %+v", st)
+	}
+}
@@ -0,0 +1,237 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
+)
+
+// NextInvoiceNumber is the number a new invoice would get. NumberLabel is
+// empty when the account has no number template.
+type NextInvoiceNumber struct {
+	BaseNumber  int64
+	NumberLabel string
+}
+
+// templateNumber is a number rendered from the account's number template.
+type templateNumber struct {
+	Label  string
+	Period string
+	Seq    int64
+}
+
+// PreviewNextInvoiceNumber returns the number a new invoice for clientID
+// issued on issueDate would get, without allocating it.
+func PreviewNextInvoiceNumber(ctx context.Context, a *app.App, clientID int64, issueDate string) (NextInvoiceNumber, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return NextInvoiceNumber{}, err
+	}
+
+	baseNumber, err := GetSuggestedNextBaseNumber(ctx, a)
+	if err != nil {
+		return NextInvoiceNumber{}, err
+	}
+
+	var clientName string
+	if err := a.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(NULLIF(TRIM(company_name), ''), name)
+		FROM clients
+		WHERE account_id = ? AND id = ?;
+	`, accountID, clientID).Scan(&clientName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return NextInvoiceNumber{}, ErrInvoiceNotFound
+		}
+		return NextInvoiceNumber{}, fmt.Errorf("load client name: %w", err)
+	}
+
+	number, err := peekTemplateNumber(ctx, a.DB, accountID, issueDate, clientName)
+	if err != nil {
+		return NextInvoiceNumber{}, err
+	}
+
+	return NextInvoiceNumber{BaseNumber: baseNumber, NumberLabel: number.Label}, nil
+}
+
+// QueryNumberLabel returns the template number of the invoice under
+// baseNumber, or the one ov would get if it is not saved yet. It is empty
+// when the invoice predates the account's number template.
+func QueryNumberLabel(ctx context.Context, db *sql.DB, clientID, baseNumber int64, ov *models.InvoiceCreateIn) (string, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var label sql.NullString
+	err = db.QueryRowContext(ctx, `
+		SELECT number_label
+		FROM invoices
+		WHERE account_id = ? AND client_id = ? AND base_number = ?;
+	`, accountID, clientID, baseNumber).Scan(&label)
+	if err == nil {
+		return label.String, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("load invoice number label: %w", err)
+	}
+
+	number, err := peekTemplateNumber(ctx, db, accountID, ov.IssueDate, numberClientName(ov))
+	if err != nil {
+		return "", err
+	}
+	return number.Label, nil
+}
+
+// numberClientName is the name {CLIENT} is rendered from.
+func numberClientName(ov *models.InvoiceCreateIn) string {
+	if strings.TrimSpace(ov.ClientCompanyName) != "" {
+		return ov.ClientCompanyName
+	}
+	return ov.ClientName
+}
+
+// allocateTemplateNumber renders the account's number template for a new
+// invoice and advances the sequence of its period. It returns a zero
+// templateNumber when the account has no template.
+func allocateTemplateNumber(ctx context.Context, tx *sql.Tx, accountID int64, issueDate, clientName string) (templateNumber, error) {
+	number, err := peekTemplateNumber(ctx, tx, accountID, issueDate, clientName)
+	if err != nil || number.Label == "" {
+		return number, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO invoice_number_periods (account_id, period, next_seq)
+		VALUES (?, ?, ?)
+		ON CONFLICT(account_id, period) DO UPDATE SET
+			next_seq = excluded.next_seq;
+	`, accountID, number.Period, number.Seq+1); err != nil {
+		return templateNumber{}, fmt.Errorf("advance invoice number period: %w", err)
+	}
+
+	return number, nil
+}
+
+// reallocateTemplateNumber gives a draft a new template number when its
+// issue date moves into another period. The old number goes back to its
+// period when it was the last one handed out, so that sequence keeps no gap.
+// Invoices numbered before the account had a template are left alone.
+func reallocateTemplateNumber(ctx context.Context, tx *sql.Tx, accountID, invoiceID int64, issueDate, clientName string) error {
+	var (
+		label  sql.NullString
+		period sql.NullString
+		seq    sql.NullInt64
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT number_label, number_period, number_seq
+		FROM invoices
+		WHERE id = ?;
+	`, invoiceID).Scan(&label, &period, &seq); err != nil {
+		return fmt.Errorf("load invoice number: %w", err)
+	}
+	if !label.Valid {
+		return nil
+	}
+
+	template, err := loadNumberTemplate(ctx, tx, accountID)
+	if err != nil || template == "" {
+		return err
+	}
+	if invoiceformat.NumberTemplatePeriod(template, issueDate) == period.String {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoice_number_periods
+		SET next_seq = ?
+		WHERE account_id = ? AND period = ? AND next_seq = ?;
+	`, seq.Int64, accountID, period.String, seq.Int64+1); err != nil {
+		return fmt.Errorf("release invoice number: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoices
+		SET number_label = NULL, number_period = NULL, number_seq = NULL
+		WHERE id = ?;
+	`, invoiceID); err != nil {
+		return fmt.Errorf("clear invoice number: %w", err)
+	}
+
+	number, err := allocateTemplateNumber(ctx, tx, accountID, issueDate, clientName)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoices
+		SET number_label = ?, number_period = ?, number_seq = ?
+		WHERE id = ?;
+	`, number.Label, number.Period, number.Seq, invoiceID); err != nil {
+		return fmt.Errorf("update invoice number: %w", err)
+	}
+	return nil
+}
+
+// loadNumberTemplate returns the account's invoice number template, or an
+// empty string when it has none.
+func loadNumberTemplate(ctx context.Context, q queryer, accountID int64) (string, error) {
+	var template string
+	err := q.QueryRowContext(ctx, `
+		SELECT invoice_number_template
+		FROM account_settings
+		WHERE account_id = ?;
+	`, accountID).Scan(&template)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("load invoice number template: %w", err)
+	}
+	return strings.TrimSpace(template), nil
+}
+
+// peekTemplateNumber renders the next free number for the period issueDate
+// falls in. Numbers already taken, e.g. after the template changed, are
+// skipped.
+func peekTemplateNumber(ctx context.Context, q queryer, accountID int64, issueDate, clientName string) (templateNumber, error) {
+	template, err := loadNumberTemplate(ctx, q, accountID)
+	if err != nil || template == "" {
+		return templateNumber{}, err
+	}
+
+	number := templateNumber{Period: invoiceformat.NumberTemplatePeriod(template, issueDate)}
+	err = q.QueryRowContext(ctx, `
+		SELECT next_seq
+		FROM invoice_number_periods
+		WHERE account_id = ? AND period = ?;
+	`, accountID, number.Period).Scan(&number.Seq)
+	if errors.Is(err, sql.ErrNoRows) {
+		number.Seq = 1
+	} else if err != nil {
+		return templateNumber{}, fmt.Errorf("load invoice number period: %w", err)
+	}
+
+	for {
+		number.Label = invoiceformat.RenderNumberTemplate(template, invoiceformat.NumberFields{
+			IssueDate: issueDate,
+			Seq:       number.Seq,
+			Client:    clientName,
+		})
+
+		var taken bool
+		if err := q.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1
+				FROM invoices
+				WHERE account_id = ? AND number_label = ?
+			);
+		`, accountID, number.Label).Scan(&taken); err != nil {
+			return templateNumber{}, fmt.Errorf("check invoice number label: %w", err)
+		}
+		if !taken {
+			return number, nil
+		}
+		number.Seq++
+	}
+}
@@ -0,0 +1,122 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreateNext_NumbersFromTemplateAndRestartsEachYear(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	if _, err := a.DB.Exec(`
+		INSERT INTO account_settings (account_id, invoice_number_template)
+		VALUES (1, 'INV-{YYYY}-{SEQ:4}')
+		ON CONFLICT(account_id) DO UPDATE SET invoice_number_template = excluded.invoice_number_template;
+	`); err != nil {
+		t.Fatalf("set number template: %v", err)
+	}
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 41, "issued")
+
+	preview, err := invoiceTx.PreviewNextInvoiceNumber(ctx, a, clientID, "2026-03-30")
+	if err != nil {
+		t.Fatalf("PreviewNextInvoiceNumber: %v", err)
+	}
+	if preview.BaseNumber != 42 || preview.NumberLabel != "INV-2026-0001" {
+		t.Fatalf("preview = %+v, want 42 INV-2026-0001", preview)
+	}
+
+	tests := []struct {
+		issueDate string
+		want      string
+	}{
+		{issueDate: "2026-03-30", want: "INV-2026-0001"},
+		{issueDate: "2026-11-02", want: "INV-2026-0002"},
+		{issueDate: "2027-01-04", want: "INV-2027-0001"},
+		{issueDate: "2026-12-31", want: "INV-2026-0003"},
+	}
+	for _, tc := range tests {
+		payload := draftUpdatePayload(clientID, 0, 1000, 0, "Line")
+		payload.Overview.IssueDate = tc.issueDate
+
+		_, _, baseNumber, err := invoiceTx.CreateNext(ctx, a, payload)
+		if err != nil {
+			t.Fatalf("CreateNext(%s): %v", tc.issueDate, err)
+		}
+
+		summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, baseNumber, 1)
+		if err != nil {
+			t.Fatalf("QueryInvoiceSummary(%d): %v", baseNumber, err)
+		}
+		if summary.NumberLabel.String != tc.want {
+			t.Fatalf("invoice %d issued %s label = %q, want %q", baseNumber, tc.issueDate, summary.NumberLabel.String, tc.want)
+		}
+	}
+
+	legacy, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 41, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary legacy: %v", err)
+	}
+	if legacy.NumberLabel.Valid {
+		t.Fatalf("legacy invoice label = %q, want NULL", legacy.NumberLabel.String)
+	}
+}
+
+func TestUpdateDraft_RenumbersWhenIssueDateChangesPeriod(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	if _, err := a.DB.Exec(`
+		INSERT INTO account_settings (account_id, invoice_number_template)
+		VALUES (1, 'INV-{YYYY}-{SEQ:4}')
+		ON CONFLICT(account_id) DO UPDATE SET invoice_number_template = excluded.invoice_number_template;
+	`); err != nil {
+		t.Fatalf("set number template: %v", err)
+	}
+
+	clientID := insertClient(t, a)
+	payload := draftUpdatePayload(clientID, 0, 1000, 0, "Line")
+	payload.Overview.IssueDate = "2026-12-30"
+	_, _, baseNumber, err := invoiceTx.CreateNext(ctx, a, payload)
+	if err != nil {
+		t.Fatalf("CreateNext: %v", err)
+	}
+
+	tests := []struct {
+		issueDate string
+		want      string
+	}{
+		{issueDate: "2026-12-31", want: "INV-2026-0001"},
+		{issueDate: "2027-01-02", want: "INV-2027-0001"},
+	}
+	for _, tc := range tests {
+		update := draftUpdatePayload(clientID, baseNumber, 1000, 0, "Line")
+		update.Overview.IssueDate = tc.issueDate
+		if _, _, err := invoiceTx.UpdateDraft(ctx, a, update); err != nil {
+			t.Fatalf("UpdateDraft(%s): %v", tc.issueDate, err)
+		}
+
+		summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, baseNumber, 1)
+		if err != nil {
+			t.Fatalf("QueryInvoiceSummary: %v", err)
+		}
+		if summary.NumberLabel.String != tc.want {
+			t.Fatalf("issued %s label = %q, want %q", tc.issueDate, summary.NumberLabel.String, tc.want)
+		}
+	}
+
+	preview, err := invoiceTx.PreviewNextInvoiceNumber(ctx, a, clientID, "2026-12-31")
+	if err != nil {
+		t.Fatalf("PreviewNextInvoiceNumber: %v", err)
+	}
+	if preview.NumberLabel != "INV-2026-0001" {
+		t.Fatalf("next 2026 label = %q, want the released INV-2026-0001", preview.NumberLabel)
+	}
+}
@@ -24,8 +24,9 @@ type paymentReceiptState struct {
 	CurrentRevisionID int64
 	RevisionID        int64
 	RevisionNo        int64
-	TotalMinor        int64
+	PayableMinor      int64 // total less any deduction withheld by the client
 	PaidMinor         int64
+	CreditedMinor     int64
 }
 
 type PaymentReceiptRow struct {
@@ -36,6 +37,8 @@ type PaymentReceiptRow struct {
 	PaymentDate       string
 	AmountMinor       int64
 	Label             sql.NullString
+	PaymentMethod     sql.NullString
+	Reference         sql.NullString
 	AppliedRevisionID int64
 	AppliedRevisionNo int64
 }
@@ -54,27 +57,59 @@ func CreatePaymentReceipt(
 	}
 	defer tx.Rollback()
 
+	invoiceID, paymentID, receiptNo, err = createPaymentReceiptInTx(ctx, tx, clientID, baseNumber, revisionNo, canonical, receiptSource{})
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commit payment receipt: %w", err)
+	}
+
+	return invoiceID, paymentID, receiptNo, nil
+}
+
+// receiptSource records where the money behind a receipt came from when it
+// was not paid against the invoice directly.
+type receiptSource struct {
+	clientPaymentID  *int64 // allocated from a client payment
+	fromClientCredit bool   // applied from the client's credit balance
+}
+
+// createPaymentReceiptInTx records one receipt against a revision.
+func createPaymentReceiptInTx(
+	ctx context.Context,
+	tx *sql.Tx,
+	clientID int64,
+	baseNumber int64,
+	revisionNo int64,
+	canonical *models.PaymentReceiptCreateIn,
+	source receiptSource,
+) (invoiceID, paymentID, receiptNo int64, err error) {
 	state, err := loadPaymentReceiptState(ctx, tx, clientID, baseNumber, revisionNo)
 	if err != nil {
 		return 0, 0, 0, err
 	}
-	if err := assertReceiptCreateAllowed(state.InvoiceStatus, state.TotalMinor, state.PaidMinor); err != nil {
+	if err := assertReceiptCreateAllowed(state.InvoiceStatus, state.PayableMinor, state.PaidMinor+state.CreditedMinor); err != nil {
+		return 0, 0, 0, err
+	}
+	if err := assertIfMatch(ctx, tx, state.InvoiceID); err != nil {
+		return 0, 0, 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, state.InvoiceID)
+	if err != nil {
 		return 0, 0, 0, err
 	}
 
 	if err := tx.QueryRowContext(ctx, `
 		SELECT COALESCE(MAX(receipt_no), 0) + 1
 		FROM payments
-		WHERE applied_in_revision_id = ?;
+		WHERE applied_in_revision_id = ?
+		  AND payment_type = 'payment';
 	`, state.RevisionID).Scan(&receiptNo); err != nil {
 		return 0, 0, 0, fmt.Errorf("next receipt number: %w", err)
 	}
 
-	var label any
-	if canonical.Label != nil {
-		label = *canonical.Label
-	}
-
 	if err := tx.QueryRowContext(ctx, `
 		INSERT INTO payments (
 			invoice_id,
@@ -83,11 +118,21 @@ func CreatePaymentReceipt(
 			amount_minor,
 			payment_date,
 			applied_in_revision_id,
-			label
+			label,
+			payment_method,
+			reference,
+			client_payment_id,
+			from_client_credit
 		)
-		VALUES (?, ?, 'payment', ?, ?, ?, ?)
+		VALUES (?, ?, 'payment', ?, ?, ?, ?, ?, ?, ?, ?)
 		RETURNING id;
-	`, state.InvoiceID, receiptNo, canonical.AmountMinor, canonical.PaymentDate, state.RevisionID, label).Scan(&paymentID); err != nil {
+	`, state.InvoiceID, receiptNo, canonical.AmountMinor, canonical.PaymentDate, state.RevisionID,
+		normalizedOptionalString(canonical.Label),
+		normalizedOptionalString(canonical.PaymentMethod),
+		normalizedOptionalString(canonical.Reference),
+		source.clientPaymentID,
+		source.fromClientCredit,
+	).Scan(&paymentID); err != nil {
 		return 0, 0, 0, fmt.Errorf("insert payment receipt: %w", err)
 	}
 
@@ -95,8 +140,8 @@ func CreatePaymentReceipt(
 		return 0, 0, 0, err
 	}
 
-	if err := tx.Commit(); err != nil {
-		return 0, 0, 0, fmt.Errorf("commit payment receipt: %w", err)
+	if err := finishInvoiceMutation(ctx, tx, state.InvoiceID, AuditReceiptCreated, paymentID, beforeHash); err != nil {
+		return 0, 0, 0, err
 	}
 
 	return state.InvoiceID, paymentID, receiptNo, nil
@@ -124,20 +169,26 @@ func UpdatePaymentReceiptMetadata(
 	if err := assertReceiptMutationAllowed(state.InvoiceStatus); err != nil {
 		return 0, err
 	}
-
-	var label any
-	if canonical.Label != nil {
-		label = *canonical.Label
+	if err := assertIfMatch(ctx, tx, state.InvoiceID); err != nil {
+		return 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, state.InvoiceID)
+	if err != nil {
+		return 0, err
 	}
 
 	err = tx.QueryRowContext(ctx, `
 		UPDATE payments
-		SET payment_date = ?, label = ?
+		SET payment_date = ?, label = ?, payment_method = ?, reference = ?
 		WHERE applied_in_revision_id = ?
 		  AND receipt_no = ?
 		  AND payment_type = 'payment'
 		RETURNING id;
-	`, canonical.PaymentDate, label, state.RevisionID, receiptNo).Scan(&paymentID)
+	`, canonical.PaymentDate,
+		normalizedOptionalString(canonical.Label),
+		normalizedOptionalString(canonical.PaymentMethod),
+		normalizedOptionalString(canonical.Reference),
+		state.RevisionID, receiptNo).Scan(&paymentID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return 0, ErrPaymentReceiptNotFound
 	}
@@ -145,6 +196,10 @@ func UpdatePaymentReceiptMetadata(
 		return 0, fmt.Errorf("update payment receipt metadata: %w", err)
 	}
 
+	if err := finishInvoiceMutation(ctx, tx, state.InvoiceID, AuditReceiptUpdated, paymentID, beforeHash); err != nil {
+		return 0, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf("commit payment receipt metadata: %w", err)
 	}
@@ -173,6 +228,16 @@ func DeletePaymentReceipt(
 	if err := assertReceiptMutationAllowed(state.InvoiceStatus); err != nil {
 		return 0, err
 	}
+	if err := assertReceiptNotRefunded(ctx, tx, state.RevisionID, receiptNo); err != nil {
+		return 0, err
+	}
+	if err := assertIfMatch(ctx, tx, state.InvoiceID); err != nil {
+		return 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, state.InvoiceID)
+	if err != nil {
+		return 0, err
+	}
 
 	err = tx.QueryRowContext(ctx, `
 		DELETE FROM payments
@@ -192,6 +257,10 @@ func DeletePaymentReceipt(
 		return 0, err
 	}
 
+	if err := finishInvoiceMutation(ctx, tx, state.InvoiceID, AuditReceiptDeleted, paymentID, beforeHash); err != nil {
+		return 0, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return 0, fmt.Errorf("commit payment receipt delete: %w", err)
 	}
@@ -222,6 +291,8 @@ func QueryPaymentReceiptByNumber(
 			p.payment_date,
 			p.amount_minor,
 			p.label,
+			p.payment_method,
+			p.reference,
 			p.applied_in_revision_id,
 			r.revision_no
 		FROM invoices i
@@ -243,6 +314,8 @@ func QueryPaymentReceiptByNumber(
 		&out.PaymentDate,
 		&out.AmountMinor,
 		&out.Label,
+		&out.PaymentMethod,
+		&out.Reference,
 		&out.AppliedRevisionID,
 		&out.AppliedRevisionNo,
 	)
@@ -276,27 +349,31 @@ func loadPaymentReceiptState(
 			i.current_revision_id,
 			r.id,
 			r.revision_no,
-			r.total_minor,
-			COALESCE(SUM(p.amount_minor), 0) AS paid_minor
+			r.total_minor - r.deduction_minor,
+			rp.paid_minor,
+			COALESCE((
+				SELECT SUM(cn.total_minor)
+				FROM credit_notes cn
+				WHERE cn.invoice_id = i.id
+			), 0) AS credited_minor
 		FROM invoices i
 		JOIN invoice_revisions r
 			ON r.invoice_id = i.id
 		   AND r.revision_no = ?
-		LEFT JOIN payments p
-			ON p.applied_in_revision_id = r.id
-		   AND p.payment_type = 'payment'
+		JOIN invoice_revision_paid rp
+			ON rp.revision_id = r.id
 		WHERE i.account_id = ?
 		  AND i.client_id = ?
-		  AND i.base_number = ?
-		GROUP BY i.id, i.status, i.current_revision_id, r.id, r.revision_no, r.total_minor;
+		  AND i.base_number = ?;
 	`, revisionNo, accountID, clientID, baseNumber).Scan(
 		&state.InvoiceID,
 		&state.InvoiceStatus,
 		&state.CurrentRevisionID,
 		&state.RevisionID,
 		&state.RevisionNo,
-		&state.TotalMinor,
+		&state.PayableMinor,
 		&state.PaidMinor,
+		&state.CreditedMinor,
 	)
 	if errors.Is(err, sql.ErrNoRows) {
 		return paymentReceiptState{}, ErrInvoiceNotFound
@@ -308,12 +385,12 @@ func loadPaymentReceiptState(
 	return state, nil
 }
 
-func assertReceiptCreateAllowed(status string, totalMinor int64, paidMinor int64) error {
+func assertReceiptCreateAllowed(status string, payableMinor int64, paidMinor int64) error {
 	if status == "void" {
 		return ErrInvoiceVoidForReceipt
 	}
 
-	if paidMinor >= totalMinor && totalMinor > 0 {
+	if paidMinor >= payableMinor && payableMinor > 0 {
 		return ErrInvoicePaidForReceipt
 	}
 
@@ -347,7 +424,11 @@ func cloneReceiptSnapshot(
 			amount_minor,
 			payment_date,
 			applied_in_revision_id,
-			label
+			label,
+			payment_method,
+			reference,
+			client_payment_id,
+			from_client_credit
 		)
 		SELECT
 			?,
@@ -356,14 +437,18 @@ func cloneReceiptSnapshot(
 			p.amount_minor,
 			p.payment_date,
 			?,
-			p.label
+			p.label,
+			p.payment_method,
+			p.reference,
+			p.client_payment_id,
+			p.from_client_credit
 		FROM payments p
 		WHERE p.applied_in_revision_id = ?
-		  AND p.payment_type = 'payment'
+		  AND p.payment_type IN ('payment','deposit')
 		ORDER BY p.receipt_no ASC, p.id ASC;
 	`, invoiceID, targetRevisionID, sourceRevisionID); err != nil {
 		return fmt.Errorf("clone payment receipt snapshot: %w", err)
 	}
 
-	return nil
+	return cloneRefundSnapshot(ctx, tx, invoiceID, sourceRevisionID, targetRevisionID)
 }
@@ -25,10 +25,9 @@ var (
 func sumPaymentsByInvoice(ctx context.Context, tx *sql.Tx, invoiceID int64) (int64, error) {
 	var existing int64
 	if err := tx.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(amount_minor), 0)
-		FROM payments
+		SELECT COALESCE(SUM(paid_minor), 0)
+		FROM invoice_revision_paid
 		WHERE invoice_id = ?
-		  AND payment_type = 'payment'
 	`, invoiceID).Scan(&existing); err != nil {
 		return 0, fmt.Errorf("sum payments: %w", err)
 	}
@@ -43,11 +42,10 @@ func sumPaymentsVisibleUpToRevision(
 ) (int64, error) {
 	var existing int64
 	if err := tx.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(p.amount_minor), 0)
-		FROM payments p
-		JOIN invoice_revisions ap ON ap.id = p.applied_in_revision_id
-		WHERE p.invoice_id = ?
-			AND p.payment_type = 'payment'
+		SELECT COALESCE(SUM(rp.paid_minor), 0)
+		FROM invoice_revision_paid rp
+		JOIN invoice_revisions ap ON ap.id = rp.revision_id
+		WHERE rp.invoice_id = ?
 			AND ap.revision_no <= ?
 	`, invoiceID, revisionNo).Scan(&existing); err != nil {
 		return 0, fmt.Errorf("sum visible payments by revision: %w", err)
@@ -55,13 +53,16 @@ func sumPaymentsVisibleUpToRevision(
 	return existing, nil
 }
 
+// sumPaymentsByRevision returns what the revision has been paid net of refunds,
+// received deposits included.
 func sumPaymentsByRevision(ctx context.Context, tx *sql.Tx, revisionID int64) (int64, error) {
 	var existing int64
 	if err := tx.QueryRowContext(ctx, `
-		SELECT COALESCE(SUM(amount_minor), 0)
-		FROM payments
-		WHERE applied_in_revision_id = ?
-		  AND payment_type = 'payment'
+		SELECT COALESCE((
+			SELECT paid_minor
+			FROM invoice_revision_paid
+			WHERE revision_id = ?
+		), 0)
 	`, revisionID).Scan(&existing); err != nil {
 		return 0, fmt.Errorf("sum payments by revision: %w", err)
 	}
@@ -77,22 +78,22 @@ func syncInvoiceStatusForCurrentRevision(ctx context.Context, tx *sql.Tx, invoic
 	var (
 		status            string
 		currentRevisionID sql.NullInt64
-		totalMinor        sql.NullInt64
+		payableMinor      sql.NullInt64
 	)
 	if err := tx.QueryRowContext(ctx, `
 		SELECT
 			i.status,
 			i.current_revision_id,
-			r.total_minor
+			r.total_minor - r.deduction_minor
 		FROM invoices i
 		LEFT JOIN invoice_revisions r
 			ON r.id = i.current_revision_id
 		WHERE i.id = ?;
-	`, invoiceID).Scan(&status, &currentRevisionID, &totalMinor); err != nil {
+	`, invoiceID).Scan(&status, &currentRevisionID, &payableMinor); err != nil {
 		return fmt.Errorf("load invoice status sync state: %w", err)
 	}
 
-	if status == "void" || !currentRevisionID.Valid || !totalMinor.Valid {
+	if status == "void" || !currentRevisionID.Valid || !payableMinor.Valid {
 		return nil
 	}
 
@@ -100,9 +101,14 @@ func syncInvoiceStatusForCurrentRevision(ctx context.Context, tx *sql.Tx, invoic
 	if err != nil {
 		return err
 	}
+	creditedMinor, err := sumCreditNotesByInvoice(ctx, tx, invoiceID)
+	if err != nil {
+		return err
+	}
+	settledMinor := paidMinor + creditedMinor
 
 	switch {
-	case status == "issued" && paidMinor >= totalMinor.Int64:
+	case status == "issued" && settledMinor >= payableMinor.Int64:
 		if _, err := tx.ExecContext(ctx, `
 			UPDATE invoices
 			SET status = 'paid'
@@ -110,7 +116,7 @@ func syncInvoiceStatusForCurrentRevision(ctx context.Context, tx *sql.Tx, invoic
 		`, invoiceID); err != nil {
 			return fmt.Errorf("set invoice paid: %w", err)
 		}
-	case status == "paid" && paidMinor < totalMinor.Int64:
+	case status == "paid" && settledMinor < payableMinor.Int64:
 		if _, err := tx.ExecContext(ctx, `
 			UPDATE invoices
 			SET status = 'issued'
@@ -0,0 +1,350 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+var (
+	// ErrRefundExceedsReceipt is returned when refunds would exceed the amount of the receipt they reverse.
+	ErrRefundExceedsReceipt = errors.New("refunds cannot exceed the payment receipt amount")
+	// ErrRefundBeforePayment is returned when a refund is dated before the payment it reverses.
+	ErrRefundBeforePayment = errors.New("refund date cannot be before the payment date")
+	// ErrPaymentReceiptRefunded is returned when deleting a receipt that already has refunds recorded.
+	ErrPaymentReceiptRefunded = errors.New("payment receipt has refunds; it can no longer be deleted")
+	ErrRefundNotFound         = errors.New("refund not found")
+)
+
+// RefundRow is a DB/query row for one refund and the receipt it reverses.
+type RefundRow struct {
+	ID                int64
+	InvoiceID         int64
+	BaseNumber        int64
+	RefundNo          int64
+	PaymentID         int64
+	ReceiptNo         int64
+	RefundDate        string
+	AmountMinor       int64
+	Reason            sql.NullString
+	AppliedRevisionID int64
+	AppliedRevisionNo int64
+}
+
+// CreateRefund records a partial or full refund of one payment receipt.
+//
+// Refunds are numbered per invoice and snapshotted with receipts, so the
+// refund lowers paid totals of the revision it was recorded on and every
+// revision saved after it. The invoice status is re-synced afterwards.
+func CreateRefund(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	baseNumber int64,
+	revisionNo int64,
+	receiptNo int64,
+	canonical *models.RefundCreateIn,
+) (invoiceID, refundID, refundNo int64, err error) {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadPaymentReceiptState(ctx, tx, clientID, baseNumber, revisionNo)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := assertReceiptMutationAllowed(state.InvoiceStatus); err != nil {
+		return 0, 0, 0, err
+	}
+	if err := assertIfMatch(ctx, tx, state.InvoiceID); err != nil {
+		return 0, 0, 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, state.InvoiceID)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	var (
+		paymentID     int64
+		paymentDate   string
+		paymentMinor  int64
+		refundedMinor int64
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			p.id,
+			p.payment_date,
+			p.amount_minor,
+			COALESCE((
+				SELECT SUM(rf.amount_minor)
+				FROM payment_refunds rf
+				WHERE rf.payment_id = p.id
+			), 0)
+		FROM payments p
+		WHERE p.applied_in_revision_id = ?
+		  AND p.receipt_no = ?
+		  AND p.payment_type = 'payment';
+	`, state.RevisionID, receiptNo).Scan(&paymentID, &paymentDate, &paymentMinor, &refundedMinor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, 0, ErrPaymentReceiptNotFound
+	}
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("load refunded payment receipt: %w", err)
+	}
+	if canonical.RefundDate < paymentDate {
+		return 0, 0, 0, ErrRefundBeforePayment
+	}
+	if refundedMinor+canonical.AmountMinor > paymentMinor {
+		return 0, 0, 0, ErrRefundExceedsReceipt
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(refund_no), 0) + 1
+		FROM payment_refunds
+		WHERE invoice_id = ?;
+	`, state.InvoiceID).Scan(&refundNo); err != nil {
+		return 0, 0, 0, fmt.Errorf("next refund number: %w", err)
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO payment_refunds (
+			invoice_id,
+			payment_id,
+			applied_in_revision_id,
+			refund_no,
+			amount_minor,
+			refund_date,
+			reason
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`, state.InvoiceID, paymentID, state.RevisionID, refundNo, canonical.AmountMinor, canonical.RefundDate,
+		normalizedOptionalString(canonical.Reason),
+	).Scan(&refundID); err != nil {
+		return 0, 0, 0, fmt.Errorf("insert refund: %w", err)
+	}
+
+	if err := syncInvoiceStatusForCurrentRevision(ctx, tx, state.InvoiceID); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := finishInvoiceMutation(ctx, tx, state.InvoiceID, AuditRefundCreated, refundID, beforeHash); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commit refund: %w", err)
+	}
+
+	return state.InvoiceID, refundID, refundNo, nil
+}
+
+// ListRefunds returns every refund recorded against an invoice, oldest first.
+// A refund carried into later revisions is reported from its latest snapshot.
+func ListRefunds(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+) ([]RefundRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			id,
+			invoice_id,
+			base_number,
+			refund_no,
+			payment_id,
+			receipt_no,
+			refund_date,
+			amount_minor,
+			reason,
+			applied_in_revision_id,
+			revision_no
+		FROM (
+			`+refundSelectSQL+`,
+				ROW_NUMBER() OVER (PARTITION BY rf.refund_no ORDER BY r.revision_no DESC) AS snapshot_rank
+			`+refundFromSQL+`
+			WHERE i.account_id = ?
+			  AND i.client_id = ?
+			  AND i.base_number = ?
+		)
+		WHERE snapshot_rank = 1
+		ORDER BY refund_no ASC;
+	`, accountID, clientID, baseNumber)
+	if err != nil {
+		return nil, fmt.Errorf("query refunds: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]RefundRow, 0)
+	for rows.Next() {
+		refund, err := scanRefund(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, refund)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate refunds: %w", err)
+	}
+
+	return out, nil
+}
+
+// QueryRefundByNumber returns one refund from the latest revision that carries it.
+func QueryRefundByNumber(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+	refundNo int64,
+) (*RefundRow, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	row := db.QueryRowContext(ctx, refundSelectSQL+refundFromSQL+`
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?
+		  AND rf.refund_no = ?
+		ORDER BY r.revision_no DESC
+		LIMIT 1;
+	`, accountID, clientID, baseNumber, refundNo)
+
+	refund, err := scanRefund(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRefundNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &refund, nil
+}
+
+const refundSelectSQL = `
+	SELECT
+		rf.id,
+		i.id AS invoice_id,
+		i.base_number,
+		rf.refund_no,
+		rf.payment_id,
+		p.receipt_no,
+		rf.refund_date,
+		rf.amount_minor,
+		rf.reason,
+		rf.applied_in_revision_id,
+		r.revision_no
+`
+
+const refundFromSQL = `
+	FROM payment_refunds rf
+	JOIN invoices i
+		ON i.id = rf.invoice_id
+	JOIN payments p
+		ON p.id = rf.payment_id
+	JOIN invoice_revisions r
+		ON r.id = rf.applied_in_revision_id
+`
+
+func scanRefund(row rowScanner) (RefundRow, error) {
+	var out RefundRow
+	err := row.Scan(
+		&out.ID,
+		&out.InvoiceID,
+		&out.BaseNumber,
+		&out.RefundNo,
+		&out.PaymentID,
+		&out.ReceiptNo,
+		&out.RefundDate,
+		&out.AmountMinor,
+		&out.Reason,
+		&out.AppliedRevisionID,
+		&out.AppliedRevisionNo,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return RefundRow{}, err
+	}
+	if err != nil {
+		return RefundRow{}, fmt.Errorf("scan refund: %w", err)
+	}
+	return out, nil
+}
+
+func assertReceiptNotRefunded(ctx context.Context, tx *sql.Tx, revisionID, receiptNo int64) error {
+	var refunded bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1
+			FROM payment_refunds rf
+			JOIN payments p
+				ON p.id = rf.payment_id
+			WHERE p.applied_in_revision_id = ?
+			  AND p.receipt_no = ?
+			  AND p.payment_type = 'payment'
+		);
+	`, revisionID, receiptNo).Scan(&refunded); err != nil {
+		return fmt.Errorf("check payment receipt refunds: %w", err)
+	}
+	if refunded {
+		return ErrPaymentReceiptRefunded
+	}
+	return nil
+}
+
+// cloneRefundSnapshot carries refunds onto the receipts cloned into targetRevisionID.
+// It must run after cloneReceiptSnapshot so the target receipts exist.
+func cloneRefundSnapshot(
+	ctx context.Context,
+	tx *sql.Tx,
+	invoiceID int64,
+	sourceRevisionID int64,
+	targetRevisionID int64,
+) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO payment_refunds (
+			invoice_id,
+			payment_id,
+			applied_in_revision_id,
+			refund_no,
+			amount_minor,
+			refund_date,
+			reason
+		)
+		SELECT
+			?,
+			tp.id,
+			?,
+			rf.refund_no,
+			rf.amount_minor,
+			rf.refund_date,
+			rf.reason
+		FROM payment_refunds rf
+		JOIN payments sp
+			ON sp.id = rf.payment_id
+		JOIN payments tp
+			ON tp.applied_in_revision_id = ?
+		   AND tp.receipt_no = sp.receipt_no
+		   AND tp.payment_type = 'payment'
+		WHERE rf.applied_in_revision_id = ?
+		ORDER BY rf.refund_no ASC;
+	`, invoiceID, targetRevisionID, targetRevisionID, sourceRevisionID); err != nil {
+		return fmt.Errorf("clone refund snapshot: %w", err)
+	}
+
+	return nil
+}
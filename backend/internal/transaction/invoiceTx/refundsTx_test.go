@@ -0,0 +1,130 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreateRefund_ReducesPaidAndReopensInvoice(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	invoiceID := insertInvoiceGraph(t, a, clientID, 620, "issued")
+
+	_, _, receiptNo, err := invoiceTx.CreatePaymentReceipt(ctx, a, clientID, 620, 1, &models.PaymentReceiptCreateIn{
+		AmountMinor: 900,
+		PaymentDate: "2026-04-01",
+	})
+	if err != nil {
+		t.Fatalf("CreatePaymentReceipt: %v", err)
+	}
+	assertInvoiceStatus(t, a, invoiceID, "paid")
+
+	_, _, _, err = invoiceTx.CreateRefund(ctx, a, clientID, 620, 1, receiptNo, &models.RefundCreateIn{
+		AmountMinor: 100,
+		RefundDate:  "2026-03-31",
+	})
+	if !errors.Is(err, invoiceTx.ErrRefundBeforePayment) {
+		t.Fatalf("CreateRefund(before payment) error = %v, want %v", err, invoiceTx.ErrRefundBeforePayment)
+	}
+
+	reason := "Returned goods"
+	_, _, refundNo, err := invoiceTx.CreateRefund(ctx, a, clientID, 620, 1, receiptNo, &models.RefundCreateIn{
+		AmountMinor: 300,
+		RefundDate:  "2026-04-05",
+		Reason:      &reason,
+	})
+	if err != nil {
+		t.Fatalf("CreateRefund: %v", err)
+	}
+	if refundNo != 1 {
+		t.Fatalf("refundNo = %d, want 1", refundNo)
+	}
+	assertInvoiceStatus(t, a, invoiceID, "issued")
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 620, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary: %v", err)
+	}
+	if summary.PaidMinor != 700 {
+		t.Fatalf("PaidMinor = %d, want 700 after refund", summary.PaidMinor)
+	}
+
+	_, _, _, err = invoiceTx.CreateRefund(ctx, a, clientID, 620, 1, receiptNo, &models.RefundCreateIn{
+		AmountMinor: 601,
+		RefundDate:  "2026-04-06",
+	})
+	if !errors.Is(err, invoiceTx.ErrRefundExceedsReceipt) {
+		t.Fatalf("CreateRefund(over receipt) error = %v, want %v", err, invoiceTx.ErrRefundExceedsReceipt)
+	}
+
+	if _, err := invoiceTx.DeletePaymentReceipt(ctx, a, clientID, 620, 1, receiptNo); !errors.Is(err, invoiceTx.ErrPaymentReceiptRefunded) {
+		t.Fatalf("DeletePaymentReceipt error = %v, want %v", err, invoiceTx.ErrPaymentReceiptRefunded)
+	}
+
+	if _, _, _, err := invoiceTx.CreateRevision(ctx, a, draftUpdatePayload(clientID, 620, 1000, 700, "Revised")); err != nil {
+		t.Fatalf("CreateRevision: %v", err)
+	}
+
+	refunds, err := invoiceTx.ListRefunds(ctx, a.DB, clientID, 620)
+	if err != nil {
+		t.Fatalf("ListRefunds: %v", err)
+	}
+	if len(refunds) != 1 {
+		t.Fatalf("refunds = %d, want the snapshot reported once", len(refunds))
+	}
+	got := refunds[0]
+	if got.RefundNo != 1 || got.AppliedRevisionNo != 2 || got.ReceiptNo != receiptNo || got.AmountMinor != 300 || got.Reason.String != reason {
+		t.Fatalf("refund = %+v", got)
+	}
+
+	summary, err = invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 620, 2)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary(revision 2): %v", err)
+	}
+	if summary.PaidMinor != 700 {
+		t.Fatalf("revision 2 PaidMinor = %d, want refund carried over (700)", summary.PaidMinor)
+	}
+
+	_, _, refundNo, err = invoiceTx.CreateRefund(ctx, a, clientID, 620, 2, receiptNo, &models.RefundCreateIn{
+		AmountMinor: 600,
+		RefundDate:  "2026-04-08",
+	})
+	if err != nil {
+		t.Fatalf("CreateRefund(revision 2): %v", err)
+	}
+	if refundNo != 2 {
+		t.Fatalf("second refundNo = %d, want 2", refundNo)
+	}
+
+	refund, err := invoiceTx.QueryRefundByNumber(ctx, a.DB, clientID, 620, 2)
+	if err != nil {
+		t.Fatalf("QueryRefundByNumber: %v", err)
+	}
+	if refund.AmountMinor != 600 || refund.AppliedRevisionNo != 2 {
+		t.Fatalf("refund 2 = %+v", refund)
+	}
+	if _, err := invoiceTx.QueryRefundByNumber(ctx, a.DB, clientID, 620, 3); !errors.Is(err, invoiceTx.ErrRefundNotFound) {
+		t.Fatalf("QueryRefundByNumber(missing) error = %v, want %v", err, invoiceTx.ErrRefundNotFound)
+	}
+}
+
+func assertInvoiceStatus(t *testing.T, a *app.App, invoiceID int64, want string) {
+	t.Helper()
+
+	var status string
+	if err := a.DB.QueryRow(`SELECT status FROM invoices WHERE id = ?`, invoiceID).Scan(&status); err != nil {
+		t.Fatalf("load invoice status: %v", err)
+	}
+	if status != want {
+		t.Fatalf("invoice status = %q, want %q", status, want)
+	}
+}
@@ -0,0 +1,316 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+var ErrRevisionNotFound = errors.New("invoice revision not found")
+
+// Revision diff sections.
+const (
+	RevisionDiffHeader   = "header"
+	RevisionDiffClient   = "client"
+	RevisionDiffDiscount = "discount"
+	RevisionDiffDeposit  = "deposit"
+	RevisionDiffVAT      = "vat"
+	RevisionDiffTotals   = "totals"
+	RevisionDiffLine     = "line"
+
+	// RevisionDiffDeduction covers the withholding deducted by the client.
+	RevisionDiffDeduction = "deduction"
+)
+
+// Revision diff line changes.
+const (
+	RevisionLineAdded   = "added"
+	RevisionLineRemoved = "removed"
+	RevisionLineChanged = "changed"
+)
+
+// RevisionFieldChange is one field whose value differs between two revisions.
+// From and To hold nil for an empty optional field.
+type RevisionFieldChange struct {
+	Section string
+	Field   string
+	From    any
+	To      any
+}
+
+// RevisionLineChange is one invoice line added, removed or changed between
+// two revisions. FromSortOrder is nil for added lines and ToSortOrder for
+// removed ones.
+type RevisionLineChange struct {
+	Change        string
+	ProductID     *int64
+	Name          string
+	FromSortOrder *int64
+	ToSortOrder   *int64
+	Fields        []RevisionFieldChange
+}
+
+// RevisionDiff is what changed from one revision of an invoice to another.
+// Unchanged fields and lines are left out.
+type RevisionDiff struct {
+	FromRevisionNo int64
+	ToRevisionNo   int64
+	Fields         []RevisionFieldChange
+	Lines          []RevisionLineChange
+}
+
+// QueryRevisionDiff compares two saved revisions of one invoice.
+//
+// Lines are matched by product, in sort order, so reordering a product line
+// shows as a change rather than a remove and add. Custom lines without a
+// product are matched by sort order.
+func QueryRevisionDiff(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+	fromRevisionNo int64,
+	toRevisionNo int64,
+) (*RevisionDiff, error) {
+	from, fromLines, err := queryRevisionForDiff(ctx, db, clientID, baseNumber, fromRevisionNo)
+	if err != nil {
+		return nil, err
+	}
+	to, toLines, err := queryRevisionForDiff(ctx, db, clientID, baseNumber, toRevisionNo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RevisionDiff{
+		FromRevisionNo: fromRevisionNo,
+		ToRevisionNo:   toRevisionNo,
+		Fields:         diffRevisionFields(from, to),
+		Lines:          diffRevisionLines(fromLines, toLines),
+	}, nil
+}
+
+func queryRevisionForDiff(
+	ctx context.Context,
+	db *sql.DB,
+	clientID int64,
+	baseNumber int64,
+	revisionNo int64,
+) (*InvoiceOverviewTotals, []ItemLine, error) {
+	summary, err := QueryInvoiceSummary(ctx, db, clientID, baseNumber, revisionNo)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("load revision %d for diff: %w", revisionNo, err)
+	}
+
+	lines, err := QueryInvoiceLines(ctx, db, clientID, baseNumber, revisionNo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load revision %d lines for diff: %w", revisionNo, err)
+	}
+
+	return summary, lines, nil
+}
+
+type revisionFieldDiffer struct {
+	changes []RevisionFieldChange
+}
+
+func (d *revisionFieldDiffer) add(section, field string, from, to any) {
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+	d.changes = append(d.changes, RevisionFieldChange{
+		Section: section,
+		Field:   field,
+		From:    from,
+		To:      to,
+	})
+}
+
+func diffRevisionFields(from, to *InvoiceOverviewTotals) []RevisionFieldChange {
+	var d revisionFieldDiffer
+
+	d.add(RevisionDiffHeader, "issueDate", from.IssueDate, to.IssueDate)
+	d.add(RevisionDiffHeader, "supplyDate", diffNullString(from.SupplyDate), diffNullString(to.SupplyDate))
+	d.add(RevisionDiffHeader, "dueByDate", diffNullString(from.DueByDate), diffNullString(to.DueByDate))
+	d.add(RevisionDiffHeader, "note", diffNullString(from.Note), diffNullString(to.Note))
+	d.add(RevisionDiffHeader, "currency", from.Currency, to.Currency)
+	d.add(RevisionDiffHeader, "exchangeRateMicro", from.ExchangeRateMicro, to.ExchangeRateMicro)
+
+	d.add(RevisionDiffClient, "clientName", from.ClientName, to.ClientName)
+	d.add(RevisionDiffClient, "clientCompanyName", from.ClientCompanyName, to.ClientCompanyName)
+	d.add(RevisionDiffClient, "clientAddress", from.ClientAddress, to.ClientAddress)
+	d.add(RevisionDiffClient, "clientEmail", from.ClientEmail, to.ClientEmail)
+	d.add(RevisionDiffClient, "clientVatNumber", from.ClientVATNumber, to.ClientVATNumber)
+
+	d.add(RevisionDiffDiscount, "discountType", from.DiscountType, to.DiscountType)
+	d.add(RevisionDiffDiscount, "discountRate", from.DiscountRate, to.DiscountRate)
+	d.add(RevisionDiffDiscount, "discountMinor", from.DiscountMinor, to.DiscountMinor)
+
+	d.add(RevisionDiffDeposit, "depositType", from.DepositType, to.DepositType)
+	d.add(RevisionDiffDeposit, "depositRate", from.DepositRate, to.DepositRate)
+	d.add(RevisionDiffDeposit, "depositMinor", from.DepositMinor, to.DepositMinor)
+
+	d.add(RevisionDiffDeduction, "deductionRate", from.DeductionRate, to.DeductionRate)
+	d.add(RevisionDiffDeduction, "deductionLineTypes", from.DeductionLineTypes, to.DeductionLineTypes)
+	d.add(RevisionDiffDeduction, "deductionMinor", from.DeductionMinor, to.DeductionMinor)
+
+	d.add(RevisionDiffVAT, "vatTreatment", from.VATTreatment, to.VATTreatment)
+	d.add(RevisionDiffVAT, "vatRate", from.VATRate, to.VATRate)
+	d.add(RevisionDiffVAT, "taxRateName", from.TaxRateName, to.TaxRateName)
+	d.add(RevisionDiffVAT, "vatAmountMinor", from.VATAmountMin, to.VATAmountMin)
+	d.add(RevisionDiffVAT, "vatBreakdown", from.VATBreakdown, to.VATBreakdown)
+
+	d.add(RevisionDiffTotals, "subtotalMinor", from.SubtotalMinor, to.SubtotalMinor)
+	d.add(RevisionDiffTotals, "totalMinor", from.TotalMinor, to.TotalMinor)
+
+	return d.changes
+}
+
+func diffRevisionLines(fromLines, toLines []ItemLine) []RevisionLineChange {
+	matchedFrom := make([]bool, len(fromLines))
+	matchedTo := make([]bool, len(toLines))
+	pairs := make([][2]int, 0, len(toLines))
+
+	// Product lines pair up by product, nth occurrence to nth occurrence.
+	byProduct := make(map[int64][]int)
+	for i, line := range fromLines {
+		if line.ProductID != nil {
+			byProduct[*line.ProductID] = append(byProduct[*line.ProductID], i)
+		}
+	}
+	for j, line := range toLines {
+		if line.ProductID == nil {
+			continue
+		}
+		queue := byProduct[*line.ProductID]
+		if len(queue) == 0 {
+			continue
+		}
+		i := queue[0]
+		byProduct[*line.ProductID] = queue[1:]
+		matchedFrom[i], matchedTo[j] = true, true
+		pairs = append(pairs, [2]int{i, j})
+	}
+
+	// Custom lines pair up by sort order.
+	bySortOrder := make(map[int64]int)
+	for i, line := range fromLines {
+		if line.ProductID == nil {
+			bySortOrder[line.SortOrder] = i
+		}
+	}
+	for j, line := range toLines {
+		if line.ProductID != nil {
+			continue
+		}
+		i, ok := bySortOrder[line.SortOrder]
+		if !ok {
+			continue
+		}
+		delete(bySortOrder, line.SortOrder)
+		matchedFrom[i], matchedTo[j] = true, true
+		pairs = append(pairs, [2]int{i, j})
+	}
+
+	out := make([]RevisionLineChange, 0)
+	for _, pair := range pairs {
+		from, to := fromLines[pair[0]], toLines[pair[1]]
+		fields := diffRevisionLineFields(from, to)
+		if len(fields) == 0 {
+			continue
+		}
+		out = append(out, RevisionLineChange{
+			Change:        RevisionLineChanged,
+			ProductID:     to.ProductID,
+			Name:          to.Name,
+			FromSortOrder: &from.SortOrder,
+			ToSortOrder:   &to.SortOrder,
+			Fields:        fields,
+		})
+	}
+	for i := range fromLines {
+		if matchedFrom[i] {
+			continue
+		}
+		line := fromLines[i]
+		out = append(out, RevisionLineChange{
+			Change:        RevisionLineRemoved,
+			ProductID:     line.ProductID,
+			Name:          line.Name,
+			FromSortOrder: &line.SortOrder,
+		})
+	}
+	for j := range toLines {
+		if matchedTo[j] {
+			continue
+		}
+		line := toLines[j]
+		out = append(out, RevisionLineChange{
+			Change:      RevisionLineAdded,
+			ProductID:   line.ProductID,
+			Name:        line.Name,
+			ToSortOrder: &line.SortOrder,
+		})
+	}
+
+	sort.SliceStable(out, func(a, b int) bool {
+		return revisionLineChangeOrder(out[a]) < revisionLineChangeOrder(out[b])
+	})
+
+	return out
+}
+
+// revisionLineChangeOrder sorts changes as they appear in the newer revision,
+// with removed lines at their old position.
+func revisionLineChangeOrder(change RevisionLineChange) int64 {
+	if change.ToSortOrder != nil {
+		return *change.ToSortOrder
+	}
+	return *change.FromSortOrder
+}
+
+func diffRevisionLineFields(from, to ItemLine) []RevisionFieldChange {
+	var d revisionFieldDiffer
+
+	d.add(RevisionDiffLine, "sortOrder", from.SortOrder, to.SortOrder)
+	d.add(RevisionDiffLine, "name", from.Name, to.Name)
+	d.add(RevisionDiffLine, "lineType", from.LineType, to.LineType)
+	d.add(RevisionDiffLine, "pricingMode", diffStringPtr(from.PricingMode), diffStringPtr(to.PricingMode))
+	d.add(RevisionDiffLine, "quantity", from.Quantity, to.Quantity)
+	d.add(RevisionDiffLine, "unit", from.Unit, to.Unit)
+	d.add(RevisionDiffLine, "minutesWorked", diffInt64Ptr(from.MinutesWorked), diffInt64Ptr(to.MinutesWorked))
+	d.add(RevisionDiffLine, "unitPriceMinor", from.UnitPriceMin, to.UnitPriceMin)
+	d.add(RevisionDiffLine, "lineTotalMinor", from.LineTotalMin, to.LineTotalMin)
+	d.add(RevisionDiffLine, "vatRate", diffInt64Ptr(from.VATRate), diffInt64Ptr(to.VATRate))
+	d.add(RevisionDiffLine, "discountType", from.DiscountType, to.DiscountType)
+	d.add(RevisionDiffLine, "discountRate", from.DiscountRate, to.DiscountRate)
+	d.add(RevisionDiffLine, "discountMinor", from.DiscountMinor, to.DiscountMinor)
+
+	return d.changes
+}
+
+func diffNullString(v sql.NullString) any {
+	if !v.Valid {
+		return nil
+	}
+	return v.String
+}
+
+func diffStringPtr(v *string) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func diffInt64Ptr(v *int64) any {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
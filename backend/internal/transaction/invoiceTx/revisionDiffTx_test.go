@@ -0,0 +1,182 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestQueryRevisionDiff_ComparesHeaderAndLines(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	invoiceID := insertInvoiceGraph(t, a, clientID, 710, "issued")
+
+	res, err := a.DB.Exec(`
+		INSERT INTO invoice_revisions (
+			invoice_id, revision_no, issue_date, due_by_date,
+			client_name, client_company_name, client_address, client_email, note,
+			vat_rate, discount_type, discount_rate, discount_minor,
+			deposit_type, deposit_rate, deposit_minor,
+			subtotal_minor, vat_amount_minor, total_minor
+		)
+		SELECT
+			invoice_id, 2, issue_date, '2026-04-24',
+			'Renamed Client', client_company_name, client_address, client_email, 'Second pass',
+			vat_rate, discount_type, discount_rate, discount_minor,
+			deposit_type, deposit_rate, deposit_minor,
+			1500, vat_amount_minor, 1500
+		FROM invoice_revisions
+		WHERE invoice_id = ? AND revision_no = 1
+	`, invoiceID)
+	if err != nil {
+		t.Fatalf("insert revision 2: %v", err)
+	}
+	revisionID, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("revision lastInsertId: %v", err)
+	}
+	if _, err := a.DB.Exec(`
+		INSERT INTO invoice_items (
+			invoice_revision_id, name, line_type, pricing_mode,
+			quantity, unit_price_minor, line_total_minor, minutes_worked, sort_order
+		) VALUES
+			(?, 'Service line', 'custom', 'flat', 1, 1200, 1200, NULL, 1),
+			(?, 'Call-out fee', 'custom', 'flat', 1, 300, 300, NULL, 2)
+	`, revisionID, revisionID); err != nil {
+		t.Fatalf("insert revision 2 items: %v", err)
+	}
+
+	diff, err := invoiceTx.QueryRevisionDiff(ctx, a.DB, clientID, 710, 1, 2)
+	if err != nil {
+		t.Fatalf("QueryRevisionDiff: %v", err)
+	}
+
+	changed := make(map[string]invoiceTx.RevisionFieldChange)
+	for _, field := range diff.Fields {
+		changed[field.Field] = field
+	}
+	if len(changed) != 6 {
+		t.Fatalf("changed fields = %+v, want dueByDate, note, clientName, vatBreakdown, subtotalMinor and totalMinor", diff.Fields)
+	}
+	if got := changed["note"]; got.Section != invoiceTx.RevisionDiffHeader || got.From != nil || got.To != "Second pass" {
+		t.Fatalf("note change = %+v", got)
+	}
+	if got := changed["totalMinor"]; got.From != int64(1000) || got.To != int64(1500) {
+		t.Fatalf("totalMinor change = %+v", got)
+	}
+
+	if len(diff.Lines) != 2 {
+		t.Fatalf("line changes = %+v, want one changed and one added", diff.Lines)
+	}
+	if got := diff.Lines[0]; got.Change != invoiceTx.RevisionLineChanged || len(got.Fields) != 2 {
+		t.Fatalf("first line change = %+v, want price and total changed", got)
+	}
+	if got := diff.Lines[1]; got.Change != invoiceTx.RevisionLineAdded || got.Name != "Call-out fee" {
+		t.Fatalf("second line change = %+v, want added call-out fee", got)
+	}
+
+	history, err := invoiceTx.QueryInvoiceHistory(ctx, a.DB, clientID, 710, invoiceTx.InvoiceHistoryFilters{IncludeRevisionDiffs: true})
+	if err != nil {
+		t.Fatalf("QueryInvoiceHistory: %v", err)
+	}
+	var diffs int
+	for _, entry := range history {
+		if entry.Diff != nil {
+			diffs++
+			if entry.RevisionNo.Int64 != 2 || len(entry.Diff.Lines) != 2 {
+				t.Fatalf("history diff on %+v", entry)
+			}
+		}
+	}
+	if diffs != 1 {
+		t.Fatalf("history diffs = %d, want 1", diffs)
+	}
+
+	if _, err := invoiceTx.QueryRevisionDiff(ctx, a.DB, clientID, 710, 1, 3); !errors.Is(err, invoiceTx.ErrRevisionNotFound) {
+		t.Fatalf("QueryRevisionDiff(missing) error = %v, want %v", err, invoiceTx.ErrRevisionNotFound)
+	}
+}
+
+func TestQueryRevisionDiff_ComparesRevisionSettings(t *testing.T) {
+	tests := []struct {
+		name       string
+		edit       string
+		wantFields []string
+	}{
+		{
+			name:       "currency",
+			edit:       `UPDATE invoice_revisions SET currency = 'EUR', exchange_rate_micro = 850000 WHERE id = ?`,
+			wantFields: []string{"currency", "exchangeRateMicro"},
+		},
+		{
+			name:       "deduction",
+			edit:       `UPDATE invoice_revisions SET deduction_rate = 2000, deduction_line_types = 'labour', deduction_minor = 200 WHERE id = ?`,
+			wantFields: []string{"deductionRate", "deductionLineTypes", "deductionMinor"},
+		},
+		{
+			name:       "tax rate name",
+			edit:       `UPDATE invoice_revisions SET tax_rate_name = 'Standard 20%' WHERE id = ?`,
+			wantFields: []string{"taxRateName"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+			a, cleanup := newTestApp(t)
+			defer cleanup()
+
+			clientID := insertClient(t, a)
+			invoiceID := insertInvoiceGraph(t, a, clientID, 720, "issued")
+
+			res, err := a.DB.Exec(`
+				INSERT INTO invoice_revisions (
+					invoice_id, revision_no, issue_date, due_by_date,
+					client_name, client_company_name, client_address, client_email, note,
+					vat_rate, discount_type, discount_rate, discount_minor,
+					deposit_type, deposit_rate, deposit_minor,
+					subtotal_minor, vat_amount_minor, total_minor
+				)
+				SELECT
+					invoice_id, 2, issue_date, due_by_date,
+					client_name, client_company_name, client_address, client_email, note,
+					vat_rate, discount_type, discount_rate, discount_minor,
+					deposit_type, deposit_rate, deposit_minor,
+					subtotal_minor, vat_amount_minor, total_minor
+				FROM invoice_revisions
+				WHERE invoice_id = ? AND revision_no = 1
+			`, invoiceID)
+			if err != nil {
+				t.Fatalf("insert revision 2: %v", err)
+			}
+			revisionID, err := res.LastInsertId()
+			if err != nil {
+				t.Fatalf("revision lastInsertId: %v", err)
+			}
+			if _, err := a.DB.Exec(tt.edit, revisionID); err != nil {
+				t.Fatalf("edit revision 2: %v", err)
+			}
+
+			diff, err := invoiceTx.QueryRevisionDiff(ctx, a.DB, clientID, 720, 1, 2)
+			if err != nil {
+				t.Fatalf("QueryRevisionDiff: %v", err)
+			}
+
+			changed := make(map[string]bool)
+			for _, field := range diff.Fields {
+				changed[field.Field] = true
+			}
+			for _, field := range tt.wantFields {
+				if !changed[field] {
+					t.Fatalf("changed fields = %+v, want %s", diff.Fields, field)
+				}
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/viktorHadz/goInvoice26/internal/models"
 )
@@ -33,24 +34,41 @@ func insertRevisionWithItems(
 		note = *ov.Note
 	}
 
+	currency, exchangeRate, err := resolveRevisionCurrency(ctx, tx, invoiceID, ov)
+	if err != nil {
+		return 0, err
+	}
+	vatTreatment, clientVATNumber, err := resolveRevisionVAT(ctx, tx, invoiceID, ov)
+	if err != nil {
+		return 0, err
+	}
+	taxRateID, taxRateName, err := resolveRevisionTaxRate(ctx, tx, ov.IssueDate, tot)
+	if err != nil {
+		return 0, err
+	}
+
 	if err := tx.QueryRowContext(ctx, `
 		INSERT INTO invoice_revisions (
 			invoice_id, revision_no,
 			issue_date, supply_date, due_by_date,
-			client_name, client_company_name, client_address, client_email, note,
-			vat_rate,
+			client_name, client_company_name, client_address, client_email, client_vat_number, note,
+			currency, exchange_rate_micro,
+			vat_treatment, vat_rate, tax_rate_id, tax_rate_name,
 			discount_type, discount_rate, discount_minor,
 			deposit_type, deposit_rate, deposit_minor,
+			deduction_rate, deduction_line_types, deduction_minor,
 			subtotal_minor, vat_amount_minor, total_minor
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		RETURNING id;
 	`,
 		invoiceID, revisionNo,
 		ov.IssueDate, supplyDate, dueBy,
-		ov.ClientName, ov.ClientCompanyName, ov.ClientAddress, ov.ClientEmail, note,
-		tot.VATRate,
+		ov.ClientName, ov.ClientCompanyName, ov.ClientAddress, ov.ClientEmail, clientVATNumber, note,
+		currency, exchangeRate,
+		vatTreatment, tot.VATRate, taxRateID, taxRateName,
 		tot.DiscountType, tot.DiscountRate, tot.DiscountMinor,
 		tot.DepositType, tot.DepositRate, tot.DepositMinor,
+		tot.DeductionRate, strings.Join(tot.DeductionLineTypes, ","), tot.DeductionMinor,
 		tot.SubtotalMinor, tot.VatAmountMinor, tot.TotalMinor,
 	).Scan(&revisionID); err != nil {
 		return 0, fmt.Errorf("insert invoice_revision: %w", err)
@@ -60,6 +78,10 @@ func insertRevisionWithItems(
 		return 0, err
 	}
 
+	if err := replaceRevisionVATBreakdown(ctx, tx, revisionID, canonical.Totals); err != nil {
+		return 0, err
+	}
+
 	if _, err := tx.ExecContext(ctx, `
 		UPDATE invoices
 		SET current_revision_id = ?
@@ -80,8 +102,9 @@ func insertRevisionItems(
 	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO invoice_items (
 			invoice_revision_id, product_id, name, line_type, pricing_mode,
-			quantity, unit_price_minor, line_total_minor, minutes_worked, sort_order
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			quantity, quantity_milli, unit, unit_price_minor, line_total_minor, minutes_worked, sort_order,
+			vat_rate, discount_type, discount_rate, discount_minor
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return fmt.Errorf("prepare invoice_items: %w", err)
@@ -99,17 +122,33 @@ func insertRevisionItems(
 			minutesWorked = *ln.MinutesWorked
 		}
 
+		var vatRate interface{}
+		if ln.VATRate != nil {
+			vatRate = *ln.VATRate
+		}
+
+		discountType := ln.DiscountType
+		if discountType == "" {
+			discountType = "none"
+		}
+
 		_, err := stmt.ExecContext(ctx,
 			revisionID,
 			productID,
 			ln.Name,
 			ln.LineType,
 			ln.PricingMode,
+			ln.Quantity.WholeUnitsCeil(),
 			ln.Quantity,
+			ln.Unit,
 			ln.UnitPriceMinor,
 			ln.LineTotalMinor,
 			minutesWorked,
 			ln.SortOrder,
+			vatRate,
+			discountType,
+			ln.DiscountRate,
+			ln.DiscountMinor,
 		)
 		if err != nil {
 			return fmt.Errorf("insert invoice_item: %w", err)
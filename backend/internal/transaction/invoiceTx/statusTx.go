@@ -0,0 +1,105 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/viktorHadz/goInvoice26/internal/app"
+)
+
+// UpdateInvoiceStatus sets invoices.status. Callers check the transition is
+// allowed first; this only records it. Issuing a draft also snapshots the
+// name of its registry tax rate onto the current revision.
+func UpdateInvoiceStatus(ctx context.Context, a *app.App, clientID, baseNumber int64, status string) error {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	invoiceID, current, err := LoadInvoiceIDAndStatus(ctx, tx, clientID, baseNumber)
+	if err != nil {
+		return err
+	}
+	if err := UpdateInvoiceStatusInTx(ctx, tx, invoiceID, current, status); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit invoice status: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateInvoiceStatusInTx moves invoiceID from current to status inside tx,
+// for callers that create and issue an invoice in one transaction. Like
+// [UpdateInvoiceStatus] it snapshots the tax rate on issue, bumps the ETag and
+// writes the audit entry.
+func UpdateInvoiceStatusInTx(ctx context.Context, tx *sql.Tx, invoiceID int64, current, status string) error {
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	if current == "draft" && status == "issued" {
+		if err := snapshotIssuedTaxRate(ctx, tx, invoiceID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoices
+		SET status = ?
+		WHERE id = ?;
+	`, status, invoiceID); err != nil {
+		return fmt.Errorf("update invoice status: %w", err)
+	}
+
+	return finishInvoiceMutation(ctx, tx, invoiceID, AuditStatusChanged, 0, beforeHash)
+}
+
+// VoidInvoice moves an invoice to void, recording why, when and by whom.
+// Like UpdateInvoiceStatus, callers check the transition is allowed first.
+func VoidInvoice(ctx context.Context, a *app.App, clientID, baseNumber int64, reason string) error {
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	invoiceID, _, err := LoadInvoiceIDAndStatus(ctx, tx, clientID, baseNumber)
+	if err != nil {
+		return err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, invoiceID)
+	if err != nil {
+		return err
+	}
+
+	userID, email := auditActor(ctx)
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoices
+		SET status = 'void',
+			void_reason = ?,
+			voided_at = strftime('%Y-%m-%dT%H:%M:%fZ','now'),
+			voided_by_user_id = ?,
+			voided_by_email = ?
+		WHERE id = ?;
+	`, strings.TrimSpace(reason), userID, email, invoiceID); err != nil {
+		return fmt.Errorf("void invoice: %w", err)
+	}
+
+	if err := finishInvoiceMutation(ctx, tx, invoiceID, AuditStatusChanged, 0, beforeHash); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit invoice void: %w", err)
+	}
+
+	return nil
+}
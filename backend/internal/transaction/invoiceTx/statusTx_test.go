@@ -0,0 +1,57 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/userscope"
+)
+
+func TestVoidInvoice_RecordsReasonAndActorInHistory(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	ctx = userscope.WithPrincipal(ctx, userscope.Principal{
+		UserID:    42,
+		AccountID: accountscope.DefaultAccountID,
+		Email:     "owner@example.com",
+		Role:      "owner",
+	})
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	insertInvoiceGraph(t, a, clientID, 940, "issued")
+
+	if err := invoiceTx.VoidInvoice(ctx, a, clientID, 940, "  Raised against the wrong client  "); err != nil {
+		t.Fatalf("VoidInvoice: %v", err)
+	}
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 940, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary: %v", err)
+	}
+	if summary.Status != "void" || summary.VoidReason.String != "Raised against the wrong client" || !summary.VoidedAt.Valid {
+		t.Fatalf("summary = status %q reason %+v voidedAt %+v", summary.Status, summary.VoidReason, summary.VoidedAt)
+	}
+
+	history, err := invoiceTx.QueryInvoiceHistory(ctx, a.DB, clientID, 940, invoiceTx.InvoiceHistoryFilters{})
+	if err != nil {
+		t.Fatalf("QueryInvoiceHistory: %v", err)
+	}
+	var voidEntry *invoiceTx.InvoiceHistoryRow
+	for i := range history {
+		if history[i].Type == "void" {
+			voidEntry = &history[i]
+		}
+	}
+	if voidEntry == nil {
+		t.Fatalf("history = %+v, want a void entry", history)
+	}
+	if voidEntry.Label.String != "Raised against the wrong client" || voidEntry.VoidedBy.String != "owner@example.com" {
+		t.Fatalf("void entry = %+v", *voidEntry)
+	}
+	if voidEntry.CreatedAt != summary.VoidedAt.String {
+		t.Fatalf("void entry created at %q, want voided at %q", voidEntry.CreatedAt, summary.VoidedAt.String)
+	}
+}
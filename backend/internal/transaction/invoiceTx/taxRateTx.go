@@ -0,0 +1,99 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+)
+
+var (
+	// ErrTaxRateNotEffective is returned when the referenced tax rate does not
+	// cover the invoice issue date.
+	ErrTaxRateNotEffective = errors.New("tax rate is not in effect on the issue date")
+	// ErrTaxRateMismatch is returned when the invoice VAT rate differs from the
+	// referenced tax rate.
+	ErrTaxRateMismatch = errors.New("VAT rate does not match the referenced tax rate")
+)
+
+// resolveRevisionTaxRate checks the registry rate a revision references and
+// returns the link and name to store on it. Without a reference the revision
+// keeps its raw VAT rate and an empty name. A missing rate returns
+// [settingsTx.ErrTaxRateNotFound].
+func resolveRevisionTaxRate(ctx context.Context, tx *sql.Tx, issueDate string, tot *models.TotalsCreateIn) (sql.NullInt64, string, error) {
+	if tot.TaxRateID == nil {
+		return sql.NullInt64{}, "", nil
+	}
+
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return sql.NullInt64{}, "", err
+	}
+
+	rate, err := settingsTx.GetTaxRate(ctx, tx, accountID, *tot.TaxRateID)
+	if err != nil {
+		return sql.NullInt64{}, "", err
+	}
+	if !settingsTx.TaxRateEffectiveOn(rate, issueDate) {
+		return sql.NullInt64{}, "", ErrTaxRateNotEffective
+	}
+	if rate.RateBps != tot.VATRate {
+		return sql.NullInt64{}, "", ErrTaxRateMismatch
+	}
+
+	return sql.NullInt64{Int64: rate.ID, Valid: true}, rate.Name, nil
+}
+
+// snapshotIssuedTaxRate re-reads the registry rate on the current revision of
+// invoiceID as the draft is issued, so the revision carries the rate's name at
+// the time of issue. A rate that has since changed percentage, or no longer
+// covers the issue date, blocks issuing rather than leave totals out of step
+// with the rate beside them. A deleted rate keeps the draft's snapshot.
+func snapshotIssuedTaxRate(ctx context.Context, tx *sql.Tx, invoiceID int64) error {
+	var (
+		accountID  int64
+		revisionID int64
+		taxRateID  sql.NullInt64
+		vatRate    int64
+		issueDate  string
+	)
+	if err := tx.QueryRowContext(ctx, `
+		SELECT i.account_id, r.id, r.tax_rate_id, r.vat_rate, r.issue_date
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.id = i.current_revision_id
+		WHERE i.id = ?;
+	`, invoiceID).Scan(&accountID, &revisionID, &taxRateID, &vatRate, &issueDate); err != nil {
+		return fmt.Errorf("load revision tax rate: %w", err)
+	}
+	if !taxRateID.Valid {
+		return nil
+	}
+
+	rate, err := settingsTx.GetTaxRate(ctx, tx, accountID, taxRateID.Int64)
+	if errors.Is(err, settingsTx.ErrTaxRateNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !settingsTx.TaxRateEffectiveOn(rate, issueDate) {
+		return ErrTaxRateNotEffective
+	}
+	if rate.RateBps != vatRate {
+		return ErrTaxRateMismatch
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE invoice_revisions
+		SET tax_rate_name = ?
+		WHERE id = ?;
+	`, rate.Name, revisionID); err != nil {
+		return fmt.Errorf("snapshot revision tax rate: %w", err)
+	}
+	return nil
+}
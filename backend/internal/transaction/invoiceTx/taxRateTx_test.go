@@ -0,0 +1,130 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+)
+
+func TestCreate_SnapshotsRegistryTaxRate(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	from := "2026-01-01"
+	rate, err := settingsTx.CreateTaxRate(ctx, a.DB, accountscope.DefaultAccountID, models.TaxRateIn{
+		Name: "Standard", RateBps: 2000, EffectiveFrom: &from,
+	})
+	if err != nil {
+		t.Fatalf("CreateTaxRate: %v", err)
+	}
+
+	mismatch := draftUpdatePayload(clientID, 1, 1000, 0, "Consulting")
+	mismatch.Totals.TaxRateID = &rate.ID
+	mismatch.Totals.VATRate = 500
+	if _, _, err := invoiceTx.Create(ctx, a, mismatch); !errors.Is(err, invoiceTx.ErrTaxRateMismatch) {
+		t.Fatalf("Create with other VAT rate error = %v, want %v", err, invoiceTx.ErrTaxRateMismatch)
+	}
+
+	early := draftUpdatePayload(clientID, 1, 1000, 0, "Consulting")
+	early.Overview.IssueDate = "2025-12-31"
+	early.Totals.TaxRateID = &rate.ID
+	early.Totals.VATRate = 2000
+	if _, _, err := invoiceTx.Create(ctx, a, early); !errors.Is(err, invoiceTx.ErrTaxRateNotEffective) {
+		t.Fatalf("Create before the rate starts error = %v, want %v", err, invoiceTx.ErrTaxRateNotEffective)
+	}
+
+	missingID := rate.ID + 100
+	missing := draftUpdatePayload(clientID, 1, 1000, 0, "Consulting")
+	missing.Totals.TaxRateID = &missingID
+	missing.Totals.VATRate = 2000
+	if _, _, err := invoiceTx.Create(ctx, a, missing); !errors.Is(err, settingsTx.ErrTaxRateNotFound) {
+		t.Fatalf("Create with unknown rate error = %v, want %v", err, settingsTx.ErrTaxRateNotFound)
+	}
+
+	inv := draftUpdatePayload(clientID, 1, 1000, 0, "Consulting")
+	inv.Totals.TaxRateID = &rate.ID
+	inv.Totals.VATRate = 2000
+	if _, _, err := invoiceTx.Create(ctx, a, inv); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Renaming the rate before issuing carries the new name onto the revision.
+	if _, err := settingsTx.UpdateTaxRate(ctx, a.DB, accountscope.DefaultAccountID, rate.ID, models.TaxRateIn{
+		Name: "Standard rate", RateBps: 2000, EffectiveFrom: &from,
+	}); err != nil {
+		t.Fatalf("UpdateTaxRate rename: %v", err)
+	}
+	if err := invoiceTx.UpdateInvoiceStatus(ctx, a, clientID, 1, "issued"); err != nil {
+		t.Fatalf("UpdateInvoiceStatus issued: %v", err)
+	}
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary: %v", err)
+	}
+	if !summary.TaxRateID.Valid || summary.TaxRateID.Int64 != rate.ID || summary.TaxRateName != "Standard rate" {
+		t.Fatalf("stored tax rate = %v %q, want %d %q", summary.TaxRateID, summary.TaxRateName, rate.ID, "Standard rate")
+	}
+
+	// Later changes to the registry leave the issued revision alone.
+	if _, err := settingsTx.UpdateTaxRate(ctx, a.DB, accountscope.DefaultAccountID, rate.ID, models.TaxRateIn{
+		Name: "Standard 2027", RateBps: 2200,
+	}); err != nil {
+		t.Fatalf("UpdateTaxRate rate change: %v", err)
+	}
+	summary, err = invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary after change: %v", err)
+	}
+	if summary.TaxRateName != "Standard rate" || summary.VATRate != 2000 {
+		t.Fatalf("issued revision tax rate = %q at %d, want the snapshot at issue", summary.TaxRateName, summary.VATRate)
+	}
+}
+
+func TestUpdateInvoiceStatus_BlocksIssueWhenTaxRateChanged(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+	rate, err := settingsTx.CreateTaxRate(ctx, a.DB, accountscope.DefaultAccountID, models.TaxRateIn{Name: "Standard", RateBps: 2000})
+	if err != nil {
+		t.Fatalf("CreateTaxRate: %v", err)
+	}
+
+	inv := draftUpdatePayload(clientID, 1, 1000, 0, "Consulting")
+	inv.Totals.TaxRateID = &rate.ID
+	inv.Totals.VATRate = 2000
+	if _, _, err := invoiceTx.Create(ctx, a, inv); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if _, err := settingsTx.UpdateTaxRate(ctx, a.DB, accountscope.DefaultAccountID, rate.ID, models.TaxRateIn{Name: "Standard", RateBps: 2100}); err != nil {
+		t.Fatalf("UpdateTaxRate: %v", err)
+	}
+	if err := invoiceTx.UpdateInvoiceStatus(ctx, a, clientID, 1, "issued"); !errors.Is(err, invoiceTx.ErrTaxRateMismatch) {
+		t.Fatalf("UpdateInvoiceStatus issued error = %v, want %v", err, invoiceTx.ErrTaxRateMismatch)
+	}
+
+	// Deleting the rate drops the link but keeps the draft's snapshot.
+	if err := settingsTx.DeleteTaxRate(ctx, a.DB, accountscope.DefaultAccountID, rate.ID); err != nil {
+		t.Fatalf("DeleteTaxRate: %v", err)
+	}
+	if err := invoiceTx.UpdateInvoiceStatus(ctx, a, clientID, 1, "issued"); err != nil {
+		t.Fatalf("UpdateInvoiceStatus after delete: %v", err)
+	}
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary: %v", err)
+	}
+	if summary.TaxRateID.Valid || summary.TaxRateName != "Standard" {
+		t.Fatalf("stored tax rate = %v %q, want no link and the saved name", summary.TaxRateID, summary.TaxRateName)
+	}
+}
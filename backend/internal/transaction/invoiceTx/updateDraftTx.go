@@ -5,7 +5,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
 	"github.com/viktorHadz/goInvoice26/internal/models"
 )
@@ -25,6 +27,10 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 	defer tx.Rollback()
 
 	ov := &canonical.Overview
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
 
 	invoiceID, status, err := LoadInvoiceIDAndStatus(ctx, tx, ov.ClientID, ov.BaseNumber)
 	if err != nil {
@@ -46,6 +52,14 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 		return 0, 0, fmt.Errorf("unexpected invoice status: %s", status)
 	}
 
+	if err := assertIfMatch(ctx, tx, invoiceID); err != nil {
+		return 0, 0, err
+	}
+	beforeHash, err := invoiceSnapshotHash(ctx, tx, invoiceID)
+	if err != nil {
+		return 0, 0, err
+	}
+
 	var revisionCount int64
 	if err := tx.QueryRowContext(ctx, `
 		SELECT
@@ -63,6 +77,10 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 		return 0, 0, ErrDraftInvoiceHasRevisions
 	}
 
+	if err := reallocateTemplateNumber(ctx, tx, accountID, invoiceID, ov.IssueDate, numberClientName(ov)); err != nil {
+		return 0, 0, err
+	}
+
 	existingPaid, err := sumPaymentsByInvoice(ctx, tx, invoiceID)
 	if err != nil {
 		return 0, 0, err
@@ -81,6 +99,19 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 		note = *ov.Note
 	}
 
+	currency, exchangeRate, err := resolveRevisionCurrency(ctx, tx, invoiceID, ov)
+	if err != nil {
+		return 0, 0, err
+	}
+	vatTreatment, clientVATNumber, err := resolveRevisionVAT(ctx, tx, invoiceID, ov)
+	if err != nil {
+		return 0, 0, err
+	}
+	taxRateID, taxRateName, err := resolveRevisionTaxRate(ctx, tx, ov.IssueDate, &canonical.Totals)
+	if err != nil {
+		return 0, 0, err
+	}
+
 	if _, err := tx.ExecContext(ctx, `
 		UPDATE invoice_revisions
 		SET
@@ -92,14 +123,23 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 			client_company_name = ?,
 			client_address = ?,
 			client_email = ?,
+			client_vat_number = ?,
 			note = ?,
+			currency = ?,
+			exchange_rate_micro = ?,
+			vat_treatment = ?,
 			vat_rate = ?,
+			tax_rate_id = ?,
+			tax_rate_name = ?,
 			discount_type = ?,
 			discount_rate = ?,
 			discount_minor = ?,
 			deposit_type = ?,
 			deposit_rate = ?,
 			deposit_minor = ?,
+			deduction_rate = ?,
+			deduction_line_types = ?,
+			deduction_minor = ?,
 			subtotal_minor = ?,
 			vat_amount_minor = ?,
 			total_minor = ?
@@ -112,14 +152,23 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 		ov.ClientCompanyName,
 		ov.ClientAddress,
 		ov.ClientEmail,
+		clientVATNumber,
 		note,
+		currency,
+		exchangeRate,
+		vatTreatment,
 		canonical.Totals.VATRate,
+		taxRateID,
+		taxRateName,
 		canonical.Totals.DiscountType,
 		canonical.Totals.DiscountRate,
 		canonical.Totals.DiscountMinor,
 		canonical.Totals.DepositType,
 		canonical.Totals.DepositRate,
 		canonical.Totals.DepositMinor,
+		canonical.Totals.DeductionRate,
+		strings.Join(canonical.Totals.DeductionLineTypes, ","),
+		canonical.Totals.DeductionMinor,
 		canonical.Totals.SubtotalMinor,
 		canonical.Totals.VatAmountMinor,
 		canonical.Totals.TotalMinor,
@@ -139,6 +188,10 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 		return 0, 0, err
 	}
 
+	if err := replaceRevisionVATBreakdown(ctx, tx, revisionID, canonical.Totals); err != nil {
+		return 0, 0, err
+	}
+
 	if _, err := tx.ExecContext(ctx, `
 		UPDATE invoices
 		SET current_revision_id = ?
@@ -151,6 +204,10 @@ func UpdateDraft(ctx context.Context, a *app.App, canonical *models.FEInvoiceIn)
 		return 0, 0, err
 	}
 
+	if err := finishInvoiceMutation(ctx, tx, invoiceID, AuditDraftUpdated, revisionID, beforeHash); err != nil {
+		return 0, 0, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return 0, 0, fmt.Errorf("commit update draft: %w", err)
 	}
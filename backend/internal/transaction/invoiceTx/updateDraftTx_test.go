@@ -34,7 +34,7 @@ func draftUpdatePayload(clientID, baseNumber int64, totalMinor int64, paidMinor
 				Name:           lineName,
 				LineType:       "custom",
 				PricingMode:    "flat",
-				Quantity:       1,
+				Quantity:       models.WholeQuantity(1),
 				UnitPriceMinor: totalMinor,
 				LineTotalMinor: totalMinor,
 				SortOrder:      1,
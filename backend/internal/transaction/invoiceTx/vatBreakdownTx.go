@@ -0,0 +1,78 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+// VATBreakdownOrDefault returns t.VATBreakdown, or one band at the invoice VAT
+// rate when no breakdown was computed (revisions saved before per-line VAT).
+func VATBreakdownOrDefault(t models.TotalsCreateIn) []models.VATBand {
+	if len(t.VATBreakdown) > 0 {
+		return t.VATBreakdown
+	}
+
+	return []models.VATBand{{
+		VATRate:  t.VATRate,
+		NetMinor: max(t.SubtotalMinor-t.DiscountMinor, 0),
+		VatMinor: t.VatAmountMinor,
+	}}
+}
+
+// replaceRevisionVATBreakdown stores the per-rate VAT summary for a revision.
+func replaceRevisionVATBreakdown(
+	ctx context.Context,
+	tx *sql.Tx,
+	revisionID int64,
+	totals models.TotalsCreateIn,
+) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM invoice_vat_breakdown
+		WHERE invoice_revision_id = ?;
+	`, revisionID); err != nil {
+		return fmt.Errorf("delete invoice_vat_breakdown: %w", err)
+	}
+
+	for _, band := range VATBreakdownOrDefault(totals) {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO invoice_vat_breakdown (invoice_revision_id, vat_rate, net_minor, vat_minor)
+			VALUES (?, ?, ?, ?);
+		`, revisionID, band.VATRate, band.NetMinor, band.VatMinor); err != nil {
+			return fmt.Errorf("insert invoice_vat_breakdown: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// queryRevisionVATBreakdown loads the stored bands for a revision, highest rate first.
+// It returns nil for revisions saved before the breakdown was persisted.
+func queryRevisionVATBreakdown(ctx context.Context, db *sql.DB, revisionID int64) ([]models.VATBand, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT vat_rate, net_minor, vat_minor
+		FROM invoice_vat_breakdown
+		WHERE invoice_revision_id = ?
+		ORDER BY vat_rate DESC
+	`, revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("load invoice_vat_breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	var bands []models.VATBand
+	for rows.Next() {
+		var band models.VATBand
+		if err := rows.Scan(&band.VATRate, &band.NetMinor, &band.VatMinor); err != nil {
+			return nil, fmt.Errorf("scan invoice_vat_breakdown: %w", err)
+		}
+		bands = append(bands, band)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("invoice_vat_breakdown rows: %w", err)
+	}
+
+	return bands, nil
+}
@@ -0,0 +1,89 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreate_PersistsLineVATRatesAndBreakdown(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a)
+
+	zeroRated := int64(0)
+	payload := draftUpdatePayload(clientID, 1, 1000, 0, "Standard")
+	payload.Lines = append(payload.Lines, models.LineCreateIn{
+		Name:           "Zero rated",
+		LineType:       "custom",
+		PricingMode:    "flat",
+		Quantity:       models.WholeQuantity(1),
+		UnitPriceMinor: 500,
+		LineTotalMinor: 500,
+		SortOrder:      2,
+		VATRate:        &zeroRated,
+	})
+	payload.Totals.VATRate = 2000
+	payload.Totals.VatAmountMinor = 200
+	payload.Totals.SubtotalMinor = 1500
+	payload.Totals.SubtotalAfterDisc = 1500
+	payload.Totals.TotalMinor = 1700
+	payload.Totals.BalanceDue = 1700
+	payload.Totals.VATBreakdown = []models.VATBand{
+		{VATRate: 2000, NetMinor: 1000, VatMinor: 200},
+		{VATRate: 0, NetMinor: 500, VatMinor: 0},
+	}
+
+	if _, _, err := invoiceTx.Create(ctx, a, payload); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary() error = %v", err)
+	}
+	if !reflect.DeepEqual(summary.VATBreakdown, payload.Totals.VATBreakdown) {
+		t.Fatalf("VATBreakdown = %+v, want %+v", summary.VATBreakdown, payload.Totals.VATBreakdown)
+	}
+
+	lines, err := invoiceTx.QueryInvoiceLines(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceLines() error = %v", err)
+	}
+	if lines[0].VATRate != nil {
+		t.Fatalf("line 1 VATRate = %v, want nil", *lines[0].VATRate)
+	}
+	if lines[1].VATRate == nil || *lines[1].VATRate != 0 {
+		t.Fatalf("line 2 VATRate = %v, want 0", lines[1].VATRate)
+	}
+}
+
+func TestQueryInvoiceSummary_FallsBackToSingleVATBand(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a)
+
+	if _, _, err := invoiceTx.Create(ctx, a, draftUpdatePayload(clientID, 1, 1000, 0, "Line")); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := a.DB.Exec(`DELETE FROM invoice_vat_breakdown`); err != nil {
+		t.Fatalf("clear breakdown: %v", err)
+	}
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary() error = %v", err)
+	}
+	want := []models.VATBand{{VATRate: 0, NetMinor: 1000, VatMinor: 0}}
+	if !reflect.DeepEqual(summary.VATBreakdown, want) {
+		t.Fatalf("VATBreakdown = %+v, want %+v", summary.VATBreakdown, want)
+	}
+}
@@ -0,0 +1,70 @@
+package invoiceTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
+)
+
+// ErrClientVATNumberRequired is returned when a reverse charge invoice is
+// saved without the client's VAT number, either on the invoice or the client.
+var ErrClientVATNumberRequired = errors.New("client VAT number is required for a reverse charge invoice")
+
+// resolveRevisionVAT returns the VAT treatment and client VAT number to store
+// on a revision of invoiceID. An empty VAT number falls back to the one saved
+// on the client.
+func resolveRevisionVAT(ctx context.Context, tx *sql.Tx, invoiceID int64, ov *models.InvoiceCreateIn) (string, string, error) {
+	treatment := invoiceformat.NormalizeVATTreatment(ov.VATTreatment)
+	vatNumber := strings.TrimSpace(ov.ClientVATNumber)
+
+	if vatNumber == "" {
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(c.vat_number, '')
+			FROM invoices i
+			JOIN clients c
+				ON c.id = i.client_id
+				AND c.account_id = i.account_id
+			WHERE i.id = ?;
+		`, invoiceID).Scan(&vatNumber); err != nil {
+			return "", "", fmt.Errorf("load client VAT number: %w", err)
+		}
+		vatNumber = strings.TrimSpace(vatNumber)
+	}
+
+	if vatNumber == "" && invoiceformat.RequiresClientVATNumber(treatment) {
+		return "", "", ErrClientVATNumberRequired
+	}
+
+	return treatment, vatNumber, nil
+}
+
+// ResolveVAT returns the VAT treatment and client VAT number to store on a
+// document for clientID, following the same rules as invoice revisions.
+// Quotes use it so they convert into a valid invoice.
+func ResolveVAT(ctx context.Context, tx *sql.Tx, accountID, clientID int64, vatTreatment, clientVATNumber string) (string, string, error) {
+	treatment := invoiceformat.NormalizeVATTreatment(vatTreatment)
+	vatNumber := strings.TrimSpace(clientVATNumber)
+
+	if vatNumber == "" {
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(vat_number, '')
+			FROM clients
+			WHERE account_id = ? AND id = ?;
+		`, accountID, clientID).Scan(&vatNumber)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return "", "", fmt.Errorf("load client VAT number: %w", err)
+		}
+		vatNumber = strings.TrimSpace(vatNumber)
+	}
+
+	if vatNumber == "" && invoiceformat.RequiresClientVATNumber(treatment) {
+		return "", "", ErrClientVATNumberRequired
+	}
+
+	return treatment, vatNumber, nil
+}
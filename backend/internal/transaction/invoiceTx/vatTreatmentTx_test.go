@@ -0,0 +1,56 @@
+package invoiceTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+func TestCreate_ReverseChargeRequiresClientVATNumber(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a)
+
+	inv := draftUpdatePayload(clientID, 1, 1000, 0, "Consulting")
+	inv.Overview.VATTreatment = invoiceformat.VATTreatmentReverseCharge
+	if _, _, err := invoiceTx.Create(ctx, a, inv); !errors.Is(err, invoiceTx.ErrClientVATNumberRequired) {
+		t.Fatalf("Create without VAT number error = %v, want %v", err, invoiceTx.ErrClientVATNumberRequired)
+	}
+
+	if _, err := a.DB.ExecContext(ctx, `UPDATE clients SET vat_number = 'DE123456789' WHERE id = ?`, clientID); err != nil {
+		t.Fatalf("set client VAT number: %v", err)
+	}
+	if _, _, err := invoiceTx.Create(ctx, a, inv); err != nil {
+		t.Fatalf("Create reverse charge: %v", err)
+	}
+
+	summary, err := invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 1, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary: %v", err)
+	}
+	if summary.VATTreatment != invoiceformat.VATTreatmentReverseCharge || summary.ClientVATNumber != "DE123456789" {
+		t.Fatalf("stored treatment = %q with VAT number %q, want reverse_charge with the client's number",
+			summary.VATTreatment, summary.ClientVATNumber)
+	}
+
+	exempt := draftUpdatePayload(clientID, 2, 1000, 0, "Training")
+	exempt.Overview.VATTreatment = invoiceformat.VATTreatmentExempt
+	exempt.Overview.ClientVATNumber = "FR987654321"
+	if _, _, err := invoiceTx.Create(ctx, a, exempt); err != nil {
+		t.Fatalf("Create exempt: %v", err)
+	}
+	summary, err = invoiceTx.QueryInvoiceSummary(ctx, a.DB, clientID, 2, 1)
+	if err != nil {
+		t.Fatalf("QueryInvoiceSummary exempt: %v", err)
+	}
+	if summary.VATTreatment != invoiceformat.VATTreatmentExempt || summary.ClientVATNumber != "FR987654321" {
+		t.Fatalf("stored treatment = %q with VAT number %q, want exempt with the invoice's number",
+			summary.VATTreatment, summary.ClientVATNumber)
+	}
+}
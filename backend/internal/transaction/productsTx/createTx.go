@@ -8,6 +8,7 @@ import (
 	"github.com/viktorHadz/goInvoice26/internal/accountscope"
 	"github.com/viktorHadz/goInvoice26/internal/app"
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
 )
 
 func InsertTx(a *app.App, ctx context.Context, in models.ProductCreate) (models.Product, error) {
@@ -15,6 +16,9 @@ func InsertTx(a *app.App, ctx context.Context, in models.ProductCreate) (models.
 	if err != nil {
 		return models.Product{}, err
 	}
+	if err := verifyTaxRate(ctx, a, accountID, in.TaxRateID); err != nil {
+		return models.Product{}, err
+	}
 
 	const q = `
 		INSERT INTO products (
@@ -25,9 +29,11 @@ func InsertTx(a *app.App, ctx context.Context, in models.ProductCreate) (models.
 			flat_price_minor,
 			hourly_rate_minor,
 			default_minutes_worked,
+			unit,
+			tax_rate_id,
 			client_id
 		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		RETURNING
 			id,
 			product_type,
@@ -36,6 +42,8 @@ func InsertTx(a *app.App, ctx context.Context, in models.ProductCreate) (models.
 			flat_price_minor,
 			hourly_rate_minor,
 			default_minutes_worked,
+			unit,
+			tax_rate_id,
 			client_id,
 			created_at,
 			updated_at;
@@ -69,6 +77,8 @@ func InsertTx(a *app.App, ctx context.Context, in models.ProductCreate) (models.
 		flat,
 		hourly,
 		minutes,
+		in.Unit,
+		in.TaxRateID,
 		in.ClientID,
 	).Scan(
 		&out.ID,
@@ -78,6 +88,8 @@ func InsertTx(a *app.App, ctx context.Context, in models.ProductCreate) (models.
 		&out.FlatPriceMinor,
 		&out.HourlyRateMinor,
 		&out.MinutesWorked,
+		&out.Unit,
+		&out.TaxRateID,
 		&out.ClientID,
 		&out.CreatedAt,
 		&updated,
@@ -92,3 +104,13 @@ func InsertTx(a *app.App, ctx context.Context, in models.ProductCreate) (models.
 
 	return out, nil
 }
+
+// verifyTaxRate checks an optional tax rate reference belongs to the account.
+// A missing rate returns [settingsTx.ErrTaxRateNotFound].
+func verifyTaxRate(ctx context.Context, a *app.App, accountID int64, taxRateID *int64) error {
+	if taxRateID == nil {
+		return nil
+	}
+	_, err := settingsTx.GetTaxRate(ctx, a.DB, accountID, *taxRateID)
+	return err
+}
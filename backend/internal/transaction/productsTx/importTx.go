@@ -34,8 +34,9 @@ func BulkInsertTx(a *app.App, ctx context.Context, rows []models.ProductCreate)
 			flat_price_minor,
 			hourly_rate_minor,
 			default_minutes_worked,
+			unit,
 			client_id
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
 		return 0, fmt.Errorf("prepare product import insert: %w", err)
@@ -66,6 +67,7 @@ func BulkInsertTx(a *app.App, ctx context.Context, rows []models.ProductCreate)
 			flat,
 			hourly,
 			minutes,
+			row.Unit,
 			row.ClientID,
 		); err != nil {
 			return 0, fmt.Errorf("insert imported product: %w", err)
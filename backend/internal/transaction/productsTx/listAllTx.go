@@ -23,6 +23,8 @@ func ListAll(a *app.App, ctx context.Context, clientID int64) ([]models.Product,
 			flat_price_minor,
 			hourly_rate_minor,
 			default_minutes_worked,
+			unit,
+			tax_rate_id,
 			client_id,
 			created_at,
 			updated_at
@@ -46,6 +48,8 @@ func ListAll(a *app.App, ctx context.Context, clientID int64) ([]models.Product,
 			&p.FlatPriceMinor,
 			&p.HourlyRateMinor,
 			&p.MinutesWorked,
+			&p.Unit,
+			&p.TaxRateID,
 			&p.ClientID,
 			&p.CreatedAt,
 			&p.UpdatedAt,
@@ -15,6 +15,9 @@ func UpdateTx(a *app.App, ctx context.Context, productID int64, in models.Produc
 	if err != nil {
 		return models.Product{}, err
 	}
+	if err := verifyTaxRate(ctx, a, accountID, in.TaxRateID); err != nil {
+		return models.Product{}, err
+	}
 
 	const q = `
 		UPDATE products
@@ -25,6 +28,8 @@ func UpdateTx(a *app.App, ctx context.Context, productID int64, in models.Produc
 			flat_price_minor = ?,
 			hourly_rate_minor = ?,
 			default_minutes_worked = ?,
+			unit = ?,
+			tax_rate_id = ?,
 			updated_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
 		WHERE id = ? AND account_id = ? AND client_id = ?
 		RETURNING
@@ -35,6 +40,8 @@ func UpdateTx(a *app.App, ctx context.Context, productID int64, in models.Produc
 			flat_price_minor,
 			hourly_rate_minor,
 			default_minutes_worked,
+			unit,
+			tax_rate_id,
 			client_id,
 			created_at,
 			updated_at;
@@ -68,6 +75,8 @@ func UpdateTx(a *app.App, ctx context.Context, productID int64, in models.Produc
 		flat,
 		hourly,
 		minutes,
+		in.Unit,
+		in.TaxRateID,
 		productID,
 		accountID,
 		in.ClientID,
@@ -79,6 +88,8 @@ func UpdateTx(a *app.App, ctx context.Context, productID int64, in models.Produc
 		&out.FlatPriceMinor,
 		&out.HourlyRateMinor,
 		&out.MinutesWorked,
+		&out.Unit,
+		&out.TaxRateID,
 		&out.ClientID,
 		&out.CreatedAt,
 		&updated,
@@ -0,0 +1,105 @@
+package quoteTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+// ErrQuoteAlreadyConverted is returned when the quote already has an invoice.
+var ErrQuoteAlreadyConverted = errors.New("quote already converted to an invoice")
+
+// ErrQuoteDeclinedForConvert is returned when converting a declined quote.
+var ErrQuoteDeclinedForConvert = errors.New("declined quotes cannot be converted")
+
+// ConvertToInvoice copies the quote's lines, totals, deduction, currency and
+// VAT treatment into a new draft invoice numbered from the invoice sequence, then marks the quote accepted
+// and links it to the invoice. Both happen in one transaction.
+func ConvertToInvoice(
+	ctx context.Context,
+	a *app.App,
+	clientID int64,
+	quoteNumber int64,
+	in models.QuoteConvertIn,
+) (invoiceID, baseNumber int64, err error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadQuoteState(ctx, tx, accountID, clientID, quoteNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+	if state.converted() {
+		return 0, 0, ErrQuoteAlreadyConverted
+	}
+	if state.Status == "declined" {
+		return 0, 0, ErrQuoteDeclinedForConvert
+	}
+
+	quote, err := getQuote(ctx, tx, accountID, clientID, quoteNumber)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	canonical := invoiceFromQuote(quote, in)
+	invoiceID, _, baseNumber, err = invoiceTx.CreateNextInTx(ctx, tx, canonical)
+	if err != nil {
+		return 0, 0, fmt.Errorf("create invoice from quote: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quotes
+		SET
+			status = 'accepted',
+			converted_invoice_id = ?,
+			updated_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		WHERE id = ?
+	`, invoiceID, state.ID); err != nil {
+		return 0, 0, fmt.Errorf("link quote to invoice: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, fmt.Errorf("commit: %w", err)
+	}
+	return invoiceID, baseNumber, nil
+}
+
+func invoiceFromQuote(q *models.QuoteOut, in models.QuoteConvertIn) *models.FEInvoiceIn {
+	ov := q.Overview
+	tot := q.Totals
+	tot.PaidMinor = 0
+	tot.BalanceDue = tot.PayableMinor
+
+	return &models.FEInvoiceIn{
+		Overview: models.InvoiceCreateIn{
+			ClientID:          ov.ClientID,
+			IssueDate:         in.IssueDate,
+			DueByDate:         in.DueByDate,
+			ClientName:        ov.ClientName,
+			ClientCompanyName: ov.ClientCompanyName,
+			ClientAddress:     ov.ClientAddress,
+			ClientEmail:       ov.ClientEmail,
+			Note:              ov.Note,
+			Currency:          ov.Currency,
+			ExchangeRateMicro: ov.ExchangeRateMicro,
+			VATTreatment:      ov.VATTreatment,
+			ClientVATNumber:   ov.ClientVATNumber,
+		},
+		Lines:  q.Lines,
+		Totals: tot,
+	}
+}
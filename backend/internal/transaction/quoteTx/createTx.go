@@ -0,0 +1,306 @@
+/*
+The quoteTx package stores quotes (estimates) and converts them into invoices.
+
+Quotes are numbered from their own quote_number_seq so sending a quote never
+consumes an invoice number.
+*/
+package quoteTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/dberr"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+// ErrQuoteNotFound is returned when a quote does not exist for the client.
+var ErrQuoteNotFound = errors.New("quote not found")
+
+// ErrQuoteNumberTaken is returned when the quote number is already used in the account.
+var ErrQuoteNumberTaken = errors.New("quote number already exists")
+
+// ErrQuoteLocked is returned when editing or deleting an accepted, declined or converted quote.
+var ErrQuoteLocked = errors.New("quote can no longer be changed")
+
+// Create inserts a new quote with its line items.
+func Create(ctx context.Context, a *app.App, canonical *models.QuoteIn) (quoteID int64, err error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	ov := &canonical.Overview
+	tot := &canonical.Totals
+
+	if err := ensureQuoteSequenceRow(ctx, tx, accountID); err != nil {
+		return 0, err
+	}
+	currency, exchangeRate, err := invoiceTx.ResolveCurrency(ctx, tx, accountID, ov.Currency, ov.ExchangeRateMicro)
+	if err != nil {
+		return 0, err
+	}
+	vatTreatment, clientVATNumber, err := invoiceTx.ResolveVAT(ctx, tx, accountID, ov.ClientID, ov.VATTreatment, ov.ClientVATNumber)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO quotes (
+			account_id, client_id, quote_number, status,
+			issue_date, valid_until,
+			client_name, client_company_name, client_address, client_email, client_vat_number, note,
+			currency, exchange_rate_micro,
+			vat_treatment, vat_rate,
+			discount_type, discount_rate, discount_minor,
+			deposit_type, deposit_rate, deposit_minor,
+			deduction_rate, deduction_line_types, deduction_minor,
+			subtotal_minor, vat_amount_minor, total_minor
+		) VALUES (?, ?, ?, 'draft', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`,
+		accountID, ov.ClientID, ov.QuoteNumber,
+		ov.IssueDate, ov.ValidUntil,
+		ov.ClientName, ov.ClientCompanyName, ov.ClientAddress, ov.ClientEmail, clientVATNumber, ov.Note,
+		currency, exchangeRate,
+		vatTreatment, tot.VATRate,
+		tot.DiscountType, tot.DiscountRate, tot.DiscountMinor,
+		tot.DepositType, tot.DepositRate, tot.DepositMinor,
+		tot.DeductionRate, strings.Join(tot.DeductionLineTypes, ","), tot.DeductionMinor,
+		tot.SubtotalMinor, tot.VatAmountMinor, tot.TotalMinor,
+	).Scan(&quoteID); err != nil {
+		if dberr.IsUniqueViolation(err) {
+			return 0, ErrQuoteNumberTaken
+		}
+		return 0, fmt.Errorf("insert quote: %w", err)
+	}
+
+	if err := insertQuoteItems(ctx, tx, quoteID, canonical.Lines); err != nil {
+		return 0, err
+	}
+	if err := replaceQuoteVATBreakdown(ctx, tx, quoteID, canonical.Totals); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quote_number_seq
+		SET next_quote_number = MAX(next_quote_number, ?)
+		WHERE account_id = ?;
+	`, ov.QuoteNumber+1, accountID); err != nil {
+		return 0, fmt.Errorf("sync quote_number_seq: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return quoteID, nil
+}
+
+// Update replaces the content of a draft or sent quote in place. Quotes have
+// no revision history; once accepted, declined or converted they are locked.
+func Update(ctx context.Context, a *app.App, canonical *models.QuoteIn) error {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	ov := &canonical.Overview
+	tot := &canonical.Totals
+
+	state, err := loadQuoteState(ctx, tx, accountID, ov.ClientID, ov.QuoteNumber)
+	if err != nil {
+		return err
+	}
+	if !state.editable() {
+		return ErrQuoteLocked
+	}
+	currency, exchangeRate, err := invoiceTx.ResolveCurrency(ctx, tx, accountID, ov.Currency, ov.ExchangeRateMicro)
+	if err != nil {
+		return err
+	}
+	vatTreatment, clientVATNumber, err := invoiceTx.ResolveVAT(ctx, tx, accountID, ov.ClientID, ov.VATTreatment, ov.ClientVATNumber)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quotes
+		SET
+			issue_date = ?,
+			valid_until = ?,
+			client_name = ?,
+			client_company_name = ?,
+			client_address = ?,
+			client_email = ?,
+			client_vat_number = ?,
+			note = ?,
+			currency = ?,
+			exchange_rate_micro = ?,
+			vat_treatment = ?,
+			vat_rate = ?,
+			discount_type = ?,
+			discount_rate = ?,
+			discount_minor = ?,
+			deposit_type = ?,
+			deposit_rate = ?,
+			deposit_minor = ?,
+			deduction_rate = ?,
+			deduction_line_types = ?,
+			deduction_minor = ?,
+			subtotal_minor = ?,
+			vat_amount_minor = ?,
+			total_minor = ?,
+			updated_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		WHERE id = ?
+	`,
+		ov.IssueDate, ov.ValidUntil,
+		ov.ClientName, ov.ClientCompanyName, ov.ClientAddress, ov.ClientEmail, clientVATNumber, ov.Note,
+		currency, exchangeRate,
+		vatTreatment, tot.VATRate,
+		tot.DiscountType, tot.DiscountRate, tot.DiscountMinor,
+		tot.DepositType, tot.DepositRate, tot.DepositMinor,
+		tot.DeductionRate, strings.Join(tot.DeductionLineTypes, ","), tot.DeductionMinor,
+		tot.SubtotalMinor, tot.VatAmountMinor, tot.TotalMinor,
+		state.ID,
+	); err != nil {
+		return fmt.Errorf("update quote: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM quote_items
+		WHERE quote_id = ?
+	`, state.ID); err != nil {
+		return fmt.Errorf("delete quote items: %w", err)
+	}
+
+	if err := insertQuoteItems(ctx, tx, state.ID, canonical.Lines); err != nil {
+		return err
+	}
+	if err := replaceQuoteVATBreakdown(ctx, tx, state.ID, canonical.Totals); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// GetSuggestedNextQuoteNumber returns the next quote number without allocating it.
+func GetSuggestedNextQuoteNumber(ctx context.Context, a *app.App) (int64, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := a.DB.ExecContext(ctx, `
+		INSERT OR IGNORE INTO quote_number_seq (account_id, next_quote_number)
+		VALUES (?, 1);
+	`, accountID); err != nil {
+		return 0, fmt.Errorf("ensure quote sequence row: %w", err)
+	}
+
+	var next int64
+	err = a.DB.QueryRowContext(ctx, `
+		SELECT MAX(
+			COALESCE((SELECT next_quote_number FROM quote_number_seq WHERE account_id = ?), 1),
+			COALESCE((SELECT MAX(quote_number) FROM quotes WHERE account_id = ?), 0) + 1
+		)
+	`, accountID, accountID).Scan(&next)
+	if err != nil {
+		return 0, fmt.Errorf("get suggested next quote number: %w", err)
+	}
+	return next, nil
+}
+
+func ensureQuoteSequenceRow(ctx context.Context, tx *sql.Tx, accountID int64) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR IGNORE INTO quote_number_seq (account_id, next_quote_number)
+		VALUES (?, 1);
+	`, accountID); err != nil {
+		return fmt.Errorf("ensure quote sequence row: %w", err)
+	}
+	return nil
+}
+
+func insertQuoteItems(ctx context.Context, tx *sql.Tx, quoteID int64, lines []models.LineCreateIn) error {
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO quote_items (
+			quote_id, product_id, name, line_type, pricing_mode,
+			quantity, quantity_milli, unit, unit_price_minor, line_total_minor, minutes_worked, sort_order,
+			vat_rate, discount_type, discount_rate, discount_minor
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare quote_items: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, ln := range lines {
+		discountType := ln.DiscountType
+		if discountType == "" {
+			discountType = "none"
+		}
+
+		if _, err := stmt.ExecContext(ctx,
+			quoteID,
+			ln.ProductID,
+			ln.Name,
+			ln.LineType,
+			ln.PricingMode,
+			ln.Quantity.WholeUnitsCeil(),
+			ln.Quantity,
+			ln.Unit,
+			ln.UnitPriceMinor,
+			ln.LineTotalMinor,
+			ln.MinutesWorked,
+			ln.SortOrder,
+			ln.VATRate,
+			discountType,
+			ln.DiscountRate,
+			ln.DiscountMinor,
+		); err != nil {
+			return fmt.Errorf("insert quote_item: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func replaceQuoteVATBreakdown(ctx context.Context, tx *sql.Tx, quoteID int64, totals models.TotalsCreateIn) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM quote_vat_breakdown
+		WHERE quote_id = ?
+	`, quoteID); err != nil {
+		return fmt.Errorf("delete quote_vat_breakdown: %w", err)
+	}
+
+	for _, band := range invoiceTx.VATBreakdownOrDefault(totals) {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO quote_vat_breakdown (quote_id, vat_rate, net_minor, vat_minor)
+			VALUES (?, ?, ?, ?)
+		`, quoteID, band.VATRate, band.NetMinor, band.VatMinor); err != nil {
+			return fmt.Errorf("insert quote_vat_breakdown: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package quoteTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+const quoteSelectSQL = `
+	SELECT
+		q.id,
+		q.status,
+		q.client_id,
+		q.quote_number,
+		q.issue_date,
+		q.valid_until,
+		q.client_name,
+		q.client_company_name,
+		q.client_address,
+		q.client_email,
+		q.client_vat_number,
+		q.note,
+		q.currency,
+		q.exchange_rate_micro,
+		q.vat_treatment,
+		q.vat_rate,
+		q.vat_amount_minor,
+		q.discount_type,
+		q.discount_rate,
+		q.discount_minor,
+		q.deposit_type,
+		q.deposit_rate,
+		q.deposit_minor,
+		q.deduction_rate,
+		q.deduction_line_types,
+		q.deduction_minor,
+		q.subtotal_minor,
+		q.total_minor,
+		ci.base_number,
+		q.created_at,
+		q.updated_at
+	FROM quotes q
+	LEFT JOIN invoices ci
+		ON ci.id = q.converted_invoice_id
+`
+
+func scanQuote(row rowScanner) (models.QuoteOut, error) {
+	var (
+		q                  models.QuoteOut
+		deductionLineTypes string
+	)
+	ov := &q.Overview
+	tot := &q.Totals
+
+	err := row.Scan(
+		&q.ID,
+		&q.Status,
+		&ov.ClientID,
+		&ov.QuoteNumber,
+		&ov.IssueDate,
+		&ov.ValidUntil,
+		&ov.ClientName,
+		&ov.ClientCompanyName,
+		&ov.ClientAddress,
+		&ov.ClientEmail,
+		&ov.ClientVATNumber,
+		&ov.Note,
+		&ov.Currency,
+		&ov.ExchangeRateMicro,
+		&ov.VATTreatment,
+		&tot.VATRate,
+		&tot.VatAmountMinor,
+		&tot.DiscountType,
+		&tot.DiscountRate,
+		&tot.DiscountMinor,
+		&tot.DepositType,
+		&tot.DepositRate,
+		&tot.DepositMinor,
+		&tot.DeductionRate,
+		&deductionLineTypes,
+		&tot.DeductionMinor,
+		&tot.SubtotalMinor,
+		&tot.TotalMinor,
+		&q.ConvertedInvoiceBaseNumber,
+		&q.CreatedAt,
+		&q.UpdatedAt,
+	)
+	if err != nil {
+		return models.QuoteOut{}, err
+	}
+
+	tot.SubtotalAfterDisc = tot.SubtotalMinor - tot.DiscountMinor
+	tot.DeductionLineTypes = invoiceTx.SplitDeductionLineTypes(deductionLineTypes)
+	tot.PayableMinor = max(tot.TotalMinor-tot.DeductionMinor, 0)
+	tot.BalanceDue = tot.PayableMinor
+	q.Lines = make([]models.LineCreateIn, 0)
+	return q, nil
+}
+
+// Get returns one quote with its line items.
+func Get(ctx context.Context, db *sql.DB, clientID, quoteNumber int64) (*models.QuoteOut, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return getQuote(ctx, db, accountID, clientID, quoteNumber)
+}
+
+func getQuote(ctx context.Context, q queryer, accountID, clientID, quoteNumber int64) (*models.QuoteOut, error) {
+	out, err := scanQuote(q.QueryRowContext(ctx, quoteSelectSQL+`
+		WHERE q.account_id = ? AND q.client_id = ? AND q.quote_number = ?
+	`, accountID, clientID, quoteNumber))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, fmt.Errorf("load quote: %w", err)
+	}
+
+	lines, err := queryQuoteItems(ctx, q, out.ID)
+	if err != nil {
+		return nil, err
+	}
+	out.Lines = lines
+
+	bands, err := queryQuoteVATBreakdown(ctx, q, out.ID)
+	if err != nil {
+		return nil, err
+	}
+	out.Totals.VATBreakdown = bands
+
+	return &out, nil
+}
+
+// List returns the client's quotes, newest number first. Lines are not
+// loaded; use [Get] for a single quote with its items.
+func List(ctx context.Context, db *sql.DB, clientID int64) ([]models.QuoteOut, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, quoteSelectSQL+`
+		WHERE q.account_id = ? AND q.client_id = ?
+		ORDER BY q.quote_number DESC
+	`, accountID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("list quotes: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]models.QuoteOut, 0)
+	for rows.Next() {
+		q, err := scanQuote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan quote: %w", err)
+		}
+		out = append(out, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("quote rows: %w", err)
+	}
+	return out, nil
+}
+
+func queryQuoteItems(ctx context.Context, q queryer, quoteID int64) ([]models.LineCreateIn, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT
+			product_id,
+			name,
+			line_type,
+			pricing_mode,
+			COALESCE(quantity_milli, quantity * 1000),
+			unit,
+			minutes_worked,
+			unit_price_minor,
+			line_total_minor,
+			sort_order,
+			vat_rate,
+			discount_type,
+			discount_rate,
+			discount_minor
+		FROM quote_items
+		WHERE quote_id = ?
+		ORDER BY sort_order ASC
+	`, quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("load quote items: %w", err)
+	}
+	defer rows.Close()
+
+	lines := make([]models.LineCreateIn, 0)
+	for rows.Next() {
+		var ln models.LineCreateIn
+		if err := rows.Scan(
+			&ln.ProductID,
+			&ln.Name,
+			&ln.LineType,
+			&ln.PricingMode,
+			&ln.Quantity,
+			&ln.Unit,
+			&ln.MinutesWorked,
+			&ln.UnitPriceMinor,
+			&ln.LineTotalMinor,
+			&ln.SortOrder,
+			&ln.VATRate,
+			&ln.DiscountType,
+			&ln.DiscountRate,
+			&ln.DiscountMinor,
+		); err != nil {
+			return nil, fmt.Errorf("scan quote item: %w", err)
+		}
+		lines = append(lines, ln)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("quote item rows: %w", err)
+	}
+	return lines, nil
+}
+
+func queryQuoteVATBreakdown(ctx context.Context, q queryer, quoteID int64) ([]models.VATBand, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT vat_rate, net_minor, vat_minor
+		FROM quote_vat_breakdown
+		WHERE quote_id = ?
+		ORDER BY vat_rate DESC
+	`, quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("load quote vat breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	var bands []models.VATBand
+	for rows.Next() {
+		var band models.VATBand
+		if err := rows.Scan(&band.VATRate, &band.NetMinor, &band.VatMinor); err != nil {
+			return nil, fmt.Errorf("scan quote vat band: %w", err)
+		}
+		bands = append(bands, band)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("quote vat band rows: %w", err)
+	}
+	return bands, nil
+}
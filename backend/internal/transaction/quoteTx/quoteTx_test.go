@@ -0,0 +1,357 @@
+package quoteTx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/db"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/quoteTx"
+)
+
+func newTestApp(t *testing.T) (*app.App, func()) {
+	t.Helper()
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.sqlite")
+
+	d, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	if err := db.Migrate(context.Background(), d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	a := &app.App{DB: d}
+	cleanup := func() {
+		_ = d.Close()
+		_ = os.Remove(dbPath)
+	}
+	return a, cleanup
+}
+
+func insertClient(t *testing.T, a *app.App, name string) int64 {
+	t.Helper()
+
+	res, err := a.DB.Exec(`INSERT INTO clients (name, address) VALUES (?, ?)`, name, "1 High Street")
+	if err != nil {
+		t.Fatalf("insert client: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("client lastInsertId: %v", err)
+	}
+	return id
+}
+
+func createQuote(t *testing.T, ctx context.Context, a *app.App, clientID, quoteNumber int64) {
+	t.Helper()
+
+	validUntil := "2026-04-01"
+	_, err := quoteTx.Create(ctx, a, &models.QuoteIn{
+		Overview: models.QuoteOverviewIn{
+			ClientID:    clientID,
+			QuoteNumber: quoteNumber,
+			IssueDate:   "2026-03-01",
+			ValidUntil:  &validUntil,
+			ClientName:  "Client",
+		},
+		Lines: []models.LineCreateIn{
+			{
+				Name:           "Kitchen fit",
+				LineType:       "custom",
+				PricingMode:    "flat",
+				Quantity:       models.WholeQuantity(3),
+				UnitPriceMinor: 400,
+				LineTotalMinor: 1200,
+				SortOrder:      1,
+			},
+		},
+		Totals: models.TotalsCreateIn{
+			VATRate:           2000,
+			VatAmountMinor:    240,
+			DiscountType:      "none",
+			DepositType:       "none",
+			SubtotalMinor:     1200,
+			SubtotalAfterDisc: 1200,
+			TotalMinor:        1440,
+			BalanceDue:        1440,
+		},
+	})
+	if err != nil {
+		t.Fatalf("create quote %d: %v", quoteNumber, err)
+	}
+}
+
+func TestCreate_UsesOwnNumberSequence(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a, "Client")
+
+	invoiceBefore, err := invoiceTx.GetSuggestedNextBaseNumber(ctx, a)
+	if err != nil {
+		t.Fatalf("next invoice number: %v", err)
+	}
+
+	createQuote(t, ctx, a, clientID, 1)
+
+	nextQuote, err := quoteTx.GetSuggestedNextQuoteNumber(ctx, a)
+	if err != nil {
+		t.Fatalf("next quote number: %v", err)
+	}
+	if nextQuote != 2 {
+		t.Fatalf("next quote number = %d, want 2", nextQuote)
+	}
+
+	invoiceAfter, err := invoiceTx.GetSuggestedNextBaseNumber(ctx, a)
+	if err != nil {
+		t.Fatalf("next invoice number: %v", err)
+	}
+	if invoiceAfter != invoiceBefore {
+		t.Fatalf("next invoice number = %d, want unchanged %d", invoiceAfter, invoiceBefore)
+	}
+
+	_, err = quoteTx.Create(ctx, a, &models.QuoteIn{
+		Overview: models.QuoteOverviewIn{ClientID: clientID, QuoteNumber: 1, IssueDate: "2026-03-01", ClientName: "Client"},
+		Totals:   models.TotalsCreateIn{DiscountType: "none", DepositType: "none"},
+	})
+	if !errors.Is(err, quoteTx.ErrQuoteNumberTaken) {
+		t.Fatalf("duplicate quote number err = %v, want ErrQuoteNumberTaken", err)
+	}
+}
+
+func TestConvertToInvoice_CopiesLinesAndTotals(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a, "Client")
+	createQuote(t, ctx, a, clientID, 7)
+
+	invoiceID, baseNumber, err := quoteTx.ConvertToInvoice(ctx, a, clientID, 7, models.QuoteConvertIn{
+		IssueDate: "2026-03-10",
+	})
+	if err != nil {
+		t.Fatalf("ConvertToInvoice() error = %v", err)
+	}
+	if baseNumber != 1 {
+		t.Fatalf("baseNumber = %d, want 1", baseNumber)
+	}
+
+	var (
+		status    string
+		issueDate string
+		total     int64
+		itemName  string
+		itemTotal int64
+	)
+	if err := a.DB.QueryRow(`
+		SELECT i.status, r.issue_date, r.total_minor, it.name, it.line_total_minor
+		FROM invoices i
+		JOIN invoice_revisions r ON r.id = i.current_revision_id
+		JOIN invoice_items it ON it.invoice_revision_id = r.id
+		WHERE i.id = ?
+	`, invoiceID).Scan(&status, &issueDate, &total, &itemName, &itemTotal); err != nil {
+		t.Fatalf("load converted invoice: %v", err)
+	}
+	if status != "draft" || issueDate != "2026-03-10" || total != 1440 {
+		t.Fatalf("invoice = (%s, %s, %d), want (draft, 2026-03-10, 1440)", status, issueDate, total)
+	}
+	if itemName != "Kitchen fit" || itemTotal != 1200 {
+		t.Fatalf("item = (%s, %d), want (Kitchen fit, 1200)", itemName, itemTotal)
+	}
+
+	quote, err := quoteTx.Get(ctx, a.DB, clientID, 7)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if quote.Status != "accepted" {
+		t.Fatalf("quote status = %s, want accepted", quote.Status)
+	}
+	if quote.ConvertedInvoiceBaseNumber == nil || *quote.ConvertedInvoiceBaseNumber != baseNumber {
+		t.Fatalf("converted base number = %v, want %d", quote.ConvertedInvoiceBaseNumber, baseNumber)
+	}
+
+	if _, _, err := quoteTx.ConvertToInvoice(ctx, a, clientID, 7, models.QuoteConvertIn{IssueDate: "2026-03-10"}); !errors.Is(err, quoteTx.ErrQuoteAlreadyConverted) {
+		t.Fatalf("second convert err = %v, want ErrQuoteAlreadyConverted", err)
+	}
+	if err := quoteTx.Delete(ctx, a, clientID, 7); !errors.Is(err, quoteTx.ErrQuoteLocked) {
+		t.Fatalf("delete converted err = %v, want ErrQuoteLocked", err)
+	}
+}
+
+func TestConvertToInvoice_RejectsDeclinedQuote(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a, "Client")
+	createQuote(t, ctx, a, clientID, 1)
+
+	if err := quoteTx.UpdateStatus(ctx, a, clientID, 1, "declined"); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+
+	_, _, err := quoteTx.ConvertToInvoice(ctx, a, clientID, 1, models.QuoteConvertIn{IssueDate: "2026-03-10"})
+	if !errors.Is(err, quoteTx.ErrQuoteDeclinedForConvert) {
+		t.Fatalf("convert declined err = %v, want ErrQuoteDeclinedForConvert", err)
+	}
+}
+
+func TestConvertToInvoice_KeepsQuoteCurrency(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a, "Client")
+
+	quote := &models.QuoteIn{
+		Overview: models.QuoteOverviewIn{
+			ClientID:    clientID,
+			QuoteNumber: 3,
+			IssueDate:   "2026-03-01",
+			ClientName:  "Client",
+			Currency:    "EUR",
+		},
+		Totals: models.TotalsCreateIn{DiscountType: "none", DepositType: "none"},
+	}
+	if _, err := quoteTx.Create(ctx, a, quote); !errors.Is(err, invoiceTx.ErrExchangeRateRequired) {
+		t.Fatalf("create without rate err = %v, want ErrExchangeRateRequired", err)
+	}
+
+	quote.Overview.ExchangeRateMicro = 850000
+	if _, err := quoteTx.Create(ctx, a, quote); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	invoiceID, _, err := quoteTx.ConvertToInvoice(ctx, a, clientID, 3, models.QuoteConvertIn{IssueDate: "2026-03-10"})
+	if err != nil {
+		t.Fatalf("ConvertToInvoice() error = %v", err)
+	}
+
+	var (
+		currency string
+		rate     int64
+	)
+	if err := a.DB.QueryRow(`
+		SELECT r.currency, r.exchange_rate_micro
+		FROM invoices i
+		JOIN invoice_revisions r ON r.id = i.current_revision_id
+		WHERE i.id = ?
+	`, invoiceID).Scan(&currency, &rate); err != nil {
+		t.Fatalf("load converted invoice: %v", err)
+	}
+	if currency != "EUR" || rate != 850000 {
+		t.Fatalf("invoice currency = %s at %d, want EUR at 850000", currency, rate)
+	}
+}
+
+func TestConvertToInvoice_KeepsVATTreatment(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a, "Client")
+
+	quote := &models.QuoteIn{
+		Overview: models.QuoteOverviewIn{
+			ClientID:     clientID,
+			QuoteNumber:  4,
+			IssueDate:    "2026-03-01",
+			ClientName:   "Client",
+			VATTreatment: "reverse_charge",
+		},
+		Totals: models.TotalsCreateIn{DiscountType: "none", DepositType: "none"},
+	}
+	if _, err := quoteTx.Create(ctx, a, quote); !errors.Is(err, invoiceTx.ErrClientVATNumberRequired) {
+		t.Fatalf("create without VAT number err = %v, want ErrClientVATNumberRequired", err)
+	}
+
+	if _, err := a.DB.Exec(`UPDATE clients SET vat_number = 'DE123456789' WHERE id = ?`, clientID); err != nil {
+		t.Fatalf("set client VAT number: %v", err)
+	}
+	if _, err := quoteTx.Create(ctx, a, quote); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	invoiceID, _, err := quoteTx.ConvertToInvoice(ctx, a, clientID, 4, models.QuoteConvertIn{IssueDate: "2026-03-10"})
+	if err != nil {
+		t.Fatalf("ConvertToInvoice() error = %v", err)
+	}
+
+	var treatment, vatNumber string
+	if err := a.DB.QueryRow(`
+		SELECT r.vat_treatment, r.client_vat_number
+		FROM invoices i
+		JOIN invoice_revisions r ON r.id = i.current_revision_id
+		WHERE i.id = ?
+	`, invoiceID).Scan(&treatment, &vatNumber); err != nil {
+		t.Fatalf("load converted invoice: %v", err)
+	}
+	if treatment != "reverse_charge" || vatNumber != "DE123456789" {
+		t.Fatalf("invoice VAT = %s %q, want reverse_charge DE123456789", treatment, vatNumber)
+	}
+}
+
+func TestConvertToInvoice_KeepsDeduction(t *testing.T) {
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	clientID := insertClient(t, a, "Client")
+	createQuote(t, ctx, a, clientID, 5)
+
+	quote, err := quoteTx.Get(ctx, a.DB, clientID, 5)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	quote.Totals.DeductionRate = 2000
+	quote.Totals.DeductionLineTypes = []string{"custom"}
+	quote.Totals.DeductionMinor = 240
+	if err := quoteTx.Update(ctx, a, &models.QuoteIn{Overview: quote.Overview, Lines: quote.Lines, Totals: quote.Totals}); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	quote, err = quoteTx.Get(ctx, a.DB, clientID, 5)
+	if err != nil {
+		t.Fatalf("Get() after update error = %v", err)
+	}
+	if quote.Totals.DeductionMinor != 240 || quote.Totals.PayableMinor != 1200 || len(quote.Totals.DeductionLineTypes) != 1 {
+		t.Fatalf("quote totals = %+v, want 240 deducted from custom lines", quote.Totals)
+	}
+
+	invoiceID, _, err := quoteTx.ConvertToInvoice(ctx, a, clientID, 5, models.QuoteConvertIn{IssueDate: "2026-03-10"})
+	if err != nil {
+		t.Fatalf("ConvertToInvoice() error = %v", err)
+	}
+
+	var (
+		rate      int64
+		lineTypes string
+		deduction int64
+	)
+	if err := a.DB.QueryRow(`
+		SELECT r.deduction_rate, r.deduction_line_types, r.deduction_minor
+		FROM invoices i
+		JOIN invoice_revisions r ON r.id = i.current_revision_id
+		WHERE i.id = ?
+	`, invoiceID).Scan(&rate, &lineTypes, &deduction); err != nil {
+		t.Fatalf("load converted invoice: %v", err)
+	}
+	if rate != 2000 || lineTypes != "custom" || deduction != 240 {
+		t.Fatalf("invoice deduction = %d %q %d, want 2000 custom 240", rate, lineTypes, deduction)
+	}
+}
@@ -0,0 +1,132 @@
+package quoteTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+)
+
+// ErrQuoteStatusTransition is returned when a status change is not allowed.
+var ErrQuoteStatusTransition = errors.New("quote status transition not allowed")
+
+type quoteState struct {
+	ID                 int64
+	Status             string
+	ConvertedInvoiceID sql.NullInt64
+}
+
+func (s quoteState) converted() bool {
+	return s.ConvertedInvoiceID.Valid
+}
+
+func (s quoteState) editable() bool {
+	return !s.converted() && (s.Status == "draft" || s.Status == "sent")
+}
+
+func loadQuoteState(ctx context.Context, tx *sql.Tx, accountID, clientID, quoteNumber int64) (quoteState, error) {
+	var s quoteState
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, status, converted_invoice_id
+		FROM quotes
+		WHERE account_id = ? AND client_id = ? AND quote_number = ?
+	`, accountID, clientID, quoteNumber).Scan(&s.ID, &s.Status, &s.ConvertedInvoiceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return quoteState{}, ErrQuoteNotFound
+		}
+		return quoteState{}, fmt.Errorf("load quote state: %w", err)
+	}
+	return s, nil
+}
+
+// AllowedStatusTransition reports whether a quote may move from one status to
+// another. Converted quotes never change status.
+func AllowedStatusTransition(from, to string, converted bool) bool {
+	if from == to || converted {
+		return false
+	}
+
+	switch from {
+	case "draft":
+		return to == "sent" || to == "accepted" || to == "declined"
+	case "sent":
+		return to == "draft" || to == "accepted" || to == "declined"
+	case "accepted", "declined":
+		return to == "sent"
+	default:
+		return false
+	}
+}
+
+// UpdateStatus moves a quote to next when [AllowedStatusTransition] permits it.
+func UpdateStatus(ctx context.Context, a *app.App, clientID, quoteNumber int64, next string) error {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadQuoteState(ctx, tx, accountID, clientID, quoteNumber)
+	if err != nil {
+		return err
+	}
+	if !AllowedStatusTransition(state.Status, next, state.converted()) {
+		return ErrQuoteStatusTransition
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quotes
+		SET status = ?, updated_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		WHERE id = ?
+	`, next, state.ID); err != nil {
+		return fmt.Errorf("update quote status: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a quote that has not been converted into an invoice.
+func Delete(ctx context.Context, a *app.App, clientID, quoteNumber int64) error {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	state, err := loadQuoteState(ctx, tx, accountID, clientID, quoteNumber)
+	if err != nil {
+		return err
+	}
+	if state.converted() {
+		return ErrQuoteLocked
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM quotes
+		WHERE id = ?
+	`, state.ID); err != nil {
+		return fmt.Errorf("delete quote: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package recurringTx
+
+import (
+	"fmt"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+const (
+	CadenceWeekly  = "weekly"
+	CadenceMonthly = "monthly"
+	CadenceYearly  = "yearly"
+)
+
+// NextRunDate returns the run date that follows from for the given cadence.
+//
+// Weekly schedules ignore dayOfMonth. Monthly and yearly schedules land on
+// dayOfMonth, clamped to the last day of short months (31 -> 30 Apr, 28/29 Feb).
+func NextRunDate(cadence string, dayOfMonth int64, from time.Time) (time.Time, error) {
+	switch cadence {
+	case CadenceWeekly:
+		return from.AddDate(0, 0, 7), nil
+	case CadenceMonthly:
+		if dayOfMonth < 1 || dayOfMonth > 31 {
+			return time.Time{}, fmt.Errorf("invalid day of month %d", dayOfMonth)
+		}
+		return clampedDate(from.Year(), from.Month()+1, dayOfMonth), nil
+	case CadenceYearly:
+		if dayOfMonth < 1 || dayOfMonth > 31 {
+			return time.Time{}, fmt.Errorf("invalid day of month %d", dayOfMonth)
+		}
+		return clampedDate(from.Year()+1, from.Month(), dayOfMonth), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown cadence %q", cadence)
+	}
+}
+
+// clampedDate builds year-month-day, normalising month overflow first and then
+// clamping day to the month's length.
+func clampedDate(year int, month time.Month, day int64) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := int64(first.AddDate(0, 1, -1).Day())
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, int(day)-1)
+}
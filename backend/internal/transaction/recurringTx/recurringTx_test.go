@@ -0,0 +1,305 @@
+package recurringTx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/db"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/recurringTx"
+)
+
+func newTestApp(t *testing.T) (*app.App, func()) {
+	t.Helper()
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.sqlite")
+
+	d, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	if err := db.Migrate(context.Background(), d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	a := &app.App{DB: d}
+	cleanup := func() {
+		_ = d.Close()
+		_ = os.Remove(dbPath)
+	}
+	return a, cleanup
+}
+
+func insertClient(t *testing.T, a *app.App, name string) int64 {
+	t.Helper()
+
+	res, err := a.DB.Exec(`INSERT INTO clients (name, address) VALUES (?, ?)`, name, "1 High Street")
+	if err != nil {
+		t.Fatalf("insert client: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("client lastInsertId: %v", err)
+	}
+	return id
+}
+
+func createSourceInvoice(t *testing.T, ctx context.Context, a *app.App, clientID, baseNumber int64) {
+	t.Helper()
+
+	dueBy := "2026-03-15"
+	_, _, err := invoiceTx.Create(ctx, a, &models.FEInvoiceIn{
+		Overview: models.InvoiceCreateIn{
+			ClientID:   clientID,
+			BaseNumber: baseNumber,
+			IssueDate:  "2026-03-01",
+			DueByDate:  &dueBy,
+			ClientName: "Old Name",
+		},
+		Lines: []models.LineCreateIn{
+			{
+				Name:           "Monthly retainer",
+				LineType:       "custom",
+				PricingMode:    "flat",
+				Quantity:       models.WholeQuantity(2),
+				UnitPriceMinor: 500,
+				LineTotalMinor: 1000,
+				SortOrder:      1,
+			},
+		},
+		Totals: models.TotalsCreateIn{
+			VATRate:           2000,
+			VatAmountMinor:    200,
+			DiscountType:      "none",
+			DepositType:       "none",
+			SubtotalMinor:     1000,
+			SubtotalAfterDisc: 1000,
+			TotalMinor:        1200,
+			BalanceDue:        1200,
+		},
+	})
+	if err != nil {
+		t.Fatalf("create source invoice: %v", err)
+	}
+}
+
+func TestNextRunDate(t *testing.T) {
+	tests := []struct {
+		name       string
+		cadence    string
+		dayOfMonth int64
+		from       string
+		want       string
+	}{
+		{name: "weekly", cadence: recurringTx.CadenceWeekly, from: "2026-03-30", want: "2026-04-06"},
+		{name: "monthly", cadence: recurringTx.CadenceMonthly, dayOfMonth: 15, from: "2026-03-15", want: "2026-04-15"},
+		{name: "monthly clamps to short month", cadence: recurringTx.CadenceMonthly, dayOfMonth: 31, from: "2026-01-31", want: "2026-02-28"},
+		{name: "monthly recovers after clamp", cadence: recurringTx.CadenceMonthly, dayOfMonth: 31, from: "2026-02-28", want: "2026-03-31"},
+		{name: "monthly crosses year", cadence: recurringTx.CadenceMonthly, dayOfMonth: 5, from: "2026-12-05", want: "2027-01-05"},
+		{name: "yearly leap day", cadence: recurringTx.CadenceYearly, dayOfMonth: 29, from: "2028-02-29", want: "2029-02-28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, err := time.Parse("2006-01-02", tt.from)
+			if err != nil {
+				t.Fatalf("parse from: %v", err)
+			}
+
+			got, err := recurringTx.NextRunDate(tt.cadence, tt.dayOfMonth, from)
+			if err != nil {
+				t.Fatalf("NextRunDate() error = %v", err)
+			}
+			if got.Format("2006-01-02") != tt.want {
+				t.Fatalf("NextRunDate() = %s, want %s", got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSchedule_CreatesInvoiceAndAdvances(t *testing.T) {
+	tests := []struct {
+		name       string
+		autoIssue  bool
+		wantStatus string
+	}{
+		{name: "draft", autoIssue: false, wantStatus: "draft"},
+		{name: "auto issue", autoIssue: true, wantStatus: "issued"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+			a, cleanup := newTestApp(t)
+			defer cleanup()
+
+			clientID := insertClient(t, a, "Current Name")
+			createSourceInvoice(t, ctx, a, clientID, 7)
+
+			day := int64(31)
+			schedule, err := recurringTx.Create(ctx, a, clientID, models.RecurringScheduleIn{
+				SourceBaseNumber: 7,
+				Cadence:          recurringTx.CadenceMonthly,
+				DayOfMonth:       &day,
+				NextRunDate:      "2026-03-31",
+				AutoIssue:        tt.autoIssue,
+			})
+			if err != nil {
+				t.Fatalf("Create(): %v", err)
+			}
+
+			due, err := recurringTx.ListDueSchedules(ctx, a.DB, "2026-03-30")
+			if err != nil {
+				t.Fatalf("ListDueSchedules(before): %v", err)
+			}
+			if len(due) != 0 {
+				t.Fatalf("due before run date = %d, want 0", len(due))
+			}
+
+			result, err := recurringTx.RunSchedule(ctx, a.DB, schedule.ID, "2026-03-31")
+			if err != nil {
+				t.Fatalf("RunSchedule(): %v", err)
+			}
+			if result.BaseNumber != 8 {
+				t.Fatalf("BaseNumber = %d, want 8", result.BaseNumber)
+			}
+			if result.NextRunDate != "2026-04-30" {
+				t.Fatalf("NextRunDate = %q, want 2026-04-30", result.NextRunDate)
+			}
+
+			var (
+				status     string
+				issueDate  string
+				dueBy      string
+				clientName string
+				totalMinor int64
+				lineCount  int64
+			)
+			if err := a.DB.QueryRow(`
+				SELECT i.status, r.issue_date, r.due_by_date, r.client_name, r.total_minor,
+					(SELECT COUNT(*) FROM invoice_items it WHERE it.invoice_revision_id = r.id)
+				FROM invoices i
+				JOIN invoice_revisions r ON r.id = i.current_revision_id
+				WHERE i.id = ?
+			`, result.InvoiceID).Scan(&status, &issueDate, &dueBy, &clientName, &totalMinor, &lineCount); err != nil {
+				t.Fatalf("load generated invoice: %v", err)
+			}
+			if status != tt.wantStatus {
+				t.Fatalf("status = %q, want %q", status, tt.wantStatus)
+			}
+			if issueDate != "2026-03-31" || dueBy != "2026-04-14" {
+				t.Fatalf("dates = %s/%s, want 2026-03-31/2026-04-14", issueDate, dueBy)
+			}
+			if clientName != "Current Name" {
+				t.Fatalf("client name = %q, want live client record", clientName)
+			}
+			if totalMinor != 1200 || lineCount != 1 {
+				t.Fatalf("total/lines = %d/%d, want 1200/1", totalMinor, lineCount)
+			}
+
+			var statusAudits int
+			if err := a.DB.QueryRow(`
+				SELECT COUNT(*)
+				FROM audit_log
+				WHERE invoice_id = ? AND action = ?
+			`, result.InvoiceID, invoiceTx.AuditStatusChanged).Scan(&statusAudits); err != nil {
+				t.Fatalf("count status audit entries: %v", err)
+			}
+			if tt.autoIssue && statusAudits != 1 {
+				t.Fatalf("status audit entries = %d, want 1 for auto issue", statusAudits)
+			}
+			if !tt.autoIssue && statusAudits != 0 {
+				t.Fatalf("status audit entries = %d, want 0 for draft", statusAudits)
+			}
+
+			next, err := invoiceTx.GetSuggestedNextBaseNumber(ctx, a)
+			if err != nil {
+				t.Fatalf("GetSuggestedNextBaseNumber(): %v", err)
+			}
+			if next != 9 {
+				t.Fatalf("next base number = %d, want 9", next)
+			}
+
+			if _, err := recurringTx.RunSchedule(ctx, a.DB, schedule.ID, "2026-03-31"); !errors.Is(err, recurringTx.ErrScheduleNotDue) {
+				t.Fatalf("second RunSchedule() error = %v, want %v", err, recurringTx.ErrScheduleNotDue)
+			}
+		})
+	}
+}
+
+func TestRunSchedule_KeepsSourceCurrency(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	clientID := insertClient(t, a, "Euro Client")
+	createSourceInvoice(t, ctx, a, clientID, 3)
+	if _, err := a.DB.Exec(`
+		UPDATE invoice_revisions
+		SET currency = 'EUR', exchange_rate_micro = 850000
+		WHERE invoice_id = (SELECT id FROM invoices WHERE base_number = 3)
+	`); err != nil {
+		t.Fatalf("set source currency: %v", err)
+	}
+
+	schedule, err := recurringTx.Create(ctx, a, clientID, models.RecurringScheduleIn{
+		SourceBaseNumber: 3,
+		Cadence:          recurringTx.CadenceWeekly,
+		NextRunDate:      "2026-04-06",
+	})
+	if err != nil {
+		t.Fatalf("Create(): %v", err)
+	}
+
+	result, err := recurringTx.RunSchedule(ctx, a.DB, schedule.ID, "2026-04-06")
+	if err != nil {
+		t.Fatalf("RunSchedule(): %v", err)
+	}
+
+	var (
+		currency string
+		rate     int64
+	)
+	if err := a.DB.QueryRow(`
+		SELECT r.currency, r.exchange_rate_micro
+		FROM invoices i
+		JOIN invoice_revisions r ON r.id = i.current_revision_id
+		WHERE i.id = ?
+	`, result.InvoiceID).Scan(&currency, &rate); err != nil {
+		t.Fatalf("load generated invoice: %v", err)
+	}
+	if currency != "EUR" || rate != 850000 {
+		t.Fatalf("currency/rate = %s/%d, want EUR/850000", currency, rate)
+	}
+}
+
+func TestCreate_RejectsSourceInvoiceFromAnotherClient(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	ownerID := insertClient(t, a, "Owner")
+	otherID := insertClient(t, a, "Other")
+	createSourceInvoice(t, ctx, a, ownerID, 3)
+
+	_, err := recurringTx.Create(ctx, a, otherID, models.RecurringScheduleIn{
+		SourceBaseNumber: 3,
+		Cadence:          recurringTx.CadenceWeekly,
+		NextRunDate:      "2026-04-01",
+	})
+	if !errors.Is(err, recurringTx.ErrSourceInvoiceNotFound) {
+		t.Fatalf("Create() error = %v, want %v", err, recurringTx.ErrSourceInvoiceNotFound)
+	}
+}
@@ -0,0 +1,323 @@
+package recurringTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+)
+
+// ErrScheduleNotDue is returned by [RunSchedule] when the schedule was paused,
+// deleted or already advanced since it was listed as due, or is not yet due in
+// its workspace's time zone.
+var ErrScheduleNotDue = errors.New("recurring schedule not due")
+
+// DueSchedule identifies a schedule that should run. It is returned by
+// [ListDueSchedules] across all accounts, so the caller must scope ctx with
+// AccountID before calling [RunSchedule].
+type DueSchedule struct {
+	ID        int64
+	AccountID int64
+}
+
+// RunResult describes the invoice generated by one schedule run.
+type RunResult struct {
+	InvoiceID   int64
+	BaseNumber  int64
+	Status      string
+	NextRunDate string
+}
+
+// ListDueSchedules returns active schedules whose next run date is on or
+// before date (YYYY-MM-DD). It is not account scoped; only the background
+// worker should call it, passing the latest date any workspace has reached.
+// [RunSchedule] rechecks each schedule against its workspace's own date.
+func ListDueSchedules(ctx context.Context, db *sql.DB, date string) ([]DueSchedule, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT id, account_id
+		FROM recurring_schedules
+		WHERE active = 1
+		  AND next_run_date <= ?
+		ORDER BY next_run_date ASC, id ASC
+	`, date)
+	if err != nil {
+		return nil, fmt.Errorf("list due recurring schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var out []DueSchedule
+	for rows.Next() {
+		var s DueSchedule
+		if err := rows.Scan(&s.ID, &s.AccountID); err != nil {
+			return nil, fmt.Errorf("scan due recurring schedule: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("due recurring schedule rows: %w", err)
+	}
+	return out, nil
+}
+
+// RunSchedule generates one invoice from a due schedule and advances it by a
+// single period, all in one transaction. The invoice is issued on the
+// schedule's run date, so a schedule that fell behind catches up one period
+// per call rather than skipping missed periods.
+func RunSchedule(ctx context.Context, db *sql.DB, scheduleID int64, today string) (RunResult, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return RunResult{}, err
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return RunResult{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var (
+		clientID         int64
+		sourceRevisionID int64
+		cadence          string
+		dayOfMonth       sql.NullInt64
+		runDate          string
+		autoIssue        bool
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT client_id, source_revision_id, cadence, day_of_month, next_run_date, auto_issue
+		FROM recurring_schedules
+		WHERE id = ?
+		  AND account_id = ?
+		  AND active = 1
+		  AND next_run_date <= ?
+	`, scheduleID, accountID, today).Scan(&clientID, &sourceRevisionID, &cadence, &dayOfMonth, &runDate, &autoIssue)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RunResult{}, ErrScheduleNotDue
+		}
+		return RunResult{}, fmt.Errorf("load recurring schedule: %w", err)
+	}
+
+	runAt, err := time.Parse(dateLayout, runDate)
+	if err != nil {
+		return RunResult{}, fmt.Errorf("parse recurring run date %q: %w", runDate, err)
+	}
+	nextAt, err := NextRunDate(cadence, dayOfMonth.Int64, runAt)
+	if err != nil {
+		return RunResult{}, err
+	}
+
+	canonical, err := loadSourceInvoice(ctx, tx, clientID, sourceRevisionID, runAt)
+	if err != nil {
+		return RunResult{}, err
+	}
+
+	invoiceID, _, baseNumber, err := invoiceTx.CreateNextInTx(ctx, tx, canonical)
+	if err != nil {
+		return RunResult{}, fmt.Errorf("create recurring invoice: %w", err)
+	}
+
+	status := "draft"
+	if autoIssue {
+		status = "issued"
+		if err := invoiceTx.UpdateInvoiceStatusInTx(ctx, tx, invoiceID, "draft", status); err != nil {
+			return RunResult{}, fmt.Errorf("issue recurring invoice: %w", err)
+		}
+	}
+
+	nextRunDate := nextAt.Format(dateLayout)
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE recurring_schedules
+		SET
+			next_run_date = ?,
+			last_run_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now')),
+			last_invoice_id = ?,
+			updated_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		WHERE id = ?
+	`, nextRunDate, invoiceID, scheduleID); err != nil {
+		return RunResult{}, fmt.Errorf("advance recurring schedule: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RunResult{}, fmt.Errorf("commit recurring run: %w", err)
+	}
+
+	return RunResult{
+		InvoiceID:   invoiceID,
+		BaseNumber:  baseNumber,
+		Status:      status,
+		NextRunDate: nextRunDate,
+	}, nil
+}
+
+// loadSourceInvoice copies a revision into a fresh invoice payload dated
+// runAt. Client details come from the live client record, the due date keeps
+// the source's issue-to-due gap and no payments are carried over. The
+// currency and exchange rate are the source's, so amounts keep their meaning.
+func loadSourceInvoice(
+	ctx context.Context,
+	tx *sql.Tx,
+	clientID int64,
+	revisionID int64,
+	runAt time.Time,
+) (*models.FEInvoiceIn, error) {
+	var (
+		out                models.FEInvoiceIn
+		sourceIssue        string
+		sourceDue          sql.NullString
+		note               sql.NullString
+		deductionLineTypes string
+	)
+	ov := &out.Overview
+	tot := &out.Totals
+
+	err := tx.QueryRowContext(ctx, `
+		SELECT
+			r.issue_date,
+			r.due_by_date,
+			r.note,
+			c.name,
+			COALESCE(c.company_name, ''),
+			COALESCE(c.address, ''),
+			COALESCE(c.email, ''),
+			r.currency,
+			r.exchange_rate_micro,
+			COALESCE(NULLIF(c.vat_number, ''), r.client_vat_number),
+			r.vat_treatment,
+			r.vat_rate,
+			r.vat_amount_minor,
+			r.discount_type,
+			r.discount_rate,
+			r.discount_minor,
+			r.deposit_type,
+			r.deposit_rate,
+			r.deposit_minor,
+			r.deduction_rate,
+			r.deduction_line_types,
+			r.deduction_minor,
+			r.subtotal_minor,
+			r.total_minor
+		FROM invoice_revisions r
+		JOIN invoices i
+			ON i.id = r.invoice_id
+		JOIN clients c
+			ON c.id = i.client_id AND c.account_id = i.account_id
+		WHERE r.id = ? AND i.client_id = ?
+	`, revisionID, clientID).Scan(
+		&sourceIssue, &sourceDue, &note,
+		&ov.ClientName, &ov.ClientCompanyName, &ov.ClientAddress, &ov.ClientEmail,
+		&ov.Currency, &ov.ExchangeRateMicro,
+		&ov.ClientVATNumber, &ov.VATTreatment,
+		&tot.VATRate, &tot.VatAmountMinor,
+		&tot.DiscountType, &tot.DiscountRate, &tot.DiscountMinor,
+		&tot.DepositType, &tot.DepositRate, &tot.DepositMinor,
+		&tot.DeductionRate, &deductionLineTypes, &tot.DeductionMinor,
+		&tot.SubtotalMinor, &tot.TotalMinor,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSourceInvoiceNotFound
+		}
+		return nil, fmt.Errorf("load recurring source revision: %w", err)
+	}
+
+	ov.ClientID = clientID
+	ov.IssueDate = runAt.Format(dateLayout)
+	if note.Valid {
+		ov.Note = &note.String
+	}
+	if sourceDue.Valid {
+		issueAt, issueErr := time.Parse(dateLayout, sourceIssue)
+		dueAt, dueErr := time.Parse(dateLayout, sourceDue.String)
+		if issueErr == nil && dueErr == nil {
+			due := runAt.Add(dueAt.Sub(issueAt)).Format(dateLayout)
+			ov.DueByDate = &due
+		}
+	}
+
+	tot.SubtotalAfterDisc = tot.SubtotalMinor - tot.DiscountMinor
+	tot.DeductionLineTypes = invoiceTx.SplitDeductionLineTypes(deductionLineTypes)
+	tot.PayableMinor = tot.TotalMinor - tot.DeductionMinor
+	tot.PaidMinor = 0
+	tot.BalanceDue = tot.PayableMinor
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			product_id,
+			name,
+			line_type,
+			pricing_mode,
+			COALESCE(quantity_milli, quantity * 1000),
+			unit,
+			minutes_worked,
+			unit_price_minor,
+			line_total_minor,
+			sort_order,
+			vat_rate,
+			discount_type,
+			discount_rate,
+			discount_minor
+		FROM invoice_items
+		WHERE invoice_revision_id = ?
+		ORDER BY sort_order ASC
+	`, revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("load recurring source lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ln models.LineCreateIn
+		if err := rows.Scan(
+			&ln.ProductID,
+			&ln.Name,
+			&ln.LineType,
+			&ln.PricingMode,
+			&ln.Quantity,
+			&ln.Unit,
+			&ln.MinutesWorked,
+			&ln.UnitPriceMinor,
+			&ln.LineTotalMinor,
+			&ln.SortOrder,
+			&ln.VATRate,
+			&ln.DiscountType,
+			&ln.DiscountRate,
+			&ln.DiscountMinor,
+		); err != nil {
+			return nil, fmt.Errorf("scan recurring source line: %w", err)
+		}
+		out.Lines = append(out.Lines, ln)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("recurring source line rows: %w", err)
+	}
+
+	bandRows, err := tx.QueryContext(ctx, `
+		SELECT vat_rate, net_minor, vat_minor
+		FROM invoice_vat_breakdown
+		WHERE invoice_revision_id = ?
+		ORDER BY vat_rate DESC
+	`, revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("load recurring source vat breakdown: %w", err)
+	}
+	defer bandRows.Close()
+
+	for bandRows.Next() {
+		var band models.VATBand
+		if err := bandRows.Scan(&band.VATRate, &band.NetMinor, &band.VatMinor); err != nil {
+			return nil, fmt.Errorf("scan recurring source vat band: %w", err)
+		}
+		tot.VATBreakdown = append(tot.VATBreakdown, band)
+	}
+	if err := bandRows.Err(); err != nil {
+		return nil, fmt.Errorf("recurring source vat band rows: %w", err)
+	}
+
+	return &out, nil
+}
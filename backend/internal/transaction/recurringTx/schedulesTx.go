@@ -0,0 +1,280 @@
+/*
+The recurringTx package stores recurring invoice schedules and runs them.
+
+A schedule points at a source invoice revision; each run copies that
+revision's lines and totals into a brand new invoice.
+*/
+package recurringTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+// ErrScheduleNotFound is returned when a schedule does not exist for the client.
+var ErrScheduleNotFound = errors.New("recurring schedule not found")
+
+// ErrSourceInvoiceNotFound is returned when the source invoice or revision does not exist for the client.
+var ErrSourceInvoiceNotFound = errors.New("recurring schedule source invoice not found")
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+const scheduleSelectSQL = `
+	SELECT
+		s.id,
+		s.client_id,
+		si.base_number,
+		sr.revision_no,
+		s.cadence,
+		s.day_of_month,
+		s.next_run_date,
+		s.auto_issue,
+		s.active,
+		s.last_run_at,
+		li.base_number,
+		s.created_at,
+		s.updated_at
+	FROM recurring_schedules s
+	JOIN invoices si
+		ON si.id = s.source_invoice_id
+	JOIN invoice_revisions sr
+		ON sr.id = s.source_revision_id
+	LEFT JOIN invoices li
+		ON li.id = s.last_invoice_id
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSchedule(row rowScanner) (models.RecurringScheduleOut, error) {
+	var out models.RecurringScheduleOut
+	err := row.Scan(
+		&out.ID,
+		&out.ClientID,
+		&out.SourceBaseNumber,
+		&out.SourceRevisionNo,
+		&out.Cadence,
+		&out.DayOfMonth,
+		&out.NextRunDate,
+		&out.AutoIssue,
+		&out.Active,
+		&out.LastRunAt,
+		&out.LastInvoiceBaseNumber,
+		&out.CreatedAt,
+		&out.UpdatedAt,
+	)
+	return out, err
+}
+
+// List returns every schedule for the client, soonest run first.
+func List(ctx context.Context, a *app.App, clientID int64) ([]models.RecurringScheduleOut, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := a.DB.QueryContext(ctx, scheduleSelectSQL+`
+		WHERE s.account_id = ? AND s.client_id = ?
+		ORDER BY s.next_run_date ASC, s.id ASC
+	`, accountID, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("list recurring schedules: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]models.RecurringScheduleOut, 0)
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan recurring schedule: %w", err)
+		}
+		out = append(out, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("recurring schedule rows: %w", err)
+	}
+	return out, nil
+}
+
+// Create inserts a schedule for the client. in must already be validated.
+func Create(ctx context.Context, a *app.App, clientID int64, in models.RecurringScheduleIn) (models.RecurringScheduleOut, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.RecurringScheduleOut{}, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return models.RecurringScheduleOut{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	invoiceID, revisionID, err := resolveSource(ctx, tx, accountID, clientID, in.SourceBaseNumber, in.SourceRevisionNo)
+	if err != nil {
+		return models.RecurringScheduleOut{}, err
+	}
+
+	var scheduleID int64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO recurring_schedules (
+			account_id, client_id, source_invoice_id, source_revision_id,
+			cadence, day_of_month, next_run_date, auto_issue, active
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`,
+		accountID, clientID, invoiceID, revisionID,
+		in.Cadence, in.DayOfMonth, in.NextRunDate, in.AutoIssue, isActive(in),
+	).Scan(&scheduleID); err != nil {
+		return models.RecurringScheduleOut{}, fmt.Errorf("insert recurring schedule: %w", err)
+	}
+
+	out, err := getSchedule(ctx, tx, accountID, clientID, scheduleID)
+	if err != nil {
+		return models.RecurringScheduleOut{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.RecurringScheduleOut{}, fmt.Errorf("commit recurring schedule: %w", err)
+	}
+	return out, nil
+}
+
+// Update replaces the editable fields of a schedule. in must already be validated.
+func Update(ctx context.Context, a *app.App, clientID, scheduleID int64, in models.RecurringScheduleIn) (models.RecurringScheduleOut, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.RecurringScheduleOut{}, err
+	}
+
+	tx, err := a.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return models.RecurringScheduleOut{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	invoiceID, revisionID, err := resolveSource(ctx, tx, accountID, clientID, in.SourceBaseNumber, in.SourceRevisionNo)
+	if err != nil {
+		return models.RecurringScheduleOut{}, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE recurring_schedules
+		SET
+			source_invoice_id = ?,
+			source_revision_id = ?,
+			cadence = ?,
+			day_of_month = ?,
+			next_run_date = ?,
+			auto_issue = ?,
+			active = ?,
+			updated_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		WHERE id = ? AND account_id = ? AND client_id = ?
+	`,
+		invoiceID, revisionID,
+		in.Cadence, in.DayOfMonth, in.NextRunDate, in.AutoIssue, isActive(in),
+		scheduleID, accountID, clientID,
+	)
+	if err != nil {
+		return models.RecurringScheduleOut{}, fmt.Errorf("update recurring schedule: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return models.RecurringScheduleOut{}, fmt.Errorf("update recurring schedule rows affected: %w", err)
+	}
+	if n == 0 {
+		return models.RecurringScheduleOut{}, ErrScheduleNotFound
+	}
+
+	out, err := getSchedule(ctx, tx, accountID, clientID, scheduleID)
+	if err != nil {
+		return models.RecurringScheduleOut{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.RecurringScheduleOut{}, fmt.Errorf("commit recurring schedule: %w", err)
+	}
+	return out, nil
+}
+
+// Delete removes a schedule. Invoices it already generated are kept.
+func Delete(ctx context.Context, a *app.App, clientID, scheduleID int64) error {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := a.DB.ExecContext(ctx, `
+		DELETE FROM recurring_schedules
+		WHERE id = ? AND account_id = ? AND client_id = ?
+	`, scheduleID, accountID, clientID)
+	if err != nil {
+		return fmt.Errorf("delete recurring schedule: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete recurring schedule rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrScheduleNotFound
+	}
+	return nil
+}
+
+func getSchedule(ctx context.Context, q queryRower, accountID, clientID, scheduleID int64) (models.RecurringScheduleOut, error) {
+	out, err := scanSchedule(q.QueryRowContext(ctx, scheduleSelectSQL+`
+		WHERE s.id = ? AND s.account_id = ? AND s.client_id = ?
+	`, scheduleID, accountID, clientID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.RecurringScheduleOut{}, ErrScheduleNotFound
+		}
+		return models.RecurringScheduleOut{}, fmt.Errorf("load recurring schedule: %w", err)
+	}
+	return out, nil
+}
+
+// resolveSource maps a client's base number and optional revision number to
+// row ids. A nil revisionNo selects the invoice's current revision.
+func resolveSource(
+	ctx context.Context,
+	tx *sql.Tx,
+	accountID int64,
+	clientID int64,
+	baseNumber int64,
+	revisionNo *int64,
+) (invoiceID, revisionID int64, err error) {
+	err = tx.QueryRowContext(ctx, `
+		SELECT i.id, r.id
+		FROM invoices i
+		JOIN invoice_revisions r
+			ON r.invoice_id = i.id
+		WHERE i.account_id = ?
+		  AND i.client_id = ?
+		  AND i.base_number = ?
+		  AND (
+			(? IS NULL AND r.id = i.current_revision_id) OR
+			r.revision_no = ?
+		  )
+	`, accountID, clientID, baseNumber, revisionNo, revisionNo).Scan(&invoiceID, &revisionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, ErrSourceInvoiceNotFound
+		}
+		return 0, 0, fmt.Errorf("resolve recurring source invoice: %w", err)
+	}
+	return invoiceID, revisionID, nil
+}
+
+func isActive(in models.RecurringScheduleIn) bool {
+	return in.Active == nil || *in.Active
+}
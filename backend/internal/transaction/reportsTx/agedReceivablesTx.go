@@ -0,0 +1,135 @@
+package reportsTx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+// addToBucket adds amount to the bucket for daysOverdue (<= 0 is current).
+func addToBucket(b *models.AgedBuckets, daysOverdue int64, amount int64) {
+	switch {
+	case daysOverdue <= 0:
+		b.CurrentMinor += amount
+	case daysOverdue <= 30:
+		b.Days1To30Minor += amount
+	case daysOverdue <= 60:
+		b.Days31To60Minor += amount
+	case daysOverdue <= 90:
+		b.Days61To90Minor += amount
+	default:
+		b.Days90PlusMinor += amount
+	}
+	b.TotalMinor += amount
+}
+
+// QueryAgedReceivables buckets the outstanding balance of every issued invoice
+// by days past due as of asOf (YYYY-MM-DD), grouped per client. Balances are
+// converted to the workspace currency at each invoice's exchange rate.
+func QueryAgedReceivables(ctx context.Context, db *sql.DB, asOf string) (models.AgedReceivablesReport, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.AgedReceivablesReport{}, err
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		WITH paid_totals AS (
+			SELECT
+				rp.revision_id AS applied_in_revision_id,
+				rp.paid_minor
+			FROM invoice_revision_paid rp
+		),
+		credit_totals AS (
+			SELECT
+				cn.invoice_id,
+				COALESCE(SUM(cn.total_minor), 0) AS credited_minor
+			FROM credit_notes cn
+			GROUP BY cn.invoice_id
+		),
+		outstanding AS (
+			SELECT
+				i.client_id,
+				cur.due_by_date,
+				cur.exchange_rate_micro,
+				cur.total_minor - cur.deduction_minor - COALESCE(pt.paid_minor, 0) - COALESCE(ct.credited_minor, 0) AS balance_minor
+			FROM invoices i
+			JOIN invoice_revisions cur
+				ON cur.id = i.current_revision_id
+			LEFT JOIN paid_totals pt
+				ON pt.applied_in_revision_id = cur.id
+			LEFT JOIN credit_totals ct
+				ON ct.invoice_id = i.id
+			WHERE i.account_id = ?
+			  AND i.status = 'issued'
+		)
+		SELECT
+			o.client_id,
+			c.name,
+			COALESCE(c.company_name, ''),
+			CAST(ROUND(o.balance_minor * o.exchange_rate_micro / 1000000.0) AS INTEGER) AS balance_base_minor,
+			CASE
+				WHEN o.due_by_date IS NULL THEN 0
+				ELSE CAST(julianday(?) - julianday(o.due_by_date) AS INTEGER)
+			END AS days_overdue
+		FROM outstanding o
+		JOIN clients c
+			ON c.id = o.client_id
+		WHERE o.balance_minor > 0
+		ORDER BY c.name COLLATE NOCASE ASC, o.client_id ASC
+	`, accountID, asOf)
+	if err != nil {
+		return models.AgedReceivablesReport{}, fmt.Errorf("query aged receivables: %w", err)
+	}
+	defer rows.Close()
+
+	out := models.AgedReceivablesReport{
+		AsOf:    asOf,
+		Clients: make([]models.AgedReceivablesClient, 0),
+	}
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE((
+			SELECT currency
+			FROM account_settings
+			WHERE account_id = ?
+		), 'GBP');
+	`, accountID).Scan(&out.BaseCurrency); err != nil {
+		return models.AgedReceivablesReport{}, fmt.Errorf("load workspace currency: %w", err)
+	}
+	indexByClient := make(map[int64]int)
+
+	for rows.Next() {
+		var (
+			clientID    int64
+			name        string
+			companyName string
+			balance     int64
+			daysOverdue int64
+		)
+		if err := rows.Scan(&clientID, &name, &companyName, &balance, &daysOverdue); err != nil {
+			return models.AgedReceivablesReport{}, fmt.Errorf("scan aged receivable: %w", err)
+		}
+
+		idx, ok := indexByClient[clientID]
+		if !ok {
+			idx = len(out.Clients)
+			indexByClient[clientID] = idx
+			out.Clients = append(out.Clients, models.AgedReceivablesClient{
+				ClientID:          clientID,
+				ClientName:        name,
+				ClientCompanyName: companyName,
+			})
+		}
+
+		out.Clients[idx].InvoiceCount++
+		addToBucket(&out.Clients[idx].AgedBuckets, daysOverdue, balance)
+		addToBucket(&out.Totals, daysOverdue, balance)
+	}
+	if err := rows.Err(); err != nil {
+		return models.AgedReceivablesReport{}, fmt.Errorf("aged receivable rows: %w", err)
+	}
+
+	return out, nil
+}
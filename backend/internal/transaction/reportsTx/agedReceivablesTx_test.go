@@ -0,0 +1,135 @@
+package reportsTx_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/app"
+	"github.com/viktorHadz/goInvoice26/internal/db"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/reportsTx"
+)
+
+func newTestApp(t *testing.T) (*app.App, func()) {
+	t.Helper()
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "test.sqlite")
+
+	d, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+
+	if err := db.Migrate(context.Background(), d); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	a := &app.App{DB: d}
+	cleanup := func() {
+		_ = d.Close()
+		_ = os.Remove(dbPath)
+	}
+	return a, cleanup
+}
+
+func insertClient(t *testing.T, a *app.App, name string) int64 {
+	t.Helper()
+
+	res, err := a.DB.Exec(`INSERT INTO clients (name) VALUES (?)`, name)
+	if err != nil {
+		t.Fatalf("insert client: %v", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		t.Fatalf("client lastInsertId: %v", err)
+	}
+	return id
+}
+
+func insertInvoice(t *testing.T, a *app.App, clientID, baseNumber int64, status string, dueBy any, totalMinor, paidMinor int64) {
+	t.Helper()
+
+	res, err := a.DB.Exec(`
+		INSERT INTO invoices (account_id, client_id, base_number, status)
+		VALUES (1, ?, ?, ?)
+	`, clientID, baseNumber, status)
+	if err != nil {
+		t.Fatalf("insert invoice: %v", err)
+	}
+	invoiceID, _ := res.LastInsertId()
+
+	res, err = a.DB.Exec(`
+		INSERT INTO invoice_revisions (
+			invoice_id, revision_no, issue_date, due_by_date, client_name,
+			vat_rate, discount_type, discount_rate, discount_minor,
+			deposit_type, deposit_rate, deposit_minor,
+			subtotal_minor, vat_amount_minor, total_minor
+		) VALUES (?, 1, '2026-01-01', ?, 'Client', 0, 'none', 0, 0, 'none', 0, 0, ?, 0, ?)
+	`, invoiceID, dueBy, totalMinor, totalMinor)
+	if err != nil {
+		t.Fatalf("insert revision: %v", err)
+	}
+	revisionID, _ := res.LastInsertId()
+
+	if _, err := a.DB.Exec(`UPDATE invoices SET current_revision_id = ? WHERE id = ?`, revisionID, invoiceID); err != nil {
+		t.Fatalf("set current revision: %v", err)
+	}
+
+	if paidMinor > 0 {
+		if _, err := a.DB.Exec(`
+			INSERT INTO payments (invoice_id, payment_type, amount_minor, payment_date, applied_in_revision_id)
+			VALUES (?, 'payment', ?, '2026-02-01', ?)
+		`, invoiceID, paidMinor, revisionID); err != nil {
+			t.Fatalf("insert payment: %v", err)
+		}
+	}
+}
+
+func TestQueryAgedReceivables_BucketsByDaysPastDue(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	acme := insertClient(t, a, "Acme")
+	bravo := insertClient(t, a, "Bravo")
+
+	insertInvoice(t, a, acme, 1, "issued", "2026-05-01", 1000, 0)   // not yet due
+	insertInvoice(t, a, acme, 2, "issued", nil, 500, 0)             // no due date
+	insertInvoice(t, a, acme, 3, "issued", "2026-03-31", 2000, 500) // 30 days
+	insertInvoice(t, a, acme, 4, "issued", "2026-01-30", 4000, 0)   // 90 days
+	insertInvoice(t, a, bravo, 5, "issued", "2026-01-29", 3000, 0)  // 91 days
+	insertInvoice(t, a, bravo, 6, "issued", "2026-03-01", 800, 0)   // 60 days
+	insertInvoice(t, a, bravo, 7, "paid", "2026-01-01", 900, 900)
+	insertInvoice(t, a, bravo, 8, "draft", "2026-01-01", 700, 0)
+	insertInvoice(t, a, bravo, 9, "void", "2026-01-01", 600, 0)
+
+	got, err := reportsTx.QueryAgedReceivables(ctx, a.DB, "2026-04-30")
+	if err != nil {
+		t.Fatalf("QueryAgedReceivables() error = %v", err)
+	}
+
+	if len(got.Clients) != 2 {
+		t.Fatalf("clients = %d, want 2", len(got.Clients))
+	}
+
+	wantAcme := models.AgedBuckets{CurrentMinor: 1500, Days1To30Minor: 1500, Days61To90Minor: 4000, TotalMinor: 7000}
+	if got.Clients[0].ClientID != acme || got.Clients[0].AgedBuckets != wantAcme || got.Clients[0].InvoiceCount != 4 {
+		t.Fatalf("acme = %+v, want %+v across 4 invoices", got.Clients[0], wantAcme)
+	}
+
+	wantBravo := models.AgedBuckets{Days31To60Minor: 800, Days90PlusMinor: 3000, TotalMinor: 3800}
+	if got.Clients[1].ClientID != bravo || got.Clients[1].AgedBuckets != wantBravo {
+		t.Fatalf("bravo = %+v, want %+v", got.Clients[1], wantBravo)
+	}
+
+	if got.Totals.TotalMinor != 10800 || got.Totals.Days90PlusMinor != 3000 {
+		t.Fatalf("totals = %+v, want total 10800 with 3000 over 90 days", got.Totals)
+	}
+}
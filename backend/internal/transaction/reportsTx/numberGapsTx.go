@@ -0,0 +1,463 @@
+package reportsTx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/service/invoiceformat"
+)
+
+// QueryInvoiceNumberGaps scans the account's invoice base numbers from the
+// first one ever used to the last one the sequence handed out and explains
+// each missing number from the deletion tombstones and the log of manual
+// starting number changes. Each template sequence is checked the same way.
+func QueryInvoiceNumberGaps(ctx context.Context, db *sql.DB) (models.InvoiceNumberGapReport, error) {
+	accountID, err := accountscope.Require(ctx)
+	if err != nil {
+		return models.InvoiceNumberGapReport{}, err
+	}
+
+	out := models.InvoiceNumberGapReport{
+		Gaps:    make([]models.InvoiceNumberGap, 0),
+		Periods: make([]models.InvoiceNumberPeriod, 0),
+	}
+
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE((
+			SELECT next_base_number
+			FROM invoice_number_seq
+			WHERE account_id = ?
+		), 1);
+	`, accountID).Scan(&out.SequenceNext); err != nil {
+		return models.InvoiceNumberGapReport{}, fmt.Errorf("load invoice number sequence: %w", err)
+	}
+
+	numbers, err := queryBaseNumbers(ctx, db, accountID)
+	if err != nil {
+		return models.InvoiceNumberGapReport{}, err
+	}
+	tombstones, err := queryTombstones(ctx, db, accountID)
+	if err != nil {
+		return models.InvoiceNumberGapReport{}, err
+	}
+	changes, err := queryStartChanges(ctx, db, accountID)
+	if err != nil {
+		return models.InvoiceNumberGapReport{}, err
+	}
+
+	periods, err := queryPeriodGaps(ctx, db, accountID, tombstones)
+	if err != nil {
+		return models.InvoiceNumberGapReport{}, err
+	}
+	out.Periods = append(out.Periods, periods...)
+
+	// A base number deleted more than once is explained by its latest deletion.
+	deletions := make(map[int64]models.InvoiceNumberDeletion, len(tombstones))
+	for _, t := range tombstones {
+		deletions[t.BaseNumber] = t.Deletion
+	}
+
+	out.InvoiceCount = int64(len(numbers))
+	if len(numbers) == 0 && len(deletions) == 0 {
+		return out, nil
+	}
+
+	// The first number is the lowest one ever used or started from, so
+	// numbers below the very first starting number are not gaps.
+	first := int64(0)
+	last := out.SequenceNext - 1
+	consider := func(n int64) {
+		if first == 0 || n < first {
+			first = n
+		}
+		if n > last {
+			last = n
+		}
+	}
+	for _, n := range numbers {
+		consider(n)
+	}
+	for n := range deletions {
+		consider(n)
+	}
+	for _, c := range changes {
+		if c.ToNumber < first {
+			first = c.ToNumber
+		}
+	}
+	out.FirstNumber = first
+	out.LastNumber = last
+	if len(numbers) > 0 && out.SequenceNext <= numbers[len(numbers)-1] {
+		out.SequenceBehind = true
+	}
+
+	next := first
+	for _, n := range numbers {
+		if n > next {
+			out.Gaps = explainGap(out.Gaps, next, n-1, deletions, changes)
+		}
+		next = n + 1
+	}
+	if next <= last {
+		out.Gaps = explainGap(out.Gaps, next, last, deletions, changes)
+	}
+
+	return out, nil
+}
+
+// explainGap appends the gaps covering the missing numbers from..to. Each
+// deleted number gets its own gap; the runs between them are explained by
+// starting number changes where one skipped them.
+func explainGap(
+	gaps []models.InvoiceNumberGap,
+	from, to int64,
+	deletions map[int64]models.InvoiceNumberDeletion,
+	changes []models.InvoiceNumberStartChange,
+) []models.InvoiceNumberGap {
+	deleted := make([]int64, 0)
+	for n := range deletions {
+		if n >= from && n <= to {
+			deleted = append(deleted, n)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i] < deleted[j] })
+
+	cur := from
+	for _, n := range deleted {
+		gaps = explainSkipped(gaps, cur, n-1, changes)
+
+		deletion := deletions[n]
+		reason := models.NumberGapDeletedInvoice
+		if deletion.Status == "draft" {
+			reason = models.NumberGapDeletedDraft
+		}
+		gaps = append(gaps, models.InvoiceNumberGap{
+			FromNumber: n,
+			ToNumber:   n,
+			Reason:     reason,
+			Deletion:   &deletion,
+		})
+		cur = n + 1
+	}
+	return explainSkipped(gaps, cur, to, changes)
+}
+
+// explainSkipped appends gaps for numbers from..to that never had an invoice.
+func explainSkipped(gaps []models.InvoiceNumberGap, from, to int64, changes []models.InvoiceNumberStartChange) []models.InvoiceNumberGap {
+	for from <= to {
+		// The change covering from, or else the first one starting after it.
+		var (
+			covering *models.InvoiceNumberStartChange
+			nextFrom = to + 1
+		)
+		for i := range changes {
+			c := &changes[i]
+			if c.FromNumber <= from && from < c.ToNumber {
+				covering = c
+				break
+			}
+			if c.FromNumber > from && c.FromNumber < nextFrom && c.FromNumber < c.ToNumber {
+				nextFrom = c.FromNumber
+			}
+		}
+
+		if covering == nil {
+			gaps = append(gaps, models.InvoiceNumberGap{
+				FromNumber: from,
+				ToNumber:   nextFrom - 1,
+				Reason:     models.NumberGapUnknown,
+			})
+			from = nextFrom
+			continue
+		}
+
+		end := min(covering.ToNumber-1, to)
+		change := *covering
+		gaps = append(gaps, models.InvoiceNumberGap{
+			FromNumber:  from,
+			ToNumber:    end,
+			Reason:      models.NumberGapStartChanged,
+			StartChange: &change,
+		})
+		from = end + 1
+	}
+	return gaps
+}
+
+// queryPeriodGaps checks each template sequence from 1 up to the next number
+// it would hand out. Sequences are keyed by period alone, so a number taken
+// under an earlier template still fills its place.
+func queryPeriodGaps(ctx context.Context, db *sql.DB, accountID int64, tombstones []tombstone) ([]models.InvoiceNumberPeriod, error) {
+	var template string
+	if err := db.QueryRowContext(ctx, `
+		SELECT COALESCE((
+			SELECT TRIM(invoice_number_template)
+			FROM account_settings
+			WHERE account_id = ?
+		), '');
+	`, accountID).Scan(&template); err != nil {
+		return nil, fmt.Errorf("load invoice number template: %w", err)
+	}
+
+	taken, err := queryPeriodSeqs(ctx, db, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make(map[string]map[int64]models.InvoiceNumberDeletion)
+	for _, t := range tombstones {
+		if !t.Period.Valid || !t.Seq.Valid {
+			continue
+		}
+		if deleted[t.Period.String] == nil {
+			deleted[t.Period.String] = make(map[int64]models.InvoiceNumberDeletion)
+		}
+		deleted[t.Period.String][t.Seq.Int64] = t.Deletion
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT period, next_seq
+		FROM invoice_number_periods
+		WHERE account_id = ?
+		ORDER BY period ASC;
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query invoice number periods: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.InvoiceNumberPeriod
+	for rows.Next() {
+		p := models.InvoiceNumberPeriod{Gaps: make([]models.InvoiceNumberLabelGap, 0)}
+		if err := rows.Scan(&p.Period, &p.NextSeq); err != nil {
+			return nil, fmt.Errorf("scan invoice number period: %w", err)
+		}
+		p.InvoiceCount = int64(len(taken[p.Period]))
+
+		label := func(seq int64) string {
+			if template == "" {
+				return ""
+			}
+			return invoiceformat.RenderNumberTemplate(template, invoiceformat.NumberFields{
+				IssueDate: periodIssueDate(p.Period),
+				Seq:       seq,
+			})
+		}
+
+		for seq := int64(1); seq < p.NextSeq; seq++ {
+			if taken[p.Period][seq] {
+				continue
+			}
+
+			if deletion, ok := deleted[p.Period][seq]; ok {
+				reason := models.NumberGapDeletedInvoice
+				if deletion.Status == "draft" {
+					reason = models.NumberGapDeletedDraft
+				}
+				deletedLabel := label(seq)
+				if deletion.NumberLabel != nil {
+					deletedLabel = *deletion.NumberLabel
+				}
+				p.Gaps = append(p.Gaps, models.InvoiceNumberLabelGap{
+					FromSeq:   seq,
+					ToSeq:     seq,
+					FromLabel: deletedLabel,
+					ToLabel:   deletedLabel,
+					Reason:    reason,
+					Deletion:  &deletion,
+				})
+				continue
+			}
+
+			if n := len(p.Gaps); n > 0 && p.Gaps[n-1].Reason == models.NumberGapUnknown && p.Gaps[n-1].ToSeq == seq-1 {
+				p.Gaps[n-1].ToSeq = seq
+				p.Gaps[n-1].ToLabel = label(seq)
+				continue
+			}
+			p.Gaps = append(p.Gaps, models.InvoiceNumberLabelGap{
+				FromSeq:   seq,
+				ToSeq:     seq,
+				FromLabel: label(seq),
+				ToLabel:   label(seq),
+				Reason:    models.NumberGapUnknown,
+			})
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("invoice number period rows: %w", err)
+	}
+	return out, nil
+}
+
+// queryPeriodSeqs returns the template sequence numbers in use, by period.
+func queryPeriodSeqs(ctx context.Context, db *sql.DB, accountID int64) (map[string]map[int64]bool, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT number_period, number_seq
+		FROM invoices
+		WHERE account_id = ? AND number_period IS NOT NULL AND number_seq IS NOT NULL;
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query invoice template numbers: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]map[int64]bool)
+	for rows.Next() {
+		var (
+			period string
+			seq    int64
+		)
+		if err := rows.Scan(&period, &seq); err != nil {
+			return nil, fmt.Errorf("scan invoice template number: %w", err)
+		}
+		if out[period] == nil {
+			out[period] = make(map[int64]bool)
+		}
+		out[period][seq] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("invoice template number rows: %w", err)
+	}
+	return out, nil
+}
+
+// periodIssueDate is the first day of period, so a template renders the
+// period's year and month from it.
+func periodIssueDate(period string) string {
+	switch len(period) {
+	case len("2006-01"):
+		return period + "-01"
+	case len("2006"):
+		return period + "-01-01"
+	default:
+		return ""
+	}
+}
+
+func queryBaseNumbers(ctx context.Context, db *sql.DB, accountID int64) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT base_number
+		FROM invoices
+		WHERE account_id = ?
+		ORDER BY base_number ASC;
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query invoice base numbers: %w", err)
+	}
+	defer rows.Close()
+
+	var numbers []int64
+	for rows.Next() {
+		var n int64
+		if err := rows.Scan(&n); err != nil {
+			return nil, fmt.Errorf("scan invoice base number: %w", err)
+		}
+		numbers = append(numbers, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("invoice base number rows: %w", err)
+	}
+	return numbers, nil
+}
+
+// tombstone is a deleted invoice with the numbers it held. Period and Seq are
+// NULL when it had no template number.
+type tombstone struct {
+	BaseNumber int64
+	Period     sql.NullString
+	Seq        sql.NullInt64
+	Deletion   models.InvoiceNumberDeletion
+}
+
+// queryTombstones returns every deletion, oldest first.
+func queryTombstones(ctx context.Context, db *sql.DB, accountID int64) ([]tombstone, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT
+			base_number,
+			number_period,
+			number_seq,
+			client_id,
+			number_label,
+			status,
+			issue_date,
+			total_minor,
+			deleted_at,
+			deleted_by_email
+		FROM invoice_tombstones
+		WHERE account_id = ?
+		ORDER BY id ASC;
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query invoice tombstones: %w", err)
+	}
+	defer rows.Close()
+
+	var out []tombstone
+	for rows.Next() {
+		var (
+			t         tombstone
+			label     sql.NullString
+			issueDate sql.NullString
+			total     sql.NullInt64
+			email     sql.NullString
+		)
+		d := &t.Deletion
+		if err := rows.Scan(&t.BaseNumber, &t.Period, &t.Seq, &d.ClientID, &label, &d.Status, &issueDate, &total, &d.DeletedAt, &email); err != nil {
+			return nil, fmt.Errorf("scan invoice tombstone: %w", err)
+		}
+		d.NumberLabel = nullStringPtr(label)
+		d.IssueDate = nullStringPtr(issueDate)
+		d.DeletedByEmail = nullStringPtr(email)
+		if total.Valid {
+			d.TotalMinor = &total.Int64
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("invoice tombstone rows: %w", err)
+	}
+	return out, nil
+}
+
+func queryStartChanges(ctx context.Context, db *sql.DB, accountID int64) ([]models.InvoiceNumberStartChange, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT from_number, to_number, changed_at, changed_by_email
+		FROM invoice_number_start_changes
+		WHERE account_id = ?
+		ORDER BY id ASC;
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("query starting number changes: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.InvoiceNumberStartChange
+	for rows.Next() {
+		var (
+			c     models.InvoiceNumberStartChange
+			email sql.NullString
+		)
+		if err := rows.Scan(&c.FromNumber, &c.ToNumber, &c.ChangedAt, &email); err != nil {
+			return nil, fmt.Errorf("scan starting number change: %w", err)
+		}
+		c.ChangedByEmail = nullStringPtr(email)
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("starting number change rows: %w", err)
+	}
+	return out, nil
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := v.String
+	return &s
+}
@@ -0,0 +1,176 @@
+package reportsTx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/accountscope"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/invoiceTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/reportsTx"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+	"github.com/viktorHadz/goInvoice26/internal/userscope"
+)
+
+func TestQueryInvoiceNumberGaps_ExplainsDeletionsAndStartChanges(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	ctx = userscope.WithPrincipal(ctx, userscope.Principal{
+		UserID:    1,
+		AccountID: accountscope.DefaultAccountID,
+		Email:     "owner@example.com",
+		Role:      "owner",
+	})
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	settings, err := settingsTx.Get(ctx, a.DB, accountscope.DefaultAccountID)
+	if err != nil {
+		t.Fatalf("get settings: %v", err)
+	}
+	settings.StartingInvoiceNumber = 10
+	if err := settingsTx.Upsert(ctx, a.DB, accountscope.DefaultAccountID, settings); err != nil {
+		t.Fatalf("change starting number: %v", err)
+	}
+
+	clientID := insertClient(t, a, "Acme")
+	for _, inv := range []struct {
+		base   int64
+		status string
+	}{
+		{10, "issued"}, {11, "draft"}, {12, "issued"}, {13, "issued"}, {15, "paid"},
+	} {
+		insertInvoice(t, a, clientID, inv.base, inv.status, nil, 1000, 0)
+	}
+	if _, err := a.DB.Exec(`UPDATE invoice_number_seq SET next_base_number = 17 WHERE account_id = 1`); err != nil {
+		t.Fatalf("advance sequence: %v", err)
+	}
+
+	for _, base := range []int64{11, 13} {
+		if err := invoiceTx.Delete(ctx, a, clientID, base); err != nil {
+			t.Fatalf("delete invoice %d: %v", base, err)
+		}
+	}
+
+	report, err := reportsTx.QueryInvoiceNumberGaps(ctx, a.DB)
+	if err != nil {
+		t.Fatalf("QueryInvoiceNumberGaps: %v", err)
+	}
+
+	if report.FirstNumber != 10 || report.LastNumber != 16 || report.InvoiceCount != 3 || report.SequenceBehind {
+		t.Fatalf("report = first %d last %d count %d behind %v, want 10 16 3 false",
+			report.FirstNumber, report.LastNumber, report.InvoiceCount, report.SequenceBehind)
+	}
+
+	want := []struct {
+		from, to int64
+		reason   string
+	}{
+		{11, 11, models.NumberGapDeletedDraft},
+		{13, 13, models.NumberGapDeletedInvoice},
+		{14, 14, models.NumberGapUnknown},
+		{16, 16, models.NumberGapUnknown},
+	}
+	if len(report.Gaps) != len(want) {
+		t.Fatalf("gaps = %+v, want %d", report.Gaps, len(want))
+	}
+	for i, w := range want {
+		got := report.Gaps[i]
+		if got.FromNumber != w.from || got.ToNumber != w.to || got.Reason != w.reason {
+			t.Fatalf("gap %d = %d-%d %s, want %d-%d %s", i, got.FromNumber, got.ToNumber, got.Reason, w.from, w.to, w.reason)
+		}
+	}
+	deletion := report.Gaps[0].Deletion
+	if deletion == nil || deletion.DeletedByEmail == nil || *deletion.DeletedByEmail != "owner@example.com" {
+		t.Fatalf("deletion = %+v, want deleted by owner@example.com", deletion)
+	}
+
+	// Deleting everything and restarting higher leaves the skipped run explained.
+	for _, base := range []int64{10, 12, 15} {
+		if err := invoiceTx.Delete(ctx, a, clientID, base); err != nil {
+			t.Fatalf("delete invoice %d: %v", base, err)
+		}
+	}
+	settings.StartingInvoiceNumber = 30
+	if err := settingsTx.Upsert(ctx, a.DB, accountscope.DefaultAccountID, settings); err != nil {
+		t.Fatalf("restart numbering: %v", err)
+	}
+	insertInvoice(t, a, clientID, 30, "issued", nil, 1000, 0)
+
+	report, err = reportsTx.QueryInvoiceNumberGaps(ctx, a.DB)
+	if err != nil {
+		t.Fatalf("QueryInvoiceNumberGaps after restart: %v", err)
+	}
+	gap := report.Gaps[len(report.Gaps)-1]
+	if gap.FromNumber != 17 || gap.ToNumber != 29 || gap.Reason != models.NumberGapStartChanged || gap.StartChange == nil || gap.StartChange.ToNumber != 30 {
+		t.Fatalf("last gap = %+v, want 17-29 start_changed to 30", gap)
+	}
+	if !report.SequenceBehind {
+		t.Fatal("SequenceBehind = false, want true while the sequence still points at 30")
+	}
+}
+
+func TestQueryInvoiceNumberGaps_ChecksTemplateSequences(t *testing.T) {
+	ctx := accountscope.WithAccountID(context.Background(), accountscope.DefaultAccountID)
+	a, cleanup := newTestApp(t)
+	defer cleanup()
+
+	if _, err := a.DB.Exec(`
+		INSERT INTO account_settings (account_id, invoice_number_template)
+		VALUES (1, 'INV-{YYYY}-{SEQ:3}')
+		ON CONFLICT(account_id) DO UPDATE SET invoice_number_template = excluded.invoice_number_template;
+	`); err != nil {
+		t.Fatalf("set number template: %v", err)
+	}
+
+	clientID := insertClient(t, a, "Acme")
+	for seq, status := range map[int64]string{1: "issued", 2: "draft", 3: "issued", 5: "paid"} {
+		base := 20 + seq
+		insertInvoice(t, a, clientID, base, status, nil, 1000, 0)
+		if _, err := a.DB.Exec(`
+			UPDATE invoices
+			SET number_label = printf('INV-2026-%03d', ?), number_period = '2026', number_seq = ?
+			WHERE account_id = 1 AND base_number = ?
+		`, seq, seq, base); err != nil {
+			t.Fatalf("number invoice %d: %v", base, err)
+		}
+	}
+	if _, err := a.DB.Exec(`
+		INSERT INTO invoice_number_periods (account_id, period, next_seq) VALUES (1, '2026', 8)
+	`); err != nil {
+		t.Fatalf("insert period: %v", err)
+	}
+	if err := invoiceTx.Delete(ctx, a, clientID, 22); err != nil {
+		t.Fatalf("delete invoice 22: %v", err)
+	}
+
+	report, err := reportsTx.QueryInvoiceNumberGaps(ctx, a.DB)
+	if err != nil {
+		t.Fatalf("QueryInvoiceNumberGaps: %v", err)
+	}
+	if len(report.Periods) != 1 {
+		t.Fatalf("periods = %+v, want 1", report.Periods)
+	}
+	period := report.Periods[0]
+	if period.Period != "2026" || period.NextSeq != 8 || period.InvoiceCount != 3 {
+		t.Fatalf("period = %s next %d count %d, want 2026 8 3", period.Period, period.NextSeq, period.InvoiceCount)
+	}
+
+	want := []models.InvoiceNumberLabelGap{
+		{FromSeq: 2, ToSeq: 2, FromLabel: "INV-2026-002", ToLabel: "INV-2026-002", Reason: models.NumberGapDeletedDraft},
+		{FromSeq: 4, ToSeq: 4, FromLabel: "INV-2026-004", ToLabel: "INV-2026-004", Reason: models.NumberGapUnknown},
+		{FromSeq: 6, ToSeq: 7, FromLabel: "INV-2026-006", ToLabel: "INV-2026-007", Reason: models.NumberGapUnknown},
+	}
+	if len(period.Gaps) != len(want) {
+		t.Fatalf("gaps = %+v, want %d", period.Gaps, len(want))
+	}
+	for i, w := range want {
+		got := period.Gaps[i]
+		got.Deletion = nil
+		if got != w {
+			t.Fatalf("gap %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if period.Gaps[0].Deletion == nil {
+		t.Fatal("deleted gap has no deletion record")
+	}
+}
@@ -22,6 +22,51 @@ func EnsureAccountSettingsColumns(ctx context.Context, tx *sql.Tx) error {
 		return fmt.Errorf("backfill account_settings.show_item_type_headers: %w", err)
 	}
 
+	if err := ensureTableColumn(ctx, tx, "account_settings", "quote_prefix", `
+		ALTER TABLE account_settings
+		ADD COLUMN quote_prefix TEXT NOT NULL DEFAULT 'QUO-';
+	`); err != nil {
+		return fmt.Errorf("ensure account_settings.quote_prefix: %w", err)
+	}
+
+	if err := ensureTableColumn(ctx, tx, "account_settings", "invoice_number_template", `
+		ALTER TABLE account_settings
+		ADD COLUMN invoice_number_template TEXT NOT NULL DEFAULT '';
+	`); err != nil {
+		return fmt.Errorf("ensure account_settings.invoice_number_template: %w", err)
+	}
+
+	if err := ensureTableColumn(ctx, tx, "account_settings", "timezone", `
+		ALTER TABLE account_settings
+		ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+	`); err != nil {
+		return fmt.Errorf("ensure account_settings.timezone: %w", err)
+	}
+
+	if err := ensureTableColumn(ctx, tx, "account_settings", "late_interest_rate_bps", `
+		ALTER TABLE account_settings
+		ADD COLUMN late_interest_rate_bps INTEGER NOT NULL DEFAULT 800
+			CHECK (late_interest_rate_bps BETWEEN 0 AND 10000);
+	`); err != nil {
+		return fmt.Errorf("ensure account_settings.late_interest_rate_bps: %w", err)
+	}
+
+	if err := ensureTableColumn(ctx, tx, "account_settings", "late_base_rate_bps", `
+		ALTER TABLE account_settings
+		ADD COLUMN late_base_rate_bps INTEGER NOT NULL DEFAULT 0
+			CHECK (late_base_rate_bps BETWEEN 0 AND 10000);
+	`); err != nil {
+		return fmt.Errorf("ensure account_settings.late_base_rate_bps: %w", err)
+	}
+
+	if err := ensureTableColumn(ctx, tx, "account_settings", "late_compensation_tiers", `
+		ALTER TABLE account_settings
+		ADD COLUMN late_compensation_tiers TEXT NOT NULL
+			DEFAULT '`+DefaultLateCompensationTiersJSON+`';
+	`); err != nil {
+		return fmt.Errorf("ensure account_settings.late_compensation_tiers: %w", err)
+	}
+
 	if err := ensureTableColumn(ctx, tx, "account_settings", "legacy_logo_url", `
 		ALTER TABLE account_settings
 		ADD COLUMN legacy_logo_url TEXT NOT NULL DEFAULT '';
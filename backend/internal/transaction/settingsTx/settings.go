@@ -3,14 +3,21 @@ package settingsTx
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/userscope"
 )
 
 const StoredFileKindLogo = "logo"
 
+// DefaultLateCompensationTiersJSON mirrors the UK statutory fixed sums:
+// £40 under £1,000, £70 under £10,000 and £100 above.
+const DefaultLateCompensationTiersJSON = `[{"fromMinor":0,"amountMinor":4000},{"fromMinor":100000,"amountMinor":7000},{"fromMinor":1000000,"amountMinor":10000}]`
+
 var (
 	ErrStartingInvoiceNumberLocked  = errors.New("starting invoice number cannot be changed while invoices exist")
 	ErrStartingInvoiceNumberInvalid = errors.New("starting invoice number must be greater than 0")
@@ -45,8 +52,14 @@ func Get(ctx context.Context, db *sql.DB, accountID int64) (models.Settings, err
 			s.phone,
 			s.company_address,
 			s.invoice_prefix,
+			s.quote_prefix,
+			s.invoice_number_template,
 			s.currency,
 			s.date_format,
+			s.timezone,
+			s.late_interest_rate_bps,
+			s.late_base_rate_bps,
+			s.late_compensation_tiers,
 			s.payment_terms,
 			s.payment_details,
 			s.notes_footer,
@@ -59,15 +72,24 @@ func Get(ctx context.Context, db *sql.DB, accountID int64) (models.Settings, err
 		WHERE s.account_id = ?;
 	`
 
-	var s models.Settings
+	var (
+		s         models.Settings
+		tiersJSON string
+	)
 	err := db.QueryRowContext(ctx, q, accountID).Scan(
 		&s.CompanyName,
 		&s.Email,
 		&s.Phone,
 		&s.CompanyAddress,
 		&s.InvoicePrefix,
+		&s.QuotePrefix,
+		&s.InvoiceNumberTemplate,
 		&s.Currency,
 		&s.DateFormat,
+		&s.Timezone,
+		&s.LateInterestRate,
+		&s.LateBaseRate,
+		&tiersJSON,
 		&s.PaymentTerms,
 		&s.PaymentDetails,
 		&s.NotesFooter,
@@ -78,6 +100,9 @@ func Get(ctx context.Context, db *sql.DB, accountID int64) (models.Settings, err
 	if err != nil {
 		return models.Settings{}, fmt.Errorf("get settings: %w", err)
 	}
+	if err := json.Unmarshal([]byte(tiersJSON), &s.LateCompensationTiers); err != nil {
+		return models.Settings{}, fmt.Errorf("decode late compensation tiers: %w", err)
+	}
 	if s.LogoStorageKey == "" {
 		s.LogoAssetID = 0
 	}
@@ -117,22 +142,34 @@ func Upsert(ctx context.Context, db *sql.DB, accountID int64, s models.Settings)
 			phone,
 			company_address,
 			invoice_prefix,
+			quote_prefix,
+			invoice_number_template,
 			currency,
 			date_format,
+			timezone,
+			late_interest_rate_bps,
+			late_base_rate_bps,
+			late_compensation_tiers,
 			payment_terms,
 			payment_details,
 			notes_footer,
 			show_item_type_headers,
 			updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, strftime('%Y-%m-%dT%H:%M:%fZ','now'))
 		ON CONFLICT(account_id) DO UPDATE SET
 			company_name = excluded.company_name,
 			email = excluded.email,
 			phone = excluded.phone,
 			company_address = excluded.company_address,
 			invoice_prefix = excluded.invoice_prefix,
+			quote_prefix = excluded.quote_prefix,
+			invoice_number_template = excluded.invoice_number_template,
 			currency = excluded.currency,
 			date_format = excluded.date_format,
+			timezone = excluded.timezone,
+			late_interest_rate_bps = excluded.late_interest_rate_bps,
+			late_base_rate_bps = excluded.late_base_rate_bps,
+			late_compensation_tiers = excluded.late_compensation_tiers,
 			payment_terms = excluded.payment_terms,
 			payment_details = excluded.payment_details,
 			notes_footer = excluded.notes_footer,
@@ -140,6 +177,15 @@ func Upsert(ctx context.Context, db *sql.DB, accountID int64, s models.Settings)
 			updated_at = strftime('%Y-%m-%dT%H:%M:%fZ','now');
 	`
 
+	tiers := s.LateCompensationTiers
+	if tiers == nil {
+		tiers = []models.LateCompensationTier{}
+	}
+	tiersJSON, err := json.Marshal(tiers)
+	if err != nil {
+		return fmt.Errorf("encode late compensation tiers: %w", err)
+	}
+
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin settings upsert tx: %w", err)
@@ -184,6 +230,23 @@ func Upsert(ctx context.Context, db *sql.DB, accountID int64, s models.Settings)
 		`, s.StartingInvoiceNumber, accountID); err != nil {
 			return fmt.Errorf("update invoice number sequence: %w", err)
 		}
+
+		var (
+			userID any
+			email  any
+		)
+		if principal, ok := userscope.PrincipalFromContext(ctx); ok {
+			userID = principal.UserID
+			if v := strings.TrimSpace(principal.Email); v != "" {
+				email = v
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO invoice_number_start_changes (account_id, from_number, to_number, changed_by_user_id, changed_by_email)
+			VALUES (?, ?, ?, ?, ?);
+		`, accountID, currentSequence, s.StartingInvoiceNumber, userID, email); err != nil {
+			return fmt.Errorf("record starting invoice number change: %w", err)
+		}
 	}
 
 	if _, err := tx.ExecContext(
@@ -195,8 +258,14 @@ func Upsert(ctx context.Context, db *sql.DB, accountID int64, s models.Settings)
 		s.Phone,
 		s.CompanyAddress,
 		s.InvoicePrefix,
+		s.QuotePrefix,
+		s.InvoiceNumberTemplate,
 		s.Currency,
 		s.DateFormat,
+		s.Timezone,
+		s.LateInterestRate,
+		s.LateBaseRate,
+		string(tiersJSON),
 		s.PaymentTerms,
 		s.PaymentDetails,
 		s.NotesFooter,
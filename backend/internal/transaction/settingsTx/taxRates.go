@@ -0,0 +1,221 @@
+package settingsTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/viktorHadz/goInvoice26/internal/dberr"
+	"github.com/viktorHadz/goInvoice26/internal/models"
+)
+
+var (
+	// ErrTaxRateNotFound is returned when a tax rate does not exist in the workspace.
+	ErrTaxRateNotFound = errors.New("tax rate not found")
+	// ErrTaxRateNameTaken is returned when another rate in the workspace has the same name.
+	ErrTaxRateNameTaken = errors.New("tax rate name already in use")
+)
+
+const taxRateSelectSQL = `
+	SELECT
+		id,
+		name,
+		rate_bps,
+		effective_from,
+		effective_to,
+		is_default,
+		created_at,
+		updated_at
+	FROM tax_rates
+`
+
+type taxRateScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTaxRate(row taxRateScanner) (models.TaxRate, error) {
+	var out models.TaxRate
+	err := row.Scan(
+		&out.ID,
+		&out.Name,
+		&out.RateBps,
+		&out.EffectiveFrom,
+		&out.EffectiveTo,
+		&out.IsDefault,
+		&out.CreatedAt,
+		&out.UpdatedAt,
+	)
+	return out, err
+}
+
+// ListTaxRates returns the workspace tax rates, the default first.
+func ListTaxRates(ctx context.Context, db *sql.DB, accountID int64) ([]models.TaxRate, error) {
+	rows, err := db.QueryContext(ctx, taxRateSelectSQL+`
+		WHERE account_id = ?
+		ORDER BY is_default DESC, name COLLATE NOCASE ASC, id ASC
+	`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("list tax rates: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]models.TaxRate, 0)
+	for rows.Next() {
+		rate, err := scanTaxRate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan tax rate: %w", err)
+		}
+		out = append(out, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("tax rate rows: %w", err)
+	}
+	return out, nil
+}
+
+// GetTaxRate loads one workspace tax rate. q may be a *sql.DB or a *sql.Tx.
+func GetTaxRate(ctx context.Context, q queryRowScanner, accountID, taxRateID int64) (models.TaxRate, error) {
+	rate, err := scanTaxRate(q.QueryRowContext(ctx, taxRateSelectSQL+`
+		WHERE id = ? AND account_id = ?
+	`, taxRateID, accountID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.TaxRate{}, ErrTaxRateNotFound
+	}
+	if err != nil {
+		return models.TaxRate{}, fmt.Errorf("get tax rate: %w", err)
+	}
+	return rate, nil
+}
+
+// CreateTaxRate adds a rate to the workspace registry. in must already be
+// validated. A new default replaces the previous one.
+func CreateTaxRate(ctx context.Context, db *sql.DB, accountID int64, in models.TaxRateIn) (models.TaxRate, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return models.TaxRate{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if in.IsDefault {
+		if err := clearDefaultTaxRate(ctx, tx, accountID); err != nil {
+			return models.TaxRate{}, err
+		}
+	}
+
+	var taxRateID int64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO tax_rates (account_id, name, rate_bps, effective_from, effective_to, is_default)
+		VALUES (?, ?, ?, ?, ?, ?)
+		RETURNING id;
+	`, accountID, in.Name, in.RateBps, in.EffectiveFrom, in.EffectiveTo, in.IsDefault).Scan(&taxRateID); err != nil {
+		if dberr.IsUniqueViolation(err) {
+			return models.TaxRate{}, ErrTaxRateNameTaken
+		}
+		return models.TaxRate{}, fmt.Errorf("insert tax rate: %w", err)
+	}
+
+	out, err := GetTaxRate(ctx, tx, accountID, taxRateID)
+	if err != nil {
+		return models.TaxRate{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TaxRate{}, fmt.Errorf("commit tax rate: %w", err)
+	}
+	return out, nil
+}
+
+// UpdateTaxRate replaces a workspace tax rate. in must already be validated.
+// Revisions that used the rate keep the name and rate they were saved with.
+func UpdateTaxRate(ctx context.Context, db *sql.DB, accountID, taxRateID int64, in models.TaxRateIn) (models.TaxRate, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return models.TaxRate{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if in.IsDefault {
+		if err := clearDefaultTaxRate(ctx, tx, accountID); err != nil {
+			return models.TaxRate{}, err
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE tax_rates
+		SET
+			name = ?,
+			rate_bps = ?,
+			effective_from = ?,
+			effective_to = ?,
+			is_default = ?,
+			updated_at = (strftime('%Y-%m-%dT%H:%M:%fZ','now'))
+		WHERE id = ? AND account_id = ?
+	`, in.Name, in.RateBps, in.EffectiveFrom, in.EffectiveTo, in.IsDefault, taxRateID, accountID)
+	if err != nil {
+		if dberr.IsUniqueViolation(err) {
+			return models.TaxRate{}, ErrTaxRateNameTaken
+		}
+		return models.TaxRate{}, fmt.Errorf("update tax rate: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return models.TaxRate{}, fmt.Errorf("update tax rate rows affected: %w", err)
+	}
+	if n == 0 {
+		return models.TaxRate{}, ErrTaxRateNotFound
+	}
+
+	out, err := GetTaxRate(ctx, tx, accountID, taxRateID)
+	if err != nil {
+		return models.TaxRate{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TaxRate{}, fmt.Errorf("commit tax rate: %w", err)
+	}
+	return out, nil
+}
+
+// DeleteTaxRate removes a workspace tax rate. Products and revisions that
+// referenced it lose the link; revisions keep their snapshot.
+func DeleteTaxRate(ctx context.Context, db *sql.DB, accountID, taxRateID int64) error {
+	result, err := db.ExecContext(ctx, `
+		DELETE FROM tax_rates
+		WHERE id = ? AND account_id = ?
+	`, taxRateID, accountID)
+	if err != nil {
+		return fmt.Errorf("delete tax rate: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("delete tax rate rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrTaxRateNotFound
+	}
+	return nil
+}
+
+// TaxRateEffectiveOn reports whether rate is in effect on date (YYYY-MM-DD).
+// Both ends of the range are inclusive.
+func TaxRateEffectiveOn(rate models.TaxRate, date string) bool {
+	if rate.EffectiveFrom != nil && date < *rate.EffectiveFrom {
+		return false
+	}
+	if rate.EffectiveTo != nil && date > *rate.EffectiveTo {
+		return false
+	}
+	return true
+}
+
+func clearDefaultTaxRate(ctx context.Context, exec execer, accountID int64) error {
+	if _, err := exec.ExecContext(ctx, `
+		UPDATE tax_rates
+		SET is_default = 0
+		WHERE account_id = ? AND is_default = 1
+	`, accountID); err != nil {
+		return fmt.Errorf("clear default tax rate: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package settingsTx_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/viktorHadz/goInvoice26/internal/models"
+	"github.com/viktorHadz/goInvoice26/internal/transaction/settingsTx"
+)
+
+func TestTaxRates_KeepsOneDefaultPerWorkspace(t *testing.T) {
+	conn, cleanup := newSettingsDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	const accountID = 1
+
+	standard, err := settingsTx.CreateTaxRate(ctx, conn, accountID, models.TaxRateIn{Name: "Standard", RateBps: 2000, IsDefault: true})
+	if err != nil {
+		t.Fatalf("CreateTaxRate standard: %v", err)
+	}
+	reduced, err := settingsTx.CreateTaxRate(ctx, conn, accountID, models.TaxRateIn{Name: "Reduced", RateBps: 500, IsDefault: true})
+	if err != nil {
+		t.Fatalf("CreateTaxRate reduced: %v", err)
+	}
+	if _, err := settingsTx.CreateTaxRate(ctx, conn, accountID, models.TaxRateIn{Name: "Reduced", RateBps: 0}); !errors.Is(err, settingsTx.ErrTaxRateNameTaken) {
+		t.Fatalf("CreateTaxRate duplicate name error = %v, want %v", err, settingsTx.ErrTaxRateNameTaken)
+	}
+
+	rates, err := settingsTx.ListTaxRates(ctx, conn, accountID)
+	if err != nil {
+		t.Fatalf("ListTaxRates: %v", err)
+	}
+	if len(rates) != 2 || rates[0].ID != reduced.ID || !rates[0].IsDefault || rates[1].IsDefault {
+		t.Fatalf("ListTaxRates() = %+v, want the reduced rate as the only default, listed first", rates)
+	}
+
+	updated, err := settingsTx.UpdateTaxRate(ctx, conn, accountID, standard.ID, models.TaxRateIn{Name: "Standard 2026", RateBps: 2100, IsDefault: true})
+	if err != nil {
+		t.Fatalf("UpdateTaxRate: %v", err)
+	}
+	if updated.Name != "Standard 2026" || updated.RateBps != 2100 || !updated.IsDefault || updated.UpdatedAt == nil {
+		t.Fatalf("UpdateTaxRate() = %+v, want renamed default at 2100", updated)
+	}
+	if got, err := settingsTx.GetTaxRate(ctx, conn, accountID, reduced.ID); err != nil || got.IsDefault {
+		t.Fatalf("GetTaxRate reduced = %+v, %v, want it no longer default", got, err)
+	}
+
+	if err := settingsTx.DeleteTaxRate(ctx, conn, accountID, reduced.ID); err != nil {
+		t.Fatalf("DeleteTaxRate: %v", err)
+	}
+	if err := settingsTx.DeleteTaxRate(ctx, conn, accountID, reduced.ID); !errors.Is(err, settingsTx.ErrTaxRateNotFound) {
+		t.Fatalf("DeleteTaxRate again error = %v, want %v", err, settingsTx.ErrTaxRateNotFound)
+	}
+	if _, err := settingsTx.UpdateTaxRate(ctx, conn, accountID, reduced.ID, models.TaxRateIn{Name: "Gone"}); !errors.Is(err, settingsTx.ErrTaxRateNotFound) {
+		t.Fatalf("UpdateTaxRate deleted error = %v, want %v", err, settingsTx.ErrTaxRateNotFound)
+	}
+}
+
+func TestTaxRateEffectiveOn(t *testing.T) {
+	from := "2026-01-01"
+	to := "2026-12-31"
+	rate := models.TaxRate{EffectiveFrom: &from, EffectiveTo: &to}
+
+	for date, want := range map[string]bool{
+		"2025-12-31": false,
+		"2026-01-01": true,
+		"2026-12-31": true,
+		"2027-01-01": false,
+	} {
+		if got := settingsTx.TaxRateEffectiveOn(rate, date); got != want {
+			t.Fatalf("TaxRateEffectiveOn(%s) = %v, want %v", date, got, want)
+		}
+	}
+	if !settingsTx.TaxRateEffectiveOn(models.TaxRate{}, "2000-01-01") {
+		t.Fatalf("open-ended rate should always be in effect")
+	}
+}
@@ -0,0 +1,51 @@
+package settingsTx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	// Embed the zone database so workspace time zones resolve on hosts without one.
+	_ "time/tzdata"
+)
+
+const DefaultTimezone = "UTC"
+
+// LoadTimezone resolves an IANA zone name, falling back to UTC for blank or
+// unknown names.
+func LoadTimezone(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidTimezone reports whether name is a loadable IANA zone name.
+func ValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Today returns the current date (YYYY-MM-DD) in the workspace time zone.
+func Today(ctx context.Context, db *sql.DB, accountID int64, now time.Time) (string, error) {
+	var name string
+	err := db.QueryRowContext(ctx, `
+		SELECT timezone
+		FROM account_settings
+		WHERE account_id = ?;
+	`, accountID).Scan(&name)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("get workspace timezone: %w", err)
+	}
+
+	return now.In(LoadTimezone(name)).Format("2006-01-02"), nil
+}